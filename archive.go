@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// getArchivePath returns the folder archived notes are kept in. Notes keep
+// their place in the folder tree there so they can go back where they were.
+func getArchivePath() string {
+	path := config.ArchiveFolder
+	if path == "" {
+		path = ".archive"
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(notesPath, path)
+	}
+	return path
+}
+
+// archiveNote moves the i-th child of the current folder into the archive
+func (m *model) archiveNote(i int) {
+	n := m.currentNode.children[i]
+	rel, err := filepath.Rel(notesPath, n.path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		m.notice = fmt.Sprintf("Can't archive %s", n.path)
+		m.noticeErr = true
+		return
+	}
+	newPath := filepath.Join(getArchivePath(), rel)
+	if _, err := os.Stat(newPath); err == nil {
+		m.notice = fmt.Sprintf("%s is already in the archive", filepath.ToSlash(rel))
+		m.noticeErr = true
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		m.notice = fmt.Sprintf("Could not archive: %v", err)
+		m.noticeErr = true
+		return
+	}
+	if err := os.Rename(n.path, newPath); err != nil {
+		m.notice = fmt.Sprintf("Could not archive: %v", err)
+		m.noticeErr = true
+		return
+	}
+	m.currentNode.children = append(m.currentNode.children[:i], m.currentNode.children[i+1:]...)
+	if m.cursor > 0 && m.cursor >= len(m.currentNode.children) {
+		m.cursor--
+	}
+	m.invalidateTagCache()
+	m.syncSearchIndex()
+	m.notice = "Archived " + n.title
+}
+
+// openArchive lists every archived note, folders included in their labels
+func (m *model) openArchive() {
+	m.archiveRoot = loadNotes(getArchivePath())
+	m.archived = nil
+	var collect func(dir *note)
+	collect = func(dir *note) {
+		for _, child := range dir.children {
+			if child.isDir {
+				collect(child)
+			} else {
+				m.archived = append(m.archived, child)
+			}
+		}
+	}
+	collect(m.archiveRoot)
+	m.archiveCursor = 0
+	m.previousMode = m.mode
+	m.mode = archiveView
+}
+
+// restoreArchived moves the selected archived note back to the folder it
+// was archived from, recreating the folder if it's gone
+func (m *model) restoreArchived() {
+	n := m.archived[m.archiveCursor]
+	rel, err := filepath.Rel(m.archiveRoot.path, n.path)
+	if err != nil {
+		return
+	}
+	dest := filepath.Join(notesPath, rel)
+	if _, err := os.Stat(dest); err == nil {
+		m.notice = fmt.Sprintf("%s already exists", filepath.ToSlash(rel))
+		m.noticeErr = true
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		m.notice = fmt.Sprintf("Could not restore: %v", err)
+		m.noticeErr = true
+		return
+	}
+	if err := os.Rename(n.path, dest); err != nil {
+		m.notice = fmt.Sprintf("Could not restore: %v", err)
+		m.noticeErr = true
+		return
+	}
+	// Drop folders the note leaves empty
+	for dir := filepath.Dir(n.path); dir != m.archiveRoot.path; dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+
+	rootNote := m.currentNode
+	for rootNote.parent != nil {
+		rootNote = rootNote.parent
+	}
+	if restored := ensureNote(rootNote, dest, false); restored != nil {
+		restored.content, restored.title = n.content, n.title
+		restored.favorite, restored.pinned, restored.tags = n.favorite, n.pinned, n.tags
+		restored.modTime = n.modTime
+	}
+	m.sortNotes()
+	m.invalidateTagCache()
+	m.syncSearchIndex()
+
+	m.archived = append(m.archived[:m.archiveCursor], m.archived[m.archiveCursor+1:]...)
+	if m.archiveCursor > 0 && m.archiveCursor >= len(m.archived) {
+		m.archiveCursor--
+	}
+	m.notice = "Restored " + filepath.ToSlash(rel)
+}
+
+func (m *model) updateArchiveView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if len(m.archived) > 0 {
+			m.archiveCursor = (m.archiveCursor + len(m.archived) - 1) % len(m.archived)
+		}
+	case "down", "j":
+		if len(m.archived) > 0 {
+			m.archiveCursor = (m.archiveCursor + 1) % len(m.archived)
+		}
+	case "r":
+		if len(m.archived) > 0 {
+			m.restoreArchived()
+		}
+	case "esc", "q", "A":
+		m.mode = m.previousMode
+		m.archived = nil
+		m.archiveRoot = nil
+	}
+	return m, nil
+}
+
+// archiveListView renders the list of archived notes, height rows at most
+func (m model) archiveListView(width, height int) string {
+	if len(m.archived) == 0 {
+		return "\n  The archive is empty. Press 'a' on a note to archive it."
+	}
+	folderStyle := lipgloss.NewStyle().Faint(true)
+	var s strings.Builder
+	height = max(1, height)
+	offset := max(0, m.archiveCursor-height+1)
+	for i := offset; i < min(offset+height, len(m.archived)); i++ {
+		n := m.archived[i]
+		name := n.title
+		if folder := noteFolder(m.archiveRoot, n); folder != "" {
+			name += folderStyle.Render(" — " + folder)
+		}
+		name = truncate(name, width-6, "…")
+		if i == m.archiveCursor {
+			s.WriteString("> " + selectedStyle.Render(name) + "\n")
+		} else {
+			s.WriteString("  " + name + "\n")
+		}
+	}
+	return s.String()
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// imageExtensions are the attachments linked as images, shown inline by
+// Markdown viewers
+var imageExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp"}
+
+// attachments is the panel listing the files attached to the note being
+// edited
+type attachments struct {
+	files    []string // file names in the note's asset folder
+	cursor   int
+	adding   bool   // typing the path of a file to attach
+	input    string // path typed so far
+	errorMsg string
+}
+
+// assetsFolderName returns the name of the folders attachments are kept in
+func assetsFolderName() string {
+	if config.AssetsFolder == "" {
+		return "assets"
+	}
+	return config.AssetsFolder
+}
+
+// assetDir returns the folder the attachments of the note at path go in:
+// one per note, inside the assets folder next to it
+func assetDir(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return filepath.Join(filepath.Dir(path), assetsFolderName(), base)
+}
+
+// assetLink returns the Markdown link to the attachment name of the note at
+// path, relative to the note
+func assetLink(path, name string) string {
+	rel := filepath.ToSlash(filepath.Join(assetsFolderName(), filepath.Base(assetDir(path)), name))
+	rel = strings.ReplaceAll(rel, " ", "%20")
+	link := "[" + name + "](" + rel + ")"
+	if slices.Contains(imageExtensions, strings.ToLower(filepath.Ext(name))) {
+		link = "!" + link
+	}
+	return link
+}
+
+// openCommand returns the program attachments are opened with
+func openCommand() string {
+	if config.OpenCommand != "" {
+		return config.OpenCommand
+	}
+	if runtime.GOOS == "darwin" {
+		return "open"
+	}
+	return "xdg-open"
+}
+
+// openAttachments shows the attachments panel for the note being edited
+func (m *model) openAttachments() {
+	if m.cursor < 0 {
+		m.notice = "Save the note before attaching files"
+		return
+	}
+	m.attachments = &attachments{}
+	m.loadAttachments()
+}
+
+// loadAttachments lists the files in the edited note's asset folder
+func (m *model) loadAttachments() {
+	entries, _ := os.ReadDir(assetDir(m.currentNotePath))
+	a := m.attachments
+	a.files = nil
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			a.files = append(a.files, entry.Name())
+		}
+	}
+	a.cursor = min(a.cursor, max(0, len(a.files)-1))
+}
+
+// attachFile copies the file at src into the edited note's asset folder and
+// inserts a link to it at the cursor. A file of the same name already
+// attached gets a number added.
+func (m *model) attachFile(src string) error {
+	if home, err := os.UserHomeDir(); err == nil && (src == "~" || strings.HasPrefix(src, "~/")) {
+		src = filepath.Join(home, src[1:])
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if info, err := in.Stat(); err != nil {
+		return err
+	} else if info.IsDir() {
+		return fmt.Errorf("%s is a folder", src)
+	}
+
+	dir := assetDir(m.currentNotePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	name := filepath.Base(src)
+	ext := filepath.Ext(name)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(dir, name)); os.IsNotExist(err) {
+			break
+		}
+		name = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(filepath.Base(src), ext), i, ext)
+	}
+
+	out, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	cursor := m.editor.GetCursor()
+	m.editor.ReplaceRange(cursor, cursor, assetLink(m.currentNotePath, name))
+	return nil
+}
+
+// openAttachment opens an attached file with the system's default program
+func openAttachment(path string) tea.Cmd {
+	command := openCommand()
+	if cmd := commandCheck(command, "open_command"); cmd != nil {
+		return cmd
+	}
+	return func() tea.Msg {
+		if err := exec.Command(command, path).Start(); err != nil {
+			return noticeMsg{text: fmt.Sprintf("Could not open %s: %v", filepath.Base(path), err), isErr: true}
+		}
+		return nil
+	}
+}
+
+// moveAttachments renames the asset folder of a note moved from oldPath to
+// newPath, updating the note's links to its attachments
+func moveAttachments(n *note, oldPath, newPath string) {
+	oldDir, newDir := assetDir(oldPath), assetDir(newPath)
+	if _, err := os.Stat(oldDir); err != nil || oldDir == newDir {
+		return
+	}
+	if err := renamePath(oldDir, newDir); err != nil {
+		return
+	}
+	oldLink := assetsFolderName() + "/" + filepath.Base(oldDir) + "/"
+	newLink := assetsFolderName() + "/" + filepath.Base(newDir) + "/"
+	n.content = strings.ReplaceAll(n.content, "("+oldLink, "("+newLink)
+}
+
+func (m *model) updateAttachments(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	a := m.attachments
+	if a.adding {
+		switch msg.Type {
+		case tea.KeyEnter:
+			if err := m.attachFile(strings.TrimSpace(a.input)); err != nil {
+				a.errorMsg = err.Error()
+				return m, nil
+			}
+			m.attachments = nil
+		case tea.KeyEsc:
+			a.adding = false
+			a.errorMsg = ""
+		case tea.KeyBackspace:
+			if runes := []rune(a.input); len(runes) > 0 {
+				a.input = string(runes[:len(runes)-1])
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			a.input += string(msg.Runes)
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if a.cursor > 0 {
+			a.cursor--
+		}
+	case "down", "j":
+		if a.cursor < len(a.files)-1 {
+			a.cursor++
+		}
+	case "a":
+		a.adding = true
+		a.input = ""
+		a.errorMsg = ""
+	case "enter", "o":
+		if len(a.files) > 0 {
+			return m, openAttachment(filepath.Join(assetDir(m.currentNotePath), a.files[a.cursor]))
+		}
+	case "i":
+		if len(a.files) > 0 {
+			cursor := m.editor.GetCursor()
+			m.editor.ReplaceRange(cursor, cursor, assetLink(m.currentNotePath, a.files[a.cursor]))
+			m.attachments = nil
+		}
+	case "esc", "alt+a":
+		m.attachments = nil
+	}
+	return m, nil
+}
+
+// attachmentsView renders the contents of the attachments panel
+func (m model) attachmentsView() string {
+	a := m.attachments
+	var content strings.Builder
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
+
+	content.WriteString(lipgloss.NewStyle().Bold(true).Render("Attachments") + "\n\n")
+	if a.adding {
+		content.WriteString("File to attach: " + a.input + "█\n")
+		if a.errorMsg != "" {
+			content.WriteString(lipgloss.NewStyle().Foreground(errorColor).Render(a.errorMsg) + "\n")
+		}
+		content.WriteString("\n" + helpStyle.Render("Enter: attach and insert a link | Esc: back"))
+		return content.String()
+	}
+
+	if len(a.files) == 0 {
+		content.WriteString("No files attached yet\n")
+	}
+	for i, name := range a.files {
+		if i == a.cursor {
+			content.WriteString("> " + selectedStyle.Render(name) + "\n")
+		} else {
+			content.WriteString("  " + name + "\n")
+		}
+	}
+	content.WriteString("\n" + helpStyle.Render("a: attach a file | Enter: open | i: insert link | Esc: close"))
+	return content.String()
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// backupTimeFormat names the backups so they sort by time
+const backupTimeFormat = "2006-01-02-150405"
+
+// backupVault writes a tar.gz of the whole notes folder, trash, archive and
+// history included, to a timestamped file in dir, then drops the oldest
+// backups past backup_limit. It returns the path of the new backup.
+func backupVault(dir string) (string, error) {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := "notes-" + time.Now().Format(backupTimeFormat)
+	path := filepath.Join(dir, name+".tar.gz")
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.tar.gz", name, n))
+	}
+
+	// Written under a temporary name so an interrupted backup isn't taken
+	// for a complete one
+	tmp := path + ".part"
+	if err := writeBackup(tmp, dir); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	pruneBackups(dir, config.BackupLimit)
+	return path, nil
+}
+
+// writeBackup archives the notes folder to path, leaving out skip when the
+// backups are kept inside it. Entries are named from the notes folder's
+// own name, so the archive unpacks into a folder.
+func writeBackup(path, skip string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+
+	base := filepath.Dir(notesPath)
+	err = filepath.WalkDir(notesPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p == skip {
+			return filepath.SkipDir
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil // symlinks and the like aren't notes
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(base, p)
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		src, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// pruneBackups removes the oldest backups in dir beyond the newest keep. A
+// keep of 0 keeps them all.
+func pruneBackups(dir string, keep int) {
+	if keep <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var backups []fs.FileInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "notes-") || !strings.HasSuffix(name, ".tar.gz") {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			backups = append(backups, info)
+		}
+	}
+	// By when they were written, as the names of backups made within the
+	// same second don't sort
+	slices.SortFunc(backups, func(a, b fs.FileInfo) int { return a.ModTime().Compare(b.ModTime()) })
+	for len(backups) > keep {
+		os.Remove(filepath.Join(dir, backups[0].Name()))
+		backups = backups[1:]
+	}
+}
+
+// getBackupFolder returns where the backups of the open vault go: a folder
+// named after it in backup_folder, unless it's the default vault, so that
+// pruning one vault's backups leaves the others'
+func getBackupFolder() string {
+	switch {
+	case openedPath != "":
+		return filepath.Join(config.BackupFolder, openedPathKey())
+	case vaultName != "":
+		return filepath.Join(config.BackupFolder, vaultName)
+	}
+	return config.BackupFolder
+}
+
+// backupCmd backs the notes up in the background and reports how it went
+// as a notice
+func backupCmd() tea.Cmd {
+	return func() tea.Msg {
+		path, err := backupVault(getBackupFolder())
+		if err != nil {
+			return noticeMsg{text: fmt.Sprintf("Backup failed: %v", err), isErr: true}
+		}
+		return noticeMsg{text: "Backed up to " + path}
+	}
+}
+
+// runBackup implements "notes backup" and returns the exit code
+func runBackup(args []string) int {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	dir := flags.String("dir", getBackupFolder(), "Folder to write the backup to")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	path, err := backupVault(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "notes backup: %v\n", err)
+		return 1
+	}
+	fmt.Println(path)
+	return 0
+}
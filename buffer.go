@@ -0,0 +1,161 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// lineBuffer is the editor's text, held as a gap buffer of lines: the lines
+// before the gap at the front of the slice, the lines after it at the back,
+// and unused room in between. Edits happen around the cursor, so the gap is
+// moved there and lines are inserted and removed without shifting the rest
+// of the note. Reading a line never moves the gap.
+type lineBuffer struct {
+	lines    []bufferLine
+	gapStart int // the first unused slot, and the number of lines before the gap
+	gapEnd   int // the first line after the gap
+}
+
+// bufferLine is a line of text with what's counted from it, kept until the
+// line changes
+type bufferLine struct {
+	text      []rune
+	rows      int // visual lines at wrapWidth, 0 until counted
+	wrapWidth int
+	words     int // words in text, -1 until counted
+}
+
+// minGap is the room left for new lines when the buffer grows
+const minGap = 64
+
+// newLineBuffer returns a buffer holding text, split at its newlines
+func newLineBuffer(text string) lineBuffer {
+	n := strings.Count(text, "\n") + 1
+	b := lineBuffer{lines: make([]bufferLine, n+minGap), gapStart: n, gapEnd: n + minGap}
+	for i := range n {
+		line, rest, _ := strings.Cut(text, "\n")
+		b.lines[i] = bufferLine{text: []rune(line), words: -1}
+		text = rest
+	}
+	return b
+}
+
+// len returns the number of lines, at least 1 once the buffer is made
+func (b *lineBuffer) len() int {
+	return len(b.lines) - (b.gapEnd - b.gapStart)
+}
+
+// index returns the slot of line i
+func (b *lineBuffer) index(i int) int {
+	if i < b.gapStart {
+		return i
+	}
+	return i + b.gapEnd - b.gapStart
+}
+
+// line returns the text of line i. It's the buffer's own slice: changes to it
+// go back in with set.
+func (b *lineBuffer) line(i int) []rune {
+	return b.lines[b.index(i)].text
+}
+
+// set replaces the text of line i
+func (b *lineBuffer) set(i int, text []rune) {
+	b.lines[b.index(i)] = bufferLine{text: text, words: -1}
+}
+
+// insert adds lines before line i, or at the end when i is len()
+func (b *lineBuffer) insert(i int, texts ...[]rune) {
+	if len(texts) > b.gapEnd-b.gapStart {
+		b.grow(len(texts))
+	}
+	b.moveGap(i)
+	for _, text := range texts {
+		b.lines[b.gapStart] = bufferLine{text: text, words: -1}
+		b.gapStart++
+	}
+}
+
+// remove deletes the lines from line from up to line to
+func (b *lineBuffer) remove(from, to int) {
+	if from >= to {
+		return
+	}
+	b.moveGap(to)
+	clear(b.lines[from:b.gapStart])
+	b.gapStart = from
+}
+
+// moveGap moves the gap to just before line i
+func (b *lineBuffer) moveGap(i int) {
+	switch {
+	case i < b.gapStart:
+		n := b.gapStart - i
+		copy(b.lines[b.gapEnd-n:b.gapEnd], b.lines[i:b.gapStart])
+		clear(b.lines[i:min(b.gapStart, b.gapEnd-n)])
+		b.gapStart, b.gapEnd = i, b.gapEnd-n
+	case i > b.gapStart:
+		n := i - b.gapStart
+		copy(b.lines[b.gapStart:b.gapStart+n], b.lines[b.gapEnd:b.gapEnd+n])
+		clear(b.lines[max(b.gapEnd, b.gapStart+n) : b.gapEnd+n])
+		b.gapStart, b.gapEnd = i, b.gapEnd+n
+	}
+}
+
+// grow makes room in the gap for at least n more lines
+func (b *lineBuffer) grow(n int) {
+	after := len(b.lines) - b.gapEnd
+	size := max(2*len(b.lines), b.len()+n+minGap)
+	lines := make([]bufferLine, size)
+	copy(lines, b.lines[:b.gapStart])
+	copy(lines[size-after:], b.lines[b.gapEnd:])
+	b.lines, b.gapEnd = lines, size-after
+}
+
+// wrappedRows returns the number of visual lines line i takes at width. It's
+// counted once and remembered until the line changes or the width does.
+func (b *lineBuffer) wrappedRows(i, width int) int {
+	l := &b.lines[b.index(i)]
+	if l.rows == 0 || l.wrapWidth != width {
+		l.rows, l.wrapWidth = len(wrapLine(l.text, width)), width
+	}
+	return l.rows
+}
+
+// words returns the number of words in line i, runs of characters between
+// spaces. It's counted once and remembered until the line changes.
+func (b *lineBuffer) words(i int) int {
+	l := &b.lines[b.index(i)]
+	if l.words < 0 {
+		l.words = 0
+		inWord := false
+		for _, r := range l.text {
+			if unicode.IsSpace(r) {
+				inWord = false
+			} else if !inWord {
+				inWord = true
+				l.words++
+			}
+		}
+	}
+	return l.words
+}
+
+// String returns the text, the lines joined with newlines
+func (b *lineBuffer) String() string {
+	var sb strings.Builder
+	n := b.len() - 1
+	for i := range b.len() {
+		n += len(b.line(i))
+	}
+	sb.Grow(n)
+	for i := range b.len() {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		for _, r := range b.line(i) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// bufferLines returns the lines of b as strings
+func bufferLines(b *lineBuffer) []string {
+	var lines []string
+	for i := range b.len() {
+		lines = append(lines, string(b.line(i)))
+	}
+	return lines
+}
+
+func TestLineBuffer(t *testing.T) {
+	b := newLineBuffer("a\nb\nc")
+	if got := bufferLines(&b); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("new buffer has %q", got)
+	}
+	if b := newLineBuffer(""); b.len() != 1 || len(b.line(0)) != 0 {
+		t.Errorf("empty text is %d lines, want one empty line", b.len())
+	}
+
+	b.insert(1, []rune("x"), []rune("y"))
+	b.set(0, []rune("A"))
+	b.remove(3, 4)
+	b.insert(b.len(), []rune("end"))
+	b.insert(0, []rune("start"))
+	if got, want := bufferLines(&b), []string{"start", "A", "x", "y", "c", "end"}; !slices.Equal(got, want) {
+		t.Errorf("buffer has %q, want %q", got, want)
+	}
+	if got := b.String(); got != "start\nA\nx\ny\nc\nend" {
+		t.Errorf("String() is %q", got)
+	}
+}
+
+// Random edits all over the buffer, growing it past its gap many times,
+// leave the same lines as the same edits made to a plain slice
+func TestLineBufferRandomEdits(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	b := newLineBuffer("")
+	want := [][]rune{{}}
+	for step := range 2000 {
+		i := rng.IntN(len(want) + 1)
+		switch op := rng.IntN(4); {
+		case op < 2 || len(want) < 2:
+			var lines [][]rune
+			for n := range rng.IntN(100) + 1 {
+				lines = append(lines, []rune(strings.Repeat("x", n%7)+string(rune('a'+step%26))))
+			}
+			b.insert(i, lines...)
+			want = slices.Insert(want, i, lines...)
+		case op == 2:
+			i = min(i, len(want)-1)
+			j := min(len(want), i+rng.IntN(50))
+			b.remove(i, j)
+			want = slices.Delete(want, i, j)
+		default:
+			i = min(i, len(want)-1)
+			line := []rune(strings.Repeat("y", step%9))
+			b.set(i, line)
+			want[i] = line
+		}
+		if b.len() != len(want) {
+			t.Fatalf("step %d: %d lines, want %d", step, b.len(), len(want))
+		}
+	}
+	for i, line := range want {
+		if string(b.line(i)) != string(line) {
+			t.Fatalf("line %d is %q, want %q", i, string(b.line(i)), string(line))
+		}
+	}
+	// The gap holds no lines, so removed ones can be collected
+	for _, l := range b.lines[b.gapStart:b.gapEnd] {
+		if l.text != nil {
+			t.Fatal("a removed line is still held in the gap")
+		}
+	}
+}
+
+func TestLineBufferCounts(t *testing.T) {
+	b := newLineBuffer("one two  three\n" + strings.Repeat("x", 25))
+	if got := b.words(0); got != 3 {
+		t.Errorf("line 0 has %d words, want 3", got)
+	}
+	if got := b.wrappedRows(1, 10); got != 3 {
+		t.Errorf("25 characters wrap to %d rows at 10, want 3", got)
+	}
+	if got := b.wrappedRows(1, 5); got != 5 {
+		t.Errorf("the count isn't redone for a new width: %d rows at 5, want 5", got)
+	}
+	b.set(0, []rune("four"))
+	b.set(1, []rune("short"))
+	if got := b.words(0); got != 1 {
+		t.Errorf("the count isn't redone for a changed line: %d words, want 1", got)
+	}
+	if got := b.wrappedRows(1, 5); got != 1 {
+		t.Errorf("the count isn't redone for a changed line: %d rows, want 1", got)
+	}
+}
+
+func TestEditorReplaceAllInPlace(t *testing.T) {
+	e := NewEditor()
+	e.SetValue("cat dog cat\nno match\ncat\n\nbobcat")
+	e.SetCursor(14)
+	e.StartFind(true)
+	e.findQuery, e.replaceText = "cat", "lion"
+	e.replaceAll()
+	if got, want := e.Value(), "lion dog lion\nno match\nlion\n\nboblion"; got != want {
+		t.Errorf("replace all gives %q, want %q", got, want)
+	}
+	if e.findStatus != "4 replaced" {
+		t.Errorf("status is %q", e.findStatus)
+	}
+
+	// A replacement can add and remove lines
+	e.SetValue("a-b\na-b")
+	e.findQuery, e.replaceText, e.findRegex = `(\w)-(\w)`, "$1\n$2", true
+	e.replaceAll()
+	if got, want := e.Value(), "a\nb\na\nb"; got != want {
+		t.Errorf("replace all gives %q, want %q", got, want)
+	}
+	e.findQuery, e.replaceText = `a\nb`, "ab"
+	e.replaceAll()
+	if got, want := e.Value(), "ab\nab"; got != want {
+		t.Errorf("replacing across lines gives %q, want %q", got, want)
+	}
+}
+
+// Matching line by line finds what matching the whole note would, and
+// searches that need the whole note still get it
+func TestEditorFindMatchesByLine(t *testing.T) {
+	text := "# Title\nfoo bar\n  Foo baz foo\nend foo\n\nfoo"
+	e := NewEditor()
+	e.SetValue(text)
+	e.StartFind(false)
+	for _, tc := range []struct {
+		query string
+		regex bool
+	}{
+		{"foo", false},
+		{"Foo", false},
+		{"o b", false},
+		{`fo+`, true},
+		{`\bfoo\b`, true},
+		{`(?m)^foo`, true},
+		{`(?m)foo$`, true},
+		{`^#`, true},
+		{`foo$`, true},
+		{`foo\s+\w+`, true},
+		{`bar\nfoo`, true},
+		{`[^a-z]+`, true},
+		{`(?s)bar.*baz`, true},
+	} {
+		e.findQuery, e.findRegex = tc.query, tc.regex
+		got := e.findMatches()
+		want, _ := findTextMatches(text, tc.query, tc.regex)
+		if !slices.Equal(got, want) {
+			t.Errorf("%q: matches %v, want %v", tc.query, got, want)
+		}
+	}
+
+	e.findQuery, e.findRegex = "(", true
+	if got := e.findMatches(); got != nil || !e.findInvalid {
+		t.Errorf("an invalid regex matches %v, invalid %v", got, e.findInvalid)
+	}
+}
+
+func TestRegexSpansLines(t *testing.T) {
+	for query, want := range map[string]bool{
+		`foo`:         false,
+		`(?m)^foo$`:   false,
+		`\bfoo\w*`:    false,
+		`f.o`:         false,
+		`[a-z ]+`:     false,
+		`^foo`:        true,
+		`foo$`:        true,
+		`\s+`:         true,
+		`a\nb`:        true,
+		`[^x]`:        true,
+		`(?s)a.b`:     true,
+		`(x|y\n)+`:    true,
+		`(?i)\Afoo`:   true,
+		`foo\z`:       true,
+		`[[:space:]]`: true,
+	} {
+		re, err := searchRegexp(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := regexSpansLines(re); got != want {
+			t.Errorf("%q spans lines: %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestEditorInsertTextSplices(t *testing.T) {
+	e := NewEditor()
+	e.SetValue("start end")
+	e.SetCursor(6)
+	e.insertText("one\ntwo\nthree ")
+	if got, want := e.Value(), "start one\ntwo\nthree end"; got != want {
+		t.Errorf("text is %q, want %q", got, want)
+	}
+	if row, col := e.CursorLineCol(); row != 3 || col != 7 {
+		t.Errorf("cursor at %d:%d, want 3:7", row, col)
+	}
+	if !e.Dirty() {
+		t.Error("inserting text doesn't mark the editor dirty")
+	}
+
+	e.selectionAnchor, e.hasSelection = 2, true
+	e.SetCursor(20)
+	e.deleteSelection()
+	if got, want := e.Value(), "stend"; got != want {
+		t.Errorf("deleting the selection leaves %q, want %q", got, want)
+	}
+	if e.GetCursor() != 2 {
+		t.Errorf("cursor at %d after deleting the selection, want 2", e.GetCursor())
+	}
+}
+
+func TestEditorBlank(t *testing.T) {
+	e := NewEditor()
+	for text, want := range map[string]bool{"": true, " \n\t\n": true, "\n\nx": false} {
+		e.SetValue(text)
+		if got := e.Blank(); got != want {
+			t.Errorf("%q blank: %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestTagPickerReplacesFilter(t *testing.T) {
+	newTestVault(t)
+	writeTestNote(t, "Tagged.txt", "text #project/alpha")
+	writeTestNote(t, "Plans.txt", "Plans\nnäher und ")
+	m := newTestModel(t, 80, 24)
+	m.openNote(m.currentNode.children[slices.IndexFunc(m.currentNode.children, func(n *note) bool { return n.title == "Plans" })])
+	m.editor.SetCursor(utf8.RuneCountInString("Plans\nnäher"))
+
+	press(m, "#", "p", "r", "o")
+	if !m.showTagPicker || len(m.tagPickerFiltered) == 0 {
+		t.Fatalf("tag picker shows %v", m.tagPickerFiltered)
+	}
+	press(m, "enter")
+	if got, want := m.editor.Value(), "Plans\nnäher#project/alpha und "; got != want {
+		t.Errorf("text is %q, want %q", got, want)
+	}
+	if got, want := m.editor.GetCursor(), utf8.RuneCountInString("Plans\nnäher#project/alpha"); got != want {
+		t.Errorf("cursor at %d, want %d after the tag", got, want)
+	}
+}
+
+// benchmarkNote is a note of about 5 MB of wrapping lines
+func benchmarkNote() string {
+	line := strings.Repeat("lorem ipsum dolor sit amet ", 4) + "\n"
+	return strings.Repeat(line, 5<<20/len(line))
+}
+
+// BenchmarkEditorTyping types into the middle of a long note, redrawing
+// after each key as the app does
+func BenchmarkEditorTyping(b *testing.B) {
+	text := benchmarkNote()
+	e := NewEditor()
+	e.SetWidth(80)
+	e.SetHeight(40)
+	e.Focus()
+	e.SetValue(text)
+	e.SetCursor(len(text) / 2)
+	b.ResetTimer()
+	for i := range b.N {
+		if i%20 == 19 {
+			e.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		} else {
+			e.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+		}
+		e.View()
+		e.CountBody()
+	}
+}
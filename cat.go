@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runCat implements "notes cat" and returns the exit code. It prints the
+// text of each note named, without its frontmatter or favorite line, so
+// other tools can read notes as they're shown in the editor.
+func runCat(root *note, args []string) int {
+	flags := flag.NewFlagSet("cat", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "notes cat: name a note by its title or path")
+		return 2
+	}
+
+	status := 0
+	for _, name := range flags.Args() {
+		n := resolveNoteArg(root, name)
+		if n == nil {
+			fmt.Fprintf(os.Stderr, "notes cat: no note %q in %s\n", name, notesPath)
+			status = 1
+			continue
+		}
+		_, body, _ := parseFrontmatter(n.content)
+		if body = strings.Trim(body, "\n"); body != "" {
+			fmt.Println(body)
+		}
+	}
+	return status
+}
+
+// resolveNoteArg finds the note a command line argument names: a path to
+// the note file, from the current folder or the notes path, or else a title
+// or alias, matched as a wikilink is
+func resolveNoteArg(root *note, name string) *note {
+	candidates := []string{name}
+	if !filepath.IsAbs(name) {
+		if abs, err := filepath.Abs(name); err == nil {
+			candidates[0] = abs
+		}
+		candidates = append(candidates, filepath.Join(notesPath, name))
+	}
+	for _, path := range candidates {
+		if n := findNoteByPath(root, filepath.Clean(path)); n != nil && !n.isDir {
+			return n
+		}
+	}
+	return findNoteByTitle(root, name)
+}
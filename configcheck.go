@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"reflect"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// configVersion is the version of the config format written by this build.
+// Config files without a version are version 0, from before it was recorded.
+const configVersion = 1
+
+// configMigrations[v] brings the options of a version v config up to
+// version v+1, before they're read
+var configMigrations = []func(fields map[string]json.RawMessage){
+	// 0 -> 1: nothing changed but the version being recorded. Colors written
+	// as numbers are still read as they are.
+	func(fields map[string]json.RawMessage) {},
+}
+
+// decodeConfig reads the options in data over cfg one at a time, migrating
+// them first if they're from an older version. An option that can't be
+// read keeps its default and is reported rather than failing the whole
+// file; only a file that isn't a JSON object at all is an error. It also
+// returns the version the file was written with.
+func decodeConfig(data []byte, cfg *Config) (problems []string, version int, err error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, 0, err
+	}
+	if raw, ok := fields["version"]; ok {
+		if json.Unmarshal(raw, &version) != nil || version < 0 {
+			problems = append(problems, fmt.Sprintf("version: %s isn't a version, read as %d", raw, configVersion))
+			version = configVersion
+		}
+	}
+	if version > configVersion {
+		problems = append(problems, fmt.Sprintf("The config is from a newer version of notes (format %d), options this one doesn't know are ignored", version))
+	}
+	for v := version; v < configVersion; v++ {
+		configMigrations[v](fields)
+	}
+
+	known := map[string]bool{}
+	value := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		name := strings.Split(value.Type().Field(i).Tag.Get("json"), ",")[0]
+		known[name] = true
+		raw, ok := fields[name]
+		if !ok || name == "version" {
+			continue
+		}
+		if name == "colors" {
+			problems = append(problems, decodeColors(raw, &cfg.Colors)...)
+			continue
+		}
+		field := reflect.New(value.Field(i).Type())
+		if err := json.Unmarshal(raw, field.Interface()); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s isn't %s, using the default", name, raw, jsonKind(value.Field(i).Type())))
+			continue
+		}
+		value.Field(i).Set(field.Elem())
+	}
+	if version <= configVersion {
+		for _, name := range slices.Sorted(maps.Keys(fields)) {
+			if !known[name] {
+				problems = append(problems, name+": no such option")
+			}
+		}
+	}
+	cfg.Version = max(version, configVersion)
+	return problems, version, nil
+}
+
+// decodeColors reads the colors option one color at a time
+func decodeColors(data []byte, colors *ColorConfig) []string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return []string{"colors: not an object of colors, using the default ones"}
+	}
+	var problems []string
+	value := reflect.ValueOf(colors).Elem()
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		raw := fields[name]
+		i := slices.IndexFunc(reflect.VisibleFields(value.Type()), func(f reflect.StructField) bool { return f.Tag.Get("json") == name })
+		if i < 0 {
+			problems = append(problems, "colors."+name+": no such color")
+			continue
+		}
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			s = string(raw)
+		}
+		color, ok := parseColor(s)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("colors.%s: %s isn't 0-255 or \"#rrggbb\", using the default", name, raw))
+			continue
+		}
+		value.Field(i).SetString(string(color))
+	}
+	return problems
+}
+
+// jsonKind describes the JSON an option of type t takes
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		return "a list"
+	case reflect.Map:
+		return "an object"
+	}
+	return "valid"
+}
+
+// validateConfig checks the values of the options, putting back the
+// default of those that can't work, and returns the problems found
+func validateConfig(cfg *Config) []string {
+	var problems []string
+	defaults := getDefaultConfig()
+
+	choice := func(name string, value *string, def string, allowed ...string) {
+		if !slices.Contains(allowed, *value) {
+			problems = append(problems, fmt.Sprintf("%s: %q isn't one of %s, using %q", name, *value, strings.Join(quoteAll(allowed), ", "), def))
+			*value = def
+		}
+	}
+	var sortModes []string
+	for _, name := range sortModeNames {
+		sortModes = append(sortModes, name)
+	}
+	slices.Sort(sortModes)
+	choice("pinned_section", &cfg.PinnedSection, defaults.PinnedSection, "", "folder", "all")
+	choice("empty_note_action", &cfg.EmptyNoteAction, defaults.EmptyNoteAction, "keep", "prompt", "trash")
+	choice("sort_mode", &cfg.SortMode, defaults.SortMode, sortModes...)
+	choice("tag_sort", &cfg.TagSort, defaults.TagSort, "name", "count")
+	choice("background", &cfg.Background, defaults.Background, "auto", "dark", "light")
+	choice("export_format", &cfg.ExportFormat, defaults.ExportFormat, exportFormats...)
+
+	atLeast := func(name string, value *int, def, least int) {
+		if *value < least {
+			problems = append(problems, fmt.Sprintf("%s: %d is less than %d, using %d", name, *value, least, def))
+			*value = def
+		}
+	}
+	atLeast("tag_picker_limit", &cfg.TagPickerLimit, defaults.TagPickerLimit, 0)
+	atLeast("tag_picker_rows", &cfg.TagPickerRows, defaults.TagPickerRows, 1)
+	atLeast("trash_retention_days", &cfg.TrashRetentionDays, defaults.TrashRetentionDays, 0)
+	atLeast("history_limit", &cfg.HistoryLimit, defaults.HistoryLimit, 0)
+	atLeast("history_days", &cfg.HistoryDays, defaults.HistoryDays, 0)
+	atLeast("recovery_interval", &cfg.RecoveryInterval, defaults.RecoveryInterval, 0)
+	atLeast("backup_limit", &cfg.BackupLimit, defaults.BackupLimit, 0)
+	atLeast("recent_tags_limit", &cfg.RecentTagsLimit, defaults.RecentTagsLimit, 0)
+	atLeast("cursor_positions_limit", &cfg.CursorPositionsLimit, defaults.CursorPositionsLimit, 0)
+	atLeast("recent_notes_limit", &cfg.RecentNotesLimit, defaults.RecentNotesLimit, 0)
+	atLeast("indent_width", &cfg.IndentWidth, defaults.IndentWidth, 1)
+
+	// These can't be fixed here, only pointed out
+	if fields := strings.Fields(cfg.ExternalEditor); len(fields) == 0 {
+		problems = append(problems, "external_editor: not set, ctrl+e won't work")
+	} else if _, err := exec.LookPath(fields[0]); err != nil {
+		problems = append(problems, fmt.Sprintf("external_editor: %q isn't installed or not in PATH", fields[0]))
+	}
+	if _, err := exec.LookPath("git"); cfg.Git && err != nil {
+		problems = append(problems, "git: git isn't installed or not in PATH, saved notes won't be committed")
+	}
+	if cfg.SyncURL != "" {
+		if _, err := newDavClient(cfg.SyncURL, "", ""); err != nil {
+			problems = append(problems, fmt.Sprintf("sync_url: %v", err))
+		}
+	}
+	if err := checkWritable(cfg.NotesPath); err != nil {
+		problems = append(problems, fmt.Sprintf("notes_path: can't write to %s: %v", cfg.NotesPath, err))
+	}
+	problems = append(problems, keymapProblems(cfg)...)
+	return problems
+}
+
+// checkWritable makes sure notes can be written in dir, creating it if
+// needed
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, ".notes-write-check-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+func quoteAll(values []string) []string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return quoted
+}
+
+func (m *model) updateConfigProblems(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter", "esc", "q":
+		m.configProblems = nil
+	}
+	return m, nil
+}
+
+// configProblemsView renders the contents of the popup listing what's wrong
+// with the config
+func (m model) configProblemsView() string {
+	var content strings.Builder
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
+
+	content.WriteString(lipgloss.NewStyle().Bold(true).Render("Config problems") + "\n\n")
+	content.WriteString("In " + getConfigPath() + ":\n\n")
+	const shown = 12
+	for i, problem := range m.configProblems {
+		if i == shown {
+			content.WriteString(fmt.Sprintf("  …and %d more\n", len(m.configProblems)-shown))
+			break
+		}
+		content.WriteString(lipgloss.NewStyle().Foreground(errorColor).Render(truncate("• "+problem, max(20, m.width-14), "…")) + "\n")
+	}
+	content.WriteString("\n" + helpStyle.Render("Enter/Esc: close"))
+	return content.String()
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileCreated returns when the file at path was created
+func fileCreated(path string, info os.FileInfo) time.Time {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Birthtimespec.Unix())
+	}
+	return info.ModTime()
+}
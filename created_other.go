@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// fileCreated returns the file's modification time, as creation times
+// aren't available on this platform
+func fileCreated(path string, info os.FileInfo) time.Time {
+	return info.ModTime()
+}
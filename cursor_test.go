@@ -0,0 +1,162 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPruneCursorPositions(t *testing.T) {
+	newTestVault(t)
+	kept := writeTestNote(t, "Kept.txt", "text")
+	other := writeTestNote(t, "Sub/Other.txt", "text")
+	deleted := filepath.Join(notesPath, "Deleted.txt")
+	// Not there, but for a reason other than the note being deleted
+	unknown := filepath.Join(kept, "child.txt")
+
+	positions := map[string]int{kept: 3, other: 1, deleted: 7, unknown: 2}
+	if !pruneCursorPositions(positions, 0) {
+		t.Error("nothing reported pruned")
+	}
+	if want := map[string]int{kept: 3, other: 1, unknown: 2}; !maps.Equal(positions, want) {
+		t.Errorf("pruned to %v, want %v", positions, want)
+	}
+	if pruneCursorPositions(positions, 0) {
+		t.Error("pruning again reports entries pruned")
+	}
+}
+
+func TestPruneCursorPositionsLimit(t *testing.T) {
+	newTestVault(t)
+	positions := make(map[string]int)
+	var paths []string
+	now := time.Now()
+	for i, name := range []string{"A.txt", "B.txt", "C.txt", "D.txt"} {
+		path := writeTestNote(t, name, "text")
+		// D was modified last
+		modTime := now.Add(time.Duration(i-4) * time.Hour)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		positions[path] = i
+		paths = append(paths, path)
+	}
+
+	if pruneCursorPositions(positions, 4) {
+		t.Error("entries within the limit reported pruned")
+	}
+	if !pruneCursorPositions(positions, 2) {
+		t.Error("nothing reported pruned past the limit")
+	}
+	got := slices.Sorted(maps.Keys(positions))
+	if want := paths[2:]; !slices.Equal(got, want) {
+		t.Errorf("kept %q, want the most recently modified %q", got, want)
+	}
+}
+
+// Positions saved for deleted notes are forgotten when the app starts
+func TestCursorPositionsPrunedAtStart(t *testing.T) {
+	newTestVault(t)
+	kept := writeTestNote(t, "Kept.txt", "some text")
+	if err := saveCursorPositions(map[string]int{kept: 4, filepath.Join(notesPath, "Gone.txt"): 2}); err != nil {
+		t.Fatal(err)
+	}
+	m := newTestModel(t, 80, 24)
+	want := map[string]int{kept: 4}
+	if !maps.Equal(m.cursorPositions, want) {
+		t.Errorf("model has %v, want %v", m.cursorPositions, want)
+	}
+	if saved := loadCursorPositions(); !maps.Equal(saved, want) {
+		t.Errorf("saved positions are %v, want %v", saved, want)
+	}
+	// And the kept one is where the note opens
+	m.openNote(m.currentNode.children[0])
+	if m.editor.GetCursor() != 4 {
+		t.Errorf("note opens with the cursor at %d, want 4", m.editor.GetCursor())
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	newTestVault(t)
+	path := writeTestNote(t, "Long.md", "one\ntwo\nthree\nfour\nfive")
+	writeTestNote(t, "Other.md", "other")
+	m := newTestModel(t, 80, 24)
+	m.openNote(m.currentNode.children[0])
+
+	m.editor.SetCursor(8)
+	press(m, "alt+m", "a")
+	m.editor.SetCursor(18)
+	press(m, "alt+m", "b")
+	m.editor.SetCursor(0)
+	press(m, "alt+j", "b")
+	if m.editor.GetCursor() != 18 {
+		t.Errorf("jumped to %d, want mark b at 18", m.editor.GetCursor())
+	}
+	press(m, "alt+j", "a")
+	if m.editor.GetCursor() != 8 {
+		t.Errorf("jumped to %d, want mark a at 8", m.editor.GetCursor())
+	}
+	// An unset mark, or a key that isn't a mark, leaves the cursor be and
+	// isn't typed
+	press(m, "alt+j", "c", "alt+j", "1")
+	if m.editor.GetCursor() != 8 || m.editor.Value() != "one\ntwo\nthree\nfour\nfive" {
+		t.Errorf("cursor at %d, text %q", m.editor.GetCursor(), m.editor.Value())
+	}
+	if want := map[string]int{"a": 8, "b": 18}; !maps.Equal(loadBookmarks()[path], want) {
+		t.Errorf("saved marks are %v, want %v", loadBookmarks()[path], want)
+	}
+
+	// Marks are kept per note and across restarts
+	m = newTestModel(t, 80, 24)
+	m.openNote(m.currentNode.children[1])
+	press(m, "alt+j", "a")
+	if m.editor.GetCursor() != 0 {
+		t.Errorf("a mark from another note moved the cursor to %d", m.editor.GetCursor())
+	}
+	m.openNote(m.currentNode.children[0])
+	m.editor.SetCursor(0)
+	press(m, "alt+j", "b")
+	if m.editor.GetCursor() != 18 {
+		t.Errorf("after a restart jumped to %d, want 18", m.editor.GetCursor())
+	}
+}
+
+// A mark past the end of a note that has since been shortened lands at the
+// end
+func TestBookmarkClamped(t *testing.T) {
+	newTestVault(t)
+	writeTestNote(t, "Long.md", "one\ntwo\nthree\nfour\nfive")
+	m := newTestModel(t, 80, 24)
+	m.openNote(m.currentNode.children[0])
+	m.editor.SetCursor(20)
+	press(m, "alt+m", "z")
+
+	writeTestNote(t, "Long.md", "one\ntwo")
+	m = newTestModel(t, 80, 24)
+	m.openNote(m.currentNode.children[0])
+	m.editor.SetCursor(0)
+	press(m, "alt+j", "z")
+	if got := m.editor.GetCursor(); got != 7 {
+		t.Errorf("jumped to %d, want the end at 7", got)
+	}
+	if row, col := m.editor.CursorLineCol(); row != 2 || col != 4 {
+		t.Errorf("cursor at %d:%d, want 2:4", row, col)
+	}
+}
+
+// Marks for notes that are gone are dropped when they're loaded
+func TestBookmarksPruned(t *testing.T) {
+	newTestVault(t)
+	kept := writeTestNote(t, "Kept.md", "text")
+	gone := filepath.Join(notesPath, "Gone.md")
+	if err := saveBookmarks(map[string]map[string]int{kept: {"a": 1}, gone: {"a": 2}}); err != nil {
+		t.Fatal(err)
+	}
+	got := loadBookmarks()
+	if len(got) != 1 || got[kept]["a"] != 1 {
+		t.Errorf("loaded %v, want only the marks for %s", got, kept)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import "strings"
+
+type diffOp int
+
+const (
+	diffEqual  diffOp = iota
+	diffDelete        // line only in the old version
+	diffInsert        // line only in the new version
+)
+
+type diffLine struct {
+	op   diffOp
+	text string
+}
+
+// diffLines returns a line diff turning a into b, built from their longest
+// common subsequence. Deletions come before insertions within a change.
+func diffLines(a, b []string) []diffLine {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []diffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, diffLine{diffEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, diffLine{diffDelete, a[i]})
+			i++
+		default:
+			diff = append(diff, diffLine{diffInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, diffLine{diffDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, diffLine{diffInsert, b[j]})
+	}
+	return diff
+}
+
+// mergeWithMarkers joins the two sides of a diff back into one text, with
+// every changed hunk wrapped in conflict markers for manual resolution.
+// Deleted lines are labelled oldLabel and inserted lines newLabel.
+func mergeWithMarkers(diff []diffLine, oldLabel, newLabel string) string {
+	var out, oldHunk, newHunk []string
+	flush := func() {
+		if len(oldHunk) == 0 && len(newHunk) == 0 {
+			return
+		}
+		out = append(out, "<<<<<<< "+oldLabel)
+		out = append(out, oldHunk...)
+		out = append(out, "=======")
+		out = append(out, newHunk...)
+		out = append(out, ">>>>>>> "+newLabel)
+		oldHunk, newHunk = nil, nil
+	}
+
+	for _, line := range diff {
+		switch line.op {
+		case diffDelete:
+			oldHunk = append(oldHunk, line.text)
+		case diffInsert:
+			newHunk = append(newHunk, line.text)
+		default:
+			flush()
+			out = append(out, line.text)
+		}
+	}
+	flush()
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// diffText writes a diff one line per entry, marked " ", "-" or "+"
+func diffText(diff []diffLine) string {
+	var lines []string
+	for _, l := range diff {
+		lines = append(lines, [...]string{" ", "-", "+"}[l.op]+l.text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestDiffLines(t *testing.T) {
+	for _, tc := range []struct {
+		a, b, want string
+	}{
+		{"a\nb\nc", "a\nb\nc", " a\n b\n c"},
+		{"", "", " "},
+		{"a\nb\nc", "a\nc", " a\n-b\n c"},
+		{"a\nc", "a\nb\nc", " a\n+b\n c"},
+		{"a\nold\nc", "a\nnew\nc", " a\n-old\n+new\n c"},
+		{"a\nb", "c\nd", "-a\n-b\n+c\n+d"},
+		// Both versions of a note edited in different places
+		{"# Title\nmilk\neggs\nbread\n#shopping", "# Title\nmilk\nbutter\neggs\nbread\n#shopping #home",
+			" # Title\n milk\n+butter\n eggs\n bread\n-#shopping\n+#shopping #home"},
+		// The longest common run is kept, not the first match
+		{"x\na\nb\nc", "a\nb\nc\nx", "-x\n a\n b\n c\n+x"},
+	} {
+		got := diffText(diffLines(strings.Split(tc.a, "\n"), strings.Split(tc.b, "\n")))
+		if got != tc.want {
+			t.Errorf("diff of %q and %q:\n%s\nwant:\n%s", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+// The old side of any diff is a and the new side b, and as many lines as
+// possible are kept
+func TestDiffLinesRandom(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	lines := func() []string {
+		var l []string
+		for range rng.IntN(12) {
+			l = append(l, string(rune('a'+rng.IntN(4))))
+		}
+		return l
+	}
+	// lcsLength is the length of the longest common subsequence, by brute force
+	var lcsLength func(a, b []string) int
+	lcsLength = func(a, b []string) int {
+		if len(a) == 0 || len(b) == 0 {
+			return 0
+		}
+		if a[0] == b[0] {
+			return 1 + lcsLength(a[1:], b[1:])
+		}
+		return max(lcsLength(a[1:], b), lcsLength(a, b[1:]))
+	}
+	for range 200 {
+		a, b := lines(), lines()
+		diff := diffLines(a, b)
+		var old, new []string
+		kept := 0
+		for _, l := range diff {
+			if l.op != diffInsert {
+				old = append(old, l.text)
+			}
+			if l.op != diffDelete {
+				new = append(new, l.text)
+			}
+			if l.op == diffEqual {
+				kept++
+			}
+		}
+		if !slices.Equal(old, a) || !slices.Equal(new, b) {
+			t.Fatalf("diff of %q and %q gives %q and %q", a, b, old, new)
+		}
+		if want := lcsLength(a, b); kept != want {
+			t.Fatalf("diff of %q and %q keeps %d lines, want %d", a, b, kept, want)
+		}
+	}
+}
+
+func TestMergeWithMarkers(t *testing.T) {
+	diff := diffLines(strings.Split("a\nold\nc\nd", "\n"), strings.Split("a\nnew\nc\nd\ne", "\n"))
+	want := "a\n<<<<<<< on disk\nold\n=======\nnew\n>>>>>>> yours\nc\nd\n<<<<<<< on disk\n=======\ne\n>>>>>>> yours"
+	if got := mergeWithMarkers(diff, "on disk", "yours"); got != want {
+		t.Errorf("merged as %q, want %q", got, want)
+	}
+}
+
+// changedOnDisk opens Shopping.txt, edits it, and then changes the file
+// behind the editor's back
+func changedOnDisk(t *testing.T) *model {
+	newTestVault(t)
+	path := writeTestNote(t, "Shopping.txt", "milk\neggs\n")
+	m := newTestModel(t, 80, 30)
+	m.openNote(m.currentNode.children[0])
+	m.editor.SetValue("milk\neggs\nbread")
+	m.editor.MarkDirty()
+	writeTestNote(t, "Shopping.txt", "milk\ncheese\neggs\n")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestDiskChangeShowsDiff(t *testing.T) {
+	withColors(t)
+	m := changedOnDisk(t)
+	press(m, "ctrl+s")
+	if !m.showDiffPanel {
+		t.Fatal("no diff for a note changed on disk while edited")
+	}
+	// From the file on disk to the buffer
+	if got, want := diffText(m.diffResult), " milk\n-cheese\n eggs\n+bread"; got != want {
+		t.Errorf("diff is:\n%s\nwant:\n%s", got, want)
+	}
+	s := render(m)
+	s.assertIntact(t)
+	for _, line := range []string{"- cheese", "+ bread", "  milk"} {
+		if row, _ := s.find(line); row < 0 {
+			t.Errorf("%q not shown:\n%s", line, strings.Join(s.rows, "\n"))
+		}
+	}
+	raw := strings.Join(s.raw, "\n")
+	if !strings.Contains(raw, lipgloss.NewStyle().Foreground(removedColor).Render("- cheese")) {
+		t.Error("removed lines aren't colored")
+	}
+	if !strings.Contains(raw, lipgloss.NewStyle().Foreground(addedColor).Render("+ bread")) {
+		t.Error("added lines aren't colored")
+	}
+	if readTestNote(t, "Shopping.txt") != "milk\ncheese\neggs\n" {
+		t.Error("the file was written before a choice was made")
+	}
+
+	// Taking theirs loads the file into the editor
+	press(m, "t")
+	if m.showDiffPanel || m.editor.Value() != "milk\ncheese\neggs" || readTestNote(t, "Shopping.txt") != "milk\ncheese\neggs\n" {
+		t.Errorf("taking theirs: panel %v, buffer %q", m.showDiffPanel, m.editor.Value())
+	}
+
+	// Keeping mine saves the buffer over the file
+	m = changedOnDisk(t)
+	press(m, "ctrl+s", "k")
+	if got := readTestNote(t, "Shopping.txt"); got != "milk\neggs\nbread\n" {
+		t.Errorf("keeping mine saved %q", got)
+	}
+
+	// Merging puts both in the buffer between markers
+	m = changedOnDisk(t)
+	press(m, "ctrl+s", "m")
+	if got := m.editor.Value(); !strings.Contains(got, "<<<<<<< on disk\ncheese\n=======\n>>>>>>> yours") {
+		t.Errorf("merged buffer is %q", got)
+	}
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"cmp"
 	"encoding/base64"
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -12,29 +19,77 @@ import (
 
 // Editor is a simple text editor with cursor position tracking
 type Editor struct {
-	lines       [][]rune // Text buffer as lines of runes
-	cursorRow   int      // Current cursor row (0-indexed)
-	cursorCol   int      // Current cursor column (0-indexed)
-	desiredCol  int      // Desired column for vertical movement (preserves column across lines)
-	viewportRow int      // Top visible visual line for scrolling
-	width       int      // Editor width
-	height      int      // Editor height
-	placeholder string   // Placeholder text when empty
-	focused     bool     // Whether editor is focused
-	killBuffer  string   // Killed text for yank (Ctrl+Y)
-	showHelp    bool     // Whether to show help overlay
-	dirty       bool     // Whether there are unsaved changes
+	lines       lineBuffer // Text buffer, a gap buffer of lines of runes
+	cursorRow   int        // Current cursor row (0-indexed)
+	cursorCol   int        // Current cursor column (0-indexed)
+	desiredCol  int        // Desired column for vertical movement (preserves column across lines)
+	viewportRow int        // Top visible visual line for scrolling
+	width       int        // Editor width
+	height      int        // Editor height
+	placeholder string     // Placeholder text when empty
+	focused     bool       // Whether editor is focused
+	killRing    []string   // Killed text for yank (Ctrl+Y), most recent last
+	showHelp    bool       // Whether to show help overlay
+	dirty       bool       // Whether there are unsaved changes
+	revision    int        // Bumped on every change to the text, to cache what's computed from it
 	// Mouse selection state
-	selecting       bool // Left mouse button is held (actively dragging)
-	hasSelection    bool // A selection exists (persists after mouse release)
-	selectionAnchor int  // Character offset where selection started
-	yOffset         int  // Editor's Y position in terminal (for mouse coord translation)
+	selecting       bool      // Left mouse button is held (actively dragging)
+	hasSelection    bool      // A selection exists (persists after mouse release)
+	selectionAnchor int       // Character offset where selection started
+	yOffset         int       // Editor's Y position in terminal (for mouse coord translation)
+	mouseDisabled   bool      // Ignore mouse events, leaving selection to the terminal
+	lastClick       time.Time // When the left button was last pressed, to spot double and triple clicks
+	lastClickPos    int       // Character offset of the last click
+	clickCount      int       // 1, 2 or 3 for a single, double or triple click
+	// Snippets expanded with Tab
+	snippets       map[string]string // trigger -> snippet body
+	snippetStops   []snippetStop     // placeholders left to visit, in order
+	snippetActive  bool              // a placeholder is being filled in
+	snippetStopEnd int               // where the current placeholder ended when selected
+	// Find and replace (ctrl+f / ctrl+r)
+	findMode     findMode
+	findQuery    string
+	replaceText  string
+	findField    int    // 0 while typing the search text, 1 for the replacement
+	findBrowsing bool   // the search text is entered and n/N move between matches
+	findRegex    bool   // the search text is a regular expression
+	findInvalid  bool   // the regex doesn't compile
+	findOrigin   int    // where the search started, for search as you type
+	findStatus   string // match position or result of the last replace
+	// Kill ring state, to join consecutive kills and cycle yanks (Alt+Y)
+	killRingPos int         // entry the last yank inserted
+	yankStart   int         // where the last yank inserted its text
+	yankEnd     int         // end of the yanked text
+	lastCommand killCommand // what the previous key did
+	command     killCommand // what the current key did
+	// Vim key bindings (vim_mode)
+	vimKeys        bool
+	vimMode        vimMode
+	vimPending     rune   // operator waiting for its second key: d, y or g
+	vimCount       int    // count typed before a command, 0 if none
+	vimVisualStart int    // where visual mode started
+	vimLineYank    string // last text yanked or deleted as whole lines, pasted as lines
+	// Spell checking, nil when off
+	spell *spellChecker
+	// Line number gutter
+	lineNumbers     bool // Show line numbers left of the text
+	relativeNumbers bool // Number lines by distance from the cursor line
+	// Indentation inserted by Tab
+	indentTabs  bool // Indent with a tab character instead of spaces
+	indentWidth int  // Spaces per indentation level, and the width tabs are drawn
+	// List handling on Enter
+	continueLists bool // Continue bullet and numbered lists on a new line
+	renumberLists bool // Renumber the rest of a numbered list after inserting an item
 }
 
+// listItemRegex matches a list marker at the start of a line: indentation,
+// then a bullet (optionally a checkbox) or a number followed by . or )
+var listItemRegex = regexp.MustCompile(`^(\s*)(?:([-*+])( \[[ xX]\])?|(\d+)([.)]))\s`)
+
 // New creates a new editor
 func NewEditor() Editor {
 	return Editor{
-		lines:           [][]rune{{}}, // Start with one empty line
+		lines:           newLineBuffer(""), // Start with one empty line
 		cursorRow:       0,
 		cursorCol:       0,
 		desiredCol:      0,
@@ -43,6 +98,7 @@ func NewEditor() Editor {
 		height:          24,
 		focused:         false,
 		selectionAnchor: -1,
+		indentWidth:     4,
 	}
 }
 
@@ -51,9 +107,41 @@ func (e *Editor) SetWidth(w int) {
 	e.width = w
 }
 
-// SetHeight sets the editor height
+// SetLineNumbers shows or hides the line number gutter. With relative set,
+// lines are numbered by their distance from the cursor line.
+func (e *Editor) SetLineNumbers(show, relative bool) {
+	e.lineNumbers = show
+	e.relativeNumbers = relative
+	e.ensureCursorVisible()
+}
+
+// gutterWidth returns the width of the line number gutter, including the
+// space after the numbers
+func (e *Editor) gutterWidth() int {
+	if !e.lineNumbers {
+		return 0
+	}
+	return max(3, len(strconv.Itoa(e.lines.len()))) + 1
+}
+
+// textWidth returns the width text wraps at, beside the gutter
+func (e *Editor) textWidth() int {
+	if e.width <= 0 {
+		return e.width
+	}
+	return max(1, e.width-e.gutterWidth())
+}
+
+// SetHeight sets the editor height, scrolling to keep the cursor in view
 func (e *Editor) SetHeight(h int) {
 	e.height = h
+	e.ensureCursorVisible()
+}
+
+// SetListContinuation configures list handling when Enter is pressed
+func (e *Editor) SetListContinuation(continueLists, renumberLists bool) {
+	e.continueLists = continueLists
+	e.renumberLists = renumberLists
 }
 
 // SetYOffset sets the Y offset of the editor in the terminal
@@ -71,6 +159,11 @@ func copyToPrimarySelection(text string) {
 	}
 }
 
+// SetMouseEnabled turns handling of mouse events on or off
+func (e *Editor) SetMouseEnabled(enabled bool) {
+	e.mouseDisabled = !enabled
+}
+
 // SetPlaceholder sets the placeholder text
 func (e *Editor) SetPlaceholder(p string) {
 	e.placeholder = p
@@ -79,6 +172,9 @@ func (e *Editor) SetPlaceholder(p string) {
 // Focus focuses the editor
 func (e *Editor) Focus() {
 	e.focused = true
+	if e.vimKeys {
+		e.resetVimMode()
+	}
 }
 
 // Blur removes focus from the editor
@@ -88,33 +184,15 @@ func (e *Editor) Blur() {
 
 // Value returns the current text content
 func (e *Editor) Value() string {
-	var sb strings.Builder
-	for i, line := range e.lines {
-		sb.WriteString(string(line))
-		if i < len(e.lines)-1 {
-			sb.WriteRune('\n')
-		}
-	}
-	return sb.String()
+	return e.lines.String()
 }
 
 // SetValue sets the text content
 func (e *Editor) SetValue(text string) {
-	e.lines = [][]rune{}
-	if text == "" {
-		e.lines = [][]rune{{}}
-		e.cursorRow = 0
-		e.cursorCol = 0
-		e.desiredCol = 0
-		e.viewportRow = 0
-		e.dirty = false
-		return
-	}
-
-	lines := strings.Split(text, "\n")
-	for _, line := range lines {
-		e.lines = append(e.lines, []rune(line))
-	}
+	e.revision++
+	e.lines = newLineBuffer(text)
+	e.snippetStops = nil
+	e.snippetActive = false
 
 	// Reset cursor to beginning
 	e.cursorRow = 0
@@ -137,6 +215,114 @@ func (e *Editor) ClearDirty() {
 // MarkDirty marks the editor as having unsaved changes.
 func (e *Editor) MarkDirty() {
 	e.dirty = true
+	e.revision++
+}
+
+// Revision returns a number that changes whenever the text does, so results
+// computed from Value can be reused until then
+func (e *Editor) Revision() int {
+	return e.revision
+}
+
+// length returns the number of characters in the text, newlines included
+func (e *Editor) length() int {
+	n := e.lines.len() - 1
+	for i := range e.lines.len() {
+		n += len(e.lines.line(i))
+	}
+	return n
+}
+
+// FirstLine returns the first line of the text
+func (e *Editor) FirstLine() string {
+	return string(e.lines.line(0))
+}
+
+// Blank reports whether the text is empty or only whitespace
+func (e *Editor) Blank() bool {
+	for i := range e.lines.len() {
+		if slices.ContainsFunc(e.lines.line(i), func(r rune) bool { return !unicode.IsSpace(r) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// CountBody returns the frontmatter block at the top of the text, if any,
+// and the words and characters after it, counted from the lines themselves
+// so a long note isn't copied to count it. Only lines that changed since
+// the last count have their words counted again.
+func (e *Editor) CountBody() (frontmatter string, words, chars int) {
+	bodyRow := 0
+	if e.lines.len() > 1 && string(e.lines.line(0)) == frontmatterDelim {
+		for row := 1; row < e.lines.len(); row++ {
+			if strings.TrimRight(string(e.lines.line(row)), " \t\r") == frontmatterDelim {
+				bodyRow = row + 1
+				break
+			}
+		}
+	}
+	if bodyRow > 0 {
+		frontmatter = e.textRange(0, e.offsetOf(bodyRow, 0))
+		if bodyRow < e.lines.len() {
+			frontmatter += "\n"
+		}
+	}
+
+	for row := bodyRow; row < e.lines.len(); row++ {
+		if row > bodyRow {
+			chars++ // the newline before the line
+		}
+		chars += len(e.lines.line(row))
+		words += e.lines.words(row)
+	}
+	return frontmatter, words, chars
+}
+
+// offsetOf returns the character offset of a row and column
+func (e *Editor) offsetOf(row, col int) int {
+	offset := 0
+	for i := range row {
+		offset += len(e.lines.line(i)) + 1
+	}
+	return offset + col
+}
+
+// positionAt returns the row and column of a character offset, clamped to
+// the end of the text
+func (e *Editor) positionAt(offset int) (int, int) {
+	for row := range e.lines.len() {
+		line := e.lines.line(row)
+		if offset <= len(line) {
+			return row, max(offset, 0)
+		}
+		offset -= len(line) + 1
+	}
+	last := e.lines.len() - 1
+	return last, len(e.lines.line(last))
+}
+
+// textRange returns the text between two character offsets
+func (e *Editor) textRange(start, end int) string {
+	startRow, startCol := e.positionAt(start)
+	endRow, endCol := e.positionAt(end)
+	return e.textBetween(startRow, startCol, endRow, endCol)
+}
+
+// textBetween returns the text from startRow, startCol up to endRow, endCol
+func (e *Editor) textBetween(startRow, startCol, endRow, endCol int) string {
+	if startRow == endRow {
+		return string(e.lines.line(startRow)[startCol:max(startCol, endCol)])
+	}
+	var sb strings.Builder
+	sb.WriteString(string(e.lines.line(startRow)[startCol:]))
+	for row := startRow + 1; row < endRow; row++ {
+		sb.WriteRune('\n')
+		sb.WriteString(string(e.lines.line(row)))
+	}
+	sb.WriteRune('\n')
+	sb.WriteString(string(e.lines.line(endRow)[:endCol]))
+	return sb.String()
 }
 
 // SetCursor sets the cursor position by character index
@@ -146,8 +332,8 @@ func (e *Editor) SetCursor(pos int) {
 	}
 
 	charCount := 0
-	for row, line := range e.lines {
-		lineLen := len(line)
+	for row := range e.lines.len() {
+		lineLen := len(e.lines.line(row))
 		if charCount+lineLen >= pos {
 			// Cursor is on this line
 			e.cursorRow = row
@@ -160,9 +346,9 @@ func (e *Editor) SetCursor(pos int) {
 	}
 
 	// Position is beyond end, put cursor at end
-	if len(e.lines) > 0 {
-		e.cursorRow = len(e.lines) - 1
-		e.cursorCol = len(e.lines[e.cursorRow])
+	if e.lines.len() > 0 {
+		e.cursorRow = e.lines.len() - 1
+		e.cursorCol = len(e.lines.line(e.cursorRow))
 		e.updateDesiredCol()
 	}
 	e.ensureCursorVisible()
@@ -171,36 +357,52 @@ func (e *Editor) SetCursor(pos int) {
 // GetCursor returns the cursor position as character index
 func (e *Editor) GetCursor() int {
 	pos := 0
-	for i := 0; i < e.cursorRow && i < len(e.lines); i++ {
-		pos += len(e.lines[i]) + 1 // +1 for newline
+	for i := 0; i < e.cursorRow && i < e.lines.len(); i++ {
+		pos += len(e.lines.line(i)) + 1 // +1 for newline
 	}
 	pos += e.cursorCol
 	return pos
 }
 
+// CursorLineCol returns the cursor's line and column, counting from 1. The
+// column counts characters, so an emoji or accented letter is one column.
+func (e *Editor) CursorLineCol() (int, int) {
+	bounds, _ := graphemes(e.lines.line(e.cursorRow)[:e.cursorCol])
+	return e.cursorRow + 1, len(bounds)
+}
+
 // countVisualLines calculates how many visual lines a logical line occupies
-// based on the editor width. Empty lines are counted as 1 visual line.
-func (e *Editor) countVisualLines(line []rune, width int) int {
-	if width <= 0 {
-		return 1
+// based on the editor width. Empty lines are counted as 1 visual line. The
+// count is kept with the line, so only lines that changed are wrapped again.
+func (e *Editor) countVisualLines(row, width int) int {
+	return e.lines.wrappedRows(row, width)
+}
+
+// visualLineAt returns the visual line of line the cursor at col is on. At
+// the end of a line that fills its last visual line, the cursor is drawn on
+// a visual line of its own.
+func (e *Editor) visualLineAt(line []rune, col, width int) int {
+	starts := wrapLine(line, width)
+	v := 0
+	for v+1 < len(starts) && starts[v+1] <= col {
+		v++
 	}
-	lineLen := len(line)
-	if lineLen == 0 {
-		return 1
+	if width > 0 && col == len(line) && col > 0 && cellWidth(line[starts[v]:]) >= width {
+		v++
 	}
-	// Ceiling division: (lineLen + width - 1) / width
-	return (lineLen + width - 1) / width
+	return v
 }
 
 // logicalToVisualRow converts a logical row and column to a global visual row.
 // This accounts for line wrapping: each logical line may span multiple visual lines.
 func (e *Editor) logicalToVisualRow(logicalRow, col int) int {
+	width := e.textWidth()
 	visual := 0
-	for i := 0; i < logicalRow && i < len(e.lines); i++ {
-		visual += e.countVisualLines(e.lines[i], e.width)
+	for i := 0; i < logicalRow && i < e.lines.len(); i++ {
+		visual += e.countVisualLines(i, width)
 	}
-	if e.width > 0 && col > 0 {
-		visual += col / e.width
+	if logicalRow < e.lines.len() {
+		visual += e.visualLineAt(e.lines.line(logicalRow), col, width)
 	}
 	return visual
 }
@@ -211,26 +413,43 @@ func (e *Editor) visualRowToLogical(visualRow int) (logicalRow int, visualOffset
 	if visualRow <= 0 {
 		return 0, 0
 	}
+	width := e.textWidth()
 	visual := 0
-	for i, line := range e.lines {
-		vl := e.countVisualLines(line, e.width)
+	for i := range e.lines.len() {
+		vl := e.countVisualLines(i, width)
 		if visual+vl > visualRow {
 			return i, visualRow - visual
 		}
 		visual += vl
 	}
 	// Past the end - return last line
-	if len(e.lines) > 0 {
-		return len(e.lines) - 1, 0
+	if e.lines.len() > 0 {
+		return e.lines.len() - 1, 0
 	}
 	return 0, 0
 }
 
+// headingRegex matches a Markdown heading line
+var headingRegex = regexp.MustCompile(`^#{1,6}\s+\S`)
+
+// CurrentHeading returns the nearest Markdown heading at or above the first
+// visible line, or "" if there is none
+func (e *Editor) CurrentHeading() string {
+	row, _ := e.visualRowToLogical(e.viewportRow)
+	for i := min(row, e.lines.len()-1); i >= 0; i-- {
+		if headingRegex.MatchString(string(e.lines.line(i))) {
+			return string(e.lines.line(i))
+		}
+	}
+	return ""
+}
+
 // totalVisualLines returns the total number of visual lines in the document.
 func (e *Editor) totalVisualLines() int {
+	width := e.textWidth()
 	total := 0
-	for _, line := range e.lines {
-		total += e.countVisualLines(line, e.width)
+	for i := range e.lines.len() {
+		total += e.countVisualLines(i, width)
 	}
 	return total
 }
@@ -252,16 +471,16 @@ func (e *Editor) ensureCursorVisible() {
 // clampCursor ensures cursor is within valid bounds
 func (e *Editor) clampCursor() {
 	// Ensure row is valid
-	if e.cursorRow >= len(e.lines) {
-		e.cursorRow = len(e.lines) - 1
+	if e.cursorRow >= e.lines.len() {
+		e.cursorRow = e.lines.len() - 1
 	}
 	if e.cursorRow < 0 {
 		e.cursorRow = 0
 	}
 
 	// Clamp column to line length
-	if e.cursorRow < len(e.lines) {
-		lineLen := len(e.lines[e.cursorRow])
+	if e.cursorRow < e.lines.len() {
+		lineLen := len(e.lines.line(e.cursorRow))
 		if e.cursorCol > lineLen {
 			e.cursorCol = lineLen
 		}
@@ -272,12 +491,18 @@ func (e *Editor) clampCursor() {
 }
 
 // updateDesiredCol updates the desired column based on current cursor position
-// This tracks the visual column (within the line wrap width) for consistent up/down movement
+// This tracks the visual column (within the line wrap width) for consistent up/down movement.
+// The column counts screen cells, so it lines up across wide characters.
 func (e *Editor) updateDesiredCol() {
-	if e.width > 0 {
-		e.desiredCol = e.cursorCol % e.width
-	} else {
-		e.desiredCol = e.cursorCol
+	e.desiredCol = 0
+	if e.cursorRow >= e.lines.len() {
+		return
+	}
+	line := e.lines.line(e.cursorRow)
+	width := e.textWidth()
+	starts := wrapLine(line, width)
+	if v := e.visualLineAt(line, e.cursorCol, width); v < len(starts) {
+		e.desiredCol = cellWidth(line[starts[v]:min(e.cursorCol, len(line))])
 	}
 }
 
@@ -300,19 +525,36 @@ func (e *Editor) deleteSelection() {
 		startOff, endOff = endOff, startOff
 	}
 
-	text := []rune(e.Value())
-	if startOff > len(text) {
-		startOff = len(text)
-	}
-	if endOff > len(text) {
-		endOff = len(text)
-	}
-
-	newText := string(text[:startOff]) + string(text[endOff:])
-	e.SetValue(newText)
-	e.SetCursor(startOff)
+	startRow, startCol := e.positionAt(startOff)
+	endRow, endCol := e.positionAt(endOff)
+	e.cursorRow, e.cursorCol = e.spliceText(startRow, startCol, endRow, endCol, "")
+	e.updateDesiredCol()
+	e.ensureCursorVisible()
 	e.clearSelection()
-	e.dirty = true
+	e.MarkDirty()
+}
+
+// spliceText replaces the text from startRow, startCol up to endRow, endCol
+// with text, rewriting only the lines it touches, and returns the row and
+// column just after the new text
+func (e *Editor) spliceText(startRow, startCol, endRow, endCol int, text string) (int, int) {
+	parts := strings.Split(text, "\n")
+	lines := make([][]rune, len(parts))
+	for i, part := range parts {
+		lines[i] = []rune(part)
+	}
+	last := len(lines) - 1
+	row, col := startRow+last, len(lines[last])
+	if last == 0 {
+		col += startCol
+	}
+	lines[0] = append(e.lines.line(startRow)[:startCol:startCol], lines[0]...)
+	lines[last] = append(lines[last], e.lines.line(endRow)[endCol:]...)
+
+	e.lines.remove(startRow+1, endRow+1)
+	e.lines.set(startRow, lines[0])
+	e.lines.insert(startRow+1, lines[1:]...)
+	return row, col
 }
 
 // mouseToPosition converts terminal mouse coordinates to editor (row, col)
@@ -328,17 +570,16 @@ func (e *Editor) mouseToPosition(mouseX, mouseY int) (int, int) {
 	globalVisual := e.viewportRow + editorY
 	logicalRow, visualOffset := e.visualRowToLogical(globalVisual)
 
-	col := visualOffset*e.width + mouseX
-	if logicalRow < len(e.lines) {
-		if col > len(e.lines[logicalRow]) {
-			col = len(e.lines[logicalRow])
-		}
+	if logicalRow >= e.lines.len() {
+		return logicalRow, 0
 	}
-	if col < 0 {
-		col = 0
+	line := e.lines.line(logicalRow)
+	starts := wrapLine(line, e.textWidth())
+	if visualOffset >= len(starts) {
+		return logicalRow, len(line)
 	}
-
-	return logicalRow, col
+	// Clicks on the line number gutter go to the start of the line
+	return logicalRow, colAtCell(line, starts, visualOffset, max(0, mouseX-e.gutterWidth()))
 }
 
 // getSelectedText returns the text within the current selection
@@ -353,14 +594,7 @@ func (e *Editor) getSelectedText() string {
 		startOff, endOff = endOff, startOff
 	}
 
-	text := []rune(e.Value())
-	if startOff > len(text) {
-		startOff = len(text)
-	}
-	if endOff > len(text) {
-		endOff = len(text)
-	}
-	return string(text[startOff:endOff])
+	return e.textRange(startOff, endOff)
 }
 
 // selectionRange returns the ordered selection range as (startRow, startCol, endRow, endCol).
@@ -377,58 +611,220 @@ func (e *Editor) selectionRange() (int, int, int, int) {
 		startOff, endOff = endOff, startOff
 	}
 
-	// Convert startOff to row, col
-	sRow, sCol := 0, 0
-	off := 0
-	for i, line := range e.lines {
-		if off+len(line) >= startOff {
-			sRow = i
-			sCol = startOff - off
-			break
+	sRow, sCol := e.positionAt(startOff)
+	eRow, eCol := e.positionAt(endOff)
+	return sRow, sCol, eRow, eCol
+}
+
+// doubleClickTime is how soon a click has to follow the last one in the same
+// place to count as a double or triple click
+const doubleClickTime = 400 * time.Millisecond
+
+// copySelection copies the selected text to the kill ring and the primary
+// selection
+func (e *Editor) copySelection() {
+	if !e.hasSelection {
+		return
+	}
+	text := e.getSelectedText()
+	e.pushKill(text)
+	copyToPrimarySelection(text)
+}
+
+// selectWordAtCursor selects the word under the cursor, or the run of spaces
+// or punctuation it's on
+func (e *Editor) selectWordAtCursor() {
+	line := e.lines.line(e.cursorRow)
+	if len(line) == 0 {
+		return
+	}
+	col := min(e.cursorCol, len(line)-1)
+	class := func(r rune) int {
+		switch {
+		case isWordChar(r) || unicode.IsLetter(r) || unicode.IsDigit(r):
+			return 0
+		case unicode.IsSpace(r):
+			return 1
 		}
-		off += len(line) + 1
+		return 2
 	}
+	c := class(line[col])
+	start, end := col, col+1
+	for start > 0 && class(line[start-1]) == c {
+		start--
+	}
+	for end < len(line) && class(line[end]) == c {
+		end++
+	}
+	e.cursorCol = start
+	e.selectionAnchor = e.GetCursor()
+	e.cursorCol = end
+	e.hasSelection = true
+	e.updateDesiredCol()
+}
 
-	// Convert endOff to row, col
-	eRow, eCol := 0, 0
-	off = 0
-	for i, line := range e.lines {
-		if off+len(line) >= endOff {
-			eRow = i
-			eCol = endOff - off
-			break
+// selectedRows returns the rows spanned by the selection, or the cursor row
+// without one. A selection ending at the start of a line doesn't include
+// that line.
+func (e *Editor) selectedRows() (int, int) {
+	startRow, _, endRow, endCol := e.selectionRange()
+	if startRow < 0 {
+		return e.cursorRow, e.cursorRow
+	}
+	if endCol == 0 && endRow > startRow {
+		endRow--
+	}
+	return startRow, endRow
+}
+
+// selectRows selects the whole lines from startRow to endRow, with the
+// cursor at the end
+func (e *Editor) selectRows(startRow, endRow int) {
+	e.cursorRow = startRow
+	e.cursorCol = 0
+	e.selectionAnchor = e.GetCursor()
+	e.cursorRow = endRow
+	e.cursorCol = len(e.lines.line(endRow))
+	e.hasSelection = e.GetCursor() != e.selectionAnchor
+}
+
+// moveLines moves the cursor line, or the lines spanned by the selection,
+// one line up (dir -1) or down (dir 1), past the line next to them
+func (e *Editor) moveLines(dir int) {
+	startRow, endRow := e.selectedRows()
+	if (dir < 0 && startRow == 0) || (dir > 0 && endRow == e.lines.len()-1) {
+		return
+	}
+	if dir < 0 {
+		passed := e.lines.line(startRow - 1)
+		for row := startRow - 1; row < endRow; row++ {
+			e.lines.set(row, e.lines.line(row+1))
 		}
-		off += len(line) + 1
+		e.lines.set(endRow, passed)
+	} else {
+		passed := e.lines.line(endRow + 1)
+		for row := endRow + 1; row > startRow; row-- {
+			e.lines.set(row, e.lines.line(row-1))
+		}
+		e.lines.set(startRow, passed)
 	}
 
-	return sRow, sCol, eRow, eCol
+	if e.hasSelection {
+		e.selectRows(startRow+dir, endRow+dir)
+	} else {
+		e.cursorRow += dir
+	}
+	e.updateDesiredCol()
+	e.ensureCursorVisible()
+	e.MarkDirty()
+}
+
+// duplicateLines inserts a copy of the cursor line, or of the lines spanned
+// by the selection, below them and moves to the copy
+func (e *Editor) duplicateLines() {
+	startRow, endRow := e.selectedRows()
+	copies := make([][]rune, 0, endRow-startRow+1)
+	for row := startRow; row <= endRow; row++ {
+		copies = append(copies, slices.Clone(e.lines.line(row)))
+	}
+	e.lines.insert(endRow+1, copies...)
+
+	count := endRow - startRow + 1
+	if e.hasSelection {
+		e.selectRows(startRow+count, endRow+count)
+	} else {
+		e.cursorRow += count
+	}
+	e.updateDesiredCol()
+	e.ensureCursorVisible()
+	e.MarkDirty()
+}
+
+// Line sort orders for sortSelectedLines
+const (
+	sortAscending = iota
+	sortDescending
+	sortNumeric
+)
+
+// leadingNumberRegex finds the first number on a line for numeric sorting
+var leadingNumberRegex = regexp.MustCompile(`-?\d+(?:\.\d+)?`)
+
+// sortSelectedLines sorts the whole lines spanned by the selection and keeps
+// the sorted block selected. A selection ending at the start of a line
+// doesn't include that line. Does nothing without a selection.
+func (e *Editor) sortSelectedLines(order int) {
+	if !e.hasSelection {
+		return
+	}
+	startRow, endRow := e.selectedRows()
+
+	block := make([]string, 0, endRow-startRow+1)
+	for row := startRow; row <= endRow; row++ {
+		block = append(block, string(e.lines.line(row)))
+	}
+
+	switch order {
+	case sortAscending:
+		slices.SortStableFunc(block, strings.Compare)
+	case sortDescending:
+		slices.SortStableFunc(block, func(a, b string) int { return strings.Compare(b, a) })
+	case sortNumeric:
+		// Lines without a number go after the numbered ones, in their original order
+		number := func(line string) (float64, bool) {
+			n, err := strconv.ParseFloat(leadingNumberRegex.FindString(line), 64)
+			return n, err == nil
+		}
+		slices.SortStableFunc(block, func(a, b string) int {
+			na, okA := number(a)
+			nb, okB := number(b)
+			switch {
+			case okA && okB:
+				return cmp.Compare(na, nb)
+			case okA:
+				return -1
+			case okB:
+				return 1
+			}
+			return 0
+		})
+	}
+
+	for i, line := range block {
+		e.lines.set(startRow+i, []rune(line))
+	}
+
+	e.selectRows(startRow, endRow)
+	e.updateDesiredCol()
+	e.ensureCursorVisible()
+	e.MarkDirty()
 }
 
 // insertRune inserts a rune at the cursor position
 func (e *Editor) insertRune(r rune) {
-	if e.cursorRow >= len(e.lines) {
-		e.lines = append(e.lines, []rune{})
-		e.cursorRow = len(e.lines) - 1
+	if e.cursorRow >= e.lines.len() {
+		e.lines.insert(e.lines.len(), []rune{})
+		e.cursorRow = e.lines.len() - 1
 	}
 
-	line := e.lines[e.cursorRow]
+	line := e.lines.line(e.cursorRow)
 	// Insert rune at cursor position
 	line = append(line[:e.cursorCol], append([]rune{r}, line[e.cursorCol:]...)...)
-	e.lines[e.cursorRow] = line
+	e.lines.set(e.cursorRow, line)
 	e.cursorCol++
 	e.updateDesiredCol()
 	e.ensureCursorVisible()
-	e.dirty = true
+	e.MarkDirty()
 }
 
 // insertNewline inserts a newline at cursor position
 func (e *Editor) insertNewline() {
-	if e.cursorRow >= len(e.lines) {
-		e.lines = append(e.lines, []rune{})
-		e.cursorRow = len(e.lines) - 1
+	if e.cursorRow >= e.lines.len() {
+		e.lines.insert(e.lines.len(), []rune{})
+		e.cursorRow = e.lines.len() - 1
 	}
 
-	currentLine := e.lines[e.cursorRow]
+	currentLine := e.lines.line(e.cursorRow)
 	// Split line at cursor
 	beforeCursor := make([]rune, len(currentLine[:e.cursorCol]))
 	copy(beforeCursor, currentLine[:e.cursorCol])
@@ -436,93 +832,164 @@ func (e *Editor) insertNewline() {
 	copy(afterCursor, currentLine[e.cursorCol:])
 
 	// Update current line and insert new line
-	e.lines[e.cursorRow] = beforeCursor
-	e.lines = append(e.lines[:e.cursorRow+1], append([][]rune{afterCursor}, e.lines[e.cursorRow+1:]...)...)
+	e.lines.set(e.cursorRow, beforeCursor)
+	e.lines.insert(e.cursorRow+1, afterCursor)
 
 	// Move cursor to start of next line
 	e.cursorRow++
 	e.cursorCol = 0
 	e.desiredCol = 0
 	e.ensureCursorVisible()
-	e.dirty = true
+	e.MarkDirty()
+}
+
+// insertNewlineContinuingList inserts a newline, starting the new line with
+// the next list marker when the cursor is on a list item. Enter on an empty
+// item ends the list by removing its marker instead.
+func (e *Editor) insertNewlineContinuingList() {
+	if !e.continueLists || e.cursorRow >= e.lines.len() {
+		e.insertNewline()
+		return
+	}
+
+	line := string(e.lines.line(e.cursorRow))
+	match := listItemRegex.FindStringSubmatch(line)
+	if match == nil || e.cursorCol < utf8.RuneCountInString(match[0]) {
+		e.insertNewline()
+		return
+	}
+	indent, bullet, checkbox, number, delim := match[1], match[2], match[3], match[4], match[5]
+
+	// Empty item: end the list
+	if strings.TrimSpace(line[len(match[0]):]) == "" {
+		e.lines.set(e.cursorRow, []rune{})
+		e.cursorCol = 0
+		e.desiredCol = 0
+		e.MarkDirty()
+		return
+	}
+
+	var marker string
+	next := 0
+	if number != "" {
+		n, _ := strconv.Atoi(number)
+		next = n + 1
+		marker = indent + strconv.Itoa(next) + delim + " "
+	} else {
+		marker = indent + bullet
+		if checkbox != "" {
+			marker += " [ ]"
+		}
+		marker += " "
+	}
+
+	e.insertNewline()
+	for _, r := range marker {
+		e.insertRune(r)
+	}
+	if number != "" && e.renumberLists {
+		e.renumberList(e.cursorRow+1, indent, next+1)
+	}
+}
+
+// renumberList renumbers the numbered items at the given indentation from
+// row onwards, starting at next. Nested lines are skipped; anything else
+// ends the list.
+func (e *Editor) renumberList(row int, indent string, next int) {
+	for ; row < e.lines.len(); row++ {
+		line := string(e.lines.line(row))
+		match := listItemRegex.FindStringSubmatch(line)
+		if match != nil && match[1] == indent && match[4] != "" {
+			renumbered := indent + strconv.Itoa(next) + line[len(indent)+len(match[4]):]
+			if renumbered != line {
+				e.lines.set(row, []rune(renumbered))
+				e.MarkDirty()
+			}
+			next++
+			continue
+		}
+		if strings.HasPrefix(line, indent) && len(line) > len(indent) &&
+			(line[len(indent)] == ' ' || line[len(indent)] == '\t') {
+			// Nested content belongs to the previous item
+			continue
+		}
+		return
+	}
 }
 
 // deleteCharBackward deletes character before cursor (backspace)
 func (e *Editor) deleteCharBackward() {
-	if e.cursorRow >= len(e.lines) {
+	if e.cursorRow >= e.lines.len() {
 		return
 	}
 
 	changed := false
 	if e.cursorCol > 0 {
-		// Delete character on current line
-		line := e.lines[e.cursorRow]
-		line = append(line[:e.cursorCol-1], line[e.cursorCol:]...)
-		e.lines[e.cursorRow] = line
-		e.cursorCol--
+		// Delete character on current line, with any combining marks
+		line := e.lines.line(e.cursorRow)
+		start := prevGrapheme(line, e.cursorCol)
+		line = append(line[:start], line[e.cursorCol:]...)
+		e.lines.set(e.cursorRow, line)
+		e.cursorCol = start
 		changed = true
 	} else if e.cursorRow > 0 {
 		// At start of line, merge with previous line
-		prevLine := e.lines[e.cursorRow-1]
-		currentLine := e.lines[e.cursorRow]
+		prevLine := e.lines.line(e.cursorRow - 1)
+		currentLine := e.lines.line(e.cursorRow)
 		e.cursorCol = len(prevLine)
-		e.lines[e.cursorRow-1] = append(prevLine, currentLine...)
-		e.lines = append(e.lines[:e.cursorRow], e.lines[e.cursorRow+1:]...)
+		e.lines.set(e.cursorRow-1, append(prevLine, currentLine...))
+		e.lines.remove(e.cursorRow, e.cursorRow+1)
 		e.cursorRow--
 		e.ensureCursorVisible()
 		changed = true
 	}
 	e.updateDesiredCol()
 	if changed {
-		e.dirty = true
+		e.MarkDirty()
 	}
 }
 
 // deleteCharForward deletes character at cursor (delete key)
 func (e *Editor) deleteCharForward() {
-	if e.cursorRow >= len(e.lines) {
+	if e.cursorRow >= e.lines.len() {
 		return
 	}
 
-	line := e.lines[e.cursorRow]
+	line := e.lines.line(e.cursorRow)
 	changed := false
 
 	if e.cursorCol < len(line) {
-		// Delete character at cursor
-		line = append(line[:e.cursorCol], line[e.cursorCol+1:]...)
-		e.lines[e.cursorRow] = line
+		// Delete character at cursor, with any combining marks
+		line = append(line[:e.cursorCol], line[nextGrapheme(line, e.cursorCol):]...)
+		e.lines.set(e.cursorRow, line)
 		changed = true
-	} else if e.cursorRow < len(e.lines)-1 {
+	} else if e.cursorRow < e.lines.len()-1 {
 		// At end of line, merge with next line
-		nextLine := e.lines[e.cursorRow+1]
-		e.lines[e.cursorRow] = append(line, nextLine...)
-		e.lines = append(e.lines[:e.cursorRow+1], e.lines[e.cursorRow+2:]...)
+		nextLine := e.lines.line(e.cursorRow + 1)
+		e.lines.set(e.cursorRow, append(line, nextLine...))
+		e.lines.remove(e.cursorRow+1, e.cursorRow+2)
 		changed = true
 	}
 	e.updateDesiredCol()
 	if changed {
-		e.dirty = true
+		e.MarkDirty()
 	}
 }
 
 // moveVisualLineUp moves the cursor up one visual line, accounting for text wrapping.
 // It uses desiredCol to maintain consistent column position across wrapped lines.
-func (e *Editor) moveVisualLineUp(cursorRow, cursorCol, width int, lines [][]rune) (int, int) {
+func (e *Editor) moveVisualLineUp(cursorRow, cursorCol, width int) (int, int) {
 	if width <= 0 {
 		width = 80 // fallback
 	}
-
-	// Calculate current visual line within the logical line
-	currentVisualLine := cursorCol / width
+	if cursorRow >= e.lines.len() {
+		return cursorRow, cursorCol
+	}
 
 	// If not on the first visual line of current logical line, move up within same line
-	if currentVisualLine > 0 {
-		newCol := (currentVisualLine-1)*width + e.desiredCol
-		// Clamp to line length
-		if cursorRow < len(lines) && newCol > len(lines[cursorRow]) {
-			newCol = len(lines[cursorRow])
-		}
-		return cursorRow, newCol
+	line := e.lines.line(cursorRow)
+	if v := e.visualLineAt(line, cursorCol, width); v > 0 {
+		return cursorRow, colAtCell(line, wrapLine(line, width), v-1, e.desiredCol)
 	}
 
 	// Already on first visual line of logical line
@@ -532,87 +999,51 @@ func (e *Editor) moveVisualLineUp(cursorRow, cursorCol, width int, lines [][]run
 		return 0, 0
 	}
 
-	// Move to previous logical line
-	prevLogicalRow := cursorRow - 1
-	prevLine := lines[prevLogicalRow]
-	prevLineLen := len(prevLine)
-	prevVisualLines := e.countVisualLines(prevLine, width)
-	lastVisualLine := prevVisualLines - 1
-
 	// Position at desiredCol on the last visual line of previous logical line
-	newCol := lastVisualLine*width + e.desiredCol
-	// Clamp to valid position (line might be shorter than full width)
-	if newCol > prevLineLen {
-		newCol = prevLineLen
-	}
-
-	return prevLogicalRow, newCol
+	prevLine := e.lines.line(cursorRow - 1)
+	starts := wrapLine(prevLine, width)
+	return cursorRow - 1, colAtCell(prevLine, starts, len(starts)-1, e.desiredCol)
 }
 
 // moveVisualLineDown moves the cursor down one visual line, accounting for text wrapping.
 // It uses desiredCol to maintain consistent column position across wrapped lines.
-func (e *Editor) moveVisualLineDown(cursorRow, cursorCol, width int, lines [][]rune) (int, int) {
+func (e *Editor) moveVisualLineDown(cursorRow, cursorCol, width int) (int, int) {
 	if width <= 0 {
 		width = 80 // fallback
 	}
 
-	if cursorRow >= len(lines) {
+	if cursorRow >= e.lines.len() {
 		return cursorRow, cursorCol
 	}
 
-	currentLine := lines[cursorRow]
-	lineLen := len(currentLine)
-
-	// Calculate current visual line within the logical line
-	currentVisualLine := cursorCol / width
-	currentVisualLines := e.countVisualLines(currentLine, width)
-
 	// If not on the last visual line of current logical line, move down within same line
-	if currentVisualLine < currentVisualLines-1 {
-		newCol := (currentVisualLine+1)*width + e.desiredCol
-		// Clamp to line end
-		if newCol > lineLen {
-			newCol = lineLen
-		}
-		return cursorRow, newCol
+	currentLine := e.lines.line(cursorRow)
+	starts := wrapLine(currentLine, width)
+	if v := e.visualLineAt(currentLine, cursorCol, width); v < len(starts)-1 {
+		return cursorRow, colAtCell(currentLine, starts, v+1, e.desiredCol)
 	}
 
 	// Already on last visual line of logical line
 	// Check if we can move to next logical line
-	if cursorRow == len(lines)-1 {
+	if cursorRow == e.lines.len()-1 {
 		// At document end
-		return cursorRow, lineLen
+		return cursorRow, len(currentLine)
 	}
 
-	// Move to next logical line
-	nextLogicalRow := cursorRow + 1
-	nextLine := lines[nextLogicalRow]
-	nextLineLen := len(nextLine)
-
 	// Position at desiredCol on the first visual line of next logical line
-	newCol := e.desiredCol
-	// Clamp to line length
-	if newCol > nextLineLen {
-		newCol = nextLineLen
-	}
-
-	return nextLogicalRow, newCol
+	nextLine := e.lines.line(cursorRow + 1)
+	return cursorRow + 1, colAtCell(nextLine, wrapLine(nextLine, width), 0, e.desiredCol)
 }
 
 // moveUp moves cursor up one visual line (accounting for text wrapping)
 func (e *Editor) moveUp() {
-	newRow, newCol := e.moveVisualLineUp(e.cursorRow, e.cursorCol, e.width, e.lines)
+	newRow, newCol := e.moveVisualLineUp(e.cursorRow, e.cursorCol, e.textWidth())
 	e.cursorRow = newRow
 	e.cursorCol = newCol
 
 	// If cursor was clamped to a shorter position, update desiredCol to match
-	if e.width > 0 {
-		visualCol := e.cursorCol % e.width
-		if visualCol != e.desiredCol && e.cursorRow < len(e.lines) {
-			if e.cursorCol == len(e.lines[e.cursorRow]) {
-				e.updateDesiredCol()
-			}
-		}
+	if e.cursorRow < e.lines.len() && e.cursorCol == len(e.lines.line(e.cursorRow)) {
+		e.updateDesiredCol()
 	}
 
 	e.ensureCursorVisible()
@@ -620,18 +1051,13 @@ func (e *Editor) moveUp() {
 
 // moveDown moves cursor down one visual line (accounting for text wrapping)
 func (e *Editor) moveDown() {
-	newRow, newCol := e.moveVisualLineDown(e.cursorRow, e.cursorCol, e.width, e.lines)
+	newRow, newCol := e.moveVisualLineDown(e.cursorRow, e.cursorCol, e.textWidth())
 	e.cursorRow = newRow
 	e.cursorCol = newCol
 
 	// If cursor was clamped to a shorter position, update desiredCol to match
-	if e.width > 0 {
-		visualCol := e.cursorCol % e.width
-		if visualCol != e.desiredCol && e.cursorRow < len(e.lines) {
-			if e.cursorCol == len(e.lines[e.cursorRow]) {
-				e.updateDesiredCol()
-			}
-		}
+	if e.cursorRow < e.lines.len() && e.cursorCol == len(e.lines.line(e.cursorRow)) {
+		e.updateDesiredCol()
 	}
 
 	e.ensureCursorVisible()
@@ -640,10 +1066,10 @@ func (e *Editor) moveDown() {
 // moveLeft moves cursor left one character
 func (e *Editor) moveLeft() {
 	if e.cursorCol > 0 {
-		e.cursorCol--
+		e.cursorCol = prevGrapheme(e.lines.line(e.cursorRow), e.cursorCol)
 	} else if e.cursorRow > 0 {
 		e.cursorRow--
-		e.cursorCol = len(e.lines[e.cursorRow])
+		e.cursorCol = len(e.lines.line(e.cursorRow))
 	}
 	e.updateDesiredCol()
 	e.ensureCursorVisible()
@@ -651,14 +1077,14 @@ func (e *Editor) moveLeft() {
 
 // moveRight moves cursor right one character
 func (e *Editor) moveRight() {
-	if e.cursorRow >= len(e.lines) {
+	if e.cursorRow >= e.lines.len() {
 		return
 	}
 
-	line := e.lines[e.cursorRow]
+	line := e.lines.line(e.cursorRow)
 	if e.cursorCol < len(line) {
-		e.cursorCol++
-	} else if e.cursorRow < len(e.lines)-1 {
+		e.cursorCol = nextGrapheme(line, e.cursorCol)
+	} else if e.cursorRow < e.lines.len()-1 {
 		e.cursorRow++
 		e.cursorCol = 0
 	}
@@ -675,8 +1101,8 @@ func (e *Editor) moveToLineStart() {
 
 // moveToLineEnd moves cursor to end of current line
 func (e *Editor) moveToLineEnd() {
-	if e.cursorRow < len(e.lines) {
-		e.cursorCol = len(e.lines[e.cursorRow])
+	if e.cursorRow < e.lines.len() {
+		e.cursorCol = len(e.lines.line(e.cursorRow))
 	}
 	e.updateDesiredCol()
 	e.ensureCursorVisible()
@@ -691,26 +1117,26 @@ func isWordChar(r rune) bool {
 // deleteToLineStart deletes from cursor to start of line (Ctrl+U)
 // If cursor is already at start of line, eats the newline (joins with previous line)
 func (e *Editor) deleteToLineStart() {
-	if e.cursorRow >= len(e.lines) {
+	if e.cursorRow >= e.lines.len() {
 		return
 	}
 	if e.cursorCol > 0 {
 		// Text before cursor: delete it
-		deleted := string(e.lines[e.cursorRow][:e.cursorCol])
-		e.killBuffer = deleted
-		e.lines[e.cursorRow] = e.lines[e.cursorRow][e.cursorCol:]
+		deleted := string(e.lines.line(e.cursorRow)[:e.cursorCol])
+		e.killText(deleted, true)
+		e.lines.set(e.cursorRow, e.lines.line(e.cursorRow)[e.cursorCol:])
 		e.cursorCol = 0
-		e.dirty = true
+		e.MarkDirty()
 	} else if e.cursorRow > 0 {
 		// At start of line: join with previous line (eat the newline)
-		e.killBuffer = "\n"
-		prevLine := e.lines[e.cursorRow-1]
-		currentLine := e.lines[e.cursorRow]
+		e.killText("\n", true)
+		prevLine := e.lines.line(e.cursorRow - 1)
+		currentLine := e.lines.line(e.cursorRow)
 		e.cursorCol = len(prevLine)
-		e.lines[e.cursorRow-1] = append(prevLine, currentLine...)
-		e.lines = append(e.lines[:e.cursorRow], e.lines[e.cursorRow+1:]...)
+		e.lines.set(e.cursorRow-1, append(prevLine, currentLine...))
+		e.lines.remove(e.cursorRow, e.cursorRow+1)
 		e.cursorRow--
-		e.dirty = true
+		e.MarkDirty()
 	}
 	e.desiredCol = 0
 	e.ensureCursorVisible()
@@ -719,34 +1145,34 @@ func (e *Editor) deleteToLineStart() {
 // deleteToLineEnd deletes from cursor to end of line (Ctrl+K)
 // If cursor is already at end of line, eats the newline (joins with next line)
 func (e *Editor) deleteToLineEnd() {
-	if e.cursorRow >= len(e.lines) {
+	if e.cursorRow >= e.lines.len() {
 		return
 	}
-	line := e.lines[e.cursorRow]
+	line := e.lines.line(e.cursorRow)
 	if e.cursorCol < len(line) {
 		// Text after cursor: delete it
 		deleted := string(line[e.cursorCol:])
-		e.killBuffer = deleted
-		e.lines[e.cursorRow] = line[:e.cursorCol]
-		e.dirty = true
-	} else if e.cursorRow < len(e.lines)-1 {
+		e.killText(deleted, false)
+		e.lines.set(e.cursorRow, line[:e.cursorCol])
+		e.MarkDirty()
+	} else if e.cursorRow < e.lines.len()-1 {
 		// At end of line: join with next line (eat the newline)
-		e.killBuffer = "\n"
-		nextLine := e.lines[e.cursorRow+1]
-		e.lines[e.cursorRow] = append(line, nextLine...)
-		e.lines = append(e.lines[:e.cursorRow+1], e.lines[e.cursorRow+2:]...)
-		e.dirty = true
+		e.killText("\n", false)
+		nextLine := e.lines.line(e.cursorRow + 1)
+		e.lines.set(e.cursorRow, append(line, nextLine...))
+		e.lines.remove(e.cursorRow+1, e.cursorRow+2)
+		e.MarkDirty()
 	}
 	e.updateDesiredCol()
 }
 
 // deleteWordBackward deletes the word before the cursor (Ctrl+W, Alt+Backspace)
 func (e *Editor) deleteWordBackward() {
-	if e.cursorRow >= len(e.lines) {
+	if e.cursorRow >= e.lines.len() {
 		return
 	}
 
-	line := e.lines[e.cursorRow]
+	line := e.lines.line(e.cursorRow)
 	if e.cursorCol == 0 {
 		e.deleteCharBackward()
 		return
@@ -761,22 +1187,22 @@ func (e *Editor) deleteWordBackward() {
 	}
 
 	deleted := string(line[e.cursorCol:startCol])
-	e.killBuffer = deleted
-	e.lines[e.cursorRow] = append(line[:e.cursorCol], line[startCol:]...)
+	e.killText(deleted, true)
+	e.lines.set(e.cursorRow, append(line[:e.cursorCol], line[startCol:]...))
 	e.updateDesiredCol()
 	if deleted != "" {
-		e.dirty = true
+		e.MarkDirty()
 	}
 	e.ensureCursorVisible()
 }
 
 // jumpWordForward moves cursor to start of next word (Ctrl+Right)
 func (e *Editor) jumpWordForward() {
-	if e.cursorRow >= len(e.lines) {
+	if e.cursorRow >= e.lines.len() {
 		return
 	}
 
-	line := e.lines[e.cursorRow]
+	line := e.lines.line(e.cursorRow)
 	for e.cursorCol < len(line) && isWordChar(line[e.cursorCol]) {
 		e.cursorCol++
 	}
@@ -784,7 +1210,7 @@ func (e *Editor) jumpWordForward() {
 		e.cursorCol++
 	}
 
-	if e.cursorCol >= len(line) && e.cursorRow < len(e.lines)-1 {
+	if e.cursorCol >= len(line) && e.cursorRow < e.lines.len()-1 {
 		e.cursorRow++
 		e.cursorCol = 0
 	}
@@ -794,15 +1220,15 @@ func (e *Editor) jumpWordForward() {
 
 // jumpWordBackward moves cursor to start of previous word (Ctrl+Left)
 func (e *Editor) jumpWordBackward() {
-	if e.cursorRow >= len(e.lines) {
+	if e.cursorRow >= e.lines.len() {
 		return
 	}
 
-	line := e.lines[e.cursorRow]
+	line := e.lines.line(e.cursorRow)
 	if e.cursorCol == 0 {
 		if e.cursorRow > 0 {
 			e.cursorRow--
-			e.cursorCol = len(e.lines[e.cursorRow])
+			e.cursorCol = len(e.lines.line(e.cursorRow))
 		}
 		e.updateDesiredCol()
 		e.ensureCursorVisible()
@@ -820,19 +1246,95 @@ func (e *Editor) jumpWordBackward() {
 	e.ensureCursorVisible()
 }
 
+// killCommand tells whether a key killed or yanked text, so the next key
+// can join the kill or cycle the yank
+type killCommand int
+
+const (
+	cmdOther killCommand = iota
+	cmdKill
+	cmdYank
+)
+
+// killRingSize is how many kills are kept for Alt+Y
+const killRingSize = 30
+
+// killText saves killed text to the kill ring. Right after another kill it's
+// joined to that one instead, in front of it when prepend is set, so several
+// Ctrl+K presses in a row yank back as one piece.
+func (e *Editor) killText(text string, prepend bool) {
+	if e.lastCommand == cmdKill && len(e.killRing) > 0 {
+		top := &e.killRing[len(e.killRing)-1]
+		if prepend {
+			*top = text + *top
+		} else {
+			*top += text
+		}
+	} else {
+		e.pushKill(text)
+	}
+	e.command = cmdKill
+}
+
+// pushKill adds text to the kill ring as a new entry, dropping the oldest
+// one when the ring is full
+func (e *Editor) pushKill(text string) {
+	if text == "" || (len(e.killRing) > 0 && e.killRing[len(e.killRing)-1] == text) {
+		e.command = cmdOther
+		return
+	}
+	e.killRing = append(e.killRing, text)
+	if len(e.killRing) > killRingSize {
+		e.killRing = e.killRing[1:]
+	}
+	e.command = cmdOther
+}
+
+// currentKill returns the most recent kill, or "" when nothing was killed
+func (e *Editor) currentKill() string {
+	if len(e.killRing) == 0 {
+		return ""
+	}
+	return e.killRing[len(e.killRing)-1]
+}
+
 // yankText inserts the killed text at cursor (Ctrl+Y)
 func (e *Editor) yankText() {
-	if e.killBuffer == "" {
+	if len(e.killRing) == 0 {
 		return
 	}
+	e.killRingPos = len(e.killRing) - 1
+	e.yankStart = e.GetCursor()
+	e.insertText(e.killRing[e.killRingPos])
+	e.yankEnd = e.GetCursor()
+	e.command = cmdYank
+}
 
-	for _, r := range e.killBuffer {
-		if r == '\n' {
-			e.insertNewline()
-		} else {
-			e.insertRune(r)
-		}
+// yankPop replaces the text just yanked with the kill before it, going round
+// to the most recent again after the oldest (Alt+Y). It only works right
+// after Ctrl+Y or another Alt+Y.
+func (e *Editor) yankPop() {
+	if e.lastCommand != cmdYank || len(e.killRing) < 2 || e.GetCursor() != e.yankEnd {
+		return
 	}
+	if e.textRange(e.yankStart, e.yankEnd) != e.killRing[e.killRingPos] {
+		return
+	}
+	e.killRingPos = (e.killRingPos + len(e.killRing) - 1) % len(e.killRing)
+	e.ReplaceRange(e.yankStart, e.yankEnd, e.killRing[e.killRingPos])
+	e.yankEnd = e.GetCursor()
+	e.command = cmdYank
+}
+
+// insertText inserts text at the cursor, leaving the cursor after it
+func (e *Editor) insertText(text string) {
+	if text == "" {
+		return
+	}
+	e.cursorRow, e.cursorCol = e.spliceText(e.cursorRow, e.cursorCol, e.cursorRow, e.cursorCol, text)
+	e.updateDesiredCol()
+	e.ensureCursorVisible()
+	e.MarkDirty()
 }
 
 // pageUp scrolls up one page
@@ -842,7 +1344,7 @@ func (e *Editor) pageUp() {
 		e.viewportRow = 0
 	}
 	for i := 0; i < e.height; i++ {
-		newRow, newCol := e.moveVisualLineUp(e.cursorRow, e.cursorCol, e.width, e.lines)
+		newRow, newCol := e.moveVisualLineUp(e.cursorRow, e.cursorCol, e.textWidth())
 		if newRow == e.cursorRow && newCol == e.cursorCol {
 			break
 		}
@@ -864,7 +1366,7 @@ func (e *Editor) pageDown() {
 		e.viewportRow = maxVisual
 	}
 	for i := 0; i < e.height; i++ {
-		newRow, newCol := e.moveVisualLineDown(e.cursorRow, e.cursorCol, e.width, e.lines)
+		newRow, newCol := e.moveVisualLineDown(e.cursorRow, e.cursorCol, e.textWidth())
 		if newRow == e.cursorRow && newCol == e.cursorCol {
 			break
 		}
@@ -885,9 +1387,9 @@ func (e *Editor) moveToTop() {
 
 // moveToBottom moves cursor to the very end of the document
 func (e *Editor) moveToBottom() {
-	if len(e.lines) > 0 {
-		e.cursorRow = len(e.lines) - 1
-		e.cursorCol = len(e.lines[e.cursorRow])
+	if e.lines.len() > 0 {
+		e.cursorRow = e.lines.len() - 1
+		e.cursorCol = len(e.lines.line(e.cursorRow))
 	}
 	e.updateDesiredCol()
 	e.ensureCursorVisible()
@@ -921,6 +1423,9 @@ func (e *Editor) Update(msg tea.Msg) tea.Cmd {
 
 	switch msg := msg.(type) {
 	case tea.MouseMsg:
+		if e.mouseDisabled {
+			return nil
+		}
 		mouseEvent := tea.MouseEvent(msg)
 
 		switch {
@@ -931,9 +1436,30 @@ func (e *Editor) Update(msg tea.Msg) tea.Cmd {
 			e.cursorCol = col
 			e.clampCursor()
 			e.updateDesiredCol()
-			e.selectionAnchor = e.GetCursor()
-			e.selecting = true
-			e.hasSelection = false
+
+			// Quick clicks in the same place select the word, then the line
+			now := time.Now()
+			if now.Sub(e.lastClick) < doubleClickTime && e.GetCursor() == e.lastClickPos {
+				e.clickCount = e.clickCount%3 + 1
+			} else {
+				e.clickCount = 1
+			}
+			e.lastClick = now
+			e.lastClickPos = e.GetCursor()
+			switch e.clickCount {
+			case 2:
+				e.selectWordAtCursor()
+				e.selecting = false
+				e.copySelection()
+			case 3:
+				e.selectRows(e.cursorRow, e.cursorRow)
+				e.selecting = false
+				e.copySelection()
+			default:
+				e.selectionAnchor = e.GetCursor()
+				e.selecting = true
+				e.hasSelection = false
+			}
 
 		case mouseEvent.Action == tea.MouseActionMotion && e.selecting:
 			// Extend selection during drag
@@ -947,10 +1473,9 @@ func (e *Editor) Update(msg tea.Msg) tea.Cmd {
 			}
 
 		case mouseEvent.Button == tea.MouseButtonLeft && mouseEvent.Action == tea.MouseActionRelease:
-			// End drag: copy selection to kill buffer and primary selection
-			if e.selecting && e.hasSelection {
-				e.killBuffer = e.getSelectedText()
-				copyToPrimarySelection(e.killBuffer)
+			// End drag: copy selection to the kill ring and primary selection
+			if e.selecting {
+				e.copySelection()
 			}
 			e.selecting = false
 
@@ -981,7 +1506,7 @@ func (e *Editor) Update(msg tea.Msg) tea.Cmd {
 			}
 
 		case mouseEvent.Button == tea.MouseButtonMiddle && mouseEvent.Action == tea.MouseActionPress:
-			// Middle click: place cursor and paste kill buffer
+			// Middle click: place cursor and paste the last kill
 			row, col := e.mouseToPosition(mouseEvent.X, mouseEvent.Y)
 			e.cursorRow = row
 			e.cursorCol = col
@@ -993,6 +1518,74 @@ func (e *Editor) Update(msg tea.Msg) tea.Cmd {
 		return nil
 
 	case tea.KeyMsg:
+		e.lastCommand, e.command = e.command, cmdOther
+
+		// The find prompt takes the keys it handles
+		if e.findMode != findOff && e.HandleFindKey(msg) {
+			return nil
+		}
+		if e.vimKeys && e.HandleVimKey(msg) {
+			return nil
+		}
+
+		// Tab moves to the next snippet placeholder, expands a trigger or
+		// indents; Shift+Tab takes a level of indentation off
+		switch msg.String() {
+		case "tab":
+			if e.nextSnippetStop() {
+				return nil
+			}
+			if e.snippetActive && e.hasSelection {
+				// The last placeholder is still selected: leave it as it is
+				e.snippetActive = false
+				e.clearSelection()
+				return nil
+			}
+			e.snippetActive = false
+			if e.hasSelection {
+				e.indentLines(false)
+			} else if !e.expandSnippet() {
+				e.insertIndent()
+			}
+			return nil
+		case "shift+tab":
+			e.indentLines(true)
+			return nil
+		}
+
+		// Shift+movement extends the selection from the keyboard
+		switch msg.String() {
+		case "shift+left", "shift+right", "shift+up", "shift+down", "shift+home", "shift+end",
+			"ctrl+shift+left", "ctrl+shift+right", "ctrl+shift+home", "ctrl+shift+end":
+			if !e.hasSelection {
+				e.selectionAnchor = e.GetCursor()
+			}
+			switch msg.String() {
+			case "shift+left":
+				e.moveLeft()
+			case "shift+right":
+				e.moveRight()
+			case "shift+up":
+				e.moveUp()
+			case "shift+down":
+				e.moveDown()
+			case "shift+home":
+				e.moveToLineStart()
+			case "shift+end":
+				e.moveToLineEnd()
+			case "ctrl+shift+left":
+				e.jumpWordBackward()
+			case "ctrl+shift+right":
+				e.jumpWordForward()
+			case "ctrl+shift+home":
+				e.moveToTop()
+			case "ctrl+shift+end":
+				e.moveToBottom()
+			}
+			e.hasSelection = e.GetCursor() != e.selectionAnchor
+			return nil
+		}
+
 		// Handle selection: delete/backspace replace selection, other keys clear it
 		if e.hasSelection {
 			switch msg.String() {
@@ -1003,6 +1596,17 @@ func (e *Editor) Update(msg tea.Msg) tea.Cmd {
 				e.deleteSelection()
 				e.insertNewline()
 				return nil
+			case "alt+w", "ctrl+w":
+				// Copy or cut the selection to the kill ring
+				e.copySelection()
+				if msg.String() == "ctrl+w" {
+					e.deleteSelection()
+				} else {
+					e.clearSelection()
+				}
+				return nil
+			case "alt+s", "alt+S", "alt+n", "ctrl+f", "ctrl+r", "alt+up", "alt+down", "ctrl+d":
+				// Sorting, find and line moves use the selection, handled below
 			case "ctrl+h", "up", "down", "left", "right", "home", "end",
 				"ctrl+left", "ctrl+right", "ctrl+home", "ctrl+end",
 				"pgup", "pgdown", "escape":
@@ -1033,7 +1637,7 @@ func (e *Editor) Update(msg tea.Msg) tea.Cmd {
 
 		switch msg.String() {
 		case "enter":
-			e.insertNewline()
+			e.insertNewlineContinuingList()
 		case "backspace":
 			e.deleteCharBackward()
 		case "delete":
@@ -1058,6 +1662,8 @@ func (e *Editor) Update(msg tea.Msg) tea.Cmd {
 			e.deleteWordBackward()
 		case "ctrl+y":
 			e.yankText()
+		case "alt+y":
+			e.yankPop()
 		case "ctrl+left":
 			e.jumpWordBackward()
 		case "ctrl+right":
@@ -1070,6 +1676,22 @@ func (e *Editor) Update(msg tea.Msg) tea.Cmd {
 			e.moveToTop()
 		case "ctrl+end":
 			e.moveToBottom()
+		case "alt+up":
+			e.moveLines(-1)
+		case "alt+down":
+			e.moveLines(1)
+		case "ctrl+d":
+			e.duplicateLines()
+		case "alt+s":
+			e.sortSelectedLines(sortAscending)
+		case "alt+S":
+			e.sortSelectedLines(sortDescending)
+		case "alt+n":
+			e.sortSelectedLines(sortNumeric)
+		case "ctrl+f":
+			e.StartFind(false)
+		case "ctrl+r":
+			e.StartFind(true)
 		default:
 			if len(msg.Runes) > 0 {
 				for _, r := range msg.Runes {
@@ -1093,7 +1715,7 @@ func (e *Editor) View() string {
 		return e.renderHelp()
 	}
 
-	if len(e.lines) == 0 {
+	if e.lines.len() == 0 {
 		if e.placeholder != "" {
 			return lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(e.placeholder)
 		}
@@ -1101,8 +1723,18 @@ func (e *Editor) View() string {
 	}
 
 	var sb strings.Builder
+	width := e.textWidth()
 	reverseStyle := lipgloss.NewStyle().Reverse(true)
 	selStyle := lipgloss.NewStyle().Background(lipgloss.Color("69")).Foreground(lipgloss.Color("255"))
+	matchStyle := lipgloss.NewStyle().Background(lipgloss.Color("238")).Foreground(lipgloss.Color("255"))
+	spellStyle := lipgloss.NewStyle().Underline(true).Foreground(errorColor)
+	linkStyle := lipgloss.NewStyle().Underline(true).Foreground(linkColor)
+
+	// Highlight every search match while the find prompt is open
+	var matchCols map[int][][2]int
+	if e.findMode != findOff {
+		matchCols = e.matchColumns()
+	}
 
 	// Get selection range in row/col coordinates
 	selStartRow, selStartCol, selEndRow, selEndCol := e.selectionRange()
@@ -1114,13 +1746,16 @@ func (e *Editor) View() string {
 	// Track character offset incrementally for logical lines before viewport
 	lineOffset := 0
 	for i := 0; i < startLogical; i++ {
-		lineOffset += len(e.lines[i]) + 1
+		lineOffset += len(e.lines.line(i)) + 1
 	}
 
 	// Render individual visual lines for consistent output height.
-	for row := startLogical; row < len(e.lines) && visualLinesRendered < e.height; row++ {
-		line := e.lines[row]
-		lineVisualLines := e.countVisualLines(line, e.width)
+	for row := startLogical; row < e.lines.len() && visualLinesRendered < e.height; row++ {
+		line := e.lines.line(row)
+		starts := wrapLine(line, width)
+		lineVisualLines := len(starts)
+		// At the end of a full last visual line the cursor gets a line of its own
+		cursorOwnLine := e.focused && row == e.cursorRow && e.visualLineAt(line, e.cursorCol, width) == lineVisualLines
 
 		firstVisual := 0
 		if row == startLogical {
@@ -1128,15 +1763,20 @@ func (e *Editor) View() string {
 		}
 
 		for v := firstVisual; v < lineVisualLines && visualLinesRendered < e.height; v++ {
-			startCol := v * e.width
-			endCol := startCol + e.width
-			if endCol > len(line) {
-				endCol = len(line)
+			startCol := starts[v]
+			endCol := len(line)
+			if v+1 < lineVisualLines {
+				endCol = starts[v+1]
 			}
 
 			if visualLinesRendered > 0 {
 				sb.WriteRune('\n')
 			}
+			if v == 0 {
+				e.renderLineNumber(&sb, row)
+			} else {
+				e.renderLineNumber(&sb, -1)
+			}
 
 			segment := line[startCol:endCol]
 
@@ -1198,12 +1838,21 @@ func (e *Editor) View() string {
 				}
 			}
 
+			// Search matches, [[links]] and misspelled words within this segment
+			segMatches := clipColumns(matchCols[row], startCol, len(segment))
+			segLinks := clipColumns(wikilinkColumns(line), startCol, len(segment))
+			var segMisspelled [][2]int
+			if e.spell != nil {
+				segMisspelled = clipColumns(e.misspelledColumns(row), startCol, len(segment))
+			}
+
 			// Render the segment with selection highlighting and cursor
-			e.renderSegment(&sb, segment, cursorPos, segSelStart, segSelEnd, reverseStyle, selStyle)
+			e.renderSegment(&sb, segment, cursorPos, segSelStart, segSelEnd, segMatches, segLinks, segMisspelled,
+				reverseStyle, selStyle, matchStyle, linkStyle, spellStyle)
 
 			// Handle cursor at end of logical line (on last visual line)
 			if e.focused && row == e.cursorRow && e.cursorCol == len(line) &&
-				v == lineVisualLines-1 && e.cursorCol-startCol == len(segment) {
+				v == lineVisualLines-1 && !cursorOwnLine {
 				sb.WriteString(reverseStyle.Render(" "))
 			}
 
@@ -1216,13 +1865,12 @@ func (e *Editor) View() string {
 			visualLinesRendered++
 		}
 
-		// Handle cursor at end of line when line length is exact multiple of width
-		if e.focused && row == e.cursorRow && e.cursorCol == len(line) &&
-			len(line) > 0 && e.width > 0 && len(line)%e.width == 0 &&
-			visualLinesRendered < e.height {
+		// Handle cursor at end of line when the line fills its last visual line
+		if cursorOwnLine && visualLinesRendered < e.height {
 			if visualLinesRendered > 0 {
 				sb.WriteRune('\n')
 			}
+			e.renderLineNumber(&sb, -1)
 			sb.WriteString(reverseStyle.Render(" "))
 			visualLinesRendered++
 		}
@@ -1231,52 +1879,120 @@ func (e *Editor) View() string {
 	}
 
 	// Show placeholder if empty and not focused
-	if len(e.lines) == 1 && len(e.lines[0]) == 0 && !e.focused && e.placeholder != "" {
+	if e.lines.len() == 1 && len(e.lines.line(0)) == 0 && !e.focused && e.placeholder != "" {
 		return lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(e.placeholder)
 	}
 
 	return sb.String()
 }
 
-// renderSegment renders a segment with batched styling for cursor and selection.
-func (e *Editor) renderSegment(sb *strings.Builder, segment []rune, cursorPos, selStart, selEnd int, reverseStyle, selStyle lipgloss.Style) {
+// renderLineNumber writes the gutter for a visual line: the number of row,
+// or blank for a wrapped continuation when row is -1
+func (e *Editor) renderLineNumber(sb *strings.Builder, row int) {
+	gutter := e.gutterWidth()
+	if gutter == 0 {
+		return
+	}
+	if row < 0 {
+		sb.WriteString(strings.Repeat(" ", gutter))
+		return
+	}
+	number, color := row+1, "240"
+	if row == e.cursorRow {
+		color = "250"
+	} else if e.relativeNumbers {
+		number = max(row-e.cursorRow, e.cursorRow-row)
+	}
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color(color))
+	sb.WriteString(style.Render(fmt.Sprintf("%*d", gutter-1, number)) + " ")
+}
+
+// clipColumns returns the column ranges within the visual line of length
+// columns starting at column start, relative to that start
+func clipColumns(ranges [][2]int, start, length int) [][2]int {
+	var clipped [][2]int
+	for _, r := range ranges {
+		if from, to := max(r[0]-start, 0), min(r[1]-start, length); from < to {
+			clipped = append(clipped, [2]int{from, to})
+		}
+	}
+	return clipped
+}
+
+// renderSegment renders a segment with batched styling for cursor, selection,
+// search matches, [[links]] and misspelled words. The selection wins over a
+// match it overlaps, a match over a link, and a link over a misspelling.
+func (e *Editor) renderSegment(sb *strings.Builder, segment []rune, cursorPos, selStart, selEnd int, matches, links, misspelled [][2]int, reverseStyle, selStyle, matchStyle, linkStyle, spellStyle lipgloss.Style) {
 	if len(segment) == 0 {
 		return
 	}
 
-	// No selection and no cursor: fast path
-	if selStart < 0 && cursorPos < 0 {
-		sb.WriteString(string(segment))
+	// No selection, cursor, matches, links or misspellings: fast path
+	if selStart < 0 && cursorPos < 0 && len(matches) == 0 && len(links) == 0 && len(misspelled) == 0 {
+		sb.WriteString(expandTabs(string(segment)))
 		return
 	}
 
+	const (
+		plain = iota
+		selected
+		matched
+		linked
+		misspelt
+	)
+	styleAt := func(i int) int {
+		if selStart >= 0 && i >= selStart && i < selEnd {
+			return selected
+		}
+		for _, m := range matches {
+			if i >= m[0] && i < m[1] {
+				return matched
+			}
+		}
+		for _, l := range links {
+			if i >= l[0] && i < l[1] {
+				return linked
+			}
+		}
+		for _, m := range misspelled {
+			if i >= m[0] && i < m[1] {
+				return misspelt
+			}
+		}
+		return plain
+	}
+
 	// Render in styled runs
 	i := 0
 	for i < len(segment) {
 		isCur := i == cursorPos
-		isSel := selStart >= 0 && i >= selStart && i < selEnd
+		style := styleAt(i)
 
 		if isCur {
-			// Cursor is always a single character
-			sb.WriteString(reverseStyle.Render(string(segment[i : i+1])))
-			i++
+			// Cursor covers a whole character, combining marks included
+			end := nextGrapheme(segment, i)
+			sb.WriteString(reverseStyle.Render(expandTabs(string(segment[i:end]))))
+			i = end
 			continue
 		}
 
 		// Find end of current run (same style, not cursor)
 		runEnd := i + 1
-		for runEnd < len(segment) && runEnd != cursorPos {
-			nextSel := selStart >= 0 && runEnd >= selStart && runEnd < selEnd
-			if nextSel != isSel {
-				break
-			}
+		for runEnd < len(segment) && runEnd != cursorPos && styleAt(runEnd) == style {
 			runEnd++
 		}
 
-		text := string(segment[i:runEnd])
-		if isSel {
+		text := expandTabs(string(segment[i:runEnd]))
+		switch style {
+		case selected:
 			sb.WriteString(selStyle.Render(text))
-		} else {
+		case matched:
+			sb.WriteString(matchStyle.Render(text))
+		case linked:
+			sb.WriteString(linkStyle.Render(text))
+		case misspelt:
+			sb.WriteString(spellStyle.Render(text))
+		default:
 			sb.WriteString(text)
 		}
 		i = runEnd
@@ -1296,9 +2012,21 @@ func (e *Editor) renderHelp() string {
 ║    End  / Ctrl+E     End of current line                    ║
 ║    Ctrl+Home         Start of entire document               ║
 ║    Ctrl+End          End of entire document                 ║
+║    Shift+Arrows      Select text                            ║
+║    Ctrl+Shift+←/→    Select by word                         ║
 ║    Page Up/Down      Scroll by page                         ║
 ║    Ctrl+Left         Jump word backward                     ║
 ║    Ctrl+Right        Jump word forward                      ║
+║    Alt+M, a-z        Set a bookmark                         ║
+║    Alt+J, a-z        Jump to a bookmark                     ║
+║    Ctrl+]            Follow the [[link]] at the cursor      ║
+║    Ctrl+F            Search; after Enter, n/N next/previous ║
+║    Ctrl+R            Find and replace (ctrl+a: all)         ║
+║    Alt+R             Toggle regex while finding             ║
+║    Alt+L             Toggle line numbers                    ║
+║    F7                Toggle spell checking                  ║
+║    F8                Suggest spellings for the word         ║
+║    Alt+A             Attach, open or link files             ║
 ║                                                              ║
 ║  EDITING                                                     ║
 ║    Enter             New line                               ║
@@ -1309,10 +2037,29 @@ func (e *Editor) renderHelp() string {
 ║    Ctrl+W            Delete word backward                   ║
 ║    Alt+Backspace     Delete word backward                   ║
 ║    Ctrl+Y            Yank (paste) killed text               ║
+║    Alt+Y             After a yank, cycle to earlier kills   ║
+║    Alt+W / Ctrl+W    Copy / cut the selection               ║
+║    Tab               Expand snippet / next placeholder      ║
+║                      or indent (the selected lines)         ║
+║    Shift+Tab         Unindent the line or selected lines    ║
+║    Alt+Up/Down       Move the line or selected lines        ║
+║    Ctrl+D            Duplicate the line or selected lines   ║
+║    Alt+S             Sort selected lines                    ║
+║    Alt+Shift+S       Sort selected lines in reverse         ║
+║    Alt+N             Sort selected lines numerically        ║
+║                                                              ║
+║  VIM MODE (vim_mode in config)                               ║
+║    i a I A o O       Insert mode; Esc back to normal mode   ║
+║    h j k l  w b      Move by character/line/word            ║
+║    0 ^ $  gg G       Line start/end, first/last line        ║
+║    x  dd  yy  p P    Delete char/line, copy line, paste     ║
+║    v                 Visual mode, then d or y               ║
 ║                                                              ║
 ║  MOUSE                                                       ║
 ║    Click             Place cursor                           ║
 ║    Drag              Select text                            ║
+║    Double click      Select word                            ║
+║    Triple click      Select line                            ║
 ║    Wheel+Drag        Scroll and extend selection            ║
 ║    Middle click      Paste last selection                   ║
 ║                                                              ║
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pressEnter sets the editor's text, with the cursor where "|" is, and
+// presses enter. It returns the text after, with "|" at the cursor.
+func pressEnter(e *Editor, text string) string {
+	at := strings.Index(text, "|")
+	e.SetValue(strings.Replace(text, "|", "", 1))
+	e.SetCursor(len([]rune(text[:at])))
+	e.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	value := []rune(e.Value())
+	cursor := e.GetCursor()
+	return string(value[:cursor]) + "|" + string(value[cursor:])
+}
+
+func TestEditorContinueLists(t *testing.T) {
+	e := NewEditor()
+	e.Focus()
+	e.SetListContinuation(true, true)
+	for _, tc := range []struct{ before, after string }{
+		// Unordered items, with their indentation and checkbox
+		{"- milk|", "- milk\n- |"},
+		{"* milk|", "* milk\n* |"},
+		{"+ milk|", "+ milk\n+ |"},
+		{"  - nested|", "  - nested\n  - |"},
+		{"- [x] done|", "- [x] done\n- [ ] |"},
+		{"- mi|lk", "- mi\n- |lk"},
+		// Ordered items, numbered on from the item and the rest renumbered
+		{"1. one|", "1. one\n2. |"},
+		{"9) nine|", "9) nine\n10) |"},
+		{"1. one|\n2. two\n   more\n3. three\nafter", "1. one\n2. |\n3. two\n   more\n4. three\nafter"},
+		// An empty item ends the list
+		{"- milk\n- |", "- milk\n|"},
+		{"1. one\n2. |", "1. one\n|"},
+		{"- [ ] |", "|"},
+		// Lines that aren't items, or a cursor in the marker, just split
+		{"milk|", "milk\n|"},
+		{"-milk|", "-milk\n|"},
+		{"-| milk", "-\n| milk"},
+	} {
+		if got := pressEnter(&e, tc.before); got != tc.after {
+			t.Errorf("enter in %q gives %q, want %q", tc.before, got, tc.after)
+		}
+	}
+
+	e.SetListContinuation(true, false)
+	if got, want := pressEnter(&e, "1. one|\n2. two"), "1. one\n2. |\n2. two"; got != want {
+		t.Errorf("without renumbering, enter gives %q, want %q", got, want)
+	}
+	e.SetListContinuation(false, false)
+	if got, want := pressEnter(&e, "- milk|"), "- milk\n|"; got != want {
+		t.Errorf("with continue_lists off, enter gives %q, want %q", got, want)
+	}
+}
+
+// selectText selects from offset from to offset to, the cursor at to
+func selectText(e *Editor, from, to int) {
+	e.SetCursor(to)
+	e.selectionAnchor, e.hasSelection = from, true
+}
+
+func TestEditorSortSelectedLines(t *testing.T) {
+	text := "head\ncherry\napple\nBanana\nbanana\ntail"
+	for _, tc := range []struct {
+		key, want string
+	}{
+		{"alt+s", "head\nBanana\napple\nbanana\ncherry\ntail"},
+		{"alt+S", "head\ncherry\nbanana\napple\nBanana\ntail"},
+	} {
+		e := NewEditor()
+		e.Focus()
+		e.SetValue(text)
+		// From the middle of "cherry" to the middle of "banana"
+		selectText(&e, 8, 27)
+		e.Update(keyMsg(tc.key))
+		if got := e.Value(); got != tc.want {
+			t.Errorf("%s gives %q, want %q", tc.key, got, tc.want)
+		}
+		// The sorted block is selected, whole lines
+		if got, want := e.getSelectedText(), strings.Join(strings.Split(tc.want, "\n")[1:5], "\n"); got != want {
+			t.Errorf("%s leaves %q selected, want %q", tc.key, got, want)
+		}
+		if !e.Dirty() {
+			t.Errorf("%s doesn't mark the editor dirty", tc.key)
+		}
+	}
+}
+
+func TestEditorSortSelectedLinesNumeric(t *testing.T) {
+	e := NewEditor()
+	e.Focus()
+	e.SetValue("10 apples\nno number\n9 pears\n-2 debt\nalso none\n1.5 kg\nitem 3\n")
+	// Ending at the start of the last line leaves that line out
+	selectText(&e, 0, e.length())
+	e.Update(keyMsg("alt+n"))
+	want := "-2 debt\n1.5 kg\nitem 3\n9 pears\n10 apples\nno number\nalso none\n"
+	if got := e.Value(); got != want {
+		t.Errorf("numeric sort gives %q, want %q", got, want)
+	}
+}
+
+func TestEditorSortWithoutSelection(t *testing.T) {
+	e := NewEditor()
+	e.Focus()
+	e.SetValue("b\na")
+	e.SetCursor(1)
+	e.Update(keyMsg("alt+s"))
+	if e.Value() != "b\na" || e.Dirty() {
+		t.Errorf("without a selection sorting gives %q, dirty %v", e.Value(), e.Dirty())
+	}
+}
+
+func TestEditorMouseDisabled(t *testing.T) {
+	text := strings.Repeat("line of text\n", 40)
+	click := tea.MouseMsg{X: 4, Y: 2, Button: tea.MouseButtonLeft, Action: tea.MouseActionPress}
+	drag := tea.MouseMsg{X: 8, Y: 3, Button: tea.MouseButtonLeft, Action: tea.MouseActionMotion}
+	wheel := tea.MouseMsg{X: 0, Y: 0, Button: tea.MouseButtonWheelDown, Action: tea.MouseActionPress}
+	newEditor := func(enabled bool) Editor {
+		e := NewEditor()
+		e.SetWidth(40)
+		e.SetHeight(10)
+		e.Focus()
+		e.SetValue(text)
+		e.SetCursor(0)
+		e.SetMouseEnabled(enabled)
+		return e
+	}
+
+	// By default a click places the cursor, a drag selects and the wheel scrolls
+	e := newEditor(true)
+	e.Update(click)
+	e.Update(drag)
+	if row, col := e.CursorLineCol(); row == 1 && col == 1 || !e.hasSelection {
+		t.Fatalf("with the mouse on, clicking and dragging leaves the cursor at %d:%d, selection %v", row, col, e.hasSelection)
+	}
+	e.Update(wheel)
+	if e.viewportRow == 0 {
+		t.Error("with the mouse on, the wheel doesn't scroll")
+	}
+
+	e = newEditor(false)
+	e.Update(click)
+	e.Update(drag)
+	e.Update(wheel)
+	if row, col := e.CursorLineCol(); row != 1 || col != 1 || e.hasSelection || e.viewportRow != 0 {
+		t.Errorf("with the mouse off, mouse events move the cursor to %d:%d, select %v, scroll to %d", row, col, e.hasSelection, e.viewportRow)
+	}
+	// Shift+arrows still select
+	e.Update(tea.KeyMsg{Type: tea.KeyShiftRight})
+	e.Update(tea.KeyMsg{Type: tea.KeyShiftDown})
+	if got := e.getSelectedText(); got != "line of text\nl" {
+		t.Errorf("shift+right, shift+down selects %q", got)
+	}
+}
+
+// The sticky header shows the last heading at or above the top row shown
+func TestEditorCurrentHeading(t *testing.T) {
+	lines := []string{
+		"intro",
+		"# Top",
+		"text",
+		"## Sub",
+		strings.Repeat("a long line that wraps ", 6),
+		"#tag, not a heading",
+		"####### seven, not a heading",
+		"### Deep",
+		"end",
+	}
+	e := NewEditor()
+	e.SetWidth(30)
+	e.SetHeight(3)
+	e.SetValue(strings.Join(lines, "\n"))
+
+	for _, tc := range []struct {
+		row     int // the logical line at the top
+		wrapped int // visual lines into it
+		want    string
+	}{
+		{0, 0, ""},
+		{1, 0, "# Top"},
+		{2, 0, "# Top"},
+		{3, 0, "## Sub"},
+		{4, 0, "## Sub"},
+		{4, 2, "## Sub"},
+		{6, 0, "## Sub"},
+		{7, 0, "### Deep"},
+		{8, 0, "### Deep"},
+	} {
+		e.viewportRow = e.logicalToVisualRow(tc.row, 0) + tc.wrapped
+		if got := e.CurrentHeading(); got != tc.want {
+			t.Errorf("scrolled to line %d (+%d): heading %q, want %q", tc.row, tc.wrapped, got, tc.want)
+		}
+	}
+	if e.countVisualLines(4, e.textWidth()) < 3 {
+		t.Error("the long line doesn't wrap, the scroll positions within it aren't tested")
+	}
+}
+
+func TestStickyHeader(t *testing.T) {
+	newTestVault(t)
+	var text strings.Builder
+	text.WriteString("# Long\n")
+	for i := range 40 {
+		fmt.Fprintf(&text, "line %d\n", i)
+	}
+	writeTestNote(t, "Long.md", text.String())
+	m := newTestModel(t, 60, 20)
+	m.openNote(m.currentNode.children[0])
+	m.Update(tea.WindowSizeMsg{Width: 60, Height: 20})
+	height := m.editor.height
+
+	config.StickyHeader = true
+	m.Update(tea.WindowSizeMsg{Width: 60, Height: 20})
+	if m.editor.height != height-1 {
+		t.Errorf("editor is %d rows with the header, want %d", m.editor.height, height-1)
+	}
+	if m.editor.yOffset != 2 {
+		t.Errorf("mouse rows start at %d, want 2 below the title and header", m.editor.yOffset)
+	}
+
+	m.editor.SetCursor(text.Len() - 1)
+	s := render(m)
+	if len(s.rows) != 20 {
+		t.Errorf("screen is %d rows, want 20", len(s.rows))
+	}
+	if got := strings.TrimSpace(s.rows[1]); got != "# Long" {
+		t.Errorf("row under the title is %q, want the heading", got)
+	}
+	if row, _ := s.find("line 39"); row != 1+m.editor.height {
+		t.Errorf("last line shown on row %d, want %d", row, 1+m.editor.height)
+	}
+	s.assertWidth(t, 60)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// exportFolder concatenates the notes under folder into one Markdown
+// document. Each note's body, without frontmatter, goes under a heading with
+// its title; subfolders get a heading of their own with their notes one
+// level deeper. Entries are in each folder's sort order and joined by
+// separator.
+func exportFolder(folder *note, sorts map[string]folderSort, separator string) string {
+	var sections []string
+	var walk func(dir *note, level int)
+	walk = func(dir *note, level int) {
+		children := slices.Clone(dir.children)
+		sortChildren(children, folderSortMode(sorts, dir.path), sorts[dir.path].Order)
+		for _, child := range children {
+			heading := strings.Repeat("#", min(level, 6)) + " " + child.title + "\n"
+			if child.isDir {
+				sections = append(sections, heading)
+				walk(child, level+1)
+				continue
+			}
+
+			_, body, _ := parseFrontmatter(child.content)
+			if body = strings.Trim(body, "\n"); body != "" {
+				heading += "\n" + body + "\n"
+			}
+			sections = append(sections, heading)
+		}
+	}
+	walk(folder, 1)
+	return strings.Join(sections, separator)
+}
+
+// exportNote returns the note n as a Markdown document, its title as the
+// heading over its body without frontmatter
+func exportNote(n *note) string {
+	doc := "# " + n.title + "\n"
+	_, body, _ := parseFrontmatter(n.content)
+	if body = strings.Trim(body, "\n"); body != "" {
+		doc += "\n" + body + "\n"
+	}
+	return doc
+}
+
+// exportFormats are the formats notes are exported to
+var exportFormats = []string{"md", "html", "pdf"}
+
+// writeExport writes the Markdown document doc titled title to path, in
+// format: as it is, as a styled HTML page, or as a PDF printed from that
+// page by pdf_command
+func writeExport(doc, title, format, path string) error {
+	switch format {
+	case "md":
+		return os.WriteFile(path, []byte(doc), 0644)
+	case "html":
+		return os.WriteFile(path, []byte(htmlPage(title, markdownHTML(doc))), 0644)
+	case "pdf":
+		return writePDF(htmlPage(title, markdownHTML(doc)), path)
+	}
+	return fmt.Errorf("no export format %q, use one of %s", format, strings.Join(exportFormats, ", "))
+}
+
+// writePDF prints the HTML page page to the PDF file path with pdf_command,
+// its {{in}} and {{out}} replaced by the page's file and path
+func writePDF(page, path string) error {
+	fields := strings.Fields(config.PDFCommand)
+	if len(fields) == 0 {
+		return fmt.Errorf("set pdf_command in %s to export PDFs", getConfigPath())
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return fmt.Errorf("%q not found, set pdf_command in %s", fields[0], getConfigPath())
+	}
+	in, err := os.CreateTemp("", "notes-export-*.html")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(in.Name())
+	_, err = in.WriteString(page)
+	if closeErr := in.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	out, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	replacer := strings.NewReplacer("{{in}}", in.Name(), "{{out}}", out)
+	for i, field := range fields {
+		fields[i] = replacer.Replace(field)
+	}
+	if output, err := exec.Command(fields[0], fields[1:]...).CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("%s: %s", fields[0], msg)
+		}
+		return fmt.Errorf("%s: %w", fields[0], err)
+	}
+	return nil
+}
+
+// exportPath names the file the note or folder n is exported to in
+// export_folder, in format
+func exportPath(n *note, format string) string {
+	name := sanitizeTitle(n.title)
+	if n.parent == nil {
+		name = sanitizeTitle(filepath.Base(n.path))
+	}
+	return filepath.Join(config.ExportFolder, name+"."+format)
+}
+
+// exportCmd exports the note or folder n to export_folder in the
+// export_format in the background, and reports how it went as a notice
+func (m *model) exportCmd(n *note) tea.Cmd {
+	doc := exportNote(n)
+	if n.isDir {
+		doc = exportFolder(n, m.folderSorts, config.ExportSeparator)
+	}
+	format := config.ExportFormat
+	return func() tea.Msg {
+		path := exportPath(n, format)
+		err := os.MkdirAll(config.ExportFolder, 0755)
+		if err == nil {
+			err = writeExport(doc, n.title, format, path)
+		}
+		if err != nil {
+			return noticeMsg{text: fmt.Sprintf("Export failed: %v", err), isErr: true}
+		}
+		return noticeMsg{text: "Exported to " + path}
+	}
+}
+
+// runExport implements "notes export" and returns the exit code
+func runExport(root *note, args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	folder := flags.String("folder", "", "Folder to export, relative to the notes path (default: all notes)")
+	noteName := flags.String("note", "", "Note to export instead of a folder, by title or path")
+	out := flags.String("out", "", "File to write (default: standard output)")
+	format := flags.String("format", "", "md, html or pdf (default: from the -out extension, else md)")
+	separator := flags.String("sep", config.ExportSeparator, "Text placed between notes")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format == "" {
+		*format = "md"
+		if ext := strings.TrimPrefix(filepath.Ext(*out), "."); slices.Contains(exportFormats, ext) {
+			*format = ext
+		}
+	}
+	if !slices.Contains(exportFormats, *format) {
+		fmt.Fprintf(os.Stderr, "notes export: -format is one of %s, not %q\n", strings.Join(exportFormats, ", "), *format)
+		return 2
+	}
+	if *format == "pdf" && *out == "" {
+		fmt.Fprintln(os.Stderr, "notes export: a PDF needs a file to go to, give -out")
+		return 2
+	}
+	if *noteName != "" && *folder != "" {
+		fmt.Fprintln(os.Stderr, "notes export: -note and -folder can't be used together")
+		return 2
+	}
+
+	var doc, title string
+	if *noteName != "" {
+		n := resolveNoteArg(root, *noteName)
+		if n == nil {
+			fmt.Fprintf(os.Stderr, "notes export: no note %q in %s\n", *noteName, notesPath)
+			return 1
+		}
+		doc, title = exportNote(n), n.title
+	} else {
+		dir := root
+		if *folder != "" {
+			path := *folder
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(notesPath, path)
+			}
+			dir = findNoteByPath(root, filepath.Clean(path))
+			if dir == nil || !dir.isDir {
+				fmt.Fprintf(os.Stderr, "notes export: %s is not a folder in %s\n", *folder, notesPath)
+				return 1
+			}
+		}
+		doc, title = exportFolder(dir, loadFolderSorts(), *separator), filepath.Base(dir.path)
+	}
+
+	if *out == "" {
+		if *format == "html" {
+			doc = htmlPage(title, markdownHTML(doc))
+		}
+		fmt.Print(doc)
+		return 0
+	}
+	if err := writeExport(doc, title, *format, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "notes export: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// exportVault writes a nested folder of notes to export, each folder with
+// its notes out of name order and a subfolder of its own
+func exportVault(t *testing.T) {
+	t.Helper()
+	newTestVault(t)
+	writeTestNote(t, "Projects/Zeta.md", "---\ntags: [work]\n---\nZeta body\n")
+	writeTestNote(t, "Projects/Alpha.md", "Alpha body\n\n")
+	writeTestNote(t, "Projects/Empty.md", "")
+	writeTestNote(t, "Projects/Garden/Seeds.md", "Seeds body\n")
+	writeTestNote(t, "Projects/Garden/Beds/Raised.md", "Raised body\n")
+	writeTestNote(t, "Elsewhere.md", "not exported\n")
+}
+
+func TestExportFolder(t *testing.T) {
+	exportVault(t)
+	root := loadNotes(notesPath)
+	dir := findNoteByPath(root, filepath.Join(notesPath, "Projects"))
+	if dir == nil {
+		t.Fatal("no Projects folder")
+	}
+
+	// By name, each folder a level deeper than its parent
+	want := "# Alpha\n\nAlpha body\n" +
+		"---\n# Empty\n" +
+		"---\n# Garden\n" +
+		"---\n## Beds\n" +
+		"---\n### Raised\n\nRaised body\n" +
+		"---\n## Seeds\n\nSeeds body\n" +
+		"---\n# Zeta\n\nZeta body\n"
+	if got := exportFolder(dir, nil, "---\n"); got != want {
+		t.Errorf("export by name is\n%s\nwant\n%s", got, want)
+	}
+
+	// Each folder is walked in its own sort order
+	sorts := map[string]folderSort{
+		dir.path: {Mode: "manual", Order: []string{"Zeta.md", "Garden", "Alpha.md"}},
+	}
+	want = "# Zeta\n\nZeta body\n" +
+		"\n# Garden\n" +
+		"\n## Beds\n" +
+		"\n### Raised\n\nRaised body\n" +
+		"\n## Seeds\n\nSeeds body\n" +
+		"\n# Alpha\n\nAlpha body\n" +
+		"\n# Empty\n"
+	if got := exportFolder(dir, sorts, "\n"); got != want {
+		t.Errorf("export in manual order is\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExportHeadingLevelsCapped(t *testing.T) {
+	newTestVault(t)
+	writeTestNote(t, "a/b/c/d/e/f/g/Deep.md", "deep\n")
+	root := loadNotes(notesPath)
+	want := "# a\n## b\n### c\n#### d\n##### e\n###### f\n###### g\n###### Deep\n\ndeep\n"
+	if got := exportFolder(root, nil, ""); got != want {
+		t.Errorf("export is\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRunExportFolder(t *testing.T) {
+	exportVault(t)
+	root := loadNotes(notesPath)
+	out := filepath.Join(t.TempDir(), "projects.md")
+	if code := runExport(root, []string{"-folder", "Projects/Garden", "-sep", "\n***\n\n", "-out", out}); code != 0 {
+		t.Fatalf("export exits with %d", code)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Beds\n\n***\n\n## Raised\n\nRaised body\n\n***\n\n# Seeds\n\nSeeds body\n"
+	if got := string(data); got != want {
+		t.Errorf("exported file is\n%s\nwant\n%s", got, want)
+	}
+
+	if code := runExport(root, []string{"-folder", "Projects/Alpha.md", "-out", out}); code != 1 {
+		t.Errorf("exporting a note as a folder exits with %d, want 1", code)
+	}
+	if code := runExport(root, []string{"-folder", "Nowhere", "-out", out}); code != 1 {
+		t.Errorf("exporting a missing folder exits with %d, want 1", code)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExternalEditorWithArguments(t *testing.T) {
+	newTestVault(t)
+	config.ExternalEditor = "sh  -e -x"
+
+	cmd := externalEditorCommand("/notes/a.txt")
+	if want := []string{"sh", "-e", "-x", "/notes/a.txt"}; !slices.Equal(cmd.Args, want) {
+		t.Errorf("editor runs as %q, want %q", cmd.Args, want)
+	}
+	if msg := openInExternalEditor("/notes/a.txt")(); msg != nil {
+		if notice, ok := msg.(noticeMsg); ok {
+			t.Errorf("an installed editor with arguments is reported: %s", notice.text)
+		}
+	}
+	for _, problem := range validateConfig(&config) {
+		if strings.HasPrefix(problem, "external_editor") {
+			t.Errorf("startup check reports %q", problem)
+		}
+	}
+}
+
+func TestExternalEditorMissing(t *testing.T) {
+	newTestVault(t)
+	config.ExternalEditor = "no-such-editor-here -w"
+
+	notice, ok := openInExternalEditor("/notes/a.txt")().(noticeMsg)
+	if !ok || !notice.isErr || !strings.Contains(notice.text, `"no-such-editor-here" not found`) {
+		t.Errorf("a missing editor is reported as %+v", notice)
+	}
+	if !slices.ContainsFunc(validateConfig(&config), func(p string) bool {
+		return strings.Contains(p, `"no-such-editor-here" isn't installed`)
+	}) {
+		t.Error("startup check doesn't report the missing editor")
+	}
+}
+
+// The other programs the app runs are checked the same way
+func TestSpawnedCommandsMissing(t *testing.T) {
+	newTestVault(t)
+	config.OpenCommand = "no-such-opener"
+	notice, ok := openAttachment("/notes/a.png")().(noticeMsg)
+	if !ok || !notice.isErr || !strings.Contains(notice.text, `"no-such-opener" not found`) || !strings.Contains(notice.text, "open_command") {
+		t.Errorf("a missing opener is reported as %+v", notice)
+	}
+
+	config.PDFCommand = "no-such-printer {{in}} {{out}}"
+	err := writeExport("# Doc\n", "Doc", "pdf", filepath.Join(t.TempDir(), "doc.pdf"))
+	if err == nil || !strings.Contains(err.Error(), `"no-such-printer" not found`) {
+		t.Errorf("exporting with a missing pdf_command gives %v", err)
+	}
+
+	// Without git installed the git option is pointed out at startup
+	t.Setenv("PATH", t.TempDir())
+	config.Git = true
+	if !slices.ContainsFunc(validateConfig(&config), func(p string) bool {
+		return strings.HasPrefix(p, "git: git isn't installed")
+	}) {
+		t.Error("startup check doesn't report git missing")
+	}
+	if root := gitRepoRoot(notesPath); root != "" {
+		t.Errorf("without git the notes are in a repository at %q", root)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// openFavorites lists the favorite notes from every folder
+func (m *model) openFavorites() {
+	m.favorites = nil
+	collectFavorites(m.treeRoot(), &m.favorites)
+	sort.SliceStable(m.favorites, func(i, j int) bool {
+		return strings.ToLower(m.favorites[i].title) < strings.ToLower(m.favorites[j].title)
+	})
+	m.favoritesCursor = 0
+	m.previousMode = m.mode
+	m.mode = favoritesView
+}
+
+// revealFavorite goes to the selected favorite in its folder
+func (m *model) revealFavorite() {
+	n := m.favorites[m.favoritesCursor]
+	m.mode = navigationView
+	m.favorites = nil
+	m.inPinned = false
+	if n.parent != m.currentNode {
+		m.clearMarks()
+		m.currentNode = n.parent
+		m.sortNotes()
+	}
+	for i, child := range m.currentNode.children {
+		if child == n {
+			m.cursor = i
+			break
+		}
+	}
+}
+
+func (m *model) updateFavoritesView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if len(m.favorites) > 0 {
+			m.favoritesCursor = (m.favoritesCursor + len(m.favorites) - 1) % len(m.favorites)
+		}
+	case "down", "j":
+		if len(m.favorites) > 0 {
+			m.favoritesCursor = (m.favoritesCursor + 1) % len(m.favorites)
+		}
+	case "enter", "right":
+		if len(m.favorites) > 0 {
+			n := m.favorites[m.favoritesCursor]
+			m.favorites = nil
+			m.openNote(n)
+		}
+	case "v":
+		if len(m.favorites) > 0 {
+			n := m.favorites[m.favoritesCursor]
+			m.revealFavorite()
+			m.openPreview(n)
+		}
+	case "o":
+		if len(m.favorites) > 0 {
+			m.revealFavorite()
+		}
+	case "f":
+		if len(m.favorites) > 0 {
+			n := m.favorites[m.favoritesCursor]
+			n.favorite = false
+			if err := m.writeNote(n); err != nil {
+				log.Printf("Could not update note: %v", err)
+			}
+			m.favorites = append(m.favorites[:m.favoritesCursor], m.favorites[m.favoritesCursor+1:]...)
+			if m.favoritesCursor > 0 && m.favoritesCursor >= len(m.favorites) {
+				m.favoritesCursor--
+			}
+			m.notice = "Removed " + n.title + " from the favorites"
+		}
+	case "esc", "left", "q", "*":
+		m.mode = m.previousMode
+		m.favorites = nil
+	}
+	return m, nil
+}
+
+// favoritesListView renders the list of favorites, height rows at most
+func (m model) favoritesListView(width, height int) string {
+	if len(m.favorites) == 0 {
+		return "\n  No favorites yet. Press 'f' on a note to add it."
+	}
+	folderStyle := lipgloss.NewStyle().Faint(true)
+	var s strings.Builder
+	height = max(1, height)
+	offset := max(0, m.favoritesCursor-height+1)
+	for i := offset; i < min(offset+height, len(m.favorites)); i++ {
+		n := m.favorites[i]
+		name := favoriteStyle.Render("★") + " " + n.title
+		if folder := noteFolder(n, n); folder != "" {
+			name += folderStyle.Render(" — " + folder)
+		}
+		name = truncate(name, width-6, "…")
+		if i == m.favoritesCursor {
+			s.WriteString("> " + selectedStyle.Render(name) + "\n")
+		} else {
+			s.WriteString("  " + name + "\n")
+		}
+	}
+	return s.String()
+}
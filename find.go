@@ -0,0 +1,474 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type findMode int
+
+const (
+	findOff findMode = iota
+	findOnly
+	findReplace
+)
+
+// StartFind opens the find prompt, or find and replace when replace is set.
+// A selection within one line becomes the search text.
+func (e *Editor) StartFind(replace bool) {
+	e.findMode = findOnly
+	if replace {
+		e.findMode = findReplace
+	}
+	e.findField = 0
+	e.findStatus = ""
+	e.findBrowsing = false
+	if e.hasSelection {
+		if text := e.getSelectedText(); !strings.Contains(text, "\n") {
+			e.findQuery = text
+		}
+	}
+	start, _ := e.selectionOffsets()
+	e.findOrigin = start
+	e.findNext(e.findOrigin, 1)
+}
+
+// Finding reports whether the find prompt is open
+func (e *Editor) Finding() bool {
+	return e.findMode != findOff
+}
+
+// CloseFind closes the find prompt, leaving the cursor on the current match
+func (e *Editor) CloseFind() {
+	e.findMode = findOff
+	e.clearSelection()
+}
+
+// FindPrompt returns the status line shown while the find prompt is open
+func (e *Editor) FindPrompt() string {
+	label, toggle := "Find: ", "alt+r: regex"
+	if e.findRegex {
+		label, toggle = "Regex: ", "alt+r: plain"
+	}
+
+	var prompt string
+	if e.findMode == findReplace {
+		query, replacement := e.findQuery, e.replaceText
+		if e.findField == 0 {
+			query += "█"
+		} else {
+			replacement += "█"
+		}
+		prompt = label + query + " | Replace: " + replacement +
+			" | enter: replace | ctrl+a: all | tab: switch | ↑/↓: prev/next | " + toggle + " | esc: done"
+	} else if e.findBrowsing {
+		prompt = label + e.findQuery + " | n/N: next/prev | ctrl+f: edit search | esc: done"
+	} else {
+		prompt = label + e.findQuery + "█ | ↑/↓: prev/next | enter: done typing | " + toggle + " | esc: done"
+	}
+	if e.findStatus != "" {
+		prompt = "[" + e.findStatus + "] " + prompt
+	}
+	return prompt
+}
+
+// selectionOffsets returns the selected character range, or the cursor
+// position twice when nothing is selected
+func (e *Editor) selectionOffsets() (int, int) {
+	cursor := e.GetCursor()
+	if !e.hasSelection {
+		return cursor, cursor
+	}
+	return min(cursor, e.selectionAnchor), max(cursor, e.selectionAnchor)
+}
+
+// textMatch is a match of the search text, as rune offsets into the note
+type textMatch struct {
+	start, end int
+}
+
+// searchRegexp compiles the search text for regex mode. Like plain search it
+// ignores case unless the search text has an uppercase letter.
+func searchRegexp(query string) (*regexp.Regexp, error) {
+	if !strings.ContainsFunc(query, unicode.IsUpper) {
+		query = "(?i)" + query
+	}
+	return regexp.Compile(query)
+}
+
+// regexMatches returns the non-empty matches of re in text as rune offsets
+func regexMatches(re *regexp.Regexp, text string) []textMatch {
+	var matches []textMatch
+	runeOffset, byteOffset := 0, 0
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		runeOffset += utf8.RuneCountInString(text[byteOffset:loc[0]])
+		start := runeOffset
+		runeOffset += utf8.RuneCountInString(text[loc[0]:loc[1]])
+		byteOffset = loc[1]
+		matches = append(matches, textMatch{start, runeOffset})
+	}
+	return matches
+}
+
+// findMatches returns every match of the search text. An invalid regex
+// matches nothing and is reported in the prompt. The note is searched a line
+// at a time, only copied out whole for a search that can match a line break
+// or is anchored to the start or end of the note.
+func (e *Editor) findMatches() []textMatch {
+	e.findInvalid = false
+	if e.findQuery == "" {
+		return nil
+	}
+	var lineMatches func(line []rune) []textMatch
+	if e.findRegex {
+		re, err := searchRegexp(e.findQuery)
+		if err != nil {
+			e.findInvalid = true
+			return nil
+		}
+		if regexSpansLines(re) {
+			return regexMatches(re, e.Value())
+		}
+		lineMatches = func(line []rune) []textMatch { return regexMatches(re, string(line)) }
+	} else {
+		if strings.Contains(e.findQuery, "\n") {
+			return plainMatches([]rune(e.Value()), e.findQuery)
+		}
+		lineMatches = func(line []rune) []textMatch { return plainMatches(line, e.findQuery) }
+	}
+
+	var matches []textMatch
+	lineStart := 0
+	for row := range e.lines.len() {
+		line := e.lines.line(row)
+		for _, match := range lineMatches(line) {
+			matches = append(matches, textMatch{lineStart + match.start, lineStart + match.end})
+		}
+		lineStart += len(line) + 1
+	}
+	return matches
+}
+
+// regexSpansLines reports whether re can match a line break, or is anchored
+// to the start or end of the text rather than of a line, so matching it line
+// by line would give different matches than over the whole text
+func regexSpansLines(re *regexp.Regexp) bool {
+	parsed, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return true
+	}
+	var spans func(r *syntax.Regexp) bool
+	spans = func(r *syntax.Regexp) bool {
+		switch r.Op {
+		case syntax.OpAnyChar, syntax.OpBeginText, syntax.OpEndText:
+			return true
+		case syntax.OpLiteral:
+			return slices.Contains(r.Rune, '\n')
+		case syntax.OpCharClass:
+			for i := 0; i+1 < len(r.Rune); i += 2 {
+				if r.Rune[i] <= '\n' && '\n' <= r.Rune[i+1] {
+					return true
+				}
+			}
+		}
+		return slices.ContainsFunc(r.Sub, spans)
+	}
+	return spans(parsed)
+}
+
+// findTextMatches returns every match of query in text, as a regex when
+// regex is set. Plain search ignores case unless query has an uppercase
+// letter, and so does regex search.
+func findTextMatches(text, query string, regex bool) ([]textMatch, error) {
+	if query == "" {
+		return nil, nil
+	}
+	if regex {
+		re, err := searchRegexp(query)
+		if err != nil {
+			return nil, err
+		}
+		return regexMatches(re, text), nil
+	}
+	return plainMatches([]rune(text), query), nil
+}
+
+// plainMatches returns the non-overlapping matches of query in text
+func plainMatches(text []rune, queryText string) []textMatch {
+	query := []rune(queryText)
+	foldCase := !strings.ContainsFunc(queryText, unicode.IsUpper)
+
+	var matches []textMatch
+	for i := 0; i+len(query) <= len(text); i++ {
+		matched := true
+		for j, q := range query {
+			r := text[i+j]
+			if foldCase {
+				r = unicode.ToLower(r)
+			}
+			if r != q {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, textMatch{i, i + len(query)})
+			i += len(query) - 1
+		}
+	}
+	return matches
+}
+
+// matchColumns returns the column ranges covered by matches, keyed by line.
+// A regex match spanning lines covers the end of each line it crosses.
+func (e *Editor) matchColumns() map[int][][2]int {
+	cols := make(map[int][][2]int)
+	row, lineStart := 0, 0
+	for _, match := range e.findMatches() {
+		for row < e.lines.len()-1 && match.start > lineStart+len(e.lines.line(row)) {
+			lineStart += len(e.lines.line(row)) + 1
+			row++
+		}
+		r, start := row, lineStart
+		for r < e.lines.len() && start <= match.end {
+			lineEnd := start + len(e.lines.line(r))
+			from, to := max(match.start, start)-start, min(match.end, lineEnd)-start
+			if from < to {
+				cols[r] = append(cols[r], [2]int{from, to})
+			}
+			start = lineEnd + 1
+			r++
+		}
+	}
+	return cols
+}
+
+// findNext selects the first match at or after from when dir is 1, or the
+// last match before from when dir is -1, wrapping around the note
+func (e *Editor) findNext(from, dir int) bool {
+	matches := e.findMatches()
+	if len(matches) == 0 {
+		e.clearSelection()
+		e.findStatus = ""
+		if e.findInvalid {
+			e.findStatus = "invalid regex"
+		} else if e.findQuery != "" {
+			e.findStatus = "no matches"
+		}
+		return false
+	}
+
+	pick := -1
+	if dir > 0 {
+		for i, match := range matches {
+			if match.start >= from {
+				pick = i
+				break
+			}
+		}
+		if pick < 0 {
+			pick = 0
+		}
+	} else {
+		for i := len(matches) - 1; i >= 0; i-- {
+			if matches[i].start < from {
+				pick = i
+				break
+			}
+		}
+		if pick < 0 {
+			pick = len(matches) - 1
+		}
+	}
+
+	e.findStatus = fmt.Sprintf("%d/%d", pick+1, len(matches))
+	e.SetCursor(matches[pick].start)
+	e.selectionAnchor = matches[pick].start
+	e.SetCursor(matches[pick].end)
+	e.hasSelection = true
+	e.ensureCursorVisible()
+	return true
+}
+
+// selectedMatch returns the match the selection covers exactly
+func (e *Editor) selectedMatch() (textMatch, bool) {
+	if !e.hasSelection {
+		return textMatch{}, false
+	}
+	start, end := e.selectionOffsets()
+	for _, match := range e.findMatches() {
+		if match.start == start {
+			return match, match.end == end
+		}
+	}
+	return textMatch{}, false
+}
+
+// replacementFor returns the text replacing matched
+func (e *Editor) replacementFor(matched string) string {
+	return expandReplacement(e.findQuery, e.replaceText, matched, e.findRegex)
+}
+
+// expandReplacement returns the text replacing matched. For a regex query
+// $1, ${name} and so on in replacement expand to the match's groups.
+func expandReplacement(query, replacement, matched string, regex bool) string {
+	if !regex {
+		return replacement
+	}
+	re, err := searchRegexp(query)
+	if err != nil {
+		return replacement
+	}
+	return re.ReplaceAllString(matched, replacement)
+}
+
+// replaceNext replaces the selected match and selects the next one. If no
+// match is selected it only moves to the next one.
+func (e *Editor) replaceNext() {
+	if _, ok := e.selectedMatch(); !ok {
+		start, _ := e.selectionOffsets()
+		e.findNext(start, 1)
+		return
+	}
+	replacement := e.replacementFor(e.getSelectedText())
+	e.deleteSelection()
+	e.insertText(replacement)
+	e.MarkDirty()
+	if !e.findNext(e.GetCursor(), 1) {
+		e.findStatus = "replaced, no more matches"
+	}
+}
+
+// replaceAll replaces every match in the note
+func (e *Editor) replaceAll() {
+	matches := e.findMatches()
+	if len(matches) == 0 {
+		e.findStatus = "no matches"
+		if e.findInvalid {
+			e.findStatus = "invalid regex"
+		}
+		return
+	}
+
+	// Find where each match is in one pass, then replace them from the last
+	// so the lines of the ones before don't move
+	type span struct {
+		startRow, startCol, endRow, endCol int
+		replacement                        string
+	}
+	spans := make([]span, len(matches))
+	row, lineStart := 0, 0
+	position := func(offset int) (int, int) {
+		for row < e.lines.len()-1 && offset > lineStart+len(e.lines.line(row)) {
+			lineStart += len(e.lines.line(row)) + 1
+			row++
+		}
+		return row, offset - lineStart
+	}
+	for i, match := range matches {
+		sp := &spans[i]
+		sp.startRow, sp.startCol = position(match.start)
+		sp.endRow, sp.endCol = position(match.end)
+		sp.replacement = e.replacementFor(e.textBetween(sp.startRow, sp.startCol, sp.endRow, sp.endCol))
+	}
+	cursor := e.GetCursor()
+	for _, sp := range slices.Backward(spans) {
+		e.spliceText(sp.startRow, sp.startCol, sp.endRow, sp.endCol, sp.replacement)
+	}
+
+	top := e.viewportRow
+	e.SetCursor(cursor)
+	e.viewportRow = top
+	e.ensureCursorVisible()
+	e.MarkDirty()
+	e.findStatus = fmt.Sprintf("%d replaced", len(matches))
+}
+
+// HandleFindKey handles a key while the find prompt is open. Once the search
+// text is entered, n and N move between matches and any other key closes
+// the prompt and is left for normal handling, returning false.
+func (e *Editor) HandleFindKey(msg tea.KeyMsg) bool {
+	if e.findBrowsing {
+		switch msg.String() {
+		case "n", "down":
+			_, end := e.selectionOffsets()
+			e.findNext(end, 1)
+		case "N", "up":
+			start, _ := e.selectionOffsets()
+			e.findNext(start, -1)
+		case "ctrl+f":
+			e.findBrowsing = false
+		case "esc", "enter":
+			e.CloseFind()
+		default:
+			e.CloseFind()
+			return false
+		}
+		return true
+	}
+
+	field := &e.findQuery
+	if e.findField == 1 {
+		field = &e.replaceText
+	}
+
+	switch msg.String() {
+	case "esc":
+		e.CloseFind()
+	case "tab":
+		if e.findMode == findReplace {
+			e.findField = 1 - e.findField
+		}
+	case "enter":
+		switch {
+		case e.findMode == findReplace && e.findField == 1:
+			e.replaceNext()
+		case e.findMode == findReplace:
+			_, end := e.selectionOffsets()
+			e.findNext(end, 1)
+		case e.hasSelection:
+			e.findBrowsing = true
+		default:
+			e.CloseFind()
+		}
+	case "down":
+		_, end := e.selectionOffsets()
+		e.findNext(end, 1)
+	case "up":
+		start, _ := e.selectionOffsets()
+		e.findNext(start, -1)
+	case "ctrl+a":
+		if e.findMode == findReplace {
+			e.replaceAll()
+		}
+	case "alt+r":
+		e.findRegex = !e.findRegex
+		e.findNext(e.findOrigin, 1)
+	case "backspace":
+		if runes := []rune(*field); len(runes) > 0 {
+			*field = string(runes[:len(runes)-1])
+			if e.findField == 0 {
+				e.findNext(e.findOrigin, 1)
+			}
+		}
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			*field += string(msg.Runes)
+			if e.findField == 0 {
+				// Search as you type, from where the search started
+				e.findNext(e.findOrigin, 1)
+			}
+		}
+	}
+	return true
+}
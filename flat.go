@@ -0,0 +1,141 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// openFlatList lists every note under the current folder, subfolders
+// included, most recently modified first
+func (m *model) openFlatList() {
+	m.flatRoot = m.currentNode
+	m.flatNotes = nil
+	var collect func(dir *note)
+	collect = func(dir *note) {
+		for _, child := range dir.children {
+			if child.isDir {
+				collect(child)
+			} else {
+				m.flatNotes = append(m.flatNotes, child)
+			}
+		}
+	}
+	collect(m.flatRoot)
+	m.flatByName = false
+	m.sortFlatList()
+	m.flatCursor = 0
+	m.previousMode = m.mode
+	m.mode = flatView
+}
+
+// sortFlatList sorts the flat listing by modification time, newest first,
+// or by path
+func (m *model) sortFlatList() {
+	if m.flatByName {
+		sort.SliceStable(m.flatNotes, func(i, j int) bool {
+			return strings.ToLower(m.flatPath(m.flatNotes[i])) < strings.ToLower(m.flatPath(m.flatNotes[j]))
+		})
+		return
+	}
+	sort.SliceStable(m.flatNotes, func(i, j int) bool {
+		return m.flatNotes[i].modified().After(m.flatNotes[j].modified())
+	})
+}
+
+// flatPath returns a note's path from the folder the flat listing was
+// opened in, titles standing in for file names
+func (m *model) flatPath(n *note) string {
+	var parts []string
+	for node := n; node != nil && node != m.flatRoot; node = node.parent {
+		parts = append([]string{node.title}, parts...)
+	}
+	return strings.Join(parts, "/")
+}
+
+// closeFlatList goes back to the navigation view, at the selected note's
+// folder when goTo is set
+func (m *model) closeFlatList(goTo bool) {
+	if goTo && len(m.flatNotes) > 0 {
+		n := m.flatNotes[m.flatCursor]
+		if n.parent != m.currentNode {
+			m.clearMarks()
+			m.currentNode = n.parent
+			m.sortNotes()
+		}
+		for i, child := range m.currentNode.children {
+			if child == n {
+				m.cursor = i
+				break
+			}
+		}
+		m.inPinned = false
+	}
+	m.mode = navigationView
+	m.flatNotes = nil
+	m.flatRoot = nil
+}
+
+func (m *model) updateFlatView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if len(m.flatNotes) > 0 {
+			m.flatCursor = (m.flatCursor + len(m.flatNotes) - 1) % len(m.flatNotes)
+		}
+	case "down", "j":
+		if len(m.flatNotes) > 0 {
+			m.flatCursor = (m.flatCursor + 1) % len(m.flatNotes)
+		}
+	case "t":
+		m.flatByName = !m.flatByName
+		m.sortFlatList()
+		m.flatCursor = 0
+	case "enter", "right":
+		if len(m.flatNotes) > 0 {
+			n := m.flatNotes[m.flatCursor]
+			m.closeFlatList(false)
+			m.openNote(n)
+		}
+	case "v":
+		if len(m.flatNotes) > 0 {
+			n := m.flatNotes[m.flatCursor]
+			m.closeFlatList(true)
+			m.openPreview(n)
+		}
+	case "o":
+		m.closeFlatList(true)
+	case "esc", "left", "q", "R":
+		m.closeFlatList(false)
+	}
+	return m, nil
+}
+
+// flatListView renders the flat listing, height rows at most. Each note
+// shows its folder path and when it was last modified.
+func (m model) flatListView(width, height int) string {
+	if len(m.flatNotes) == 0 {
+		return "\n  No notes in this folder or below it."
+	}
+	faint := lipgloss.NewStyle().Faint(true)
+	var s strings.Builder
+	height = max(1, height)
+	offset := max(0, m.flatCursor-height+1)
+	for i := offset; i < min(offset+height, len(m.flatNotes)); i++ {
+		n := m.flatNotes[i]
+		modified := n.modified().Format("2006-01-02 15:04")
+		path := truncate(m.flatPath(n), max(10, width-8-len(modified)-2), "…")
+		if i == m.flatCursor {
+			path = selectedStyle.Render(path)
+		}
+		gap := max(2, width-8-lipgloss.Width(path)-len(modified))
+		line := path + strings.Repeat(" ", gap) + faint.Render(modified)
+		if i == m.flatCursor {
+			s.WriteString("> " + line + "\n")
+		} else {
+			s.WriteString("  " + line + "\n")
+		}
+	}
+	return s.String()
+}
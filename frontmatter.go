@@ -0,0 +1,160 @@
+package main
+
+import (
+	"strings"
+)
+
+const frontmatterDelim = "---"
+
+// parseFrontmatter splits a leading "---" delimited block of "key: value"
+// lines off the content. Quotes around values are dropped, and a YAML block
+// list ("- item" lines under a key without a value) becomes the inline form
+// "[a, b]". ok is false when the content has no frontmatter, in which case
+// body is the content unchanged.
+func parseFrontmatter(content string) (fields map[string]string, body string, ok bool) {
+	if !strings.HasPrefix(content, frontmatterDelim+"\n") {
+		return nil, content, false
+	}
+
+	rest := content[len(frontmatterDelim)+1:]
+	fields = make(map[string]string)
+	listKey := "" // key with no value of its own, which "- item" lines belong to
+	for {
+		lineEnd := strings.Index(rest, "\n")
+		line := rest
+		if lineEnd >= 0 {
+			line = rest[:lineEnd]
+		}
+
+		if strings.TrimRight(line, " \t\r") == frontmatterDelim {
+			if lineEnd >= 0 {
+				return fields, rest[lineEnd+1:], true
+			}
+			return fields, "", true
+		}
+		if lineEnd < 0 {
+			// Unterminated block, treat it as ordinary content
+			return nil, content, false
+		}
+
+		if item, isItem := listItem(line); isItem && listKey != "" {
+			fields[listKey] = appendListItem(fields[listKey], item)
+		} else if key, value, found := strings.Cut(line, ":"); found {
+			key = strings.ToLower(strings.TrimSpace(key))
+			fields[key] = unquote(strings.TrimSpace(value))
+			listKey = ""
+			if fields[key] == "" {
+				listKey = key
+			}
+		}
+		rest = rest[lineEnd+1:]
+	}
+}
+
+// listItem returns the value of a YAML block list line such as "  - work"
+func listItem(line string) (string, bool) {
+	item, ok := strings.CutPrefix(strings.TrimLeft(line, " \t"), "- ")
+	return unquote(strings.TrimSpace(item)), ok
+}
+
+// appendListItem adds item to a list value kept in the "[a, b]" form of an
+// inline list
+func appendListItem(list, item string) string {
+	if list == "" {
+		return "[" + item + "]"
+	}
+	return strings.TrimSuffix(list, "]") + ", " + item + "]"
+}
+
+// unquote strips the quotes around a quoted value
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// splitList splits a frontmatter list such as "[a, b]" into its items.
+// Unlike splitTagList, items may contain spaces.
+func splitList(value string) []string {
+	value = strings.Trim(strings.TrimSpace(value), "[]")
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = unquote(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// noteAliases returns the other names a note goes by, from an "aliases"
+// list in its frontmatter
+func noteAliases(content string) []string {
+	fields, _, ok := parseFrontmatter(content)
+	if !ok {
+		return nil
+	}
+	return splitList(fields["aliases"])
+}
+
+// quoteValue quotes a frontmatter value that wouldn't read back as written
+// without quotes
+func quoteValue(value string) string {
+	if value == "" || strings.ContainsAny(value, ":#[]{},\"'") || value != strings.TrimSpace(value) {
+		if strings.Contains(value, `"`) {
+			return "'" + value + "'"
+		}
+		return `"` + value + `"`
+	}
+	return value
+}
+
+// setFrontmatterKey sets key to value in the content's frontmatter, adding
+// the block if there's none
+func setFrontmatterKey(content, key, value string) string {
+	_, content, _ = removeFrontmatterKey(content, key)
+	line := key + ": " + value + "\n"
+	if _, _, ok := parseFrontmatter(content); ok {
+		return frontmatterDelim + "\n" + line + content[len(frontmatterDelim)+1:]
+	}
+	return frontmatterDelim + "\n" + line + frontmatterDelim + "\n" + content
+}
+
+// removeFrontmatterKey removes the first line for key from the content's
+// frontmatter, dropping the block if nothing else is left in it. found is
+// false, and content is returned unchanged, when the key isn't present.
+func removeFrontmatterKey(content, key string) (value, out string, found bool) {
+	if _, _, ok := parseFrontmatter(content); !ok {
+		return "", content, false
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\n")
+		if strings.TrimRight(line, " \t\r") == frontmatterDelim {
+			break
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok || strings.ToLower(strings.TrimSpace(k)) != key {
+			continue
+		}
+
+		// A key without a value may have a block list under it
+		end := i + 1
+		if strings.TrimSpace(v) == "" {
+			for end < len(lines) {
+				if _, isItem := listItem(strings.TrimRight(lines[end], "\n")); !isItem {
+					break
+				}
+				end++
+			}
+		}
+		lines = append(lines[:i], lines[end:]...)
+		// An empty block is just the two delimiters
+		if strings.TrimRight(lines[1], " \t\r\n") == frontmatterDelim {
+			lines = lines[2:]
+		}
+		return unquote(strings.TrimSpace(v)), strings.Join(lines, ""), true
+	}
+	return "", content, false
+}
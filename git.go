@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// With the git option on and the notes in a git repository, saved notes are
+// committed every gitPollInterval, each batch with a message made from
+// git_message. In between, the working tree is checked so the title bar can
+// show changes not committed yet, whoever made them. The history panel shows
+// the note's commits with g.
+
+const gitPollInterval = 2 * time.Second
+
+// gitTickMsg is sent every gitPollInterval to commit the notes saved since
+// the last one, or check the working tree
+type gitTickMsg struct{}
+
+// gitStatusMsg reports on the working tree after a check or a commit
+type gitStatusMsg struct {
+	dirty bool
+	err   error // the commit failed
+}
+
+func gitTick() tea.Cmd {
+	return tea.Tick(gitPollInterval, func(time.Time) tea.Msg {
+		return gitTickMsg{}
+	})
+}
+
+// gitRepoRoot returns the top folder of the git repository dir is in, or ""
+// when the git option is off or dir isn't in one
+func gitRepoRoot(dir string) string {
+	if !config.Git {
+		return ""
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// gitOutput runs git in the repository at root and returns what it printed.
+// Errors carry git's own message.
+func gitOutput(root string, args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"-C", root}, args...)...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// gitDirty reports whether the notes folder has changes not committed.
+// The app's own .history and .trash folders don't count.
+func gitDirty(root string) bool {
+	out, err := gitOutput(root, "status", "--porcelain", "--", notesPath,
+		":(exclude)"+filepath.Join(notesPath, ".history"), ":(exclude)"+filepath.Join(notesPath, ".trash"))
+	return err == nil && strings.TrimSpace(out) != ""
+}
+
+// gitMessage fills in the git_message placeholders for a commit of the
+// notes at paths: {{title}} and {{path}} (the notes' titles and paths
+// relative to the notes folder, comma separated), {{count}}, {{date}} and
+// {{time}}
+func gitMessage(paths []string, now time.Time) string {
+	var titles, rels []string
+	for _, path := range paths {
+		titles = append(titles, fileTitle(path))
+		if rel, err := filepath.Rel(notesPath, path); err == nil {
+			path = rel
+		}
+		rels = append(rels, filepath.ToSlash(path))
+	}
+	return strings.NewReplacer(
+		"{{title}}", strings.Join(titles, ", "),
+		"{{path}}", strings.Join(rels, ", "),
+		"{{count}}", plural(len(paths), "note"),
+		"{{date}}", now.Format("2006-01-02"),
+		"{{time}}", now.Format("15:04"),
+	).Replace(config.GitMessage)
+}
+
+// gitCommitCmd commits the notes at paths in the background, leaving other
+// changes in the repository alone, then checks the working tree
+func gitCommitCmd(root string, paths []string) tea.Cmd {
+	return func() tea.Msg {
+		// Notes renamed or trashed since they were saved are left out
+		var saved []string
+		for _, path := range paths {
+			if _, err := os.Stat(path); err == nil {
+				saved = append(saved, path)
+			}
+		}
+		if len(saved) == 0 {
+			return gitStatusMsg{dirty: gitDirty(root)}
+		}
+		paths = saved
+		args := append([]string{"add", "--"}, paths...)
+		if _, err := gitOutput(root, args...); err != nil {
+			return gitStatusMsg{dirty: gitDirty(root), err: err}
+		}
+		// Saves that changed nothing leave nothing to commit
+		args = append([]string{"diff", "--cached", "--quiet", "--"}, paths...)
+		if _, err := gitOutput(root, args...); err != nil {
+			args = append([]string{"commit", "-m", gitMessage(paths, time.Now()), "--"}, paths...)
+			if _, err := gitOutput(root, args...); err != nil {
+				return gitStatusMsg{dirty: gitDirty(root), err: err}
+			}
+		}
+		return gitStatusMsg{dirty: gitDirty(root)}
+	}
+}
+
+// gitStatusCmd checks the working tree in the background
+func gitStatusCmd(root string) tea.Cmd {
+	return func() tea.Msg {
+		return gitStatusMsg{dirty: gitDirty(root)}
+	}
+}
+
+// gitCommitLater queues the note at path for the next commit
+func (m *model) gitCommitLater(path string) {
+	if m.gitRoot == "" {
+		return
+	}
+	for _, queued := range m.gitPending {
+		if queued == path {
+			return
+		}
+	}
+	m.gitPending = append(m.gitPending, path)
+}
+
+// flushGit commits the notes saved since the last tick right away, before
+// the app quits or opens other notes
+func (m *model) flushGit() {
+	if m.gitRoot != "" && len(m.gitPending) > 0 {
+		gitCommitCmd(m.gitRoot, m.gitPending)()
+		m.gitPending = nil
+	}
+}
+
+// updateGit commits what was saved since the last tick, or else checks the
+// working tree, and schedules the next tick
+func (m *model) updateGit() tea.Cmd {
+	if m.gitRoot == "" {
+		return gitTick()
+	}
+	if len(m.gitPending) > 0 {
+		paths := m.gitPending
+		m.gitPending = nil
+		return tea.Batch(gitCommitCmd(m.gitRoot, paths), gitTick())
+	}
+	return tea.Batch(gitStatusCmd(m.gitRoot), gitTick())
+}
+
+// openGitLog shows the commits of the note being edited, with their changes
+func (m *model) openGitLog() {
+	h := m.history
+	if m.gitRoot == "" {
+		m.notice = "The notes aren't in a git repository, or the git option is off"
+		return
+	}
+	out, err := gitOutput(m.gitRoot, "log", "-p", "--follow", "--format=commit %h%n%ad  %s", "--date=format:%a %b %-d %Y %H:%M", "--", m.currentNotePath)
+	if err != nil {
+		m.notice = fmt.Sprintf("Could not read the git log: %v", err)
+		m.noticeErr = true
+		return
+	}
+	h.gitLog = strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if out == "" {
+		h.gitLog = []string{"No commits of this note yet"}
+	}
+	h.offset = 0
+}
+
+// gitLogView renders the git log shown in the history panel
+func (m model) gitLogView() string {
+	h := m.history
+	var content strings.Builder
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
+	removedStyle := lipgloss.NewStyle().Foreground(removedColor)
+	addedStyle := lipgloss.NewStyle().Foreground(addedColor)
+	commitStyle := lipgloss.NewStyle().Bold(true)
+	lineWidth := max(20, m.width-14)
+
+	content.WriteString(lipgloss.NewStyle().Bold(true).Render("Git log") + "\n\n")
+	end := min(h.offset+m.diffPanelHeight(), len(h.gitLog))
+	for _, line := range h.gitLog[h.offset:end] {
+		text := truncate(line, lineWidth, "…")
+		switch {
+		case strings.HasPrefix(line, "commit "):
+			text = commitStyle.Render(text)
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			text = helpStyle.Render(text)
+		case strings.HasPrefix(line, "+"):
+			text = addedStyle.Render(text)
+		case strings.HasPrefix(line, "-"):
+			text = removedStyle.Render(text)
+		}
+		content.WriteString(text + "\n")
+	}
+	content.WriteString("\n" + helpStyle.Render("↑/↓: scroll | pgup/pgdn: page | Esc: back"))
+	return content.String()
+}
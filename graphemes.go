@@ -0,0 +1,155 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// tabWidth is the number of cells a tab character is drawn in
+var tabWidth = 4
+
+// expandTabs replaces the tab characters in text with the spaces they're
+// drawn as
+func expandTabs(text string) string {
+	if !strings.Contains(text, "\t") {
+		return text
+	}
+	return strings.ReplaceAll(text, "\t", strings.Repeat(" ", tabWidth))
+}
+
+// isASCII reports whether line has only ASCII characters other than tabs,
+// which are one cluster and one cell each
+func isASCII(line []rune) bool {
+	for _, r := range line {
+		if r >= utf8.RuneSelf || r == '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// graphemes returns the rune offsets where the grapheme clusters of line
+// start, followed by len(line), and the cells each cluster takes on screen.
+// A cluster the terminal draws with no width, like a lone combining mark or
+// a control character, counts as one cell so the cursor can sit on it. Tabs
+// take tabWidth cells.
+func graphemes(line []rune) (bounds, widths []int) {
+	if isASCII(line) {
+		bounds = make([]int, len(line)+1)
+		widths = make([]int, len(line))
+		for i := range line {
+			bounds[i] = i
+			widths[i] = 1
+		}
+		bounds[len(line)] = len(line)
+		return bounds, widths
+	}
+
+	rest := string(line)
+	state := -1
+	pos := 0
+	for rest != "" {
+		var cluster string
+		var width int
+		cluster, rest, width, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		if cluster == "\t" {
+			width = tabWidth
+		}
+		bounds = append(bounds, pos)
+		widths = append(widths, max(width, 1))
+		pos += utf8.RuneCountInString(cluster)
+	}
+	return append(bounds, pos), widths
+}
+
+// prevGrapheme returns the start of the grapheme cluster before col
+func prevGrapheme(line []rune, col int) int {
+	if col <= 0 {
+		return 0
+	}
+	bounds, _ := graphemes(line)
+	for i := len(bounds) - 1; i >= 0; i-- {
+		if bounds[i] < col {
+			return bounds[i]
+		}
+	}
+	return 0
+}
+
+// nextGrapheme returns the end of the grapheme cluster at col
+func nextGrapheme(line []rune, col int) int {
+	if col >= len(line) {
+		return len(line)
+	}
+	bounds, _ := graphemes(line)
+	for _, b := range bounds {
+		if b > col {
+			return b
+		}
+	}
+	return len(line)
+}
+
+// cellWidth returns the cells text takes on screen
+func cellWidth(text []rune) int {
+	if isASCII(text) {
+		return len(text)
+	}
+	_, widths := graphemes(text)
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+	return total
+}
+
+// wrapLine returns the rune offsets where the visual lines of line start when
+// it's wrapped at width cells. Clusters are never split: one too wide for the
+// rest of a visual line moves to the next.
+func wrapLine(line []rune, width int) []int {
+	starts := []int{0}
+	if width <= 0 {
+		return starts
+	}
+	if isASCII(line) {
+		for start := width; start < len(line); start += width {
+			starts = append(starts, start)
+		}
+		return starts
+	}
+
+	bounds, widths := graphemes(line)
+	cells := 0
+	for i, w := range widths {
+		if cells > 0 && cells+w > width {
+			starts = append(starts, bounds[i])
+			cells = 0
+		}
+		cells += w
+	}
+	return starts
+}
+
+// colAtCell returns the rune offset of the cluster at cell on visual line v
+// of line, given the starts from wrapLine. Past the end of the text it's the
+// end of the line on the last visual line, and the last cluster on others.
+func colAtCell(line []rune, starts []int, v, cell int) int {
+	start, end := starts[v], len(line)
+	if v+1 < len(starts) {
+		end = starts[v+1]
+	}
+	bounds, widths := graphemes(line[start:end])
+	cells := 0
+	for i, w := range widths {
+		if cell < cells+w {
+			return start + bounds[i]
+		}
+		cells += w
+	}
+	if end < len(line) {
+		return start + bounds[max(0, len(widths)-1)]
+	}
+	return end
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+)
+
+// The tests run the app headless: newTestVault points it at an empty notes
+// folder under a temporary home, newTestModel builds the model it starts
+// with at a fixed size, and render captures View() as a grid of rows to
+// assert on.
+
+// newTestVault sets the app up with the default config and an empty notes
+// folder, all under a temporary home, and returns the notes folder
+func newTestVault(t testing.TB) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	for _, env := range []string{"XDG_CONFIG_HOME", "XDG_STATE_HOME", "XDG_CACHE_HOME", "NOTES_CONFIG", "NOTES_SYNC_PASSWORD"} {
+		t.Setenv(env, "")
+	}
+	config = getDefaultConfig()
+	// Keeps the terminal from being asked for its background
+	config.Background = "dark"
+	if err := selectVault(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := makeVaultFolders(); err != nil {
+		t.Fatal(err)
+	}
+	applyColorConfig()
+	return notesPath
+}
+
+// writeTestNote writes content to the note at rel under the notes folder,
+// creating its folders, and returns its path
+func writeTestNote(t testing.TB, rel, content string) string {
+	t.Helper()
+	path := filepath.Join(notesPath, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readTestNote returns the content of the note at rel under the notes folder
+func readTestNote(t *testing.T, rel string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(notesPath, filepath.FromSlash(rel)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// newTestModel returns the model the app starts with on the notes written
+// so far, sized to width by height
+func newTestModel(t testing.TB, width, height int) *model {
+	t.Helper()
+	m := vaultModel(loadNotes(notesPath), NewEditor())
+	m.Update(tea.WindowSizeMsg{Width: width, Height: height})
+	return &m
+}
+
+// withColors renders styles with true color for the rest of the test, as
+// they would be in a terminal
+func withColors(t *testing.T) {
+	profile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	t.Cleanup(func() { lipgloss.SetColorProfile(profile) })
+}
+
+// keyMsg returns the key message for a key written as the keymap writes it
+// ("enter", "ctrl+s", "alt+h", "x")
+func keyMsg(key string) tea.KeyMsg {
+	msg := tea.KeyMsg{Type: tea.KeyRunes}
+	if rest, ok := strings.CutPrefix(key, "alt+"); ok && rest != "" {
+		msg.Alt, key = true, rest
+	}
+	for k := tea.KeyType(-100); k <= tea.KeyBackspace; k++ {
+		if k != tea.KeyRunes && k.String() == key {
+			msg.Type = k
+			if k == tea.KeySpace {
+				// As the terminal reader sends it
+				msg.Runes = []rune{' '}
+			}
+			return msg
+		}
+	}
+	msg.Runes = []rune(key)
+	return msg
+}
+
+// typeText presses the keys that type text, enter for each newline
+func typeText(m *model, text string) {
+	for _, r := range text {
+		if r == '\n' {
+			press(m, "enter")
+		} else {
+			press(m, string(r))
+		}
+	}
+}
+
+// press sends the keys to m one at a time. The commands that come back
+// aren't run.
+func press(m *model, keys ...string) {
+	for _, key := range keys {
+		m.Update(keyMsg(key))
+	}
+}
+
+// screen is a rendered view, one row per line
+type screen struct {
+	raw  []string // as rendered, escape sequences and all
+	rows []string // as shown, styling stripped
+}
+
+func render(m *model) screen {
+	return newScreen(m.View())
+}
+
+func newScreen(view string) screen {
+	s := screen{raw: strings.Split(view, "\n")}
+	for _, line := range s.raw {
+		s.rows = append(s.rows, ansi.Strip(line))
+	}
+	return s
+}
+
+// cells returns the text shown in row from column col, n cells wide
+func (s screen) cells(row, col, n int) string {
+	return ansi.Cut(s.rows[row], col, col+n)
+}
+
+// find returns the row and column where text is first shown, or -1, -1
+func (s screen) find(text string) (int, int) {
+	for row, line := range s.rows {
+		if i := strings.Index(line, text); i >= 0 {
+			return row, ansi.StringWidth(line[:i])
+		}
+	}
+	return -1, -1
+}
+
+// escapeRegex matches one whole escape sequence: CSI, OSC or a two-byte one
+var escapeRegex = regexp.MustCompile("^\x1b(\\[[0-?]*[ -/]*[@-~]|\\][^\x07\x1b]*(\x07|\x1b\\\\)|[@-Z\\\\-_])")
+
+// assertIntact fails the test when a row has an escape sequence cut short
+// or a character cut in the middle of its bytes
+func (s screen) assertIntact(t *testing.T) {
+	t.Helper()
+	for row, line := range s.raw {
+		if !utf8.ValidString(line) {
+			t.Errorf("row %d has a broken character: %q", row, line)
+		}
+		for i := strings.IndexByte(line, '\x1b'); i >= 0; i = strings.IndexByte(line, '\x1b') {
+			seq := escapeRegex.FindString(line[i:])
+			if seq == "" {
+				t.Errorf("row %d has an escape sequence cut short at byte %d: %q", row, i, line)
+				break
+			}
+			line = line[i+len(seq):]
+		}
+	}
+}
+
+// assertWidth fails the test when a row is wider than width cells
+func (s screen) assertWidth(t *testing.T, width int) {
+	t.Helper()
+	for row, line := range s.rows {
+		if w := ansi.StringWidth(line); w > width {
+			t.Errorf("row %d is %d cells wide, more than %d: %q", row, w, width, line)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Every save of a note leaves a copy of the file under .history in the
+// notes folder, in a folder of its own mirroring the note's path, named by
+// the time it was saved. Old copies are pruned by count and by age.
+
+// snapshotTimeFormat names the snapshots so they sort by time
+const snapshotTimeFormat = "20060102-150405.000"
+
+// noteHistory is the panel listing the saved versions of the note being
+// edited
+type noteHistory struct {
+	versions []string // snapshot files, newest first
+	cursor   int
+	diff     []diffLine // the selected version against the buffer, nil when not shown
+	gitLog   []string   // lines of the note's git log, nil when not shown
+	offset   int        // first diff or git log line shown
+}
+
+// historyDir returns the folder holding the snapshots of the note or folder
+// at path
+func historyDir(path string) string {
+	rel, err := filepath.Rel(notesPath, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	return filepath.Join(notesPath, ".history", rel)
+}
+
+// noteSnapshots returns the snapshot files of the note at path, newest first
+func noteSnapshots(path string) []string {
+	entries, err := os.ReadDir(historyDir(path))
+	if err != nil {
+		return nil
+	}
+	var snapshots []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			snapshots = append(snapshots, filepath.Join(historyDir(path), entry.Name()))
+		}
+	}
+	slices.Reverse(snapshots)
+	return snapshots
+}
+
+// snapshotTime returns when a snapshot was taken, read from its name
+func snapshotTime(snapshot string) time.Time {
+	name := filepath.Base(snapshot)
+	t, _ := time.ParseInLocation(snapshotTimeFormat, strings.TrimSuffix(name, filepath.Ext(name)), time.Local)
+	return t
+}
+
+// snapshotNote keeps content as the latest version of the note at path,
+// unless it's the same as the one before, then prunes the versions past
+// history_limit or older than history_days. A history_limit of 0 turns the
+// history off.
+func snapshotNote(path, content string) {
+	if config.HistoryLimit <= 0 {
+		return
+	}
+	snapshots := noteSnapshots(path)
+	if len(snapshots) > 0 {
+		if latest, err := os.ReadFile(snapshots[0]); err == nil && string(latest) == content {
+			return
+		}
+	}
+	if err := os.MkdirAll(historyDir(path), 0755); err != nil {
+		log.Printf("Could not keep a version of %s: %v", path, err)
+		return
+	}
+	snapshot := filepath.Join(historyDir(path), time.Now().Format(snapshotTimeFormat)+filepath.Ext(path))
+	if err := os.WriteFile(snapshot, []byte(content), 0644); err != nil {
+		log.Printf("Could not keep a version of %s: %v", path, err)
+		return
+	}
+
+	snapshots = append([]string{snapshot}, snapshots...)
+	for i, old := range snapshots {
+		tooOld := config.HistoryDays > 0 && time.Since(snapshotTime(old)) > time.Duration(config.HistoryDays)*24*time.Hour
+		if i >= config.HistoryLimit || (i > 0 && tooOld) {
+			os.Remove(old)
+		}
+	}
+}
+
+// moveHistory carries the versions of a note or folder moved from oldPath
+// over to newPath
+func moveHistory(oldPath, newPath string) {
+	oldDir, newDir := historyDir(oldPath), historyDir(newPath)
+	if _, err := os.Stat(oldDir); err != nil || oldDir == newDir {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(newDir), 0755); err != nil {
+		return
+	}
+	if err := renamePath(oldDir, newDir); err != nil {
+		log.Printf("Could not move the history of %s: %v", oldPath, err)
+	}
+}
+
+// openHistory shows the versions of the note being edited
+func (m *model) openHistory() {
+	if m.cursor < 0 || m.currentNotePath == "" {
+		m.notice = "Save the note to start its history"
+		return
+	}
+	m.history = &noteHistory{versions: noteSnapshots(m.currentNotePath)}
+}
+
+// snapshotBody returns the body the editor shows for a snapshot
+func (m *model) snapshotBody(snapshot string) (string, error) {
+	data, err := os.ReadFile(snapshot)
+	if err != nil {
+		return "", err
+	}
+	_, body := parserFor(m.currentNotePath).ParseMeta(string(data))
+	return body, nil
+}
+
+func (m *model) updateHistory(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	h := m.history
+	if h.gitLog != nil {
+		page := m.diffPanelHeight()
+		last := max(len(h.gitLog)-page, 0)
+		switch msg.String() {
+		case "up", "k":
+			h.offset = max(h.offset-1, 0)
+		case "down", "j":
+			h.offset = min(h.offset+1, last)
+		case "pgup":
+			h.offset = max(h.offset-page, 0)
+		case "pgdown":
+			h.offset = min(h.offset+page, last)
+		case "esc", "g", "q":
+			h.gitLog = nil
+			h.offset = 0
+		}
+		return m, nil
+	}
+	if h.diff != nil {
+		switch msg.String() {
+		case "up", "k":
+			h.offset = max(h.offset-1, 0)
+		case "down", "j":
+			h.offset = min(h.offset+1, max(len(h.diff)-m.diffPanelHeight(), 0))
+		case "r":
+			m.restoreSnapshot()
+		case "esc", "enter", "d", "q":
+			h.diff = nil
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if h.cursor > 0 {
+			h.cursor--
+		}
+	case "down", "j":
+		if h.cursor < len(h.versions)-1 {
+			h.cursor++
+		}
+	case "enter", "d":
+		if len(h.versions) > 0 {
+			body, err := m.snapshotBody(h.versions[h.cursor])
+			if err != nil {
+				m.notice = fmt.Sprintf("Could not read that version: %v", err)
+				m.noticeErr = true
+				return m, nil
+			}
+			h.diff = diffLines(strings.Split(body, "\n"), strings.Split(m.editor.Value(), "\n"))
+			h.offset = 0
+		}
+	case "r":
+		if len(h.versions) > 0 {
+			m.restoreSnapshot()
+		}
+	case "g":
+		m.openGitLog()
+	case "esc", "alt+h", "q":
+		m.history = nil
+	}
+	return m, nil
+}
+
+// restoreSnapshot puts the selected version in the editor in place of the
+// text, as an edit saved like any other
+func (m *model) restoreSnapshot() {
+	h := m.history
+	body, err := m.snapshotBody(h.versions[h.cursor])
+	if err != nil {
+		m.notice = fmt.Sprintf("Could not read that version: %v", err)
+		m.noticeErr = true
+		return
+	}
+	m.editor.ReplaceRange(0, m.editor.length(), body)
+	m.editor.SetCursor(0)
+	m.history = nil
+	m.notice = "Restored the version of " + snapshotTime(h.versions[h.cursor]).Format("Jan 2 15:04") + ", save to keep it"
+}
+
+// historyView renders the contents of the history panel
+func (m model) historyView() string {
+	h := m.history
+	var content strings.Builder
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
+	lineWidth := max(20, m.width-14)
+
+	if h.gitLog != nil {
+		return m.gitLogView()
+	}
+	if h.diff != nil {
+		when := snapshotTime(h.versions[h.cursor]).Format("Jan 2 15:04:05")
+		content.WriteString(lipgloss.NewStyle().Bold(true).Render("Changes since "+when) + "\n\n")
+		removedStyle := lipgloss.NewStyle().Foreground(removedColor)
+		addedStyle := lipgloss.NewStyle().Foreground(addedColor)
+		end := min(h.offset+m.diffPanelHeight(), len(h.diff))
+		for _, line := range h.diff[h.offset:end] {
+			switch line.op {
+			case diffDelete:
+				content.WriteString(removedStyle.Render(truncate("- "+line.text, lineWidth, "…")))
+			case diffInsert:
+				content.WriteString(addedStyle.Render(truncate("+ "+line.text, lineWidth, "…")))
+			default:
+				content.WriteString(truncate("  "+line.text, lineWidth, "…"))
+			}
+			content.WriteString("\n")
+		}
+		content.WriteString("\n" + removedStyle.Render("- that version") + "  " + addedStyle.Render("+ now") + "\n")
+		content.WriteString(helpStyle.Render("↑/↓: scroll | r: restore | Esc: back"))
+		return content.String()
+	}
+
+	content.WriteString(lipgloss.NewStyle().Bold(true).Render("History") + "\n\n")
+	if len(h.versions) == 0 {
+		content.WriteString("No saved versions yet\n")
+	}
+	shown := m.diffPanelHeight()
+	offset := max(0, h.cursor-shown+1)
+	for i := offset; i < min(offset+shown, len(h.versions)); i++ {
+		label := snapshotTime(h.versions[i]).Format("Mon Jan 2 2006 15:04:05")
+		if i == 0 {
+			label += " (latest save)"
+		}
+		if i == h.cursor {
+			content.WriteString("> " + selectedStyle.Render(label) + "\n")
+		} else {
+			content.WriteString("  " + label + "\n")
+		}
+	}
+	help := "Enter: diff with the note | r: restore | Esc: close"
+	if m.gitRoot != "" {
+		help = "Enter: diff with the note | r: restore | g: git log | Esc: close"
+	}
+	content.WriteString("\n" + helpStyle.Render(help))
+	return content.String()
+}
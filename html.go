@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// The HTML export renders the Markdown notes are written in: headings,
+// paragraphs, nested lists and task lists, quotes, code, rules, links,
+// images and emphasis. [[Wikilinks]] link to the heading of the note they
+// name, which is in the same document when a folder is exported.
+
+var (
+	htmlHeadingRegex  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	htmlListRegex     = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	htmlTaskRegex     = regexp.MustCompile(`^\[([ xX])\]\s+(.*)$`)
+	htmlRuleRegex     = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	htmlCodeRegex     = regexp.MustCompile("`([^`]+)`")
+	htmlImageRegex    = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+	htmlLinkRegex     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	htmlBoldRegex     = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	htmlItalicRegex   = regexp.MustCompile(`(^|[^\w*])[*_](\S(?:.*?\S)?)[*_]($|[^\w*])`)
+	htmlStrikeRegex   = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	htmlTagRegex      = regexp.MustCompile(`(^|\s)#(\w+(?:/\w+)*)`)
+	htmlPlaceholderRx = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// headingID turns heading text into the id it's linked to by
+func headingID(text string) string {
+	var id strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && id.Len() > 0 {
+				id.WriteByte('-')
+			}
+			id.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return id.String()
+}
+
+// safeURL reports whether a link or image target can go in the page: a web
+// or mail address, a relative path or an anchor. Other schemes, such as
+// javascript:, are left as text.
+func safeURL(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// inlineHTML renders the emphasis, code, links and tags within a line
+func inlineHTML(text string) string {
+	// Code spans and link targets are set aside so nothing inside them is
+	// taken for emphasis
+	var kept []string
+	keep := func(s string) string {
+		kept = append(kept, s)
+		return fmt.Sprintf("\x00%d\x00", len(kept)-1)
+	}
+	text = htmlCodeRegex.ReplaceAllStringFunc(text, func(m string) string {
+		return keep("<code>" + html.EscapeString(m[1:len(m)-1]) + "</code>")
+	})
+	text = htmlImageRegex.ReplaceAllStringFunc(text, func(m string) string {
+		parts := htmlImageRegex.FindStringSubmatch(m)
+		if !safeURL(parts[2]) {
+			return m
+		}
+		return keep(fmt.Sprintf(`<img src="%s" alt="%s">`, html.EscapeString(parts[2]), html.EscapeString(parts[1])))
+	})
+	text = wikilinkRegex.ReplaceAllStringFunc(text, func(m string) string {
+		inner := m[2 : len(m)-2]
+		target, shown, ok := strings.Cut(inner, "|")
+		if !ok {
+			shown = target
+		}
+		return keep(fmt.Sprintf(`<a href="#%s">%s</a>`, headingID(strings.TrimSpace(target)), html.EscapeString(strings.TrimSpace(shown))))
+	})
+	text = htmlLinkRegex.ReplaceAllStringFunc(text, func(m string) string {
+		parts := htmlLinkRegex.FindStringSubmatch(m)
+		if !safeURL(parts[2]) {
+			return m
+		}
+		return keep(fmt.Sprintf(`<a href="%s">`, html.EscapeString(parts[2]))) + parts[1] + keep("</a>")
+	})
+
+	text = html.EscapeString(text)
+	text = htmlBoldRegex.ReplaceAllString(text, "<strong>$2</strong>")
+	text = htmlItalicRegex.ReplaceAllString(text, "$1<em>$2</em>$3")
+	text = htmlStrikeRegex.ReplaceAllString(text, "<del>$1</del>")
+	text = htmlTagRegex.ReplaceAllString(text, `$1<span class="tag">#$2</span>`)
+
+	return htmlPlaceholderRx.ReplaceAllStringFunc(text, func(m string) string {
+		var i int
+		fmt.Sscanf(strings.Trim(m, "\x00"), "%d", &i)
+		return kept[i]
+	})
+}
+
+// markdownHTML renders Markdown text as HTML
+func markdownHTML(md string) string {
+	var out strings.Builder
+	var paragraph []string
+	ids := map[string]int{}
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + inlineHTML(strings.Join(paragraph, " ")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+
+	// Open lists, innermost last, with the indentation of their items
+	type openList struct {
+		tag    string
+		indent int
+	}
+	var lists []openList
+	closeLists := func(indent int) {
+		for len(lists) > 0 && lists[len(lists)-1].indent >= indent {
+			out.WriteString("</li>\n</" + lists[len(lists)-1].tag + ">\n")
+			lists = lists[:len(lists)-1]
+		}
+	}
+
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if fence, ok := strings.CutPrefix(trimmed, "```"); ok {
+			flushParagraph()
+			closeLists(0)
+			class := ""
+			if lang := strings.TrimSpace(fence); lang != "" {
+				class = ` class="language-` + html.EscapeString(lang) + `"`
+			}
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, html.EscapeString(lines[i]))
+			}
+			out.WriteString("<pre><code" + class + ">" + strings.Join(code, "\n") + "</code></pre>\n")
+			continue
+		}
+
+		if trimmed == "" {
+			flushParagraph()
+			// A blank line between items doesn't end the list
+			if len(lists) > 0 && i+1 < len(lines) && htmlListRegex.MatchString(lines[i+1]) {
+				continue
+			}
+			closeLists(0)
+			continue
+		}
+
+		if match := htmlListRegex.FindStringSubmatch(line); match != nil && !htmlRuleRegex.MatchString(line) {
+			flushParagraph()
+			indent := len(strings.ReplaceAll(match[1], "\t", "    "))
+			tag := "ul"
+			if unicode.IsDigit(rune(match[2][0])) {
+				tag = "ol"
+			}
+			closeLists(indent + 1)
+			if len(lists) > 0 && lists[len(lists)-1].indent == indent {
+				if lists[len(lists)-1].tag == tag {
+					out.WriteString("</li>\n")
+				} else {
+					closeLists(indent)
+				}
+			}
+			if len(lists) == 0 || lists[len(lists)-1].indent < indent {
+				out.WriteString("<" + tag + ">\n")
+				lists = append(lists, openList{tag, indent})
+			}
+			item := match[3]
+			if task := htmlTaskRegex.FindStringSubmatch(item); task != nil {
+				checked := ""
+				if task[1] != " " {
+					checked = " checked"
+				}
+				out.WriteString(`<li class="task"><input type="checkbox" disabled` + checked + "> " + inlineHTML(task[2]))
+			} else {
+				out.WriteString("<li>" + inlineHTML(item))
+			}
+			continue
+		}
+		// Lines indented under an item carry it on
+		if len(lists) > 0 && line != trimmed {
+			out.WriteString(" " + inlineHTML(trimmed))
+			continue
+		}
+		closeLists(0)
+
+		switch {
+		case htmlRuleRegex.MatchString(line):
+			flushParagraph()
+			out.WriteString("<hr>\n")
+		case htmlHeadingRegex.MatchString(trimmed):
+			flushParagraph()
+			match := htmlHeadingRegex.FindStringSubmatch(trimmed)
+			id := headingID(match[2])
+			if ids[id]++; ids[id] > 1 {
+				id = fmt.Sprintf("%s-%d", id, ids[id])
+			}
+			out.WriteString(fmt.Sprintf("<h%d id=\"%s\">%s</h%d>\n", len(match[1]), id, inlineHTML(match[2]), len(match[1])))
+		case strings.HasPrefix(trimmed, ">"):
+			flushParagraph()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				text := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quote = append(quote, strings.TrimPrefix(text, " "))
+			}
+			i--
+			out.WriteString("<blockquote>\n" + markdownHTML(strings.Join(quote, "\n")) + "</blockquote>\n")
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flushParagraph()
+	closeLists(0)
+	return out.String()
+}
+
+// htmlExportStyle is the stylesheet of exported pages
+const htmlExportStyle = `body { max-width: 46em; margin: 2em auto; padding: 0 1em; font: 16px/1.6 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; }
+h1, h2, h3, h4, h5, h6 { line-height: 1.25; margin: 1.6em 0 0.6em; }
+h1 { border-bottom: 1px solid #ddd; padding-bottom: 0.3em; }
+a { color: #0563c1; }
+code { font-family: SFMono-Regular, Consolas, Menlo, monospace; font-size: 0.9em; background: #f3f3f3; padding: 0.1em 0.3em; border-radius: 3px; }
+pre { background: #f6f8fa; padding: 0.8em 1em; overflow: auto; border-radius: 4px; }
+pre code { background: none; padding: 0; }
+blockquote { margin: 0; padding: 0 1em; color: #555; border-left: 4px solid #ddd; }
+li.task { list-style: none; margin-left: -1.3em; }
+hr { border: none; border-top: 1px solid #ddd; margin: 2em 0; }
+img { max-width: 100%; }
+.tag { color: #7a3e9d; }
+@media print { body { margin: 0; max-width: none; } }
+`
+
+// htmlPage wraps the HTML of a document in a page of its own
+func htmlPage(title, body string) string {
+	return "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>" + html.EscapeString(title) +
+		"</title>\n<style>\n" + htmlExportStyle + "</style>\n</head>\n<body>\n" + body + "</body>\n</html>\n"
+}
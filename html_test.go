@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInlineHTMLLinks(t *testing.T) {
+	for md, want := range map[string]string{
+		"[site](https://example.com/a?b=1&c=2)": `<a href="https://example.com/a?b=1&amp;c=2">site</a>`,
+		"[site](http://example.com)":            `<a href="http://example.com">site</a>`,
+		"[mail](mailto:me@example.com)":         `<a href="mailto:me@example.com">mail</a>`,
+		"[other](Other%20note.md)":              `<a href="Other%20note.md">other</a>`,
+		"[up](../notes/plan.md)":                `<a href="../notes/plan.md">up</a>`,
+		"[top](#plans)":                         `<a href="#plans">top</a>`,
+		"![cat](assets/cat.png)":                `<img src="assets/cat.png" alt="cat">`,
+		"![logo](https://example.com/logo.png)": `<img src="https://example.com/logo.png" alt="logo">`,
+
+		// Any other scheme is shown as the text it was written as
+		"[x](javascript:alert(1))":             "[x](javascript:alert(1))",
+		"[x](JavaScript:alert(1))":             "[x](JavaScript:alert(1))",
+		"[x](vbscript:msgbox)":                 "[x](vbscript:msgbox)",
+		"[x](data:text/html,<script>)":         "[x](data:text/html,&lt;script&gt;)",
+		"![x](javascript:alert(1))":            "![x](javascript:alert(1))",
+		"![x](data:image/svg+xml,<svg>)":       "![x](data:image/svg+xml,&lt;svg&gt;)",
+		"[x](file:///etc/passwd)":              "[x](file:///etc/passwd)",
+		"[x](java\tscript:alert(1))":           "[x](java\tscript:alert(1))",
+		"see [x](javascript:void) and [y](#y)": `see [x](javascript:void) and <a href="#y">y</a>`,
+	} {
+		got := inlineHTML(md)
+		if got != want {
+			t.Errorf("%q renders %q, want %q", md, got, want)
+		}
+		if strings.Contains(strings.ToLower(got), `href="javascript`) || strings.Contains(strings.ToLower(got), `src="javascript`) {
+			t.Errorf("%q renders a script link: %q", md, got)
+		}
+	}
+}
+
+// A script link in a note doesn't make it into an exported page as a link
+func TestExportScriptLink(t *testing.T) {
+	page := htmlPage("Note", markdownHTML("# Note\n\nClick [here](javascript:alert(document.cookie)) now\n"))
+	if strings.Contains(page, `href="javascript`) {
+		t.Errorf("exported page has a script link:\n%s", page)
+	}
+	if !strings.Contains(page, "Click [here](javascript:alert(document.cookie)) now") {
+		t.Errorf("the link isn't kept as text:\n%s", page)
+	}
+}
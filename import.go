@@ -0,0 +1,595 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// importedNote is a note read from another app, before it's written out as
+// a note of this one
+type importedNote struct {
+	source   string // where it came from, for the summary
+	id       string // what it is in the app it came from, kept in the note
+	folder   string // folder under the one imported into, "" for that one
+	title    string
+	ext      string // file extension to keep, "" for note_extension
+	body     string
+	tags     []string
+	favorite bool
+	pinned   bool
+	created  time.Time // zero when not known
+	modified time.Time
+	dropped  int // attachments that couldn't be brought along
+}
+
+// importSkip is a note, or something taken for one, that wasn't imported
+type importSkip struct {
+	source string
+	reason string
+}
+
+// createdLayouts are the forms of the frontmatter "created" key that are
+// read as a date
+var createdLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// frontmatterCreated returns the date in the "created" key of content's
+// frontmatter, as imported notes have
+func frontmatterCreated(content string) (time.Time, bool) {
+	fields, _, ok := parseFrontmatter(content)
+	if !ok || fields["created"] == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range createdLayouts {
+		if t, err := time.ParseInLocation(layout, fields["created"], time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// importedFromKey is the frontmatter key, or org header, an imported note
+// keeps its id in, so an import run again can tell it's already there
+const importedFromKey = "imported_from"
+
+// importedFrom returns the id of the note at path that content was imported
+// from, or "" when it wasn't imported
+func importedFrom(path, content string) string {
+	if _, isOrg := parserFor(path).(orgParser); isOrg {
+		for _, line := range strings.Split(content, "\n") {
+			if !strings.HasPrefix(line, "#+") {
+				break
+			}
+			if key, value, _ := strings.Cut(line[2:], ":"); strings.EqualFold(strings.TrimSpace(key), importedFromKey) {
+				return strings.TrimSpace(value)
+			}
+		}
+		return ""
+	}
+	fields, _, _ := parseFrontmatter(content)
+	return fields[importedFromKey]
+}
+
+// collectImportedFrom adds the ids of the notes imported under n to ids
+func collectImportedFrom(n *note, ids map[string]bool) {
+	for _, child := range n.children {
+		if child.isDir {
+			collectImportedFrom(child, ids)
+		} else if id := importedFrom(child.path, child.content); id != "" {
+			ids[id] = true
+		}
+	}
+}
+
+// uniquePath returns path, or when it's taken the first of "name (2).ext",
+// "name (3).ext"... that isn't
+func uniquePath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = fmt.Sprintf("%s (%d)%s", base, n, ext)
+	}
+}
+
+// writeImported writes n as a note under dir, with its tags, creation date,
+// favorite and pinned marks the way this app keeps them, and the id it had
+// in the app it came from. A note whose file name is taken, by another
+// note with the same title or one that sanitizes to the same name, gets a
+// numbered name and keeps its title in its content.
+func writeImported(n importedNote, dir string) (string, error) {
+	dir = filepath.Join(dir, n.folder)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := newNotePath(dir, n.title)
+	if n.ext != "" {
+		path = filepath.Join(dir, sanitizeTitle(n.title)+n.ext)
+	}
+	path = uniquePath(path)
+
+	content := withTags(path, titledContent(path, n.title, n.body), n.tags)
+	if _, isOrg := parserFor(path).(orgParser); isOrg {
+		if n.id != "" {
+			content = "#+" + strings.ToUpper(importedFromKey) + ": " + n.id + "\n" + content
+		}
+	} else {
+		if !n.created.IsZero() {
+			if _, ok := frontmatterCreated(content); !ok {
+				content = setFrontmatterKey(content, "created", n.created.Local().Format("2006-01-02 15:04"))
+			}
+		}
+		if n.id != "" {
+			content = setFrontmatterKey(content, importedFromKey, n.id)
+		}
+	}
+	imported := &note{title: n.title, path: path, content: content, favorite: n.favorite, pinned: n.pinned}
+	data := imported.fileContent()
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		return "", err
+	}
+	if !n.modified.IsZero() {
+		os.Chtimes(path, n.modified, n.modified)
+	}
+	snapshotNote(path, data)
+	return path, nil
+}
+
+// importMarkdownFolder reads the notes of a folder of Markdown or text files,
+// like an Obsidian vault, keeping its subfolders. Hidden folders, such as
+// .obsidian, are passed over, and files that aren't notes are skipped.
+func importMarkdownFolder(root string) ([]importedNote, []importSkip, error) {
+	var notes []importedNote
+	var skipped []importSkip
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".md" && ext != ".markdown" && ext != ".txt" && ext != ".org" {
+			skipped = append(skipped, importSkip{path, "not a note"})
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			skipped = append(skipped, importSkip{path, err.Error()})
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			skipped = append(skipped, importSkip{path, err.Error()})
+			return nil
+		}
+		folder, _ := filepath.Rel(root, filepath.Dir(path))
+		if folder == "." {
+			folder = ""
+		}
+		if ext == ".markdown" {
+			ext = ".md"
+		}
+		id, err := filepath.Abs(path)
+		if err != nil {
+			id = path
+		}
+		meta, body := parserFor(path).ParseMeta(strings.ReplaceAll(string(data), "\r\n", "\n"))
+		notes = append(notes, importedNote{
+			source:   path,
+			id:       id,
+			folder:   folder,
+			title:    strings.TrimSuffix(d.Name(), filepath.Ext(d.Name())),
+			ext:      ext,
+			body:     body,
+			favorite: meta.favorite,
+			pinned:   meta.pinned,
+			created:  fileCreated(path, info),
+			modified: info.ModTime(),
+		})
+		return nil
+	})
+	return notes, skipped, err
+}
+
+// importSimplenote reads the notes of a Simplenote export, its notes.json.
+// Notes in Simplenote's trash are skipped.
+func importSimplenote(path string) ([]importedNote, []importSkip, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	type simplenote struct {
+		ID           string   `json:"id"`
+		Content      string   `json:"content"`
+		CreationDate string   `json:"creationDate"`
+		LastModified string   `json:"lastModified"`
+		Tags         []string `json:"tags"`
+		Pinned       bool     `json:"pinned"`
+	}
+	var export struct {
+		ActiveNotes  []simplenote `json:"activeNotes"`
+		TrashedNotes []simplenote `json:"trashedNotes"`
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, nil, fmt.Errorf("not a Simplenote export: %w", err)
+	}
+	if export.ActiveNotes == nil && export.TrashedNotes == nil {
+		return nil, nil, errors.New("not a Simplenote export: no activeNotes")
+	}
+
+	var notes []importedNote
+	var skipped []importSkip
+	for _, sn := range export.ActiveNotes {
+		content := strings.ReplaceAll(sn.Content, "\r\n", "\n")
+		first, body, _ := strings.Cut(strings.TrimLeft(content, "\n"), "\n")
+		title := strings.TrimSpace(strings.TrimLeft(first, "# "))
+		if title == "" {
+			skipped = append(skipped, importSkip{"note " + sn.ID, "empty"})
+			continue
+		}
+		created, _ := time.Parse(time.RFC3339, sn.CreationDate)
+		modified, _ := time.Parse(time.RFC3339, sn.LastModified)
+		notes = append(notes, importedNote{
+			source:   "note " + sn.ID,
+			id:       "simplenote:" + sn.ID,
+			title:    title,
+			body:     strings.TrimLeft(body, "\n"),
+			tags:     sn.Tags,
+			pinned:   sn.Pinned,
+			created:  created,
+			modified: modified,
+		})
+	}
+	for _, sn := range export.TrashedNotes {
+		skipped = append(skipped, importSkip{"note " + sn.ID, "in the Simplenote trash"})
+	}
+	return notes, skipped, nil
+}
+
+// enexTimeFormat is how Evernote exports write times
+const enexTimeFormat = "20060102T150405Z"
+
+// importEvernote reads the notes of an Evernote export (.enex), converting
+// their text to Markdown. Attachments aren't carried over.
+func importEvernote(path string) ([]importedNote, []importSkip, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	type enexNote struct {
+		Title     string   `xml:"title"`
+		Content   string   `xml:"content"`
+		Created   string   `xml:"created"`
+		Updated   string   `xml:"updated"`
+		Tags      []string `xml:"tag"`
+		Resources []struct {
+			Mime string `xml:"mime"`
+		} `xml:"resource"`
+	}
+	decoder := xml.NewDecoder(file)
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	var notes []importedNote
+	var skipped []importSkip
+	found := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("not an Evernote export: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local == "en-export" {
+			found = true
+		}
+		if start.Name.Local != "note" {
+			continue
+		}
+		var en enexNote
+		if err := decoder.DecodeElement(&en, &start); err != nil {
+			return nil, nil, fmt.Errorf("not an Evernote export: %w", err)
+		}
+		source := "note " + en.Title
+		title := strings.TrimSpace(en.Title)
+		if title == "" {
+			skipped = append(skipped, importSkip{source, "untitled"})
+			continue
+		}
+		body, err := enmlMarkdown(en.Content)
+		if err != nil {
+			skipped = append(skipped, importSkip{source, err.Error()})
+			continue
+		}
+		created, _ := time.Parse(enexTimeFormat, en.Created)
+		modified, _ := time.Parse(enexTimeFormat, en.Updated)
+		if modified.IsZero() {
+			modified = created
+		}
+		notes = append(notes, importedNote{
+			source:   source,
+			id:       "evernote:" + en.Created + " " + title,
+			title:    title,
+			body:     body,
+			tags:     en.Tags,
+			created:  created,
+			modified: modified,
+			dropped:  len(en.Resources),
+		})
+	}
+	if !found {
+		return nil, nil, errors.New("not an Evernote export: no en-export element")
+	}
+	return notes, skipped, nil
+}
+
+// enmlMarkdown converts the ENML (Evernote's XHTML) of a note to Markdown:
+// headings, paragraphs, lists, checkboxes, emphasis, links and code
+func enmlMarkdown(enml string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(enml))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.AutoClose = xml.HTMLAutoClose
+
+	var out strings.Builder
+	var lists []string // "ul" or "ol" of the open lists, innermost last
+	var counters []int
+	var href string
+	pre := false
+	newline := func() {
+		if s := out.String(); s != "" && !strings.HasSuffix(s, "\n") {
+			out.WriteString("\n")
+		}
+	}
+	blankLine := func() {
+		newline()
+		if s := out.String(); s != "" && !strings.HasSuffix(s, "\n\n") {
+			out.WriteString("\n")
+		}
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("unreadable content: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch name := strings.ToLower(t.Name.Local); name {
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				blankLine()
+				out.WriteString(strings.Repeat("#", int(name[1]-'0')) + " ")
+			case "p", "div", "blockquote":
+				if len(lists) == 0 {
+					newline()
+				}
+			case "br":
+				out.WriteString("\n")
+			case "hr":
+				blankLine()
+				out.WriteString("---\n\n")
+			case "ul", "ol":
+				newline()
+				lists = append(lists, name)
+				counters = append(counters, 0)
+			case "li":
+				newline()
+				indent := strings.Repeat("  ", max(len(lists)-1, 0))
+				if len(lists) > 0 && lists[len(lists)-1] == "ol" {
+					counters[len(counters)-1]++
+					out.WriteString(fmt.Sprintf("%s%d. ", indent, counters[len(counters)-1]))
+				} else {
+					out.WriteString(indent + "- ")
+				}
+			case "en-todo":
+				checked := false
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "checked" && attr.Value == "true" {
+						checked = true
+					}
+				}
+				// A checkbox outside a list starts a task list item
+				if len(lists) == 0 && (out.Len() == 0 || strings.HasSuffix(out.String(), "\n")) {
+					out.WriteString("- ")
+				}
+				if checked {
+					out.WriteString("[x] ")
+				} else {
+					out.WriteString("[ ] ")
+				}
+			case "b", "strong":
+				out.WriteString("**")
+			case "i", "em":
+				out.WriteString("*")
+			case "s", "strike", "del":
+				out.WriteString("~~")
+			case "code":
+				if !pre {
+					out.WriteString("`")
+				}
+			case "pre":
+				blankLine()
+				out.WriteString("```\n")
+				pre = true
+			case "a":
+				href = ""
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "href" {
+						href = attr.Value
+					}
+				}
+				if href != "" {
+					out.WriteString("[")
+				}
+			}
+		case xml.EndElement:
+			switch name := strings.ToLower(t.Name.Local); name {
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				out.WriteString("\n\n")
+			case "p", "div", "blockquote":
+				if len(lists) == 0 {
+					newline()
+				}
+			case "ul", "ol":
+				if len(lists) > 0 {
+					lists = lists[:len(lists)-1]
+					counters = counters[:len(counters)-1]
+				}
+				if len(lists) == 0 {
+					blankLine()
+				}
+			case "b", "strong":
+				out.WriteString("**")
+			case "i", "em":
+				out.WriteString("*")
+			case "s", "strike", "del":
+				out.WriteString("~~")
+			case "code":
+				if !pre {
+					out.WriteString("`")
+				}
+			case "pre":
+				newline()
+				out.WriteString("```\n\n")
+				pre = false
+			case "a":
+				if href != "" {
+					out.WriteString("](" + href + ")")
+					href = ""
+				}
+			}
+		case xml.CharData:
+			if pre {
+				out.WriteString(string(t))
+				continue
+			}
+			raw := strings.ReplaceAll(string(t), "\u00a0", " ")
+			text := strings.Join(strings.Fields(raw), " ")
+			if text == "" {
+				continue
+			}
+			// Keep the spaces between words split across elements
+			if s := out.String(); s != "" && !strings.HasSuffix(s, "\n") && !strings.HasSuffix(s, " ") && unicode.IsSpace(rune(raw[0])) {
+				out.WriteString(" ")
+			}
+			out.WriteString(text)
+			if unicode.IsSpace(rune(raw[len(raw)-1])) {
+				out.WriteString(" ")
+			}
+		}
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	md := strings.Trim(strings.Join(lines, "\n"), "\n")
+	for strings.Contains(md, "\n\n\n") {
+		md = strings.ReplaceAll(md, "\n\n\n", "\n\n")
+	}
+	return md, nil
+}
+
+// runImport implements "notes import" and returns the exit code. It reads
+// a folder of Markdown files, a Simplenote notes.json or an Evernote .enex
+// file, picked by what source is, and writes their notes in the folder given.
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	folder := flags.String("folder", "", "Folder to import into, relative to the notes path (default: the top)")
+	// -folder may come before or after the source
+	var sources []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return 2
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		sources = append(sources, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(sources) != 1 {
+		fmt.Fprintln(os.Stderr, "notes import: give one folder of Markdown files, Simplenote .json or Evernote .enex file")
+		return 2
+	}
+	source := sources[0]
+
+	var notes []importedNote
+	var skipped []importSkip
+	info, err := os.Stat(source)
+	switch {
+	case err != nil:
+	case info.IsDir():
+		notes, skipped, err = importMarkdownFolder(source)
+	case strings.EqualFold(filepath.Ext(source), ".json"):
+		notes, skipped, err = importSimplenote(source)
+	case strings.EqualFold(filepath.Ext(source), ".enex"):
+		notes, skipped, err = importEvernote(source)
+	default:
+		err = fmt.Errorf("%s isn't a folder, .json or .enex file", source)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "notes import: %v\n", err)
+		return 1
+	}
+
+	dir := *folder
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(notesPath, dir)
+	}
+	// Notes imported by an earlier run are passed over
+	done := make(map[string]bool)
+	collectImportedFrom(loadNotes(dir), done)
+	imported, dropped := 0, 0
+	for _, n := range notes {
+		if done[n.id] {
+			skipped = append(skipped, importSkip{n.source, "already imported"})
+			continue
+		}
+		path, err := writeImported(n, dir)
+		if err != nil {
+			skipped = append(skipped, importSkip{n.source, err.Error()})
+			continue
+		}
+		fmt.Println(path)
+		imported++
+		dropped += n.dropped
+	}
+	for _, skip := range skipped {
+		fmt.Fprintf(os.Stderr, "notes import: skipped %s: %s\n", skip.source, skip.reason)
+	}
+	summary := fmt.Sprintf("Imported %s, skipped %d", plural(imported, "note"), len(skipped))
+	if dropped > 0 {
+		summary += fmt.Sprintf(" (%s left out)", plural(dropped, "attachment"))
+	}
+	fmt.Println(summary)
+	return 0
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// simplenoteExport writes a Simplenote notes.json holding notes, given as
+// id and content pairs, and returns its path
+func simplenoteExport(t *testing.T, notes ...string) string {
+	t.Helper()
+	var entries []string
+	for i := 0; i < len(notes); i += 2 {
+		entries = append(entries, `{"id": "`+notes[i]+`", "content": "`+notes[i+1]+`", "creationDate": "2024-01-02T03:04:05Z"}`)
+	}
+	path := filepath.Join(t.TempDir(), "notes.json")
+	data := `{"activeNotes": [` + strings.Join(entries, ", ") + `]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// importedNotes returns the notes under the notes folder, by file name, as
+// title and body
+func importedNotes(t *testing.T) map[string]string {
+	t.Helper()
+	notes := make(map[string]string)
+	var walk func(dir *note)
+	walk = func(dir *note) {
+		for _, n := range dir.children {
+			if n.isDir {
+				walk(n)
+				continue
+			}
+			rel, _ := filepath.Rel(notesPath, n.path)
+			_, body, _ := parseFrontmatter(n.content)
+			notes[filepath.ToSlash(rel)] = n.title + ": " + strings.TrimSpace(body)
+		}
+	}
+	walk(loadNotes(notesPath))
+	return notes
+}
+
+// Notes whose titles make the same file name are all imported, each under a
+// name of its own with its title kept
+func TestImportSameFileName(t *testing.T) {
+	newTestVault(t)
+	export := simplenoteExport(t,
+		"a1", `Plans\nfirst`,
+		"a2", `Plans\nsecond`,
+		"b1", `日本語\njapanese`,
+		"b2", `Ωμέγα\ngreek`)
+	if code := runImport([]string{export}); code != 0 {
+		t.Fatalf("import exits with %d", code)
+	}
+	want := map[string]string{
+		"Plans.txt":        "Plans: first",
+		"Plans (2).txt":    "Plans: second",
+		"Untitled.txt":     "日本語: japanese",
+		"Untitled (2).txt": "Ωμέγα: greek",
+	}
+	if got := importedNotes(t); !maps.Equal(got, want) {
+		t.Errorf("imported %q, want %q", got, want)
+	}
+}
+
+// Running an import again passes over the notes it brought in before, by
+// where they came from, and brings in new ones even with a title taken
+func TestImportAgain(t *testing.T) {
+	newTestVault(t)
+	export := simplenoteExport(t, "a1", `Plans\nfirst`, "b1", `日本語\njapanese`)
+	runImport([]string{export})
+	if got := importedFrom(filepath.Join(notesPath, "Plans.txt"), readTestNote(t, "Plans.txt")); got != "simplenote:a1" {
+		t.Errorf("imported note keeps %q as where it's from", got)
+	}
+
+	export = simplenoteExport(t, "a1", `Plans\nfirst`, "b1", `日本語\njapanese`, "a3", `Plans\nthird`)
+	runImport([]string{export})
+	want := map[string]string{
+		"Plans.txt":     "Plans: first",
+		"Untitled.txt":  "日本語: japanese",
+		"Plans (2).txt": "Plans: third",
+	}
+	if got := importedNotes(t); !maps.Equal(got, want) {
+		t.Errorf("after importing again %q, want %q", got, want)
+	}
+}
+
+// Notes from a folder keep their path, org notes in a header line
+func TestImportFolderAgain(t *testing.T) {
+	newTestVault(t)
+	source := t.TempDir()
+	for name, content := range map[string]string{
+		"Plan.md":      "# Plan\n\nmarkdown",
+		"Plan.org":     "#+TITLE: Plan\norg",
+		"sub/Deep.txt": "deep",
+	} {
+		path := filepath.Join(source, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runImport([]string{source, "-folder", "Imported"})
+	runImport([]string{source, "-folder", "Imported"})
+
+	var names []string
+	for name := range importedNotes(t) {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if want := []string{"Imported/Plan.md", "Imported/Plan.org", "Imported/sub/Deep.txt"}; !slices.Equal(names, want) {
+		t.Errorf("imported twice gives %q, want %q", names, want)
+	}
+	org := readTestNote(t, "Imported/Plan.org")
+	if want := "#+IMPORTED_FROM: " + filepath.Join(source, "Plan.org") + "\n"; !strings.HasPrefix(org, want) {
+		t.Errorf("org note is %q, want it to start %q", org, want)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import "strings"
+
+// SetIndent sets what Tab inserts: a tab character, or spaces up to the next
+// multiple of width. Tab characters are drawn width cells wide.
+func (e *Editor) SetIndent(useTabs bool, width int) {
+	e.indentTabs = useTabs
+	e.indentWidth = max(1, width)
+	tabWidth = e.indentWidth
+}
+
+// indentUnit returns one level of indentation
+func (e *Editor) indentUnit() string {
+	if e.indentTabs {
+		return "\t"
+	}
+	return strings.Repeat(" ", e.indentWidth)
+}
+
+// insertIndent inserts indentation at the cursor (Tab)
+func (e *Editor) insertIndent() {
+	if e.indentTabs {
+		e.insertRune('\t')
+		return
+	}
+	col := cellWidth(e.lines.line(e.cursorRow)[:e.cursorCol])
+	e.insertText(strings.Repeat(" ", e.indentWidth-col%e.indentWidth))
+}
+
+// dedentWidth returns how many runes of indentation one Shift+Tab takes off
+// the start of line: a tab, or up to an indent width of spaces
+func (e *Editor) dedentWidth(line []rune) int {
+	if len(line) > 0 && line[0] == '\t' {
+		return 1
+	}
+	n := 0
+	for n < len(line) && n < e.indentWidth && line[n] == ' ' {
+		n++
+	}
+	return n
+}
+
+// indentLines adds a level of indentation to the selected lines (Tab), or
+// takes one away with dedent set (Shift+Tab), which also works on the cursor
+// line without a selection. The lines stay selected.
+func (e *Editor) indentLines(dedent bool) {
+	startRow, endRow := e.selectedRows()
+	unit := []rune(e.indentUnit())
+	changed := false
+	for row := startRow; row <= endRow; row++ {
+		line := e.lines.line(row)
+		switch {
+		case dedent:
+			n := e.dedentWidth(line)
+			if n == 0 {
+				continue
+			}
+			e.lines.set(row, line[n:])
+			if row == e.cursorRow && !e.hasSelection {
+				e.cursorCol = max(0, e.cursorCol-n)
+			}
+		case len(line) > 0:
+			e.lines.set(row, append(append([]rune{}, unit...), line...))
+		default:
+			continue // blank lines stay blank
+		}
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	e.MarkDirty()
+
+	if e.hasSelection {
+		e.selectRows(startRow, endRow)
+	}
+	e.updateDesiredCol()
+	e.ensureCursorVisible()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// searchIndex is an inverted index of the words in every note. It's kept on
+// disk and only notes changed since they were indexed are read again, so
+// content search doesn't have to scan every note.
+type searchIndex struct {
+	ModTimes map[string]time.Time `json:"mod_times"` // note path -> modification time when indexed
+	Terms    map[string][]string  `json:"terms"`     // word -> paths of the notes containing it
+}
+
+func getSearchIndexPath() string {
+	return filepath.Join(getCacheDir(), "search_index.json")
+}
+
+func loadSearchIndex() *searchIndex {
+	idx := &searchIndex{}
+	if data, err := os.ReadFile(getSearchIndexPath()); err == nil {
+		_ = json.Unmarshal(data, idx) // Start over from whatever could be read
+	}
+	if idx.ModTimes == nil {
+		idx.ModTimes = make(map[string]time.Time)
+	}
+	if idx.Terms == nil {
+		idx.Terms = make(map[string][]string)
+	}
+	return idx
+}
+
+func saveSearchIndex(idx *searchIndex) error {
+	configDir := filepath.Dir(getSearchIndexPath())
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(getSearchIndexPath(), data, 0644)
+}
+
+// indexWords returns the distinct lowercase words of text. Single characters
+// aren't worth indexing and are skipped.
+func indexWords(text string) []string {
+	seen := make(map[string]bool)
+	var words []string
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range fields {
+		if utf8.RuneCountInString(word) < 2 || seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	return words
+}
+
+// update indexes the note at path, replacing what was indexed for it before
+func (idx *searchIndex) update(path, content string, modTime time.Time) {
+	idx.removeWhere(func(p string) bool { return p == path })
+	for _, word := range indexWords(content) {
+		idx.Terms[word] = append(idx.Terms[word], path)
+	}
+	idx.ModTimes[path] = modTime
+}
+
+// removeWhere forgets every indexed note whose path gone accepts
+func (idx *searchIndex) removeWhere(gone func(path string) bool) {
+	found := false
+	for path := range idx.ModTimes {
+		if gone(path) {
+			delete(idx.ModTimes, path)
+			found = true
+		}
+	}
+	if !found {
+		return
+	}
+	for word, paths := range idx.Terms {
+		if paths = slices.DeleteFunc(paths, gone); len(paths) == 0 {
+			delete(idx.Terms, word)
+		} else {
+			idx.Terms[word] = paths
+		}
+	}
+}
+
+// sync indexes the notes under root that changed since they were indexed and
+// forgets the ones that no longer exist. Reports whether the index changed.
+func (idx *searchIndex) sync(root *note) bool {
+	present := make(map[string]bool)
+	changed := false
+	var walk func(n *note)
+	walk = func(n *note) {
+		for _, child := range n.children {
+			if child.isDir {
+				walk(child)
+				continue
+			}
+			present[child.path] = true
+			if indexed, ok := idx.ModTimes[child.path]; !ok || !indexed.Equal(child.modified()) {
+				idx.update(child.path, child.content, child.modified())
+				changed = true
+			}
+		}
+	}
+	walk(root)
+
+	if len(present) < len(idx.ModTimes) {
+		idx.removeWhere(func(path string) bool { return !present[path] })
+		changed = true
+	}
+	return changed
+}
+
+// search returns the sorted paths of the notes containing every word of
+// query. Query words match as prefixes so results show up while typing.
+func (idx *searchIndex) search(query string) []string {
+	words := indexWords(query)
+	if len(words) == 0 {
+		return nil
+	}
+
+	var result map[string]bool
+	for _, word := range words {
+		matches := make(map[string]bool)
+		for term, paths := range idx.Terms {
+			if !strings.HasPrefix(term, word) {
+				continue
+			}
+			for _, path := range paths {
+				if result == nil || result[path] {
+					matches[path] = true
+				}
+			}
+		}
+		if len(matches) == 0 {
+			return nil
+		}
+		result = matches
+	}
+
+	paths := make([]string, 0, len(result))
+	for path := range result {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// dailyNotePath returns the path of the journal note for day. The name comes
+// from the journal format, which may carry its own extension; otherwise new
+// notes' extension is added.
+func dailyNotePath(day time.Time) string {
+	layout := config.JournalFormat
+	if layout == "" {
+		layout = "2006-01-02"
+	}
+	name := day.Format(layout)
+	if ext := filepath.Ext(layout); ext == "" || strings.ContainsAny(ext, "0123456789") {
+		name += noteExtension()
+	}
+	folder := config.JournalFolder
+	if !filepath.IsAbs(folder) {
+		folder = filepath.Join(notesPath, folder)
+	}
+	return filepath.Join(folder, name)
+}
+
+// dailyNoteContent returns the text a new daily note starts with: the
+// journal template with {{date}} and {{time}} filled in, or nothing
+func dailyNoteContent(day time.Time) string {
+	path := config.JournalTemplate
+	if path == "" {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(notesPath, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Could not read journal template: %v", err)
+		return ""
+	}
+	text, _ := renderSnippet(string(data), day)
+	return text
+}
+
+// openDailyNote opens today's journal note, creating it from the template
+// if needed
+func (m *model) openDailyNote() {
+	now := time.Now()
+	m.openPath(dailyNotePath(now), dailyNoteContent(now))
+}
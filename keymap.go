@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// keyAction is an action whose key can be changed with the keymap option
+type keyAction struct {
+	name string // as written in the keymap
+	key  string // the key it's on by default, as tea.KeyMsg.String() has it
+	desc string
+}
+
+// keyActions lists the actions the keymap can rebind, by where they work.
+// Movement, Enter and Esc stay as they are.
+var keyActions = []struct {
+	view    string
+	actions []keyAction
+}{
+	{"ANYWHERE", []keyAction{
+		{"scratch", "ctrl+@", "Open the scratch note"},
+		{"journal", "alt+t", "Open today's journal note"},
+		{"switcher", "ctrl+p", "Go to a note by name"},
+		{"recent_notes", "ctrl+o", "Reopen a recently opened note"},
+	}},
+	{"NOTES LIST", []keyAction{
+		{"quit", "q", "Quit"},
+		{"new_note", "n", "Create new note"},
+		{"new_folder", "F", "Create new folder"},
+		{"preview", "v", "Preview note read-only"},
+		{"mark", " ", "Mark for a bulk action"},
+		{"rename", "r", "Rename note/folder"},
+		{"trash", "d", "Move to trash"},
+		{"duplicate", "y", "Duplicate note"},
+		{"favorite", "f", "Toggle favorite"},
+		{"pin", "p", "Pin or unpin note"},
+		{"archive", "a", "Archive note/folder"},
+		{"archive_view", "A", "View archive"},
+		{"favorites_view", "*", "View favorites from all folders"},
+		{"flat_list", "R", "List every note in and under this folder"},
+		{"trash_view", "ctrl+t", "View trash"},
+		{"tags", "g", "Open tag browser"},
+		{"config", "c", "Open configuration"},
+		{"help", "?", "Show help"},
+		{"sort", "t", "Cycle sort mode"},
+		{"tree", "L", "Toggle the folder tree"},
+		{"replace", "ctrl+r", "Search and replace in all notes"},
+		{"backup", "B", "Back up all notes"},
+		{"sync", "S", "Sync notes with the WebDAV server"},
+		{"export", "E", "Export note/folder to HTML or PDF"},
+		{"vaults", "V", "Switch to another vault"},
+		{"external_editor", "ctrl+e", "Open in external editor (also in the editor)"},
+	}},
+	{"EDITOR", []keyAction{
+		{"save", "ctrl+s", "Save"},
+		{"history", "alt+h", "Saved versions of the note"},
+		{"attachments", "alt+a", "Attachments of the note"},
+		{"line_numbers", "alt+l", "Toggle line numbers"},
+		{"spell_check", "f7", "Toggle spell checking"},
+		{"spell_suggest", "f8", "Spelling suggestions"},
+		{"set_bookmark", "alt+m", "Set a bookmark"},
+		{"jump_bookmark", "alt+j", "Jump to a bookmark"},
+		{"follow_link", "ctrl+]", "Follow the [[link]] at the cursor"},
+	}},
+	{"TRASH VIEW", []keyAction{
+		{"restore", "r", "Restore item"},
+		{"delete", "d", "Delete permanently"},
+	}},
+}
+
+// defaultKey returns the key action is on by default, and the other
+// actions of its view
+func defaultKey(action string) (string, []keyAction, bool) {
+	for _, group := range keyActions {
+		for _, a := range group.actions {
+			if a.name == action {
+				return a.key, group.actions, true
+			}
+		}
+	}
+	return "", nil, false
+}
+
+// keyFor returns the key action is on: the one set in the keymap, or its
+// default unless the keymap gave that to another action of the same view.
+// It's "" when the action has no key.
+func keyFor(action string) string {
+	if key, ok := config.Keymap[action]; ok {
+		return parseKeyName(key)
+	}
+	key, view, _ := defaultKey(action)
+	for _, a := range view {
+		if bound, ok := config.Keymap[a.name]; ok && a.name != action && parseKeyName(bound) == key {
+			return ""
+		}
+	}
+	return key
+}
+
+// parseKeyName reads a key as written in the keymap. Space and ctrl+space
+// can be spelled out.
+func parseKeyName(key string) string {
+	switch strings.ToLower(key) {
+	case "space":
+		return " "
+	case "ctrl+space":
+		return "ctrl+@"
+	}
+	return key
+}
+
+// keyLabel shows a key the way it's written in the keymap
+func keyLabel(key string) string {
+	switch key {
+	case " ":
+		return "space"
+	case "ctrl+@":
+		return "ctrl+space"
+	}
+	return key
+}
+
+// keymapProblems reports keymap entries naming no action
+func keymapProblems(cfg *Config) []string {
+	var problems []string
+	for action := range cfg.Keymap {
+		if _, _, ok := defaultKey(action); !ok {
+			problems = append(problems, fmt.Sprintf("keymap: no action called %q", action))
+		}
+	}
+	slices.Sort(problems)
+	return problems
+}
+
+// keymapHelp lists the actions of the keymap with the keys they're on,
+// marking the ones changed in the config
+func keymapHelp() string {
+	var s strings.Builder
+	s.WriteString("Keys marked * are changed by keymap in config.json\n\n")
+	for _, group := range keyActions {
+		s.WriteString(group.view + "\n")
+		for _, a := range group.actions {
+			key := keyLabel(keyFor(a.name))
+			if key == "" {
+				key = "(none)"
+			}
+			if key != keyLabel(a.key) {
+				key += " *"
+			}
+			s.WriteString(fmt.Sprintf("  %-14s %-16s %s\n", key, a.name, a.desc))
+		}
+		s.WriteString("\n")
+	}
+	return s.String()
+}
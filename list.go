@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// runList implements "notes list" and returns the exit code. It prints one
+// line per note, its path, title and modification time separated by tabs,
+// for piping into grep, cut or fzf.
+func runList(root *note, args []string) int {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	folder := flags.String("folder", "", "Folder to list, relative to the notes path (default: all notes)")
+	tag := flags.String("tag", "", "Only list notes with this tag, or a tag nested under it")
+	favorites := flags.Bool("favorites", false, "Only list favorite notes")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "notes list: unexpected %q\n", flags.Arg(0))
+		return 2
+	}
+
+	dir := root
+	if *folder != "" {
+		path := *folder
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(notesPath, path)
+		}
+		dir = findNoteByPath(root, filepath.Clean(path))
+		if dir == nil || !dir.isDir {
+			fmt.Fprintf(os.Stderr, "notes list: %s is not a folder in %s\n", *folder, notesPath)
+			return 1
+		}
+	}
+	filter := strings.TrimPrefix(*tag, "#")
+
+	var notes []*note
+	var walk func(n *note)
+	walk = func(n *note) {
+		for _, child := range n.children {
+			switch {
+			case child.isDir:
+				walk(child)
+			case *favorites && !child.favorite:
+			case filter != "" && !child.hasTag(filter):
+			default:
+				notes = append(notes, child)
+			}
+		}
+	}
+	walk(dir)
+	sort.Slice(notes, func(i, j int) bool { return notes[i].path < notes[j].path })
+
+	for _, n := range notes {
+		title := strings.ReplaceAll(n.title, "\t", " ")
+		fmt.Printf("%s\t%s\t%s\n", n.path, title, n.modified().Format("2006-01-02 15:04"))
+	}
+	return 0
+}
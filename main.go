@@ -11,11 +11,16 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 //go:embed VERSION
@@ -26,46 +31,116 @@ func getVersion() string {
 }
 
 type ColorConfig struct {
-	TitleBg         int `json:"title_bg"`
-	TitleFg         int `json:"title_fg"`
-	StatusBg        int `json:"status_bg"`
-	StatusFg        int `json:"status_fg"`
-	BorderColor     int `json:"border_color"`
-	SelectedFg      int `json:"selected_fg"`
-	FavoriteColor   int `json:"favorite_color"`
-	TagBarBg        int `json:"tag_bar_bg"`
-	TagBarFg        int `json:"tag_bar_fg"`
-	TagSelectedBg   int `json:"tag_selected_bg"`
-	TagSelectedFg   int `json:"tag_selected_fg"`
+	TitleBg       colorValue `json:"title_bg"`
+	TitleFg       colorValue `json:"title_fg"`
+	StatusBg      colorValue `json:"status_bg"`
+	StatusFg      colorValue `json:"status_fg"`
+	BorderColor   colorValue `json:"border_color"`
+	SelectedFg    colorValue `json:"selected_fg"`
+	FavoriteColor colorValue `json:"favorite_color"`
+	TagBarBg      colorValue `json:"tag_bar_bg"`
+	TagBarFg      colorValue `json:"tag_bar_fg"`
+	TagSelectedBg colorValue `json:"tag_selected_bg"`
+	TagSelectedFg colorValue `json:"tag_selected_fg"`
 }
 
 type Config struct {
-	NotesPath      string      `json:"notes_path"`
-	ExternalEditor string      `json:"external_editor"`
-	Colors         ColorConfig `json:"colors"`
+	Version               int               `json:"version"` // format of the file, see configVersion
+	NotesPath             string            `json:"notes_path"`
+	Vaults                map[string]string `json:"vaults"` // name -> folder of the other vaults, switched to with V
+	ExternalEditor        string            `json:"external_editor"`
+	DefaultTags           []string          `json:"default_tags"`
+	FrontmatterTags       bool              `json:"frontmatter_tags"`        // tags added by the app go in the frontmatter tags list, not inline
+	TagPickerLimit        int               `json:"tag_picker_limit"`        // max matches collected per keystroke, 0 = unlimited
+	TagPickerRows         int               `json:"tag_picker_rows"`         // rows the tag picker may grow to while filtering, 1 = single line
+	PinnedSection         string            `json:"pinned_section"`          // "", "folder" or "all": favorites shown above the listing
+	EmptyNoteAction       string            `json:"empty_note_action"`       // "keep", "prompt" or "trash" when an existing note is saved empty
+	TrashRetentionDays    int               `json:"trash_retention_days"`    // trash entries older than this are deleted at startup, 0 = never
+	ConfirmDelete         bool              `json:"confirm_delete"`          // ask before trashing a non-empty folder or deleting from the trash
+	HistoryLimit          int               `json:"history_limit"`           // saved versions kept per note under .history, 0 = no history
+	HistoryDays           int               `json:"history_days"`            // versions older than this are dropped, 0 = keep them by count only
+	RecoveryInterval      int               `json:"recovery_interval"`       // seconds between drafts of unsaved edits kept for crash recovery, 0 = off
+	BackupFolder          string            `json:"backup_folder"`           // where B and "notes backup" write a tar.gz of the notes folder
+	BackupLimit           int               `json:"backup_limit"`            // newest backups kept in backup_folder, 0 = keep all
+	RecentTagsLimit       int               `json:"recent_tags_limit"`       // recently used tags listed first in the tag picker, 0 = off
+	ContinueLists         bool              `json:"continue_lists"`          // continue bullet/numbered lists on Enter
+	RenumberLists         bool              `json:"renumber_lists"`          // renumber following items after inserting into a numbered list
+	SortMode              string            `json:"sort_mode"`               // "name", "date", "size", "created" or "manual" for folders not sorted with 't'
+	DirsFirst             bool              `json:"dirs_first"`              // list folders before notes whatever the sort
+	TreeView              bool              `json:"tree_view"`               // list the whole folder tree instead of one folder, toggled with L
+	TagSort               string            `json:"tag_sort"`                // "name" or "count" order of the tag browser, toggled with s
+	ScratchNote           string            `json:"scratch_note"`            // scratch note opened with ctrl+space, relative to NotesPath
+	StartFolder           string            `json:"start_folder"`            // folder shown at startup, relative to NotesPath
+	ReopenLast            bool              `json:"reopen_last"`             // start where the last session was left instead
+	JournalFolder         string            `json:"journal_folder"`          // folder of the daily notes opened with alt+t, relative to NotesPath
+	JournalFormat         string            `json:"journal_format"`          // Go time layout naming daily notes, e.g. "2006-01-02"
+	JournalTemplate       string            `json:"journal_template"`        // file new daily notes start from, relative to NotesPath
+	ArchiveFolder         string            `json:"archive_folder"`          // where archived notes are kept, relative to NotesPath
+	AssetsFolder          string            `json:"assets_folder"`           // folder next to notes holding their attachments
+	OpenCommand           string            `json:"open_command"`            // program attachments are opened with, "" for the system default
+	CursorPositionsLimit  int               `json:"cursor_positions_limit"`  // max remembered cursor positions, 0 = unlimited
+	EnsureTrailingNewline bool              `json:"ensure_trailing_newline"` // saved notes end with exactly one newline
+	DisableMouse          bool              `json:"disable_mouse"`           // leave mouse selection and scrolling to the terminal
+	Snippets              map[string]string `json:"snippets"`                // trigger -> text expanded with Tab before the cursor
+	Keymap                map[string]string `json:"keymap"`                  // action -> key, for the actions listed with tab in the help
+	ExportSeparator       string            `json:"export_separator"`        // text between notes in "notes export"
+	ExportFolder          string            `json:"export_folder"`           // where E writes the exported note or folder
+	ExportFormat          string            `json:"export_format"`           // "html", "pdf" or "md": what E exports to
+	PDFCommand            string            `json:"pdf_command"`             // prints {{in}}, an HTML file, to the PDF {{out}}
+	StickyHeader          bool              `json:"sticky_header"`           // show the heading of the section at the top of the editor
+	Theme                 string            `json:"theme"`                   // color preset last picked with ThemeKey
+	ThemeKey              string            `json:"theme_key"`               // navigation key cycling the color presets
+	Background            string            `json:"background"`              // "auto", "dark" or "light": the terminal's background, for the default theme
+	RecentNotesLimit      int               `json:"recent_notes_limit"`      // notes listed by ctrl+o, most recently opened first
+	VimMode               bool              `json:"vim_mode"`                // modal vim key bindings in the editor
+	LineNumbers           bool              `json:"line_numbers"`            // line number gutter in the editor, toggled with alt+l
+	SpellDictionary       string            `json:"spell_dictionary"`        // word list for spell checking, one word per line
+	RelativeLineNumbers   bool              `json:"relative_line_numbers"`   // number lines by distance from the cursor line
+	IndentWithTabs        bool              `json:"indent_with_tabs"`        // Tab inserts a tab character instead of spaces
+	IndentWidth           int               `json:"indent_width"`            // spaces per indentation level, also the width tabs are drawn
+	NoteExtension         string            `json:"note_extension"`          // file extension new notes are created with, e.g. ".md"
+	Git                   bool              `json:"git"`                     // commit saved notes when the notes folder is in a git repository
+	GitMessage            string            `json:"git_message"`             // commit message, with {{title}}, {{path}}, {{count}}, {{date}} and {{time}} filled in
+	SyncURL               string            `json:"sync_url"`                // WebDAV folder S syncs the notes with, "" for no syncing
+	SyncUser              string            `json:"sync_user"`               // user name on the WebDAV server
+	SyncPassword          string            `json:"sync_password"`           // password on the WebDAV server, unless NOTES_SYNC_PASSWORD is set
+	Colors                ColorConfig       `json:"colors"`
 }
 
 var (
-	config       Config
-	notesPath    string
-	nonAlphanum  = regexp.MustCompile(`[^a-zA-Z0-9_ ]+`)
-	tagRegex     = regexp.MustCompile(`(^|\s)#(\w+)`)
-	statusStyle  lipgloss.Style
-	contentStyle lipgloss.Style
-	titleStyle   lipgloss.Style
-	borderStyle  lipgloss.Style
+	config        Config
+	notesPath     string
+	nonAlphanum   = regexp.MustCompile(`[^a-zA-Z0-9_ ]+`)
+	tagRegex      = regexp.MustCompile(`(^|\s)#(\w+(?:/\w+)*)`) // nested tags like #work/clientA
+	statusStyle   lipgloss.Style
+	contentStyle  lipgloss.Style
+	titleStyle    lipgloss.Style
+	borderStyle   lipgloss.Style
 	selectedStyle lipgloss.Style
 	favoriteStyle lipgloss.Style
 )
 
 func getConfigPath() string {
-	homeDir, _ := os.UserHomeDir()
-	return filepath.Join(homeDir, ".config", "notes", "config.json")
+	if path := os.Getenv("NOTES_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(getConfigDir(), "config.json")
 }
 
 func getCursorPositionsPath() string {
-	homeDir, _ := os.UserHomeDir()
-	return filepath.Join(homeDir, ".config", "notes", "cursor_positions.json")
+	return filepath.Join(getStateDir(), "cursor_positions.json")
+}
+
+func getLastSeenPath() string {
+	return filepath.Join(getStateDir(), "last_seen.json")
+}
+
+func getRecentNotesPath() string {
+	return filepath.Join(getStateDir(), "recent_notes.json")
+}
+
+func getBookmarksPath() string {
+	return filepath.Join(getStateDir(), "bookmarks.json")
 }
 
 func loadCursorPositions() map[string]int {
@@ -78,6 +153,120 @@ func loadCursorPositions() map[string]int {
 	return positions
 }
 
+// pruneCursorPositions drops entries for notes that no longer exist and, when
+// limit is positive, keeps only the most recently modified notes. Paths that
+// can't be stat'ed for any other reason are kept. Reports whether anything
+// was removed.
+func pruneCursorPositions(positions map[string]int, limit int) bool {
+	modTimes := make(map[string]time.Time, len(positions))
+	pruned := false
+	for path := range positions {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			delete(positions, path)
+			pruned = true
+			continue
+		}
+		if err == nil {
+			modTimes[path] = info.ModTime()
+		}
+	}
+
+	if limit <= 0 || len(positions) <= limit {
+		return pruned
+	}
+
+	paths := make([]string, 0, len(positions))
+	for path := range positions {
+		paths = append(paths, path)
+	}
+	// Newest first; unknown modification times sort last
+	sort.Slice(paths, func(i, j int) bool {
+		return modTimes[paths[i]].After(modTimes[paths[j]])
+	})
+	for _, path := range paths[limit:] {
+		delete(positions, path)
+	}
+	return true
+}
+
+// loadBookmarks loads the named marks set in notes, as note path -> mark ->
+// character offset. Marks for notes that no longer exist are dropped.
+func loadBookmarks() map[string]map[string]int {
+	bookmarks := make(map[string]map[string]int)
+	data, err := os.ReadFile(getBookmarksPath())
+	if err != nil {
+		return bookmarks
+	}
+	_ = json.Unmarshal(data, &bookmarks)
+	for path := range bookmarks {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			delete(bookmarks, path)
+		}
+	}
+	return bookmarks
+}
+
+func saveBookmarks(bookmarks map[string]map[string]int) error {
+	configDir := filepath.Dir(getBookmarksPath())
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(bookmarks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(getBookmarksPath(), data, 0644)
+}
+
+// loadRecentNotes loads the paths of the most recently opened notes, most
+// recent first
+func loadRecentNotes() []string {
+	var recent []string
+	data, err := os.ReadFile(getRecentNotesPath())
+	if err != nil {
+		return recent
+	}
+	_ = json.Unmarshal(data, &recent)
+	return recent
+}
+
+func saveRecentNotes(recent []string) error {
+	configDir := filepath.Dir(getRecentNotesPath())
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(recent, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(getRecentNotesPath(), data, 0644)
+}
+
+// loadLastSeen loads the modification time each note had when it was last
+// opened or saved in the app
+func loadLastSeen() map[string]time.Time {
+	seen := make(map[string]time.Time)
+	data, err := os.ReadFile(getLastSeenPath())
+	if err != nil {
+		return seen
+	}
+	_ = json.Unmarshal(data, &seen)
+	return seen
+}
+
+func saveLastSeen(seen map[string]time.Time) error {
+	configDir := filepath.Dir(getLastSeenPath())
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(seen, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(getLastSeenPath(), data, 0644)
+}
+
 func saveCursorPositions(positions map[string]int) error {
 	configDir := filepath.Dir(getCursorPositionsPath())
 	if err := os.MkdirAll(configDir, 0755); err != nil {
@@ -93,40 +282,84 @@ func saveCursorPositions(positions map[string]int) error {
 func getDefaultConfig() Config {
 	homeDir, _ := os.UserHomeDir()
 	return Config{
-		NotesPath:      filepath.Join(homeDir, "Documents", "notes"),
-		ExternalEditor: "nano",
-		Colors: ColorConfig{
-			TitleBg:       4,   // Blue
-			TitleFg:       15,  // Bright White
-			StatusBg:      8,   // Dark Gray
-			StatusFg:      7,   // Light Gray
-			BorderColor:   12,  // Bright Blue
-			SelectedFg:    11,  // Bright Yellow
-			FavoriteColor: 9,   // Bright Red
-			TagBarBg:      235, // Dark Gray
-			TagBarFg:      250, // Light Gray
-			TagSelectedBg: 11,  // Bright Yellow
-			TagSelectedFg: 0,   // Black
-		},
-	}
-}
-
-func loadConfig() Config {
-	configPath := getConfigPath()
-	data, err := os.ReadFile(configPath)
-	if err != nil {
+		Version:               configVersion,
+		NotesPath:             filepath.Join(homeDir, "Documents", "notes"),
+		ExternalEditor:        "nano",
+		TagPickerLimit:        200,
+		TagPickerRows:         4,
+		EmptyNoteAction:       "keep",
+		RecentTagsLimit:       5,
+		ContinueLists:         true,
+		ConfirmDelete:         true,
+		HistoryLimit:          20,
+		RecoveryInterval:      30,
+		BackupFolder:          filepath.Join(homeDir, "Documents", "notes-backups"),
+		BackupLimit:           10,
+		ExportFolder:          filepath.Join(homeDir, "Documents", "notes-exports"),
+		ExportFormat:          "html",
+		PDFCommand:            "wkhtmltopdf --quiet {{in}} {{out}}",
+		SortMode:              "name",
+		TagSort:               "name",
+		ScratchNote:           "scratch.txt",
+		JournalFolder:         "journal",
+		JournalFormat:         "2006-01-02",
+		ArchiveFolder:         ".archive",
+		AssetsFolder:          "assets",
+		EnsureTrailingNewline: true,
+		ExportSeparator:       "\n",
+		Theme:                 "default",
+		ThemeKey:              "T",
+		Background:            "auto",
+		RecentNotesLimit:      20,
+		SpellDictionary:       "/usr/share/dict/words",
+		IndentWidth:           4,
+		NoteExtension:         ".txt",
+		GitMessage:            "Update {{title}}",
+		Colors:                colorThemes[0].colors,
+	}
+}
+
+// loadConfig reads the config, along with the problems found in it. A
+// config from an older version is saved in the current format, with the
+// old file kept next to it.
+func loadConfig() (Config, []string) {
+	if _, err := os.Stat(getConfigPath()); os.IsNotExist(err) {
 		// Config doesn't exist, create default
 		cfg := getDefaultConfig()
 		saveConfig(cfg)
-		return cfg
+		return cfg, validateConfig(&cfg)
+	}
+	stampConfig()
+	cfg, version, problems, err := readConfig()
+	if err != nil {
+		cfg = getDefaultConfig()
+		return cfg, append([]string{fmt.Sprintf("Could not read the config, using the defaults until it's fixed (saving settings in the app replaces it): %v", err)}, validateConfig(&cfg)...)
+	}
+	if version < configVersion {
+		data, err := os.ReadFile(getConfigPath())
+		old := fmt.Sprintf("%s.v%d.bak", getConfigPath(), version)
+		if err == nil && os.WriteFile(old, data, 0644) == nil {
+			saveConfig(cfg)
+		}
 	}
+	return cfg, problems
+}
 
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		log.Printf("Error parsing config, using defaults: %v", err)
-		return getDefaultConfig()
+// readConfig reads the config file, returning the version it was written
+// with and the problems found in it. Options missing from it keep their
+// defaults, and so do ones that can't be read or used.
+func readConfig() (cfg Config, version int, problems []string, err error) {
+	data, err := os.ReadFile(getConfigPath())
+	if err != nil {
+		return Config{}, 0, nil, err
+	}
+	// Start from the defaults so options missing from older config files keep sensible values
+	cfg = getDefaultConfig()
+	problems, version, err = decodeConfig(data, &cfg)
+	if err != nil {
+		return Config{}, 0, nil, err
 	}
-	return cfg
+	return cfg, version, append(problems, validateConfig(&cfg)...), nil
 }
 
 func saveConfig(cfg Config) error {
@@ -141,29 +374,36 @@ func saveConfig(cfg Config) error {
 		return err
 	}
 
-	return os.WriteFile(configPath, data, 0644)
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		return err
+	}
+	// Not an edit to reload
+	stampConfig()
+	return nil
 }
 
 func applyColorConfig() {
+	detectBackground()
+	resolveColors()
 	titleStyle = lipgloss.NewStyle().
-		Background(lipgloss.Color(fmt.Sprintf("%d", config.Colors.TitleBg))).
-		Foreground(lipgloss.Color(fmt.Sprintf("%d", config.Colors.TitleFg))).
+		Background(lipgloss.Color(uiColors.TitleBg)).
+		Foreground(lipgloss.Color(uiColors.TitleFg)).
 		Padding(0, 1)
 
 	statusStyle = lipgloss.NewStyle().
-		Background(lipgloss.Color(fmt.Sprintf("%d", config.Colors.StatusBg))).
-		Foreground(lipgloss.Color(fmt.Sprintf("%d", config.Colors.StatusFg)))
+		Background(lipgloss.Color(uiColors.StatusBg)).
+		Foreground(lipgloss.Color(uiColors.StatusFg))
 
 	borderStyle = lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color(fmt.Sprintf("%d", config.Colors.BorderColor)))
+		BorderForeground(lipgloss.Color(uiColors.BorderColor))
 
 	selectedStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color(fmt.Sprintf("%d", config.Colors.SelectedFg))).
+		Foreground(lipgloss.Color(uiColors.SelectedFg)).
 		Bold(true)
 
 	favoriteStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color(fmt.Sprintf("%d", config.Colors.FavoriteColor)))
+		Foreground(lipgloss.Color(uiColors.FavoriteColor))
 
 	contentStyle = lipgloss.NewStyle()
 }
@@ -179,54 +419,178 @@ const (
 	tagBrowserView
 	configView
 	helpView
+	previewView
+	archiveView
+	favoritesView
+	flatView
 )
 
 const (
 	sortByName sortMode = iota
 	sortByDate
+	sortBySize
+	sortByCreated
+	sortManual
 )
 
+// sortModeNames maps sort modes to their names in the config file
+var sortModeNames = map[sortMode]string{
+	sortByName:    "name",
+	sortByDate:    "date",
+	sortBySize:    "size",
+	sortByCreated: "created",
+	sortManual:    "manual",
+}
+
+// parseSortMode returns the sort mode for a config name, defaulting to by name
+func parseSortMode(name string) sortMode {
+	for mode, modeName := range sortModeNames {
+		if modeName == name {
+			return mode
+		}
+	}
+	return sortByName
+}
+
 type note struct {
 	title    string
 	content  string
 	path     string
 	isDir    bool
 	favorite bool
+	pinned   bool
 	tags     []string
 	children []*note
 	parent   *note
 	modTime  os.FileInfo
 }
 
+// titleInContent reports whether n's title comes from its content rather
+// than its file name, in which case renaming it leaves the file alone
+func (n *note) titleInContent() bool {
+	return !n.isDir && n.title != fileTitle(n.path)
+}
+
+// retitleNote gives n a title kept in its content and saves it
+func (m *model) retitleNote(n *note, title string) {
+	n.content = parserFor(n.path).SetTitle(n.content, title)
+	n.title = title
+	if err := m.writeNote(n); err != nil {
+		log.Printf("Error saving note: %v", err)
+	}
+}
+
+// setMeta updates n with the metadata read from its file
+func (n *note) setMeta(meta noteMeta) {
+	n.favorite, n.pinned, n.tags = meta.favorite, meta.pinned, meta.tags
+	n.title = meta.title
+	if n.title == "" {
+		n.title = fileTitle(n.path)
+	}
+}
+
+// fileContent returns the note as written to disk, with its metadata
+// formatted by the parser for its file type
+func (n *note) fileContent() string {
+	content := parserFor(n.path).Format(noteMeta{favorite: n.favorite, pinned: n.pinned, tags: n.tags}, n.content)
+	if config.EnsureTrailingNewline && content != "" {
+		content = strings.TrimRight(content, "\n") + "\n"
+	}
+	return content
+}
+
+// parseNoteFile splits a note file into its metadata and the body shown in
+// the editor. The final newline added by fileContent isn't part of the body.
+func parseNoteFile(path, data string) (noteMeta, string) {
+	meta, body := parserFor(path).ParseMeta(data)
+	if config.EnsureTrailingNewline {
+		body = strings.TrimSuffix(body, "\n")
+	}
+	return meta, body
+}
+
+// modified returns the note's modification time. Notes created this session
+// have no FileInfo yet, so fall back to the file on disk.
+func (n *note) modified() time.Time {
+	if n.modTime != nil {
+		return n.modTime.ModTime()
+	}
+	if info, err := os.Stat(n.path); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
 type model struct {
-	mode          viewMode
-	previousMode  viewMode
-	currentNode   *note
-	trashNode     *note
-	cursor        int
-	sort          sortMode
-	editor        Editor
-	quitting      bool
-	isNameTaken   bool
-	width         int
-	height        int
-	allTags       []string
-	selectedTag   string
-	filteredNotes []*note
-	configCursor  int
-	tempConfig    ColorConfig
-	editingPath   bool
-	pathInput     string
-	editingEditor bool
-	editorInput   string
+	mode            viewMode
+	previousMode    viewMode
+	helpKeymap      bool // the help shows the keymap instead
+	currentNode     *note
+	trashNode       *note
+	trashInfo       map[string]trashEntry // when and where from each trash entry was trashed, by name
+	archiveRoot     *note                 // archive folder tree while the archive view is open
+	archived        []*note               // archived notes listed in the archive view
+	archiveCursor   int
+	favorites       []*note // favorites from every folder, listed in the favorites view
+	favoritesCursor int
+	flatRoot        *note   // folder the flat listing was opened in
+	flatNotes       []*note // every note under flatRoot, listed in the flat view
+	flatCursor      int
+	flatByName      bool // the flat listing is sorted by path instead of date
+	cursor          int
+	listOffset      int       // first visible row of the navigation listing
+	lastClick       time.Time // when an entry was last clicked, to spot double clicks
+	lastClickRow    int       // listing row last clicked, pinned rows counting down from -2
+	sort            sortMode
+	folderSorts     map[string]folderSort // sort order chosen per folder, by folder path
+	expanded        map[string]bool       // folders expanded in the tree layout, by path
+	editor          Editor
+	quitting        bool
+	isNameTaken     bool
+	width           int
+	height          int
+	allTags         []string
+	collapsedTags   map[string]bool // tags whose nested tags are hidden in the tag browser
+	tagCounts       map[string]int  // notes carrying each tag or one nested under it
+	tagFolderScope  bool            // tag browser limited to the notes under the current folder
+	tagFilters      []string        // active tag browser filters, shown as chips; "-tag" excludes tag
+	filteredNotes   []*note         // notes matching tagFilters
+	tagMatchAny     bool            // match notes with any filter tag instead of all of them
+	addingFilter    bool            // picking another tag from the list while filters are active
+	chipFocus       bool            // cursor is on the filter chips rather than the notes
+	chipCursor      int
+	configCursor    int
+	tempConfig      ColorConfig
+	editingPath     bool
+	pathInput       string
+	editingEditor   bool
+	editorInput     string
+	editingColor    bool
+	colorInput      string
+	tempTheme       string // theme picked in the config view
+	editingTheme    bool
+	themeInput      string
 	// Tag picker state
 	showTagPicker     bool
 	tagPickerFilter   string
 	tagPickerCursor   int
 	tagPickerFiltered []string
+	tagPickerBuf      []string // reused backing array for filtered matches
+	tagPickerMore     bool     // matches were cut off at config.TagPickerLimit
+	recentTags        []string // most recently used first, for this session
+	// Tag cache for the picker, rebuilt only after notes change
+	tagCache      []string
+	tagCacheLower []string
+	tagCacheValid bool
 	// Cursor position tracking
-	cursorPositions map[string]int // note path -> cursor position
-	currentNotePath string         // path of currently edited note
+	cursorPositions map[string]int            // note path -> cursor position
+	bookmarks       map[string]map[string]int // note path -> mark -> cursor position
+	lastSeen        map[string]time.Time      // note path -> modification time when last opened or saved here
+	recentNotes     []string                  // paths of the last opened notes, most recent first
+	markPending     string                    // "set" or "jump" while waiting for the mark letter
+	notice          string                    // message shown in the status bar until the next key
+	noticeErr       bool                      // the notice is an error
+	currentNotePath string                    // path of currently edited note
 	// Rename popup state
 	showRenamePopup bool
 	renameInput     string
@@ -234,19 +598,147 @@ type model struct {
 	// Folder creation popup state
 	showFolderPopup bool
 	folderInput     string
+	// Pinned section state (cursor is in the pinned section when inPinned is set)
+	inPinned     bool
+	pinnedCursor int
+	// Jumping between notes carrying a tag with { and }
+	jumpTag          string
+	showJumpTagPopup bool
+	jumpTagInput     string
+	jumpTagDir       int // direction to jump once the tag is entered
+	// Empty note prompt state
+	showEmptyNotePopup bool
+	emptyNoteKey       tea.KeyMsg // save key (ctrl+s or esc) to replay if the empty note is kept
+	// Delete confirmation popup state
+	showDeletePopup bool
+	deletePrompt    string     // what's about to be deleted
+	deleteKey       tea.KeyMsg // delete key to replay once confirmed
+	deleteConfirmed bool       // the replayed key goes ahead without asking
+	// Edits made to the note's file outside the app
+	diskContent   string      // file content when the edited note was opened or last saved
+	showDiffPanel bool        // the file changed on disk while the buffer had edits
+	diffResult    []diffLine  // disk version -> editor buffer
+	diffTheirs    string      // file content found on disk
+	diffKey       *tea.KeyMsg // save key to replay once the conflict is resolved
+	diffOffset    int         // first diff line shown in the panel
+	// Quick switcher (ctrl+p) state
+	showSwitcher    bool
+	switcherInput   string
+	switcherNotes   []switcherEntry // every note, collected when the switcher opens
+	switcherMatches []switcherEntry
+	switcherCursor  int
+	switcherOffset  int  // first match shown in the popup
+	switcherRegex   bool // match the input as a regex against titles and contents
+	switcherRecent  bool // list only recently opened notes, most recent first
+	switcherInvalid bool // the regex doesn't compile
+	searchIndex     *searchIndex
+	vaultReplace    *vaultReplace  // search and replace across all notes, nil when closed
+	vaultPicker     *vaultPicker   // vault switcher popup, nil when closed
+	reopenPending   bool           // notes_path changed in the config, to be opened once back in the notes list
+	spellChecker    *spellChecker  // loaded when spell checking is first turned on
+	spellSuggest    *spellSuggest  // spelling suggestions popup, nil when closed
+	attachments     *attachments   // attachments panel, nil when closed
+	history         *noteHistory   // saved versions panel, nil when closed
+	gitRoot         string         // git repository the notes are committed to, "" when not committing
+	gitPending      []string       // notes saved since the last commit
+	gitChanges      bool           // the notes folder has changes not committed
+	recovery        *draftRecovery // unsaved drafts offered back at startup, nil when closed
+	configProblems  []string       // problems found in the config at startup or reload, shown until dismissed
+	draftFile       string         // draft of the buffer's unsaved edits, "" when none was written
+	draftRevision   int            // the editor's revision when the draft was written
+	marked          map[*note]bool // entries of the current folder marked for a bulk action
+	bulkAction      *bulkAction    // move or tag popup for the marked entries, nil when closed
+	tagEdit         *tagEdit       // tag delete or merge popup of the tag browser, nil when closed
+	// WebDAV sync (S)
+	syncing    bool      // a sync is running
+	syncDone   int       // files the running sync has done
+	syncTotal  int       // files the running sync has to do, 0 until it knows
+	lastSync   time.Time // when the last sync finished
+	syncReload bool      // a sync changed the notes, to be listed again once back in the notes list
+	// Read-only preview of a note (v)
+	previewNote   *note
+	previewOffset int // first wrapped line shown
+	statsCache    *noteStatsCache
+}
+
+// cachedTags returns every tag in the tree, collecting them only when the
+// cache has been invalidated by a change to the notes
+func (m *model) cachedTags() []string {
+	if !m.tagCacheValid {
+		rootNote := m.currentNode
+		for rootNote.parent != nil {
+			rootNote = rootNote.parent
+		}
+		m.tagCache = getAllTags(rootNote)
+		m.tagCacheLower = make([]string, len(m.tagCache))
+		for i, tag := range m.tagCache {
+			m.tagCacheLower[i] = strings.ToLower(tag)
+		}
+		m.tagCacheValid = true
+	}
+	return m.tagCache
+}
+
+func (m *model) invalidateTagCache() {
+	m.tagCacheValid = false
+}
+
+// rememberTags moves tags to the front of the recent tags list
+func (m *model) rememberTags(tags ...string) {
+	limit := config.RecentTagsLimit
+	if limit <= 0 {
+		return
+	}
+	for _, tag := range tags {
+		for i, recent := range m.recentTags {
+			if recent == tag {
+				m.recentTags = append(m.recentTags[:i], m.recentTags[i+1:]...)
+				break
+			}
+		}
+		m.recentTags = append([]string{tag}, m.recentTags...)
+	}
+	if len(m.recentTags) > limit {
+		m.recentTags = m.recentTags[:limit]
+	}
 }
 
 func (m *model) filterTags() {
+	m.tagPickerMore = false
 	if m.tagPickerFilter == "" {
-		m.tagPickerFiltered = m.allTags
-	} else {
-		m.tagPickerFiltered = []string{}
-		filterLower := strings.ToLower(m.tagPickerFilter)
+		// Recently used tags first, then everything else in order
+		m.tagPickerFiltered = m.tagPickerBuf[:0]
+		isRecent := make(map[string]bool, len(m.recentTags))
+		for _, recent := range m.recentTags {
+			isRecent[recent] = true
+		}
+		for _, recent := range m.recentTags {
+			if slices.Contains(m.allTags, recent) {
+				m.tagPickerFiltered = append(m.tagPickerFiltered, recent)
+			}
+		}
 		for _, tag := range m.allTags {
-			if strings.Contains(strings.ToLower(tag), filterLower) {
+			if !isRecent[tag] {
 				m.tagPickerFiltered = append(m.tagPickerFiltered, tag)
 			}
 		}
+		m.tagPickerBuf = m.tagPickerFiltered
+	} else {
+		// Reuse the match buffer and stop once there are more matches
+		// than the picker could ever show
+		m.tagPickerFiltered = m.tagPickerBuf[:0]
+		filterLower := strings.ToLower(m.tagPickerFilter)
+		limit := config.TagPickerLimit
+		for i, tagLower := range m.tagCacheLower {
+			if strings.Contains(tagLower, filterLower) {
+				if limit > 0 && len(m.tagPickerFiltered) >= limit {
+					m.tagPickerMore = true
+					break
+				}
+				m.tagPickerFiltered = append(m.tagPickerFiltered, m.tagCache[i])
+			}
+		}
+		m.tagPickerBuf = m.tagPickerFiltered
 	}
 	// Reset cursor if out of bounds
 	if m.tagPickerCursor >= len(m.tagPickerFiltered) {
@@ -265,7 +757,7 @@ func (m *model) checkName(name string) {
 	if m.mode == creatingFolderView {
 		path = filepath.Join(m.currentNode.path, sanitized)
 	} else {
-		path = filepath.Join(m.currentNode.path, sanitized+".txt")
+		path = filepath.Join(m.currentNode.path, sanitized+noteExtension())
 	}
 	_, err := os.Stat(path)
 	m.isNameTaken = !os.IsNotExist(err)
@@ -273,7 +765,7 @@ func (m *model) checkName(name string) {
 
 func (m *model) checkNameForRename(name string) {
 	sanitized := sanitizeTitle(name)
-	if sanitized == "" {
+	if sanitized == "" || m.renamingNode.titleInContent() {
 		m.isNameTaken = false
 		return
 	}
@@ -284,18 +776,51 @@ func (m *model) checkNameForRename(name string) {
 	if m.renamingNode.isDir {
 		newPath = filepath.Join(parentPath, sanitized)
 	} else {
-		newPath = filepath.Join(parentPath, sanitized+".txt")
+		newPath = renamedNotePath(m.renamingNode.path, sanitized)
 	}
 
-	// Check if the new path already exists AND it's not the same as the current path
+	// Check if the new path already exists AND it's not the same as the current path.
+	// On case-insensitive filesystems a case-only rename finds the note itself.
 	if newPath != m.renamingNode.path {
 		_, err := os.Stat(newPath)
-		m.isNameTaken = !os.IsNotExist(err)
+		m.isNameTaken = !os.IsNotExist(err) && !isSameFile(newPath, m.renamingNode.path)
 	} else {
 		m.isNameTaken = false // Same name, not taken
 	}
 }
 
+// isSameFile reports whether both paths name the same file, as they do for
+// names differing only in case on case-insensitive filesystems
+func isSameFile(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
+
+// renamePath renames oldPath to newPath. Case-only renames go through a
+// temporary name, since renaming straight to a name the filesystem considers
+// identical can be a no-op on case-insensitive filesystems.
+func renamePath(oldPath, newPath string) error {
+	if !strings.EqualFold(oldPath, newPath) {
+		return os.Rename(oldPath, newPath)
+	}
+	tmpPath := oldPath + ".renaming"
+	if err := os.Rename(oldPath, tmpPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, newPath); err != nil {
+		os.Rename(tmpPath, oldPath)
+		return err
+	}
+	return nil
+}
+
 func (m *model) checkNameForFolder(name string) {
 	sanitized := sanitizeTitle(name)
 	if sanitized == "" {
@@ -308,6 +833,29 @@ func (m *model) checkNameForFolder(name string) {
 	m.isNameTaken = !os.IsNotExist(err)
 }
 
+// noteExtension returns the extension new notes are created with
+func noteExtension() string {
+	ext := strings.TrimSpace(config.NoteExtension)
+	if ext == "" {
+		return ".txt"
+	}
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// newNotePath returns the path of a new note titled title in dir
+func newNotePath(dir, title string) string {
+	return filepath.Join(dir, sanitizeTitle(title)+noteExtension())
+}
+
+// renamedNotePath returns the path of the note at path after renaming it to
+// sanitized, keeping its extension
+func renamedNotePath(path, sanitized string) string {
+	return filepath.Join(filepath.Dir(path), sanitized+filepath.Ext(path))
+}
+
 func sanitizeTitle(title string) string {
 	title = nonAlphanum.ReplaceAllString(title, "")
 	title = strings.TrimSpace(title)
@@ -318,6 +866,61 @@ func sanitizeTitle(title string) string {
 	return title
 }
 
+// extractTags returns the inline #tags found in content
+func extractTags(content string) []string {
+	var tags []string
+	for _, match := range tagRegex.FindAllStringSubmatch(content, -1) {
+		tags = append(tags, match[2])
+	}
+	return tags
+}
+
+// withDefaultTags adds any configured default tags that the content of the
+// note at path doesn't already carry. Used when a new note is saved for the
+// first time.
+func withDefaultTags(path, content string) string {
+	return withTags(path, content, config.DefaultTags)
+}
+
+// withTags adds those of tags the content of the note at path doesn't
+// already carry. They go in the note's header tag list when it has one or
+// frontmatter_tags is set, else on a line of inline #tags at the end.
+func withTags(path, content string, tags []string) string {
+	present := make(map[string]bool)
+	for _, tag := range noteTags(path, content) {
+		present[tag] = true
+	}
+
+	var missing []string
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		if tag == "" || present[tag] {
+			continue
+		}
+		present[tag] = true
+		missing = append(missing, "#"+tag)
+	}
+	if len(missing) == 0 {
+		return content
+	}
+	p := parserFor(path)
+	if config.FrontmatterTags || len(p.HeaderTags(content)) > 0 {
+		for i, tag := range missing {
+			missing[i] = strings.TrimPrefix(tag, "#")
+		}
+		return p.AddHeaderTags(content, missing)
+	}
+
+	tagLine := strings.Join(missing, " ")
+	if content == "" {
+		return tagLine
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + "\n" + tagLine
+}
+
 func newNote(parent *note, path, title, content string, isDir, favorite bool, modTime os.FileInfo, tags []string) *note {
 	return &note{
 		parent:   parent,
@@ -353,6 +956,59 @@ func getAllTags(root *note) []string {
 	return tags
 }
 
+// tagMatches reports whether tag is filter or nested under it, as
+// #work/clientA is under #work
+func tagMatches(tag, filter string) bool {
+	return tag == filter || strings.HasPrefix(tag, filter+"/")
+}
+
+// hasTag reports whether n carries tag or a tag nested under it
+func (n *note) hasTag(tag string) bool {
+	return slices.ContainsFunc(n.tags, func(t string) bool { return tagMatches(t, tag) })
+}
+
+// findNotesByTags collects notes carrying all of tags, or any of them when
+// matchAll is false, and none of excluded. With only excluded tags every
+// other note matches. Tags nested under a filter tag match it.
+func findNotesByTags(n *note, tags, excluded []string, matchAll bool, results *[]*note) {
+	if !n.isDir && len(tags)+len(excluded) > 0 && !slices.ContainsFunc(excluded, n.hasTag) {
+		matched := 0
+		for _, tag := range tags {
+			if n.hasTag(tag) {
+				matched++
+			}
+		}
+		if len(tags) == 0 || (matchAll && matched == len(tags)) || (!matchAll && matched > 0) {
+			*results = append(*results, n)
+		}
+	}
+	for _, child := range n.children {
+		findNotesByTags(child, tags, excluded, matchAll, results)
+	}
+}
+
+// splitTagFilters separates the tag browser filters into the tags notes
+// must carry and the "-tag" ones they mustn't
+func splitTagFilters(filters []string) (tags, excluded []string) {
+	for _, filter := range filters {
+		if tag, ok := strings.CutPrefix(filter, "-"); ok {
+			excluded = append(excluded, tag)
+		} else {
+			tags = append(tags, filter)
+		}
+	}
+	return tags, excluded
+}
+
+// tagFilterLabel returns a tag browser filter as shown, "#tag" or "-#tag"
+// for an excluded one
+func tagFilterLabel(filter string) string {
+	if tag, ok := strings.CutPrefix(filter, "-"); ok {
+		return "-#" + tag
+	}
+	return "#" + filter
+}
+
 func findNotesByTag(n *note, tag string, results *[]*note) {
 	if !n.isDir {
 		for _, t := range n.tags {
@@ -367,6 +1023,71 @@ func findNotesByTag(n *note, tag string, results *[]*note) {
 	}
 }
 
+// noteStats holds the live counts shown while editing a note. The limits come
+// from optional "limit" (characters) and "word_limit" frontmatter keys.
+type noteStats struct {
+	words     int
+	chars     int
+	charLimit int // 0 means no limit
+	wordLimit int // 0 means no limit
+}
+
+func computeNoteStats(content string) noteStats {
+	fields, body, _ := parseFrontmatter(content)
+	stats := noteStats{
+		words: len(strings.Fields(body)),
+		chars: utf8.RuneCountInString(body),
+	}
+	if limit, err := strconv.Atoi(fields["limit"]); err == nil && limit > 0 {
+		stats.charLimit = limit
+	}
+	if limit, err := strconv.Atoi(fields["word_limit"]); err == nil && limit > 0 {
+		stats.wordLimit = limit
+	}
+	return stats
+}
+
+// noteStatsCache keeps the counts of the edited note until its text changes,
+// so a long note isn't counted again on every redraw
+type noteStatsCache struct {
+	revision int
+	stats    noteStats
+}
+
+// editorStats returns the counts for the note in the editor
+func (m model) editorStats() noteStats {
+	c := m.statsCache
+	if c != nil && c.revision == m.editor.Revision() {
+		return c.stats
+	}
+	frontmatter, words, chars := m.editor.CountBody()
+	stats := computeNoteStats(frontmatter)
+	stats.words, stats.chars = words, chars
+	if c != nil {
+		c.stats = stats
+		c.revision = m.editor.Revision()
+	}
+	return stats
+}
+
+// overLimit reports whether either soft limit has been exceeded.
+func (s noteStats) overLimit() bool {
+	return (s.charLimit > 0 && s.chars > s.charLimit) ||
+		(s.wordLimit > 0 && s.words > s.wordLimit)
+}
+
+func (s noteStats) String() string {
+	words := fmt.Sprintf("%d words", s.words)
+	if s.wordLimit > 0 {
+		words = fmt.Sprintf("%d/%d words", s.words, s.wordLimit)
+	}
+	chars := fmt.Sprintf("%d chars", s.chars)
+	if s.charLimit > 0 {
+		chars = fmt.Sprintf("%d/%d chars", s.chars, s.charLimit)
+	}
+	return words + ", " + chars
+}
+
 func loadNotes(rootPath string) *note {
 	root := &note{title: "All Notes", path: rootPath, isDir: true}
 	nodes := map[string]*note{rootPath: root}
@@ -378,8 +1099,8 @@ func loadNotes(rootPath string) *note {
 		if path == rootPath {
 			return nil
 		}
-		// Skip .trash directory
-		if d.Name() == ".trash" && d.IsDir() {
+		// Skip the .trash, .history, .git, archive and attachment directories
+		if d.IsDir() && (d.Name() == ".trash" || d.Name() == ".history" || d.Name() == ".git" || d.Name() == assetsFolderName() || path == getArchivePath()) {
 			return filepath.SkipDir
 		}
 		parentPath := filepath.Dir(path)
@@ -388,29 +1109,20 @@ func loadNotes(rootPath string) *note {
 			return nil
 		}
 		info, _ := d.Info()
-		title := d.Name()
-		if !d.IsDir() {
-			title = strings.TrimSuffix(title, filepath.Ext(title))
-		}
-		title = strings.ReplaceAll(title, "-", " ")
+		title := strings.ReplaceAll(d.Name(), "-", " ")
 		var content string
-		var favorite bool
-		var tags []string
+		var meta noteMeta
 		if !d.IsDir() {
-			fileContent, err := os.ReadFile(path)
-			if err == nil {
-				content = string(fileContent)
-				if strings.HasPrefix(content, "favorite: true\n") {
-					favorite = true
-					content = strings.TrimPrefix(content, "favorite: true\n")
-				}
-				matches := tagRegex.FindAllStringSubmatch(content, -1)
-				for _, match := range matches {
-					tags = append(tags, match[2])
-				}
+			title = fileTitle(path)
+			if fileContent, err := os.ReadFile(path); err == nil {
+				meta, content = parseNoteFile(path, string(fileContent))
+			}
+			if meta.title != "" {
+				title = meta.title
 			}
 		}
-		n := newNote(parent, path, title, content, d.IsDir(), favorite, info, tags)
+		n := newNote(parent, path, title, content, d.IsDir(), meta.favorite, info, meta.tags)
+		n.pinned = meta.pinned
 		parent.children = append(parent.children, n)
 		if d.IsDir() {
 			nodes[path] = n
@@ -421,7 +1133,7 @@ func loadNotes(rootPath string) *note {
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	return tea.Batch(draftTick(), configTick(), gitTick())
 }
 
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -432,36 +1144,129 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		m.editor.SetWidth(m.width)
-		// Calculate editor height: total height - title (1 line) - status bar (1 line in editing mode)
-		m.editor.SetHeight(m.height - 1 - 1)
-		m.editor.SetYOffset(1) // title bar = 1 line
+		m.fitEditorHeight()
+		m.editor.SetYOffset(1 + stickyHeaderHeight()) // title bar = 1 line
+		return m, nil
+	case externalEditorDoneMsg:
+		if msg.err != nil {
+			m.notice = fmt.Sprintf("External editor failed: %v", msg.err)
+			m.noticeErr = true
+		}
+		m.reloadAfterExternalEdit(msg.path)
+		return m, nil
+	case noticeMsg:
+		m.notice = msg.text
+		m.noticeErr = msg.isErr
+		return m, nil
+	case draftTickMsg:
+		m.saveDraft()
+		return m, draftTick()
+	case configTickMsg:
+		if configChanged() {
+			m.reloadConfig()
+		}
+		if m.reopenPending {
+			m.reopenDefaultVault()
+		}
+		m.reloadSynced()
+		return m, configTick()
+	case syncProgressMsg:
+		m.syncDone, m.syncTotal = msg.done, msg.total
+		return m, waitSync(msg.updates)
+	case syncDoneMsg:
+		m.finishSync(msg)
+		return m, nil
+	case gitTickMsg:
+		return m, m.updateGit()
+	case gitStatusMsg:
+		m.gitChanges = msg.dirty
+		if msg.err != nil {
+			m.notice = fmt.Sprintf("Git commit failed: %v", msg.err)
+			m.noticeErr = true
+		}
 		return m, nil
 	case tea.MouseMsg:
 		mouseEvent := tea.MouseEvent(msg)
+		if m.mode == navigationView {
+			return m.updateNavigationMouse(mouseEvent)
+		}
 		switch mouseEvent.Button {
 		case tea.MouseButtonWheelUp:
-			if m.mode == navigationView && len(m.currentNode.children) > 0 {
-				if m.cursor > 0 {
-					m.cursor--
-				}
+			if m.mode == previewView {
+				m.scrollPreview(-3)
 			}
 		case tea.MouseButtonWheelDown:
-			if m.mode == navigationView && len(m.currentNode.children) > 0 {
-				if m.cursor < len(m.currentNode.children)-1 {
-					m.cursor++
-				}
+			if m.mode == previewView {
+				m.scrollPreview(3)
 			}
 		}
 	case tea.KeyMsg:
-		if msg.String() == "ctrl+c" || (m.mode == navigationView && msg.String() == "q") {
+		m.notice = ""
+		if msg.String() == "ctrl+c" {
+			m.saveDraft()
+			m.saveSession()
+			m.flushGit()
+			m.quitting = true
+			return m, tea.Quit
+		}
+		if m.configProblems != nil {
+			return m.updateConfigProblems(msg)
+		}
+		if m.recovery != nil {
+			return m.updateDraftRecovery(msg)
+		}
+		// ctrl+space opens the scratch note from anywhere
+		if msg.String() == keyFor("scratch") {
+			m.openScratch()
+			return m, nil
+		}
+		// alt+t opens today's journal note from anywhere
+		if msg.String() == keyFor("journal") {
+			m.openDailyNote()
+			return m, nil
+		}
+		// Popups take typed text, q included
+		if m.showSwitcher {
+			return m.updateSwitcher(msg)
+		}
+		if m.vaultReplace != nil {
+			return m.updateVaultReplace(msg)
+		}
+		if m.vaultPicker != nil {
+			return m.updateVaultPicker(msg)
+		}
+		if m.bulkAction != nil {
+			return m.updateBulkAction(msg)
+		}
+		if m.showDeletePopup {
+			return m.updateDeletePopup(msg)
+		}
+		if m.tagEdit != nil {
+			return m.updateTagEdit(msg)
+		}
+		if m.mode == navigationView && msg.String() == keyFor("quit") {
+			m.saveDraft()
+			m.saveSession()
+			m.flushGit()
 			m.quitting = true
 			return m, tea.Quit
 		}
+		// ctrl+p opens the quick switcher, ctrl+o the recently opened notes
+		if (msg.String() == keyFor("switcher") || msg.String() == keyFor("recent_notes")) && m.canOpenSwitcher() {
+			m.openSwitcher(msg.String() == keyFor("recent_notes"))
+			return m, nil
+		}
 		switch m.mode {
 		case navigationView:
-			return m.updateNavigationView(msg)
+			model, cmd := m.updateNavigationView(msg)
+			if m.mode == navigationView {
+				m.ensureListCursorVisible()
+			}
+			return model, cmd
 		case editingView:
-			return m.updateEditingView(msg)
+			model, cmd := m.updateEditingView(msg)
+			m.fitEditorHeight()
+			return model, cmd
 		case creatingFolderView:
 			return m.updateCreatingFolderView(msg)
 		case trashView:
@@ -472,6 +1277,14 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.updateConfigView(msg)
 		case helpView:
 			return m.updateHelpView(msg)
+		case previewView:
+			return m.updatePreviewView(msg)
+		case archiveView:
+			return m.updateArchiveView(msg)
+		case favoritesView:
+			return m.updateFavoritesView(msg)
+		case flatView:
+			return m.updateFlatView(msg)
 		}
 	}
 
@@ -483,8 +1296,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.mode == creatingFolderView {
 			m.checkName(m.editor.Value())
 		} else if m.mode == editingView && m.cursor == -1 { // Only for new notes
-			lines := strings.SplitN(m.editor.Value(), "\n", 2)
-			m.checkName(lines[0])
+			m.checkName(m.editor.FirstLine())
 		}
 
 		return m, cmd
@@ -493,22 +1305,174 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// sortNotes sorts the current folder by the order chosen for it
 func (m *model) sortNotes() {
-	switch m.sort {
-	case sortByName:
-		sort.Slice(m.currentNode.children, func(i, j int) bool {
-			return m.currentNode.children[i].title < m.currentNode.children[j].title
-		})
-	case sortByDate:
-		sort.Slice(m.currentNode.children, func(i, j int) bool {
-			return m.currentNode.children[i].modTime.ModTime().After(m.currentNode.children[j].modTime.ModTime())
+	m.sort = folderSortMode(m.folderSorts, m.currentNode.path)
+	sortFolder(m.folderSorts, m.currentNode)
+}
+
+// pinnedNotes returns the favorites shown in the pinned section, either from
+// the current folder or from the whole tree depending on config
+func (m *model) pinnedNotes() []*note {
+	if config.TreeView {
+		return nil // the tree has no room for it
+	}
+	var pinned []*note
+	switch config.PinnedSection {
+	case "folder":
+		for _, child := range m.currentNode.children {
+			if child.favorite && !child.isDir {
+				pinned = append(pinned, child)
+			}
+		}
+	case "all":
+		rootNote := m.currentNode
+		for rootNote.parent != nil {
+			rootNote = rootNote.parent
+		}
+		collectFavorites(rootNote, &pinned)
+		sort.Slice(pinned, func(i, j int) bool {
+			return pinned[i].title < pinned[j].title
 		})
 	}
+	return pinned
 }
 
-func (m *model) updateNavigationView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	// Handle rename popup if it's showing
-	if m.showRenamePopup {
+// countNotes returns the number of notes in a folder, subfolders included
+func countNotes(dir *note) int {
+	count := 0
+	for _, child := range dir.children {
+		if child.isDir {
+			count += countNotes(child)
+		} else {
+			count++
+		}
+	}
+	return count
+}
+
+func collectFavorites(n *note, results *[]*note) {
+	if !n.isDir && n.favorite {
+		*results = append(*results, n)
+	}
+	for _, child := range n.children {
+		collectFavorites(child, results)
+	}
+}
+
+// listEntryName returns the name of a note or folder as listed, with its
+// markers
+func (m *model) listEntryName(n *note) string {
+	name := n.title
+	if n.isDir {
+		name = lipgloss.NewStyle().Bold(true).Render(name) + "/" +
+			lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf(" (%d)", countNotes(n)))
+	}
+
+	// Apply pin and favorite markers
+	if n.favorite {
+		name = favoriteStyle.Render("★") + " " + name
+	}
+	if n.pinned {
+		name = favoriteStyle.Render("↑") + " " + name
+	}
+	if m.marked[n] {
+		name = favoriteStyle.Render("✓") + " " + name
+	}
+	// Mark notes changed outside the app since they were last opened
+	if m.changedSinceSeen(n) {
+		name += " " + favoriteStyle.Render("•")
+	}
+	return name
+}
+
+// navHeaderHeight is the number of lines above the listing: blank line,
+// folder title, underline and another blank line
+const navHeaderHeight = 4
+
+// pinnedRows returns how many pinned notes are shown and the total lines the
+// pinned section takes up (label and spacer included). The section never
+// takes more than half of the list area so the listing stays usable.
+func (m *model) pinnedRows(pinned []*note) (shown, lines int) {
+	if len(pinned) == 0 {
+		return 0, 0
+	}
+	area := m.height - 1 - m.getStatusBarHeight() - navHeaderHeight
+	shown = len(pinned)
+	if maxShown := area/2 - 2; shown > maxShown {
+		shown = max(maxShown, 1)
+	}
+	return shown, shown + 2
+}
+
+// navListHeight returns how many rows of the regular listing fit on screen
+func (m *model) navListHeight() int {
+	_, pinnedLines := m.pinnedRows(m.pinnedNotes())
+	h := m.height - 1 - m.getStatusBarHeight() - navHeaderHeight - pinnedLines
+	if h < 1 {
+		h = 1
+	}
+	return h
+}
+
+// ensureListCursorVisible scrolls the navigation listing so the cursor stays
+// on screen, and keeps the pinned cursor within the pinned section
+func (m *model) ensureListCursorVisible() {
+	pinned := m.pinnedNotes()
+	if len(pinned) == 0 {
+		m.inPinned = false
+	}
+	if m.pinnedCursor >= len(pinned) {
+		m.pinnedCursor = max(len(pinned)-1, 0)
+	}
+	if shown, _ := m.pinnedRows(pinned); m.pinnedCursor >= shown {
+		m.pinnedCursor = max(shown-1, 0)
+	}
+
+	height := m.navListHeight()
+	cursor, total := m.cursor, len(m.currentNode.children)
+	if config.TreeView {
+		rows := m.treeRows()
+		cursor, total = max(m.treeCursorRow(rows), 0), len(rows)
+	}
+	if cursor < m.listOffset {
+		m.listOffset = cursor
+	}
+	if cursor >= m.listOffset+height {
+		m.listOffset = cursor - height + 1
+	}
+	if maxOffset := total - height; m.listOffset > maxOffset {
+		m.listOffset = max(maxOffset, 0)
+	}
+	if m.listOffset < 0 {
+		m.listOffset = 0
+	}
+}
+
+// revealPinned moves the cursor from the pinned section to the note's own
+// entry, entering its folder if needed, so regular actions apply to it
+func (m *model) revealPinned() {
+	pinned := m.pinnedNotes()
+	m.inPinned = false
+	if m.pinnedCursor >= len(pinned) {
+		return
+	}
+	selected := pinned[m.pinnedCursor]
+	if selected.parent != m.currentNode {
+		m.currentNode = selected.parent
+		m.sortNotes()
+	}
+	for i, child := range m.currentNode.children {
+		if child == selected {
+			m.cursor = i
+			break
+		}
+	}
+}
+
+func (m *model) updateNavigationView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Handle rename popup if it's showing
+	if m.showRenamePopup {
 		switch msg.String() {
 		case "enter":
 			if m.isNameTaken {
@@ -516,7 +1480,10 @@ func (m *model) updateNavigationView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 			newName := m.renameInput
 			sanitizedName := sanitizeTitle(newName)
-			if sanitizedName != "" && m.renamingNode != nil {
+			if sanitizedName != "" && m.renamingNode != nil && m.renamingNode.titleInContent() {
+				// The title is kept in the note, so the file stays as it is
+				m.retitleNote(m.renamingNode, strings.TrimSpace(newName))
+			} else if sanitizedName != "" && m.renamingNode != nil {
 				oldPath := m.renamingNode.path
 				parentPath := filepath.Dir(oldPath)
 
@@ -525,31 +1492,41 @@ func (m *model) updateNavigationView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				if m.renamingNode.isDir {
 					newPath = filepath.Join(parentPath, sanitizedName)
 				} else {
-					newPath = filepath.Join(parentPath, sanitizedName+".txt")
+					newPath = renamedNotePath(oldPath, sanitizedName)
 				}
 
 				// Only rename if the path has actually changed
 				if oldPath != newPath {
-					if err := os.Rename(oldPath, newPath); err != nil {
+					if err := renamePath(oldPath, newPath); err != nil {
 						log.Printf("Error renaming: %v", err)
 					} else {
 						// Update the note structure
 						m.renamingNode.title = newName
-						m.renamingNode.path = newPath
+						setNotePath(m.renamingNode, newPath)
+						m.folderSortsMoved(oldPath, newPath)
+						if m.renamingNode.isDir {
+							moveHistory(oldPath, newPath)
+						}
 
 						// Update cursor position tracking if it's a file
 						if !m.renamingNode.isDir {
-							if pos, exists := m.cursorPositions[oldPath]; exists {
-								delete(m.cursorPositions, oldPath)
-								m.cursorPositions[newPath] = pos
-								saveCursorPositions(m.cursorPositions)
+							before := m.renamingNode.content
+							moveAttachments(m.renamingNode, oldPath, newPath)
+							if m.renamingNode.content != before {
+								m.writeNote(m.renamingNode)
 							}
+							m.notePathChanged(oldPath, newPath)
 						}
 					}
+					m.syncSearchIndex()
 				} else {
 					// Just update the title if only display name changed
 					m.renamingNode.title = newName
 				}
+				if !m.renamingNode.isDir && m.renamingNode.path == newPath && fileTitle(newPath) != newName {
+					// The file name can't carry the title as typed
+					m.retitleNote(m.renamingNode, newName)
+				}
 			}
 			// Close popup
 			m.showRenamePopup = false
@@ -580,6 +1557,32 @@ func (m *model) updateNavigationView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	// Handle jump-to-tag popup if it's showing
+	if m.showJumpTagPopup {
+		switch msg.String() {
+		case "enter":
+			m.showJumpTagPopup = false
+			if tag := strings.TrimPrefix(strings.TrimSpace(m.jumpTagInput), "#"); tag != "" {
+				m.jumpTag = tag
+				m.jumpToNote(m.jumpTagDir, m.hasJumpTag)
+			}
+			return m, nil
+		case "esc":
+			m.showJumpTagPopup = false
+			return m, nil
+		case "backspace":
+			if len(m.jumpTagInput) > 0 {
+				m.jumpTagInput = m.jumpTagInput[:len(m.jumpTagInput)-1]
+			}
+			return m, nil
+		default:
+			if len(msg.String()) == 1 {
+				m.jumpTagInput += msg.String()
+			}
+			return m, nil
+		}
+	}
+
 	// Handle folder creation popup if it's showing
 	if m.showFolderPopup {
 		switch msg.String() {
@@ -625,54 +1628,118 @@ func (m *model) updateNavigationView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	if config.ThemeKey != "" && msg.String() == config.ThemeKey {
+		m.cycleTheme()
+		return m, nil
+	}
+
+	// With entries marked, d, f, m and + act on all of them
+	if m.updateMarkedNotes(msg) {
+		return m, nil
+	}
+
+	if config.TreeView && m.updateTreeNavigation(msg) {
+		return m, nil
+	}
+
+	// Actions on a pinned entry apply to the note itself
+	if m.inPinned {
+		switch msg.String() {
+		case "right", "enter", keyFor("mark"), keyFor("preview"), keyFor("favorite"), keyFor("rename"), keyFor("trash"), keyFor("duplicate"), keyFor("external_editor"):
+			m.revealPinned()
+		}
+	}
+
 	switch msg.String() {
 	case "up", "k":
-		if len(m.currentNode.children) > 0 {
+		pinnedShown, _ := m.pinnedRows(m.pinnedNotes())
+		if m.inPinned {
+			if m.pinnedCursor > 0 {
+				m.pinnedCursor--
+			} else if len(m.currentNode.children) > 0 {
+				m.inPinned = false
+				m.cursor = len(m.currentNode.children) - 1
+			} else {
+				m.pinnedCursor = pinnedShown - 1
+			}
+		} else if len(m.currentNode.children) > 0 {
 			if m.cursor > 0 {
 				m.cursor--
+			} else if pinnedShown > 0 {
+				m.inPinned = true
+				m.pinnedCursor = pinnedShown - 1
 			} else {
 				m.cursor = len(m.currentNode.children) - 1
 			}
 		}
 	case "down", "j":
-		if len(m.currentNode.children) > 0 {
+		pinnedShown, _ := m.pinnedRows(m.pinnedNotes())
+		if m.inPinned {
+			if m.pinnedCursor < pinnedShown-1 {
+				m.pinnedCursor++
+			} else if len(m.currentNode.children) > 0 {
+				m.inPinned = false
+				m.cursor = 0
+			} else {
+				m.pinnedCursor = 0
+			}
+		} else if len(m.currentNode.children) > 0 {
 			if m.cursor < len(m.currentNode.children)-1 {
 				m.cursor++
+			} else if pinnedShown > 0 {
+				m.inPinned = true
+				m.pinnedCursor = 0
 			} else {
 				m.cursor = 0
 			}
 		}
+	case "]", "[":
+		dir := 1
+		if msg.String() == "[" {
+			dir = -1
+		}
+		m.jumpToNote(dir, func(n *note) bool { return !n.isDir && n.favorite })
+	case "}", "{", "#":
+		dir := 1
+		if msg.String() == "{" {
+			dir = -1
+		}
+		if m.jumpTag == "" || msg.String() == "#" {
+			m.showJumpTagPopup = true
+			m.jumpTagInput = m.jumpTag
+			m.jumpTagDir = dir
+			return m, nil
+		}
+		m.jumpToNote(dir, m.hasJumpTag)
 	case "right", "enter":
 		if len(m.currentNode.children) > 0 {
 			selectedNote := m.currentNode.children[m.cursor]
 			if selectedNote.isDir {
 				m.currentNode = selectedNote
 				m.cursor = 0
+				m.listOffset = 0
+				m.clearMarks()
 				m.sortNotes()
 			} else {
-				m.mode = editingView
-				m.currentNotePath = selectedNote.path
-				m.editor.SetValue(selectedNote.content)
-
-				// Restore cursor position if we have one saved
-				if savedPos, exists := m.cursorPositions[selectedNote.path]; exists {
-					// Clamp to content length to avoid out of bounds
-					maxPos := len(selectedNote.content)
-					if savedPos > maxPos {
-						savedPos = maxPos
-					}
-					m.editor.SetCursor(savedPos)
-				}
-
-				m.editor.Focus()
+				m.openNote(selectedNote)
 				return m, nil
 			}
 		}
+	case keyFor("mark"):
+		m.toggleMark()
+		return m, nil
+	case keyFor("preview"):
+		if len(m.currentNode.children) > 0 && !m.currentNode.children[m.cursor].isDir {
+			m.openPreview(m.currentNode.children[m.cursor])
+		}
+		return m, nil
 	case "left", "esc":
 		if m.currentNode.parent != nil {
+			m.inPinned = false
 			// Remember which folder we're coming from
 			previousNode := m.currentNode
 			m.currentNode = m.currentNode.parent
+			m.clearMarks()
 			m.sortNotes()
 			// Find the index of the folder we just left and position cursor there
 			for i, child := range m.currentNode.children {
@@ -682,68 +1749,110 @@ func (m *model) updateNavigationView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				}
 			}
 		}
-	case "n":
-		m.mode = editingView
-		m.currentNotePath = "" // New note doesn't have a path yet
-		m.editor.SetValue("")
-		m.editor.SetPlaceholder("New Note: first line is the title. ESC to save.")
-		m.editor.Focus()
-		m.isNameTaken = false
-		m.cursor = -1
+	case keyFor("new_note"):
+		m.startNewNote()
 		return m, nil
-	case "F":
+	case keyFor("new_folder"):
 		m.showFolderPopup = true
 		m.folderInput = ""
 		m.isNameTaken = false
 		return m, nil
-	case "ctrl+t":
+	case keyFor("replace"):
+		m.vaultReplace = &vaultReplace{}
+		return m, nil
+	case keyFor("vaults"):
+		m.openVaultPicker()
+	case keyFor("backup"):
+		m.notice = "Backing up…"
+		return m, backupCmd()
+	case keyFor("sync"):
+		return m, m.startSync()
+	case keyFor("export"):
+		if len(m.currentNode.children) > 0 {
+			m.notice = "Exporting…"
+			return m, m.exportCmd(m.currentNode.children[m.cursor])
+		}
+		return m, nil
+	case keyFor("archive"):
+		if len(m.currentNode.children) > 0 {
+			m.archiveNote(m.cursor)
+		}
+		return m, nil
+	case keyFor("archive_view"):
+		m.openArchive()
+		return m, nil
+	case keyFor("favorites_view"):
+		m.openFavorites()
+		return m, nil
+	case keyFor("flat_list"):
+		m.openFlatList()
+		return m, nil
+	case keyFor("trash_view"):
 		m.previousMode = m.mode
 		m.mode = trashView
 		m.currentNode = m.trashNode
 		m.cursor = 0
 		return m, nil
-	case "g":
-		m.previousMode = m.mode
-		m.mode = tagBrowserView
-		rootNote := m.currentNode
-		for rootNote.parent != nil {
-			rootNote = rootNote.parent
-		}
-		m.allTags = getAllTags(rootNote)
-		m.cursor = 0
+	case keyFor("tags"):
+		m.openTagBrowser()
 		return m, nil
-	case "c":
+	case keyFor("config"):
 		m.previousMode = m.mode
 		m.mode = configView
 		m.configCursor = 0
 		m.tempConfig = config.Colors
+		m.tempTheme = config.Theme
 		return m, nil
-	case "?":
+	case keyFor("help"):
 		m.previousMode = m.mode
 		m.mode = helpView
 		return m, nil
-	case "t":
-		m.sort = (m.sort + 1) % 2
-		m.sortNotes()
+	case keyFor("sort"):
+		m.cycleSort()
+		return m, nil
+	case keyFor("tree"):
+		m.toggleTreeView()
+		return m, nil
+	case "K", "shift+up":
+		if !m.inPinned {
+			m.moveInOrder(-1)
+		}
 		return m, nil
-	case "f":
+	case "J", "shift+down":
+		if !m.inPinned {
+			m.moveInOrder(1)
+		}
+		return m, nil
+	case keyFor("favorite"):
 		if len(m.currentNode.children) > 0 {
 			selectedNote := m.currentNode.children[m.cursor]
 			if !selectedNote.isDir {
 				selectedNote.favorite = !selectedNote.favorite
-				var content string
-				if selectedNote.favorite {
-					content = "favorite: true\n" + selectedNote.content
-				} else {
-					content = selectedNote.content
+				if err := m.writeNote(selectedNote); err != nil {
+					log.Printf("Could not update note: %v", err)
 				}
-				if err := os.WriteFile(selectedNote.path, []byte(content), 0644); err != nil {
+			}
+		}
+		return m, nil
+	case keyFor("pin"):
+		if len(m.currentNode.children) > 0 {
+			selectedNote := m.currentNode.children[m.cursor]
+			if !selectedNote.isDir {
+				selectedNote.pinned = !selectedNote.pinned
+				if err := m.writeNote(selectedNote); err != nil {
 					log.Printf("Could not update note: %v", err)
 				}
+				m.sortNotes()
+				for i, child := range m.currentNode.children {
+					if child == selectedNote {
+						m.cursor = i
+						break
+					}
+				}
 			}
 		}
 		return m, nil
-	case "r":
+	case keyFor("rename"):
 		if len(m.currentNode.children) > 0 {
 			selectedNote := m.currentNode.children[m.cursor]
 			m.renamingNode = selectedNote
@@ -753,21 +1862,24 @@ func (m *model) updateNavigationView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		return m, nil
-	case "d":
+	case keyFor("trash"):
 		if len(m.currentNode.children) > 0 {
-			selectedNote := m.currentNode.children[m.cursor]
-			trashPath := filepath.Join(notesPath, ".trash")
-			newPath := filepath.Join(trashPath, selectedNote.title)
-			if err := os.Rename(selectedNote.path, newPath); err != nil {
-				log.Printf("Could not move to trash: %v", err)
+			n := m.currentNode.children[m.cursor]
+			if n.isDir && len(n.children) > 0 &&
+				!m.confirmDelete(fmt.Sprintf("Move %s/ to the trash? It holds %s.", n.title, plural(countNotes(n), "note")), msg) {
+				return m, nil
 			}
-			m.currentNode.children = append(m.currentNode.children[:m.cursor], m.currentNode.children[m.cursor+1:]...)
-			if m.cursor > 0 {
+			if m.moveToTrash(m.cursor) == nil && m.cursor > 0 {
 				m.cursor--
 			}
 		}
 		return m, nil
-	case "ctrl+e":
+	case keyFor("duplicate"):
+		if len(m.currentNode.children) > 0 {
+			m.duplicateNote(m.cursor)
+		}
+		return m, nil
+	case keyFor("external_editor"):
 		if len(m.currentNode.children) > 0 {
 			selectedNote := m.currentNode.children[m.cursor]
 			if !selectedNote.isDir {
@@ -779,6 +1891,110 @@ func (m *model) updateNavigationView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// notePathChanged carries the cursor position, bookmarks and other state
+// kept for the note at oldPath over to newPath
+func (m *model) notePathChanged(oldPath, newPath string) {
+	if pos, exists := m.cursorPositions[oldPath]; exists {
+		delete(m.cursorPositions, oldPath)
+		m.cursorPositions[newPath] = pos
+		saveCursorPositions(m.cursorPositions)
+	}
+	if marks, exists := m.bookmarks[oldPath]; exists {
+		delete(m.bookmarks, oldPath)
+		m.bookmarks[newPath] = marks
+		saveBookmarks(m.bookmarks)
+	}
+	if seen, exists := m.lastSeen[oldPath]; exists {
+		delete(m.lastSeen, oldPath)
+		m.lastSeen[newPath] = seen
+		saveLastSeen(m.lastSeen)
+	}
+	if i := slices.Index(m.recentNotes, oldPath); i >= 0 {
+		m.recentNotes[i] = newPath
+		saveRecentNotes(m.recentNotes)
+	}
+	moveHistory(oldPath, newPath)
+}
+
+// copyPath returns a free path next to the note at path for a copy of it,
+// "name (label)" or "name (label 2)" and so on
+func copyPath(path, label string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(filepath.Base(path), ext)
+	copied := filepath.Join(filepath.Dir(path), base+" ("+label+")"+ext)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(copied); os.IsNotExist(err) {
+			return copied
+		}
+		copied = filepath.Join(filepath.Dir(path), fmt.Sprintf("%s (%s %d)%s", base, label, n, ext))
+	}
+}
+
+// newCopy returns a note at path, in the current folder, holding content as
+// a copy of original. A title kept in the content gets the same suffix as
+// the file name.
+func (m *model) newCopy(original *note, path, content string) *note {
+	title := fileTitle(path)
+	copied := newNote(m.currentNode, path, title, content, false, false, nil, noteTags(path, content))
+	if original.titleInContent() {
+		copied.content = parserFor(path).SetTitle(copied.content, original.title+strings.TrimPrefix(title, fileTitle(original.path)))
+		copied.title = noteTitle(path, copied.content)
+	}
+	return copied
+}
+
+// duplicateNote copies the i-th child of the current folder to "Title
+// (copy)" next to it and moves the cursor onto the copy. The copy isn't a
+// favorite or pinned, so it doesn't crowd the original.
+func (m *model) duplicateNote(i int) {
+	original := m.currentNode.children[i]
+	if original.isDir {
+		m.notice = "Only notes can be duplicated"
+		return
+	}
+	copied := m.newCopy(original, copyPath(original.path, "copy"), original.content)
+	if err := m.writeNote(copied); err != nil {
+		m.notice = fmt.Sprintf("Could not duplicate: %v", err)
+		m.noticeErr = true
+		return
+	}
+	m.currentNode.children = append(m.currentNode.children, copied)
+	m.sortNotes()
+	m.cursor = slices.Index(m.currentNode.children, copied)
+	m.invalidateTagCache()
+	m.notice = "Duplicated as " + copied.title
+}
+
+// moveToTrash moves the i-th child of the current folder into the trash.
+// On failure the entry stays where it was and the error is shown.
+func (m *model) moveToTrash(i int) error {
+	selectedNote := m.currentNode.children[i]
+	trashPath := filepath.Join(notesPath, ".trash")
+	newPath := trashDest(trashPath, selectedNote.path)
+	if err := os.Rename(selectedNote.path, newPath); err != nil {
+		m.notice = fmt.Sprintf("Could not move %s to the trash: %v", selectedNote.title, err)
+		m.noticeErr = true
+		return err
+	}
+	m.setTrashed(newPath, selectedNote.path)
+	m.currentNode.children = append(m.currentNode.children[:i], m.currentNode.children[i+1:]...)
+	m.invalidateTagCache()
+	m.syncSearchIndex()
+	return nil
+}
+
+// syncSearchIndex brings the search index up to date with the note tree
+// after notes were moved or removed
+func (m *model) syncSearchIndex() {
+	rootNote := m.currentNode
+	for rootNote.parent != nil {
+		rootNote = rootNote.parent
+	}
+	if m.searchIndex.sync(rootNote) {
+		saveSearchIndex(m.searchIndex)
+	}
+}
+
 func (m *model) updateTrashView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "up", "k":
@@ -801,14 +2017,24 @@ func (m *model) updateTrashView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.mode = m.previousMode
 		m.currentNode = loadNotes(notesPath)
 		m.cursor = 0
+		m.invalidateTagCache()
+		m.syncSearchIndex() // picks up restored notes
 		return m, nil
-	case "r":
+	case keyFor("restore"):
 		if len(m.currentNode.children) > 0 {
 			selectedNote := m.currentNode.children[m.cursor]
-			newPath := filepath.Join(notesPath, selectedNote.title)
+			newPath := m.restoreDest(selectedNote)
+			if _, err := os.Lstat(newPath); err == nil {
+				m.notice = fmt.Sprintf("Could not restore %s: %s already exists", selectedNote.title, filepath.Base(newPath))
+				m.noticeErr = true
+				return m, nil
+			}
 			if err := os.Rename(selectedNote.path, newPath); err != nil {
-				log.Printf("Could not restore note: %v", err)
+				m.notice = fmt.Sprintf("Could not restore %s: %v", selectedNote.title, err)
+				m.noticeErr = true
+				return m, nil
 			}
+			m.forgetTrashed(selectedNote.path)
 			m.trashNode = loadNotes(filepath.Join(notesPath, ".trash"))
 			m.currentNode = m.trashNode
 			if m.cursor > 0 {
@@ -816,12 +2042,18 @@ func (m *model) updateTrashView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 		}
 		return m, nil
-	case "d":
+	case keyFor("delete"):
 		if len(m.currentNode.children) > 0 {
 			selectedNote := m.currentNode.children[m.cursor]
+			if !m.confirmDelete("Delete "+selectedNote.title+" permanently?", msg) {
+				return m, nil
+			}
 			if err := os.RemoveAll(selectedNote.path); err != nil {
-				log.Printf("Could not delete note: %v", err)
+				m.notice = fmt.Sprintf("Could not delete %s: %v", selectedNote.title, err)
+				m.noticeErr = true
+				return m, nil
 			}
+			m.forgetTrashed(selectedNote.path)
 			m.currentNode.children = append(m.currentNode.children[:m.cursor], m.currentNode.children[m.cursor+1:]...)
 			if m.cursor > 0 {
 				m.cursor--
@@ -832,102 +2064,583 @@ func (m *model) updateTrashView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// showingTaggedNotes reports whether the tag browser lists notes for the
+// active filters rather than the tag list
+func (m *model) showingTaggedNotes() bool {
+	return len(m.tagFilters) > 0 && !m.addingFilter
+}
+
 func (m *model) updateTagBrowserView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "up", "k":
-		if len(m.filteredNotes) > 0 {
-			if m.cursor > 0 {
-				m.cursor--
+	// Handle the filter chips if they have focus
+	if m.chipFocus {
+		switch msg.String() {
+		case "left", "h":
+			if m.chipCursor > 0 {
+				m.chipCursor--
 			} else {
-				m.cursor = len(m.filteredNotes) - 1
+				m.chipCursor = len(m.tagFilters) - 1
 			}
-		} else if len(m.allTags) > 0 {
+		case "right", "l":
+			if m.chipCursor < len(m.tagFilters)-1 {
+				m.chipCursor++
+			} else {
+				m.chipCursor = 0
+			}
+		case "x", "!":
+			// Flip the filter between required and excluded
+			filter := m.tagFilters[m.chipCursor]
+			if tag, ok := strings.CutPrefix(filter, "-"); ok {
+				filter = tag
+			} else {
+				filter = "-" + filter
+			}
+			if !slices.Contains(m.tagFilters, filter) {
+				m.tagFilters[m.chipCursor] = filter
+				m.applyTagFilters()
+			}
+		case "d", "backspace", "delete":
+			m.tagFilters = append(m.tagFilters[:m.chipCursor], m.tagFilters[m.chipCursor+1:]...)
+			m.applyTagFilters()
+			if len(m.tagFilters) == 0 {
+				// Last chip removed, back to the tag list
+				m.chipFocus = false
+			} else if m.chipCursor >= len(m.tagFilters) {
+				m.chipCursor = len(m.tagFilters) - 1
+			}
+		case "tab", "down", "j", "esc":
+			m.chipFocus = false
+		}
+		return m, nil
+	}
+
+	tagRows := m.tagTreeRows()
+	listLen := len(tagRows)
+	if m.showingTaggedNotes() {
+		listLen = len(m.filteredNotes)
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if listLen > 0 {
 			if m.cursor > 0 {
 				m.cursor--
 			} else {
-				m.cursor = len(m.allTags) - 1
+				m.cursor = listLen - 1
 			}
 		}
 	case "down", "j":
-		if len(m.filteredNotes) > 0 {
-			if m.cursor < len(m.filteredNotes)-1 {
-				m.cursor++
-			} else {
-				m.cursor = 0
-			}
-		} else if len(m.allTags) > 0 {
-			if m.cursor < len(m.allTags)-1 {
+		if listLen > 0 {
+			if m.cursor < listLen-1 {
 				m.cursor++
 			} else {
 				m.cursor = 0
 			}
 		}
+	case "tab":
+		if m.showingTaggedNotes() {
+			m.chipFocus = true
+			m.chipCursor = len(m.tagFilters) - 1
+		}
+		return m, nil
+	case "a", "+":
+		if m.showingTaggedNotes() {
+			m.addingFilter = true
+			m.cursor = 0
+		}
+		return m, nil
+	case "o":
+		if m.showingTaggedNotes() {
+			m.tagMatchAny = !m.tagMatchAny
+			m.applyTagFilters()
+		}
+		return m, nil
+	case "s":
+		if !m.showingTaggedNotes() {
+			m.toggleTagSort()
+		}
+		return m, nil
+	case "c":
+		m.toggleTagScope()
+		return m, nil
+	case "D":
+		m.openTagEdit("delete")
+		return m, nil
+	case "M":
+		m.openTagEdit("merge")
+		return m, nil
+	case "left", "h", "right", "l":
+		if !m.showingTaggedNotes() && len(tagRows) > 0 {
+			m.foldTag(tagRows[m.cursor], msg.String() == "right" || msg.String() == "l")
+		}
+		return m, nil
 	case "esc":
-		if len(m.filteredNotes) > 0 {
+		if m.addingFilter {
+			// Back to the notes for the current filters
+			m.addingFilter = false
+			m.cursor = 0
+		} else if len(m.tagFilters) > 0 {
 			// Go back to tag list
+			m.tagFilters = nil
 			m.filteredNotes = nil
-			m.selectedTag = ""
 			m.cursor = 0
 		} else {
 			// Go back to previous mode
 			m.mode = m.previousMode
 			m.cursor = 0
 		}
-		return m, nil
-	case "enter":
-		if len(m.filteredNotes) > 0 {
-			// Open the selected note
-			selectedNote := m.filteredNotes[m.cursor]
-			m.mode = editingView
-			m.currentNotePath = selectedNote.path
-			m.editor.SetValue(selectedNote.content)
-
-			// Restore cursor position if we have one saved
-			if savedPos, exists := m.cursorPositions[selectedNote.path]; exists {
-				maxPos := len(selectedNote.content)
-				if savedPos > maxPos {
-					savedPos = maxPos
-				}
-				m.editor.SetCursor(savedPos)
-			}
+		return m, nil
+	case "enter":
+		if m.showingTaggedNotes() {
+			// Open the selected note
+			if len(m.filteredNotes) > 0 {
+				m.openNote(m.filteredNotes[m.cursor])
+			}
+		} else if len(tagRows) > 0 {
+			// Filter notes by selected tag and the tags nested under it
+			m.selectTag(tagRows[m.cursor])
+		}
+		return m, nil
+	case "x", "!":
+		if !m.showingTaggedNotes() && len(tagRows) > 0 {
+			// Filter out the notes with the selected tag
+			m.selectTag("-" + tagRows[m.cursor])
+		}
+		return m, nil
+	}
+	return m, nil
+}
+
+// openTagBrowser switches to the tag list, collecting tags from the whole tree
+func (m *model) openTagBrowser() {
+	m.previousMode = m.mode
+	m.mode = tagBrowserView
+	m.loadTagBrowserTags()
+	m.tagFilters = nil
+	m.filteredNotes = nil
+	m.addingFilter = false
+	m.chipFocus = false
+	m.cursor = 0
+}
+
+// openTagBrowserOn opens the tag browser filtered to tag, as "notes tag"
+// starts. It's left unfiltered when no note has the tag.
+func (m *model) openTagBrowserOn(tag string) {
+	tag = strings.TrimPrefix(tag, "#")
+	m.openTagBrowser()
+	for _, t := range m.allTags {
+		if tagMatches(t, tag) {
+			m.selectTag(tag)
+			break
+		}
+	}
+}
+
+// tagTreeRows returns the tags listed in the tag browser: every tag, with
+// the tags nested under it following it unless it's collapsed. Parents no
+// note carries themselves, like #work for #work/clientA, are listed too.
+func (m *model) tagTreeRows() []string {
+	all := make(map[string]bool)
+	for _, tag := range m.allTags {
+		all[tag] = true
+		for i, r := range tag {
+			if r == '/' {
+				all[tag[:i]] = true
+			}
+		}
+	}
+	tags := make([]string, 0, len(all))
+	for tag := range all {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return m.tagLess(tags[i], tags[j]) })
+
+	rows := tags[:0]
+	collapsed := ""
+	for _, tag := range tags {
+		if collapsed != "" && tagMatches(tag, collapsed) {
+			continue
+		}
+		collapsed = ""
+		rows = append(rows, tag)
+		if m.collapsedTags[tag] {
+			collapsed = tag
+		}
+	}
+	return rows
+}
+
+// tagLess orders the tag browser rows, each tag followed by the tags nested
+// under it. Tags side by side are sorted by name or, with tag_sort "count",
+// by how many notes use them.
+func (m *model) tagLess(a, b string) bool {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		if config.TagSort == "count" {
+			ca := m.tagCounts[strings.Join(as[:i+1], "/")]
+			cb := m.tagCounts[strings.Join(bs[:i+1], "/")]
+			if ca != cb {
+				return ca > cb
+			}
+		}
+		return as[i] < bs[i]
+	}
+	return len(as) < len(bs)
+}
+
+// tagBrowserRoot returns the folder the tag browser collects tags from: the
+// current one when scoped to it, else the notes root
+func (m *model) tagBrowserRoot() *note {
+	if m.tagFolderScope {
+		return m.currentNode
+	}
+	rootNote := m.currentNode
+	for rootNote.parent != nil {
+		rootNote = rootNote.parent
+	}
+	return rootNote
+}
+
+// loadTagBrowserTags collects the tags listed in the tag browser, with how
+// many notes use each
+func (m *model) loadTagBrowserTags() {
+	m.allTags = getAllTags(m.tagBrowserRoot())
+	m.tagCounts = countTags(m.tagBrowserRoot())
+}
+
+// toggleTagScope switches the tag browser between the whole vault and the
+// notes under the current folder, keeping the active filters
+func (m *model) toggleTagScope() {
+	m.tagFolderScope = !m.tagFolderScope
+	m.loadTagBrowserTags()
+	if m.showingTaggedNotes() {
+		m.applyTagFilters()
+	}
+	m.cursor = 0
+	if m.tagFolderScope {
+		m.notice = "Tags in " + breadcrumbLabel(m.currentNode)
+	} else {
+		m.notice = "Tags in all notes"
+	}
+}
+
+// countTags returns how many notes under dir carry each tag. A note counts
+// towards the tags its tags are nested under as well, once for each.
+func countTags(dir *note) map[string]int {
+	counts := make(map[string]int)
+	var walk func(n *note)
+	walk = func(n *note) {
+		if !n.isDir {
+			seen := make(map[string]bool)
+			for _, tag := range n.tags {
+				for i, r := range tag + "/" {
+					if r == '/' && !seen[tag[:i]] {
+						seen[tag[:i]] = true
+						counts[tag[:i]]++
+					}
+				}
+			}
+		}
+		for _, child := range n.children {
+			walk(child)
+		}
+	}
+	walk(dir)
+	return counts
+}
+
+// toggleTagSort switches the tag browser between sorting tags by name and
+// by count, and saves the choice
+func (m *model) toggleTagSort() {
+	if config.TagSort == "count" {
+		config.TagSort = "name"
+		m.notice = "Tags sorted by name"
+	} else {
+		config.TagSort = "count"
+		m.notice = "Tags sorted by count"
+	}
+	saveConfig(config)
+	m.cursor = 0
+}
+
+// hasNestedTags reports whether any tag is nested under tag
+func (m *model) hasNestedTags(tag string) bool {
+	return slices.ContainsFunc(m.allTags, func(t string) bool { return strings.HasPrefix(t, tag+"/") })
+}
+
+// foldTag expands or collapses the tags nested under tag in the tag
+// browser. Collapsing a tag with nothing under it moves to its parent.
+func (m *model) foldTag(tag string, expand bool) {
+	if m.collapsedTags == nil {
+		m.collapsedTags = make(map[string]bool)
+	}
+	switch {
+	case expand:
+		delete(m.collapsedTags, tag)
+	case m.hasNestedTags(tag) && !m.collapsedTags[tag]:
+		m.collapsedTags[tag] = true
+	default:
+		if i := strings.LastIndex(tag, "/"); i >= 0 {
+			if row := slices.Index(m.tagTreeRows(), tag[:i]); row >= 0 {
+				m.cursor = row
+			}
+		}
+	}
+}
+
+// selectTag adds tag to the tag browser filters and shows the matching notes
+func (m *model) selectTag(tag string) {
+	// A tag is either required or excluded, whichever was picked last
+	opposite := "-" + tag
+	if excluded, ok := strings.CutPrefix(tag, "-"); ok {
+		opposite = excluded
+	}
+	m.tagFilters = slices.DeleteFunc(m.tagFilters, func(f string) bool { return f == opposite })
+	if !slices.Contains(m.tagFilters, tag) {
+		m.tagFilters = append(m.tagFilters, tag)
+	}
+	m.addingFilter = false
+	m.applyTagFilters()
+}
+
+// applyTagFilters re-runs the tag browser filter after the chips change
+func (m *model) applyTagFilters() {
+	m.filteredNotes = make([]*note, 0)
+	tags, excluded := splitTagFilters(m.tagFilters)
+	findNotesByTags(m.tagBrowserRoot(), tags, excluded, !m.tagMatchAny, &m.filteredNotes)
+	m.cursor = 0
+}
+
+// findNoteByPath returns the note or folder at path within the tree, or nil
+func findNoteByPath(n *note, path string) *note {
+	if n.path == path {
+		return n
+	}
+	for _, child := range n.children {
+		if child.path == path || (child.isDir && strings.HasPrefix(path, child.path+string(filepath.Separator))) {
+			return findNoteByPath(child, path)
+		}
+	}
+	return nil
+}
+
+// ensureNote returns the note at path, adding it and any missing parent
+// folders to the tree if they were created after the tree was loaded. It
+// returns nil for paths outside the tree.
+func ensureNote(root *note, path string, isDir bool) *note {
+	if path == root.path {
+		return root
+	}
+	if !strings.HasPrefix(path, root.path+string(filepath.Separator)) {
+		return nil
+	}
+	if existing := findNoteByPath(root, path); existing != nil {
+		return existing
+	}
+	parent := ensureNote(root, filepath.Dir(path), true)
+	if parent == nil {
+		return nil
+	}
+	title := filepath.Base(path)
+	if !isDir {
+		title = strings.TrimSuffix(title, filepath.Ext(title))
+	}
+	title = strings.ReplaceAll(title, "-", " ")
+	info, _ := os.Stat(path)
+	n := newNote(parent, path, title, "", isDir, false, info, nil)
+	parent.children = append(parent.children, n)
+	return n
+}
+
+// openScratch opens the scratch note, creating it if needed. A note being
+// edited is saved and closed first.
+func (m *model) openScratch() {
+	path := config.ScratchNote
+	if path == "" {
+		path = "scratch.txt"
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(notesPath, path)
+	}
+	m.openPath(path, "")
+}
+
+// openPath opens the note at path, creating it with content if it doesn't
+// exist. A note being edited is saved and closed first.
+func (m *model) openPath(path, content string) {
+	if m.mode == editingView && m.currentNotePath == path {
+		return
+	}
+
+	if !m.leaveEditor() {
+		return
+	}
+
+	created := false
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			log.Printf("Could not create note %s: %v", path, err)
+			return
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			log.Printf("Could not create note %s: %v", path, err)
+			return
+		}
+		created = true
+	}
+
+	rootNote := m.currentNode
+	for rootNote.parent != nil {
+		rootNote = rootNote.parent
+	}
+	if m.mode == trashView {
+		// The trash has its own tree, go back to the notes
+		rootNote = loadNotes(notesPath)
+	}
+	n := ensureNote(rootNote, path, false)
+	if n == nil {
+		log.Printf("Note %s is outside the notes folder", path)
+		return
+	}
+	if created && content != "" {
+		meta, body := parseNoteFile(path, content)
+		n.content = body
+		n.setMeta(meta)
+		m.invalidateTagCache()
+	}
+	m.chipFocus = false
+	m.openNote(n)
+}
+
+// leaveEditor closes any popup and the note being edited, saving it first.
+// It returns false if the note couldn't be saved, e.g. when a new note's
+// name is taken.
+func (m *model) leaveEditor() bool {
+	m.showRenamePopup = false
+	m.showFolderPopup = false
+	if m.mode == editingView {
+		m.showTagPicker = false
+		m.showEmptyNotePopup = false
+		m.editor.CloseFind()
+		m.updateEditingView(tea.KeyMsg{Type: tea.KeyEsc})
+		if m.mode == editingView {
+			return false
+		}
+	}
+	return true
+}
+
+// nextMatchingNote returns the index of the first note after from, moving
+// in direction dir (1 or -1) and wrapping around, that match accepts. It
+// returns -1 if no other note matches.
+func nextMatchingNote(children []*note, from, dir int, match func(*note) bool) int {
+	n := len(children)
+	for step := 1; step < n; step++ {
+		i := ((from+dir*step)%n + n) % n
+		if match(children[i]) {
+			return i
+		}
+	}
+	return -1
+}
 
-			m.editor.Focus()
-			// Store the note for editing
-			m.currentNode = selectedNote.parent
-			for i, n := range m.currentNode.children {
-				if n == selectedNote {
-					m.cursor = i
-					break
-				}
-			}
-			return m, nil
-		} else if len(m.allTags) > 0 {
-			// Filter notes by selected tag
-			m.selectedTag = m.allTags[m.cursor]
-			m.filteredNotes = make([]*note, 0)
-			rootNote := m.currentNode
-			for rootNote.parent != nil {
-				rootNote = rootNote.parent
-			}
-			findNotesByTag(rootNote, m.selectedTag, &m.filteredNotes)
-			m.cursor = 0
+// jumpToNote moves the navigation cursor to the next note in direction dir
+// that match accepts, leaving it in place when there is none
+func (m *model) jumpToNote(dir int, match func(*note) bool) {
+	if i := nextMatchingNote(m.currentNode.children, m.cursor, dir, match); i >= 0 {
+		m.cursor = i
+		m.inPinned = false
+	}
+}
+
+// hasJumpTag reports whether n carries the tag used by { and }
+func (m *model) hasJumpTag(n *note) bool {
+	return !n.isDir && n.hasTag(m.jumpTag)
+}
+
+// rememberRecent moves path to the front of the recently opened notes
+func (m *model) rememberRecent(path string) {
+	m.recentNotes = slices.DeleteFunc(m.recentNotes, func(p string) bool { return p == path })
+	m.recentNotes = append([]string{path}, m.recentNotes...)
+	if limit := config.RecentNotesLimit; limit > 0 && len(m.recentNotes) > limit {
+		m.recentNotes = m.recentNotes[:limit]
+	}
+	saveRecentNotes(m.recentNotes)
+}
+
+// startNewNote opens the editor on a new note in the current folder, named
+// from its first line when saved
+func (m *model) startNewNote() {
+	m.dropDraft()
+	m.mode = editingView
+	m.currentNotePath = "" // New note doesn't have a path yet
+	m.editor.SetValue("")
+	m.editor.SetPlaceholder("New Note: first line is the title. ESC to save.")
+	m.editor.Focus()
+	m.isNameTaken = false
+	m.cursor = -1
+}
+
+// openNote opens n in the editor, moving navigation to its folder so the
+// save paths find it at m.currentNode.children[m.cursor]
+func (m *model) openNote(n *note) {
+	m.dropDraft()
+	m.mode = editingView
+	m.currentNotePath = n.path
+	m.editor.SetValue(n.content)
+	m.markSeen(n)
+	m.rememberRecent(n.path)
+	m.diskContent = ""
+	if data, err := os.ReadFile(n.path); err == nil {
+		m.diskContent = string(data)
+	}
+
+	// Restore cursor position if we have one saved
+	if savedPos, exists := m.cursorPositions[n.path]; exists {
+		// Clamp to content length to avoid out of bounds
+		maxPos := len(n.content)
+		if savedPos > maxPos {
+			savedPos = maxPos
+		}
+		m.editor.SetCursor(savedPos)
+	}
+
+	m.editor.Focus()
+	if n.parent != m.currentNode {
+		m.currentNode = n.parent
+		m.sortNotes()
+	}
+	for i, child := range m.currentNode.children {
+		if child == n {
+			m.cursor = i
+			break
 		}
-		return m, nil
 	}
-	return m, nil
 }
 
 func (m *model) updateHelpView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
-	case "esc", "q", "?":
+	case "tab":
+		m.helpKeymap = !m.helpKeymap
+	case "esc", "q", "?", keyFor("help"):
 		m.mode = m.previousMode
+		m.helpKeymap = false
 		return m, nil
 	}
 	return m, nil
 }
 
+// Rows of the config view: the notes path, the external editor, the theme,
+// then the colors
+const (
+	configThemeRow = 2
+	configColorRow = 3
+)
+
 func (m *model) updateConfigView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	const numConfigElements = 13 // 1 path + 1 editor + 11 colors
+	numConfigElements := configColorRow + len(m.tempConfig.fields())
 
 	// If editing path, handle differently
 	if m.editingPath {
@@ -936,6 +2649,7 @@ func (m *model) updateConfigView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			if msg.String() == "enter" && m.pathInput != "" {
 				config.NotesPath = m.pathInput
 				saveConfig(config)
+				m.reopenPending = true
 			}
 			m.editingPath = false
 			m.pathInput = ""
@@ -979,6 +2693,71 @@ func (m *model) updateConfigView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	// If naming a theme to save, handle differently
+	if m.editingTheme {
+		switch msg.String() {
+		case "enter":
+			name := strings.TrimSpace(m.themeInput)
+			if !themeNameRegex.MatchString(name) {
+				return m, nil
+			}
+			if err := saveTheme(name, m.tempConfig); err != nil {
+				m.notice = fmt.Sprintf("Could not save the theme: %v", err)
+				m.noticeErr = true
+				return m, nil
+			}
+			m.tempTheme = name
+			m.editingTheme = false
+			m.themeInput = ""
+			m.notice = "Saved theme " + name + " to " + getThemesDir()
+			return m, nil
+		case "esc":
+			m.editingTheme = false
+			m.themeInput = ""
+			return m, nil
+		case "backspace":
+			if len(m.themeInput) > 0 {
+				m.themeInput = m.themeInput[:len(m.themeInput)-1]
+			}
+			return m, nil
+		default:
+			if len(msg.String()) == 1 {
+				m.themeInput += msg.String()
+			}
+			return m, nil
+		}
+	}
+
+	// If typing a color, handle differently
+	if m.editingColor {
+		switch msg.String() {
+		case "enter":
+			if color, ok := parseColor(m.colorInput); ok {
+				*m.tempConfig.fields()[m.configCursor-configColorRow].value = color
+				// Apply temp config for live preview
+				config.Colors = m.tempConfig
+				applyColorConfig()
+				m.editingColor = false
+				m.colorInput = ""
+			}
+			return m, nil
+		case "esc":
+			m.editingColor = false
+			m.colorInput = ""
+			return m, nil
+		case "backspace":
+			if len(m.colorInput) > 0 {
+				m.colorInput = m.colorInput[:len(m.colorInput)-1]
+			}
+			return m, nil
+		default:
+			if len(msg.String()) == 1 {
+				m.colorInput += msg.String()
+			}
+			return m, nil
+		}
+	}
+
 	switch msg.String() {
 	case "up", "k":
 		if m.configCursor > 0 {
@@ -1005,63 +2784,30 @@ func (m *model) updateConfigView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.editorInput = config.ExternalEditor
 			return m, nil
 		}
-	case "left", "h":
-		// Decrease color index (skip if on path or editor)
-		if m.configCursor > 1 {
-			switch m.configCursor {
-			case 2:
-				m.tempConfig.TitleBg = (m.tempConfig.TitleBg - 1 + 256) % 256
-			case 3:
-				m.tempConfig.TitleFg = (m.tempConfig.TitleFg - 1 + 256) % 256
-			case 4:
-				m.tempConfig.StatusBg = (m.tempConfig.StatusBg - 1 + 256) % 256
-			case 5:
-				m.tempConfig.StatusFg = (m.tempConfig.StatusFg - 1 + 256) % 256
-			case 6:
-				m.tempConfig.BorderColor = (m.tempConfig.BorderColor - 1 + 256) % 256
-			case 7:
-				m.tempConfig.SelectedFg = (m.tempConfig.SelectedFg - 1 + 256) % 256
-			case 8:
-				m.tempConfig.FavoriteColor = (m.tempConfig.FavoriteColor - 1 + 256) % 256
-			case 9:
-				m.tempConfig.TagBarBg = (m.tempConfig.TagBarBg - 1 + 256) % 256
-			case 10:
-				m.tempConfig.TagBarFg = (m.tempConfig.TagBarFg - 1 + 256) % 256
-			case 11:
-				m.tempConfig.TagSelectedBg = (m.tempConfig.TagSelectedBg - 1 + 256) % 256
-			case 12:
-				m.tempConfig.TagSelectedFg = (m.tempConfig.TagSelectedFg - 1 + 256) % 256
-			}
-			// Apply temp config for live preview
-			config.Colors = m.tempConfig
-			applyColorConfig()
+		// On the theme, name a theme file to save the colors to
+		if m.configCursor == configThemeRow {
+			m.editingTheme = true
+			m.themeInput = m.tempTheme
+			return m, nil
 		}
-	case "right", "l":
-		// Increase color index (skip if on path or editor)
-		if m.configCursor > 1 {
-			switch m.configCursor {
-			case 2:
-				m.tempConfig.TitleBg = (m.tempConfig.TitleBg + 1) % 256
-			case 3:
-				m.tempConfig.TitleFg = (m.tempConfig.TitleFg + 1) % 256
-			case 4:
-				m.tempConfig.StatusBg = (m.tempConfig.StatusBg + 1) % 256
-			case 5:
-				m.tempConfig.StatusFg = (m.tempConfig.StatusFg + 1) % 256
-			case 6:
-				m.tempConfig.BorderColor = (m.tempConfig.BorderColor + 1) % 256
-			case 7:
-				m.tempConfig.SelectedFg = (m.tempConfig.SelectedFg + 1) % 256
-			case 8:
-				m.tempConfig.FavoriteColor = (m.tempConfig.FavoriteColor + 1) % 256
-			case 9:
-				m.tempConfig.TagBarBg = (m.tempConfig.TagBarBg + 1) % 256
-			case 10:
-				m.tempConfig.TagBarFg = (m.tempConfig.TagBarFg + 1) % 256
-			case 11:
-				m.tempConfig.TagSelectedBg = (m.tempConfig.TagSelectedBg + 1) % 256
-			case 12:
-				m.tempConfig.TagSelectedFg = (m.tempConfig.TagSelectedFg + 1) % 256
+		// On a color, type its index or hex value
+		m.editingColor = true
+		m.colorInput = string(*m.tempConfig.fields()[m.configCursor-configColorRow].value)
+		return m, nil
+	case "left", "h", "right", "l":
+		// Pick a theme or step the color (skip if on path or editor)
+		if m.configCursor >= configThemeRow {
+			delta := 1
+			if msg.String() == "left" || msg.String() == "h" {
+				delta = -1
+			}
+			if m.configCursor == configThemeRow {
+				theme := nextTheme(m.tempTheme, delta)
+				m.tempTheme = theme.name
+				m.tempConfig = theme.colors
+			} else {
+				color := m.tempConfig.fields()[m.configCursor-configColorRow].value
+				*color = color.step(delta)
 			}
 			// Apply temp config for live preview
 			config.Colors = m.tempConfig
@@ -1070,6 +2816,7 @@ func (m *model) updateConfigView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "esc":
 		// Save config and exit
 		config.Colors = m.tempConfig
+		config.Theme = m.tempTheme
 		saveConfig(config)
 		applyColorConfig()
 		m.mode = m.previousMode
@@ -1078,9 +2825,301 @@ func (m *model) updateConfigView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// editedNoteEmptied reports whether the existing note being edited had
+// content that has now all been deleted
+func (m *model) editedNoteEmptied() bool {
+	if m.cursor < 0 || m.cursor >= len(m.currentNode.children) {
+		return false
+	}
+	return m.editor.Blank() &&
+		strings.TrimSpace(m.currentNode.children[m.cursor].content) != ""
+}
+
+// handleEmptiedNote applies config.EmptyNoteAction to an emptied note. It
+// returns false when the note should just be saved as usual.
+func (m *model) handleEmptiedNote(key tea.KeyMsg) bool {
+	switch config.EmptyNoteAction {
+	case "trash":
+		m.trashEditedNote()
+		return true
+	case "prompt":
+		m.showEmptyNotePopup = true
+		m.emptyNoteKey = key
+		return true
+	}
+	return false
+}
+
+// trashEditedNote moves the note being edited to the trash and closes the
+// editor. The trash keeps the last saved content so it can be restored.
+func (m *model) trashEditedNote() {
+	path := m.currentNode.children[m.cursor].path
+	if m.moveToTrash(m.cursor) != nil {
+		return
+	}
+	if _, exists := m.cursorPositions[path]; exists {
+		delete(m.cursorPositions, path)
+		saveCursorPositions(m.cursorPositions)
+	}
+	if _, exists := m.bookmarks[path]; exists {
+		delete(m.bookmarks, path)
+		saveBookmarks(m.bookmarks)
+	}
+	if _, exists := m.lastSeen[path]; exists {
+		delete(m.lastSeen, path)
+		saveLastSeen(m.lastSeen)
+	}
+	if m.cursor > 0 {
+		m.cursor--
+	}
+	m.editor.Blur()
+	m.editor.ClearDirty()
+	m.mode = navigationView
+}
+
+// writeNote saves n to its file, remembering what was written so later
+// changes made outside the app can be told apart from our own
+func (m *model) writeNote(n *note) error {
+	content := n.fileContent()
+	m.diskContent = content
+	err := os.WriteFile(n.path, []byte(content), 0644)
+	m.markSeen(n)
+	if err == nil {
+		snapshotNote(n.path, content)
+		m.gitCommitLater(n.path)
+		m.searchIndex.update(n.path, n.content, n.modified())
+		saveSearchIndex(m.searchIndex)
+	}
+	return err
+}
+
+// markSeen records the current modification time of n's file, clearing its
+// changed-on-disk marker
+func (m *model) markSeen(n *note) {
+	info, err := os.Stat(n.path)
+	if err != nil {
+		return
+	}
+	n.modTime = info
+	m.lastSeen[n.path] = info.ModTime()
+	saveLastSeen(m.lastSeen)
+}
+
+// changedSinceSeen reports whether n's file was modified after it was last
+// opened or saved in the app. Notes never opened here aren't marked.
+func (m *model) changedSinceSeen(n *note) bool {
+	seen, exists := m.lastSeen[n.path]
+	return exists && !n.isDir && n.modified().After(seen)
+}
+
+// checkDiskConflict compares the edited note's file with the content it was
+// opened or last saved with, unless its modification time shows it wasn't
+// written since. A file changed outside the app is reloaded into
+// the buffer if it has no edits. Otherwise the diff panel is shown and true is
+// returned so the caller doesn't save; key is replayed once the conflict is
+// resolved, and may be nil when there's nothing to replay.
+func (m *model) checkDiskConflict(key *tea.KeyMsg) bool {
+	if m.cursor < 0 || m.cursor >= len(m.currentNode.children) {
+		return false
+	}
+	n := m.currentNode.children[m.cursor]
+	info, err := os.Stat(n.path)
+	if err != nil || (n.modTime != nil && info.ModTime().Equal(n.modTime.ModTime()) && info.Size() == n.modTime.Size()) {
+		return false
+	}
+	data, err := os.ReadFile(n.path)
+	if err != nil || string(data) == m.diskContent {
+		return false
+	}
+
+	if !m.editor.Dirty() {
+		m.takeDiskVersion(string(data))
+		return false
+	}
+
+	_, theirs := parseNoteFile(n.path, string(data))
+	m.showDiffPanel = true
+	m.diffTheirs = string(data)
+	m.diffResult = diffLines(strings.Split(theirs, "\n"), strings.Split(m.editor.Value(), "\n"))
+	m.diffKey = key
+	m.diffOffset = 0
+	return true
+}
+
+// takeDiskVersion replaces the edited note and the buffer with data read
+// from the note's file
+func (m *model) takeDiskVersion(data string) {
+	n := m.currentNode.children[m.cursor]
+	meta, body := parseNoteFile(n.path, data)
+	n.content = body
+	n.setMeta(meta)
+
+	cursor := m.editor.GetCursor()
+	m.editor.SetValue(body)
+	m.editor.SetCursor(min(cursor, utf8.RuneCountInString(body)))
+	m.editor.ClearDirty()
+	m.diskContent = data
+	m.markSeen(n)
+	m.invalidateTagCache()
+}
+
+// saveConflictCopy leaves the note's file as it was changed on disk and
+// saves the buffer next to it as a "(conflicted copy)", which becomes the
+// note being edited. It reports whether the copy was saved.
+func (m *model) saveConflictCopy() bool {
+	n := m.currentNode.children[m.cursor]
+	mine, cursor := m.editor.Value(), m.editor.GetCursor()
+	copied := m.newCopy(n, copyPath(n.path, "conflicted copy"), mine)
+	if err := m.writeNote(copied); err != nil {
+		m.notice = fmt.Sprintf("Could not save a copy: %v", err)
+		m.noticeErr = true
+		return false
+	}
+	m.takeDiskVersion(m.diffTheirs)
+
+	m.currentNode.children = append(m.currentNode.children, copied)
+	m.sortNotes()
+	m.cursor = slices.Index(m.currentNode.children, copied)
+	m.currentNotePath = copied.path
+	m.editor.SetValue(copied.content)
+	m.editor.SetCursor(min(cursor, utf8.RuneCountInString(copied.content)))
+	m.editor.ClearDirty()
+	m.rememberRecent(copied.path)
+	m.invalidateTagCache()
+	m.notice = "Saved your version as " + copied.title
+	return true
+}
+
+// reloadAfterExternalEdit picks up changes made to the note at path by the
+// external editor
+func (m *model) reloadAfterExternalEdit(path string) {
+	if m.mode == editingView && m.currentNotePath == path {
+		m.checkDiskConflict(nil)
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, n := range m.currentNode.children {
+		if n.path == path {
+			meta, body := parseNoteFile(path, string(data))
+			n.content = body
+			n.setMeta(meta)
+			m.markSeen(n)
+			m.invalidateTagCache()
+			return
+		}
+	}
+}
+
+// diffPanelHeight is the number of diff lines shown in the diff panel
+func (m model) diffPanelHeight() int {
+	return max(3, m.height-14)
+}
+
+// setBookmark remembers the cursor position in the edited note as mark
+func (m *model) setBookmark(mark string) {
+	if m.currentNotePath == "" {
+		return // a new note has nowhere to keep marks until it's saved
+	}
+	if m.bookmarks[m.currentNotePath] == nil {
+		m.bookmarks[m.currentNotePath] = make(map[string]int)
+	}
+	m.bookmarks[m.currentNotePath][mark] = m.editor.GetCursor()
+	saveBookmarks(m.bookmarks)
+}
+
+// jumpToBookmark moves the cursor to mark in the edited note. A mark past
+// the end of a note that has since shrunk lands at the end.
+func (m *model) jumpToBookmark(mark string) {
+	if pos, exists := m.bookmarks[m.currentNotePath][mark]; exists {
+		m.editor.clearSelection()
+		m.editor.SetCursor(pos)
+	}
+}
+
 func (m *model) updateEditingView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
+	// Handle the diff panel for a note changed on disk
+	if m.showDiffPanel {
+		maxOffset := max(0, len(m.diffResult)-m.diffPanelHeight())
+		switch msg.String() {
+		case "k":
+			// Keep the buffer: the next save overwrites the file
+			m.showDiffPanel = false
+			m.diskContent = m.diffTheirs
+			if m.diffKey != nil {
+				return m.updateEditingView(*m.diffKey)
+			}
+		case "t":
+			m.showDiffPanel = false
+			m.takeDiskVersion(m.diffTheirs)
+			if m.diffKey != nil {
+				return m.updateEditingView(*m.diffKey)
+			}
+		case "c":
+			m.showDiffPanel = false
+			if !m.saveConflictCopy() {
+				return m, nil
+			}
+			if m.diffKey != nil {
+				return m.updateEditingView(*m.diffKey)
+			}
+		case "m":
+			// Merge into the buffer with conflict markers to resolve by hand
+			m.showDiffPanel = false
+			m.editor.SetValue(mergeWithMarkers(m.diffResult, "on disk", "yours"))
+			m.editor.MarkDirty()
+			m.diskContent = m.diffTheirs
+		case "up":
+			m.diffOffset = max(0, m.diffOffset-1)
+		case "down":
+			m.diffOffset = min(maxOffset, m.diffOffset+1)
+		case "pgup":
+			m.diffOffset = max(0, m.diffOffset-m.diffPanelHeight())
+		case "pgdown":
+			m.diffOffset = min(maxOffset, m.diffOffset+m.diffPanelHeight())
+		case "esc":
+			m.showDiffPanel = false
+		}
+		return m, nil
+	}
+
+	// Handle the spelling suggestions if they're showing
+	if m.spellSuggest != nil {
+		return m.updateSpellSuggest(msg)
+	}
+
+	// Handle the history panel if it's showing
+	if m.history != nil {
+		return m.updateHistory(msg)
+	}
+
+	// Handle the attachments panel if it's showing
+	if m.attachments != nil {
+		return m.updateAttachments(msg)
+	}
+
+	// Handle empty note prompt if it's showing
+	if m.showEmptyNotePopup {
+		switch msg.String() {
+		case "y", "Y":
+			m.showEmptyNotePopup = false
+			m.trashEditedNote()
+		case "n", "N":
+			// Keep the empty note: forget the old content so the save goes through
+			m.showEmptyNotePopup = false
+			m.currentNode.children[m.cursor].content = ""
+			return m.updateEditingView(m.emptyNoteKey)
+		case "esc":
+			m.showEmptyNotePopup = false
+		}
+		return m, nil
+	}
+
 	// Handle tag picker if it's showing
 	if m.showTagPicker {
 		switch msg.String() {
@@ -1104,25 +3143,13 @@ func (m *model) updateEditingView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			// Insert selected tag
 			if len(m.tagPickerFiltered) > 0 {
 				selectedTag := m.tagPickerFiltered[m.tagPickerCursor]
-				// Remove the filter text and insert the selected tag
-				currentText := m.editor.Value()
-				// Find the last # and replace the filter text with the selected tag
-				lastHash := strings.LastIndex(currentText, "#")
-				if lastHash >= 0 {
-					// Calculate where the filter text ends
-					filterEndPos := lastHash + 1 + len(m.tagPickerFilter)
-					// Preserve text before # and after filter
-					beforeHash := currentText[:lastHash+1]
-					afterFilter := ""
-					if filterEndPos < len(currentText) {
-						afterFilter = currentText[filterEndPos:]
-					}
-					newText := beforeHash + selectedTag + afterFilter
-					m.editor.SetValue(newText)
-					// Position cursor right after the inserted tag
-					cursorPos := lastHash + 1 + len(selectedTag)
-					m.editor.SetCursor(cursorPos)
-					m.editor.MarkDirty()
+				m.rememberTags(selectedTag)
+				// The filter was typed after the # just before the cursor:
+				// replace it with the selected tag
+				cursor := m.editor.GetCursor()
+				start := cursor - utf8.RuneCountInString(m.tagPickerFilter)
+				if start > 0 && m.editor.textRange(start-1, cursor) == "#"+m.tagPickerFilter {
+					m.editor.ReplaceRange(start, cursor, selectedTag)
 				}
 			}
 			m.showTagPicker = false
@@ -1154,7 +3181,7 @@ func (m *model) updateEditingView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			if len(key) == 1 {
 				char := key[0]
 				if (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') ||
-					(char >= '0' && char <= '9') || char == '-' || char == '_' {
+					(char >= '0' && char <= '9') || char == '-' || char == '_' || char == '/' {
 					// Add to filter
 					m.tagPickerFilter += key
 					m.filterTags()
@@ -1175,77 +3202,123 @@ func (m *model) updateEditingView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	// The find prompt takes the keys it handles
+	if m.editor.Finding() && m.editor.HandleFindKey(msg) {
+		return m, nil
+	}
+
+	// The key after alt+m / alt+j names the bookmark
+	if m.markPending != "" {
+		pending := m.markPending
+		m.markPending = ""
+		if key := msg.String(); len(key) == 1 && key >= "a" && key <= "z" {
+			if pending == "set" {
+				m.setBookmark(key)
+			} else {
+				m.jumpToBookmark(key)
+			}
+		}
+		return m, nil
+	}
+
+	// In vim normal and visual mode typed keys are commands, and esc in
+	// insert mode goes back to normal mode
+	if m.editor.VimMode() != "" && m.editor.HandleVimKey(msg) {
+		return m, nil
+	}
+
 	// Check if # was just typed to trigger tag picker
 	if msg.String() == "#" {
-		// Get all tags from the root note
-		rootNote := m.currentNode
-		for rootNote.parent != nil {
-			rootNote = rootNote.parent
-		}
-		m.allTags = getAllTags(rootNote)
+		m.allTags = m.cachedTags()
 		m.showTagPicker = true
 		m.tagPickerFilter = ""
-		m.tagPickerFiltered = m.allTags
 		m.tagPickerCursor = 0
+		m.filterTags()
 		cmd = m.editor.Update(msg)
 		return m, cmd
 	}
 
 	switch msg.String() {
-	case "ctrl+e":
+	case keyFor("follow_link"):
+		if !m.followWikilink(false) {
+			m.notice = "No [[link]] at the cursor"
+		}
+		return m, nil
+	case "enter":
+		// Enter inside a [[link]] follows it instead of breaking the line
+		if m.followWikilink(true) {
+			return m, nil
+		}
+	case keyFor("set_bookmark"):
+		m.markPending = "set"
+		return m, nil
+	case keyFor("jump_bookmark"):
+		m.markPending = "jump"
+		return m, nil
+	case keyFor("spell_check"):
+		m.toggleSpellCheck()
+		return m, nil
+	case keyFor("spell_suggest"):
+		m.openSpellSuggest()
+		return m, nil
+	case keyFor("attachments"):
+		m.openAttachments()
+		return m, nil
+	case keyFor("history"):
+		m.openHistory()
+		return m, nil
+	case keyFor("line_numbers"):
+		config.LineNumbers = !config.LineNumbers
+		m.editor.SetLineNumbers(config.LineNumbers, config.RelativeLineNumbers)
+		// Remember the choice for the next session
+		saveConfig(config)
+		return m, nil
+	case keyFor("external_editor"):
 		// Save current content first, then open in external editor
 		var noteToUpdate *note
-		content := m.editor.Value()
 
 		if m.cursor == -1 { // New note - save it first
-			if content != "" {
+			if content := m.editor.Value(); content != "" {
 				lines := strings.SplitN(content, "\n", 2)
 				title := strings.TrimSpace(lines[0])
 				noteContent := ""
 				if len(lines) > 1 {
 					noteContent = lines[1]
 				}
-				sanitizedTitle := sanitizeTitle(title)
-				path := filepath.Join(m.currentNode.path, sanitizedTitle+".txt")
-				matches := tagRegex.FindAllStringSubmatch(noteContent, -1)
-				var tags []string
-				for _, match := range matches {
-					tags = append(tags, match[2])
-				}
+				path := newNotePath(m.currentNode.path, title)
+				noteContent = withDefaultTags(path, titledContent(path, title, noteContent))
+				tags := noteTags(path, noteContent)
 				noteToUpdate = newNote(m.currentNode, path, title, noteContent, false, false, nil, tags)
 				m.currentNode.children = append(m.currentNode.children, noteToUpdate)
-				var contentToSave string
-				if noteToUpdate.favorite {
-					contentToSave = "favorite: true\n" + noteToUpdate.content
-				} else {
-					contentToSave = noteToUpdate.content
-				}
-				os.WriteFile(noteToUpdate.path, []byte(contentToSave), 0644)
+				m.writeNote(noteToUpdate)
 				m.editor.ClearDirty()
+				m.invalidateTagCache()
+				m.rememberTags(extractTags(content)...)
 				return m, openInExternalEditor(noteToUpdate.path)
 			}
 		} else { // Existing note
+			if m.checkDiskConflict(&msg) {
+				return m, nil
+			}
+			content := m.editor.Value() // may have been reloaded from disk
 			noteToUpdate = m.currentNode.children[m.cursor]
 			noteToUpdate.content = content
-			var contentToSave string
-			if noteToUpdate.favorite {
-				contentToSave = "favorite: true\n" + noteToUpdate.content
-			} else {
-				contentToSave = noteToUpdate.content
-			}
-			os.WriteFile(noteToUpdate.path, []byte(contentToSave), 0644)
+			noteToUpdate.title = noteTitle(noteToUpdate.path, content)
+			m.writeNote(noteToUpdate)
 			m.editor.ClearDirty()
+			m.invalidateTagCache()
+			m.rememberTags(extractTags(content)...)
 			return m, openInExternalEditor(noteToUpdate.path)
 		}
 		return m, nil
-	case "ctrl+s":
+	case keyFor("save"):
 		if m.cursor == -1 && m.isNameTaken {
 			return m, nil // Don't save if name is taken
 		}
-		content := m.editor.Value()
 		var noteToUpdate *note
 
 		if m.cursor == -1 { // New note
+			content := m.editor.Value()
 			if content == "" {
 				return m, nil
 			}
@@ -1255,25 +3328,21 @@ func (m *model) updateEditingView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			if len(lines) > 1 {
 				noteContent = lines[1]
 			}
-			sanitizedTitle := sanitizeTitle(title)
-			path := filepath.Join(m.currentNode.path, sanitizedTitle+".txt")
-			matches := tagRegex.FindAllStringSubmatch(noteContent, -1)
-			var tags []string
-			for _, match := range matches {
-				tags = append(tags, match[2])
+			path := newNotePath(m.currentNode.path, title)
+			titled := titledContent(path, title, noteContent)
+			shift := utf8.RuneCountInString(titled) - utf8.RuneCountInString(noteContent)
+			noteContent = withDefaultTags(path, titled)
+			if !strings.HasPrefix(noteContent, titled) {
+				// The tags went in the header, above the cursor
+				shift += utf8.RuneCountInString(noteContent) - utf8.RuneCountInString(titled)
 			}
+			tags := noteTags(path, noteContent)
 			noteToUpdate = newNote(m.currentNode, path, title, noteContent, false, false, nil, tags)
 			m.currentNode.children = append(m.currentNode.children, noteToUpdate)
 			// Set cursor to the newly created note
 			m.cursor = len(m.currentNode.children) - 1
 
-			var contentToSave string
-			if noteToUpdate.favorite {
-				contentToSave = "favorite: true\n" + noteToUpdate.content
-			} else {
-				contentToSave = noteToUpdate.content
-			}
-			os.WriteFile(noteToUpdate.path, []byte(contentToSave), 0644)
+			m.writeNote(noteToUpdate)
 
 			// Switch editor to the saved content (without the title line)
 			prevCursor := m.editor.GetCursor()
@@ -1282,7 +3351,7 @@ func (m *model) updateEditingView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				removedLen++
 			}
 			m.editor.SetValue(noteToUpdate.content)
-			newCursor := prevCursor - removedLen
+			newCursor := prevCursor - removedLen + shift
 			if newCursor < 0 {
 				newCursor = 0
 			}
@@ -1291,26 +3360,25 @@ func (m *model) updateEditingView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.cursorPositions[noteToUpdate.path] = m.editor.GetCursor()
 			saveCursorPositions(m.cursorPositions)
 			m.editor.ClearDirty()
+			m.invalidateTagCache()
+			m.rememberTags(extractTags(content)...)
 			return m, nil
 		}
 
 		// Existing note
+		if m.editedNoteEmptied() && m.handleEmptiedNote(msg) {
+			return m, nil
+		}
+		if m.checkDiskConflict(&msg) {
+			return m, nil
+		}
+		content := m.editor.Value() // may have been reloaded from disk
 		noteToUpdate = m.currentNode.children[m.cursor]
 		noteToUpdate.content = content
-		matches := tagRegex.FindAllStringSubmatch(content, -1)
-		var tags []string
-		for _, match := range matches {
-			tags = append(tags, match[2])
-		}
-		noteToUpdate.tags = tags
+		noteToUpdate.title = noteTitle(noteToUpdate.path, content)
+		noteToUpdate.tags = noteTags(noteToUpdate.path, content)
 
-		var contentToSave string
-		if noteToUpdate.favorite {
-			contentToSave = "favorite: true\n" + noteToUpdate.content
-		} else {
-			contentToSave = noteToUpdate.content
-		}
-		err := os.WriteFile(noteToUpdate.path, []byte(contentToSave), 0644)
+		err := m.writeNote(noteToUpdate)
 		if err != nil {
 			log.Printf("Error saving note: %v", err)
 		}
@@ -1319,11 +3387,19 @@ func (m *model) updateEditingView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.cursorPositions[noteToUpdate.path] = m.editor.GetCursor()
 		saveCursorPositions(m.cursorPositions)
 		m.editor.ClearDirty()
+		m.invalidateTagCache()
+		m.rememberTags(extractTags(content)...)
 		return m, nil
 	case "esc":
 		if m.cursor == -1 && m.isNameTaken {
 			return m, nil // Don't save if name is taken
 		}
+		if m.editedNoteEmptied() && m.handleEmptiedNote(msg) {
+			return m, nil
+		}
+		if m.checkDiskConflict(&msg) {
+			return m, nil
+		}
 		m.editor.Blur()
 		content := m.editor.Value()
 		var noteToUpdate *note
@@ -1336,13 +3412,9 @@ func (m *model) updateEditingView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				if len(lines) > 1 {
 					noteContent = lines[1]
 				}
-				sanitizedTitle := sanitizeTitle(title)
-				path := filepath.Join(m.currentNode.path, sanitizedTitle+".txt")
-				matches := tagRegex.FindAllStringSubmatch(noteContent, -1)
-				var tags []string
-				for _, match := range matches {
-					tags = append(tags, match[2])
-				}
+				path := newNotePath(m.currentNode.path, title)
+				noteContent = withDefaultTags(path, titledContent(path, title, noteContent))
+				tags := noteTags(path, noteContent)
 				noteToUpdate = newNote(m.currentNode, path, title, noteContent, false, false, nil, tags)
 				m.currentNode.children = append(m.currentNode.children, noteToUpdate)
 				// Set cursor to the newly created note
@@ -1354,23 +3426,13 @@ func (m *model) updateEditingView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		} else { // Existing note
 			noteToUpdate = m.currentNode.children[m.cursor]
 			noteToUpdate.content = content
-			matches := tagRegex.FindAllStringSubmatch(content, -1)
-			var tags []string
-			for _, match := range matches {
-				tags = append(tags, match[2])
-			}
-			noteToUpdate.tags = tags
+			noteToUpdate.title = noteTitle(noteToUpdate.path, content)
+			noteToUpdate.tags = noteTags(noteToUpdate.path, content)
 			// Keep cursor on the same note (m.cursor unchanged)
 		}
 
 		if noteToUpdate != nil {
-			var contentToSave string
-			if noteToUpdate.favorite {
-				contentToSave = "favorite: true\n" + noteToUpdate.content
-			} else {
-				contentToSave = noteToUpdate.content
-			}
-			err := os.WriteFile(noteToUpdate.path, []byte(contentToSave), 0644)
+			err := m.writeNote(noteToUpdate)
 			if err != nil {
 				log.Printf("Error saving note: %v", err)
 			}
@@ -1380,6 +3442,8 @@ func (m *model) updateEditingView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			saveCursorPositions(m.cursorPositions)
 		}
 		m.editor.ClearDirty()
+		m.invalidateTagCache()
+		m.rememberTags(extractTags(content)...)
 		m.mode = navigationView
 		return m, nil
 	}
@@ -1415,119 +3479,269 @@ func (m *model) updateCreatingFolderView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.editor.Blur()
 		return m, nil
 	}
-	cmd = m.editor.Update(msg)
-	return m, cmd
+	cmd = m.editor.Update(msg)
+	return m, cmd
+}
+
+func (m model) titleView() string {
+	var title string
+	switch m.mode {
+	case trashView:
+		title = appTitle() + " - Trash"
+	case archiveView:
+		title = appTitle() + " - Archive"
+	case favoritesView:
+		title = appTitle() + " - ★ Favorites"
+	case flatView:
+		title = appTitle() + " - All notes in " + breadcrumbLabel(m.flatRoot)
+	case configView:
+		title = appTitle() + " - Configuration"
+	case tagBrowserView:
+		if len(m.tagFilters) > 0 {
+			var labels []string
+			for _, filter := range m.tagFilters {
+				labels = append(labels, tagFilterLabel(filter))
+			}
+			title = appTitle() + " - Tag: " + strings.Join(labels, " ")
+		} else {
+			title = appTitle() + " - Tags"
+		}
+		if m.tagFolderScope {
+			title += " in " + breadcrumbLabel(m.currentNode)
+		}
+	case navigationView:
+		if m.currentNode.parent == nil {
+			title = appTitle()
+		} else {
+			title = appTitle() + " - " + m.currentNode.title
+		}
+	case previewView:
+		title = appTitle() + " - " + m.previewNote.title + " [READ-ONLY]"
+	default:
+		title = appTitle()
+	}
+
+	if m.mode == editingView && m.editor.Dirty() {
+		title += " [UNSAVED]"
+	}
+	if m.mode == editingView && m.editorStats().overLimit() {
+		title += " [OVER LIMIT]"
+	}
+	if m.gitChanges {
+		title += " [UNCOMMITTED]"
+	}
+
+	w := m.width
+	if w <= 0 {
+		w = 80
+	}
+	return titleStyle.Width(w).Render(truncate(title, w-2, "…"))
+}
+
+// tagPickerPrefix is the label at the start of the tag picker bar
+func (m model) tagPickerPrefix() string {
+	prefix := "Tags"
+	if m.tagPickerFilter != "" {
+		prefix += ": #" + m.tagPickerFilter
+	}
+	return prefix + " │ "
+}
+
+// tagPickerMaxRows is how many rows the picker may use. It only grows past
+// a single line while a filter is being typed.
+func (m model) tagPickerMaxRows() int {
+	if m.tagPickerFilter == "" || config.TagPickerRows < 1 {
+		return 1
+	}
+	return config.TagPickerRows
 }
 
-func (m model) titleView() string {
-	var title string
-	switch m.mode {
-	case trashView:
-		title = "Notes v" + getVersion() + " - Trash"
-	case configView:
-		title = "Notes v" + getVersion() + " - Configuration"
-	case tagBrowserView:
-		if len(m.filteredNotes) > 0 {
-			title = "Notes v" + getVersion() + " - Tag: #" + m.selectedTag
-		} else {
-			title = "Notes v" + getVersion() + " - Tags"
-		}
-	case navigationView:
-		if m.currentNode.parent == nil {
-			title = "Notes v" + getVersion()
-		} else {
-			title = "Notes v" + getVersion() + " - " + m.currentNode.title
+// tagPickerLayout splits the filtered tags into rows that fit the bar width,
+// returning the indices of the tags on each row
+func (m model) tagPickerLayout() [][]int {
+	w := m.width
+	if w <= 0 {
+		w = 80
+	}
+	// Leave room for the bar padding and a trailing "... N more"
+	availableWidth := w - 2 - lipgloss.Width(m.tagPickerPrefix()) - 14
+
+	var rows [][]int
+	var row []int
+	rowWidth := 0
+	for i, tag := range m.tagPickerFiltered {
+		tagWidth := lipgloss.Width("#"+tag) + 3 // +3 for padding and separator
+		if len(row) > 0 && rowWidth+tagWidth > availableWidth {
+			rows = append(rows, row)
+			row = nil
+			rowWidth = 0
 		}
-	default:
-		title = "Notes v" + getVersion()
+		row = append(row, i)
+		rowWidth += tagWidth
 	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return rows
+}
 
-	if m.mode == editingView && m.editor.Dirty() {
-		title += " [UNSAVED]"
+// tagPickerHeight returns the number of lines the tag picker bar takes up
+func (m model) tagPickerHeight() int {
+	if !m.showTagPicker {
+		return 0
+	}
+	return max(1, min(len(m.tagPickerLayout()), m.tagPickerMaxRows()))
+}
+
+// fitEditorHeight sizes the editor to the space left by the title, tag
+// picker and status bar
+func (m *model) fitEditorHeight() {
+	m.editor.SetHeight(max(1, m.height-1-m.getStatusBarHeight()-m.tagPickerHeight()-stickyHeaderHeight()))
+}
+
+// stickyHeaderHeight is the number of lines above the editor used by the
+// sticky section header
+func stickyHeaderHeight() int {
+	if config.StickyHeader {
+		return 1
 	}
+	return 0
+}
 
+// stickyHeaderView renders the heading of the section at the top of the
+// editor, or a blank line outside any section
+func (m model) stickyHeaderView() string {
 	w := m.width
 	if w <= 0 {
 		w = 80
 	}
-	return titleStyle.Width(w).Render(title)
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color(uiColors.BorderColor))
+	return headerStyle.Render(truncate(m.editor.CurrentHeading(), w, "…"))
 }
 
-
 func (m model) tagPickerView() string {
 	if !m.showTagPicker {
 		return ""
 	}
 
-	var tags strings.Builder
-
 	// Style for tag picker bar
 	tagBarStyle := lipgloss.NewStyle().
-		Background(lipgloss.Color(fmt.Sprintf("%d", config.Colors.TagBarBg))).
-		Foreground(lipgloss.Color(fmt.Sprintf("%d", config.Colors.TagBarFg))).
+		Background(lipgloss.Color(uiColors.TagBarBg)).
+		Foreground(lipgloss.Color(uiColors.TagBarFg)).
 		Padding(0, 1)
 
 	// Style for selected tag (reversed/highlighted)
 	highlightStyle := lipgloss.NewStyle().
-		Background(lipgloss.Color(fmt.Sprintf("%d", config.Colors.TagSelectedBg))).
-		Foreground(lipgloss.Color(fmt.Sprintf("%d", config.Colors.TagSelectedFg))).
+		Background(lipgloss.Color(uiColors.TagSelectedBg)).
+		Foreground(lipgloss.Color(uiColors.TagSelectedFg)).
 		Bold(true).
 		Padding(0, 1)
 
 	// Style for unselected tags (must set background to match bar)
 	tagStyle := lipgloss.NewStyle().
-		Background(lipgloss.Color(fmt.Sprintf("%d", config.Colors.TagBarBg))).
-		Foreground(lipgloss.Color(fmt.Sprintf("%d", config.Colors.TagBarFg))).
+		Background(lipgloss.Color(uiColors.TagBarBg)).
+		Foreground(lipgloss.Color(uiColors.TagBarFg)).
 		Padding(0, 1)
 
-	// Build the tag line
-	prefix := "Tags"
-	if m.tagPickerFilter != "" {
-		prefix += ": #" + m.tagPickerFilter
+	w := m.width
+	if w <= 0 {
+		w = 80
 	}
-	tags.WriteString(prefix + " │ ")
 
+	prefix := m.tagPickerPrefix()
 	if len(m.tagPickerFiltered) == 0 {
-		tags.WriteString(tagStyle.Render("No matches"))
-	} else {
-		// Calculate how many tags we can fit
-		availableWidth := m.width - len(prefix) - 4 // Account for prefix and separators
-		currentWidth := 0
-		displayedCount := 0
-
-		for i, tag := range m.tagPickerFiltered {
-			tagText := "#" + tag
-			tagWidth := len(tagText) + 3 // +3 for padding and separator
-
-			if currentWidth + tagWidth > availableWidth {
-				// Show "... N more" if we can't fit all
-				remaining := len(m.tagPickerFiltered) - displayedCount
-				if remaining > 0 {
-					tags.WriteString(tagStyle.Render(fmt.Sprintf("... %d more", remaining)))
-				}
-				break
-			}
+		return tagBarStyle.Width(w).Render(truncate(prefix+tagStyle.Render("No matches"), w-2, "…"))
+	}
+
+	// Scroll the visible rows so the row holding the cursor is shown
+	rows := m.tagPickerLayout()
+	maxRows := m.tagPickerMaxRows()
+	cursorRow := 0
+	for r, row := range rows {
+		if m.tagPickerCursor >= row[0] && m.tagPickerCursor <= row[len(row)-1] {
+			cursorRow = r
+			break
+		}
+	}
+	first := 0
+	if cursorRow >= maxRows {
+		first = cursorRow - maxRows + 1
+	}
+	last := min(first+maxRows, len(rows))
+
+	indent := strings.Repeat(" ", lipgloss.Width(prefix))
+	lines := make([]string, 0, last-first)
+	for r := first; r < last; r++ {
+		var line strings.Builder
+		if r == first {
+			line.WriteString(prefix)
+		} else {
+			line.WriteString(indent)
+		}
 
+		for j, i := range rows[r] {
+			if j > 0 {
+				line.WriteString(" ")
+			}
+			tagText := "#" + m.tagPickerFiltered[i]
 			if i == m.tagPickerCursor {
-				tags.WriteString(highlightStyle.Render(tagText))
+				line.WriteString(highlightStyle.Render(tagText))
 			} else {
-				tags.WriteString(tagStyle.Render(tagText))
+				line.WriteString(tagStyle.Render(tagText))
 			}
+		}
 
-			if i < len(m.tagPickerFiltered)-1 {
-				tags.WriteString(" ")
+		// Show "... N more" after the last visible row if tags are hidden
+		if r == last-1 {
+			remaining := 0
+			for _, row := range rows[last:] {
+				remaining += len(row)
+			}
+			if m.tagPickerMore {
+				line.WriteString(" " + tagStyle.Render(fmt.Sprintf("... %d+ more", remaining)))
+			} else if remaining > 0 {
+				line.WriteString(" " + tagStyle.Render(fmt.Sprintf("... %d more", remaining)))
 			}
+		}
+		lines = append(lines, truncate(line.String(), w-2, "…"))
+	}
 
-			currentWidth += tagWidth
-			displayedCount++
+	return tagBarStyle.Width(w).Render(strings.Join(lines, "\n"))
+}
+
+// tagChipsView renders the active tag browser filters as chips, with the
+// chip under the cursor highlighted when the chips have focus
+func (m model) tagChipsView() string {
+	chipStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color(uiColors.TagBarBg)).
+		Foreground(lipgloss.Color(uiColors.TagBarFg)).
+		Padding(0, 1)
+	selectedChipStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color(uiColors.TagSelectedBg)).
+		Foreground(lipgloss.Color(uiColors.TagSelectedFg)).
+		Bold(true).
+		Padding(0, 1)
+
+	var chips []string
+	for i, filter := range m.tagFilters {
+		if m.chipFocus && i == m.chipCursor {
+			chips = append(chips, selectedChipStyle.Render(tagFilterLabel(filter)+" ×"))
+		} else {
+			chips = append(chips, chipStyle.Render(tagFilterLabel(filter)+" ×"))
 		}
 	}
 
+	match := "all"
+	if m.tagMatchAny {
+		match = "any"
+	}
 	w := m.width
 	if w <= 0 {
 		w = 80
 	}
-	return tagBarStyle.Width(w).Render(tags.String())
+	return truncate("Filters ("+match+"): "+strings.Join(chips, " "), w-6, "…")
 }
 
 func (m model) getStatusBarHeight() int {
@@ -1546,7 +3760,7 @@ func (m model) getStatusBarHeight() int {
 		} else {
 			return 4 // Narrow: 4 lines
 		}
-	case editingView, creatingFolderView, trashView, tagBrowserView, configView, helpView:
+	case editingView, creatingFolderView, trashView, tagBrowserView, configView, helpView, previewView, archiveView, favoritesView, flatView:
 		return 1 // Most other views use single line
 	default:
 		return 2 // Default fallback
@@ -1570,7 +3784,7 @@ func (m model) statusView() string {
 			status = line1 + "\n" + line2
 		} else if w > 60 {
 			// Medium: 3 lines with smart grouping
-			line1 := "↑/↓: nav | ←/esc: back | →/enter: open"
+			line1 := "↑/↓: nav | ←/esc: back | →/enter: open | v: preview"
 			line2 := "n: new note | F: folder | r: rename | d: del | f: fav | t: sort"
 			line3 := "g: tags | c: config | ctrl+e: editor | ctrl+t: trash | ?: help | q: quit"
 			status = line1 + "\n" + line2 + "\n" + line3
@@ -1582,15 +3796,69 @@ func (m model) statusView() string {
 			line4 := "g: tags  c: config  ?: help  q: quit"
 			status = line1 + "\n" + line2 + "\n" + line3 + "\n" + line4
 		}
+		// The first line lists the bulk actions while entries are marked
+		if marked := len(m.markedNotes()); marked > 0 {
+			lines := strings.Split(status, "\n")
+			lines[0] = fmt.Sprintf("%d marked | d: trash | m: move | f: fav | +: tag | esc: clear", marked)
+			if w <= 60 {
+				lines[0] = fmt.Sprintf("%d marked  d/m/f/+  esc", marked)
+			}
+			status = strings.Join(lines, "\n")
+		}
+		// The number of notes goes at the end of the line with the most room
+		lines := strings.Split(status, "\n")
+		count := plural(countNotes(m.treeRoot()), "note")
+		if sync := m.syncStatus(); sync != "" {
+			count = sync + " · " + count
+		}
+		shortest := 0
+		for i, line := range lines {
+			if lipgloss.Width(line) < lipgloss.Width(lines[shortest]) {
+				shortest = i
+			}
+		}
+		if room := w - lipgloss.Width(lines[shortest]) - len(count); room >= 2 {
+			lines[shortest] += strings.Repeat(" ", room) + count
+		}
+		status = strings.Join(lines, "\n")
 	case editingView:
 		if m.isNameTaken {
 			status = "NAME TAKEN! | esc: cancel"
+		} else if m.markPending == "set" {
+			status = "Set mark: press a-z"
+		} else if m.markPending == "jump" {
+			status = "Jump to mark: press a-z"
+		} else if m.editor.Finding() {
+			status = m.editor.FindPrompt()
 		} else {
 			if w > 80 {
 				status = "esc: save and close | ctrl+s: save | ctrl+e: external editor | #: tag picker"
 			} else {
 				status = "esc: save | ctrl+s: save | ctrl+e: editor | #: tags"
 			}
+			switch mode := m.editor.VimMode(); mode {
+			case "":
+			case "NORMAL":
+				status = "NORMAL | esc: save and close | ctrl+s: save | i: insert | v: visual"
+			default:
+				status = mode + " | esc: normal mode | ctrl+s: save | ctrl+e: editor"
+			}
+
+			// Right-align the cursor position and live counts when there's
+			// room for them, dropping the counts first
+			stats := m.editorStats()
+			line, col := m.editor.CursorLineCol()
+			position := fmt.Sprintf("Ln %d, Col %d", line, col)
+			counts := stats.String()
+			countStyle := statusStyle
+			if stats.overLimit() {
+				countStyle = statusStyle.Foreground(errorColor).Bold(true)
+			}
+			if gap := w - lipgloss.Width(status) - lipgloss.Width(position) - 3 - lipgloss.Width(counts); gap > 0 {
+				status += strings.Repeat(" ", gap) + position + " | " + countStyle.Render(counts)
+			} else if gap := w - lipgloss.Width(status) - lipgloss.Width(position); gap > 0 {
+				status += strings.Repeat(" ", gap) + position
+			}
 		}
 	case creatingFolderView:
 		if m.isNameTaken {
@@ -1604,35 +3872,84 @@ func (m model) statusView() string {
 		} else {
 			status = "↑/↓ k/j | r: restore | d: delete | esc: back"
 		}
+	case archiveView:
+		if w > 70 {
+			status = "↑/↓: nav | r: restore to its folder | esc: back"
+		} else {
+			status = "↑/↓ k/j | r: restore | esc: back"
+		}
+	case flatView:
+		sortName := "date"
+		if m.flatByName {
+			sortName = "path"
+		}
+		if w > 90 {
+			status = "↑/↓: nav | enter: open | v: preview | o: go to folder | t: sort (" + sortName + ") | esc: back"
+		} else {
+			status = "↑/↓ k/j | enter: open | o: folder | t: " + sortName + " | esc"
+		}
+	case favoritesView:
+		if w > 90 {
+			status = "↑/↓: nav | enter: open | v: preview | o: go to folder | f: unfavorite | esc: back"
+		} else {
+			status = "↑/↓ k/j | enter: open | o: folder | f: unfav | esc"
+		}
 	case tagBrowserView:
-		if len(m.filteredNotes) > 0 {
-			if w > 70 {
-				status = "↑/↓: nav | enter: open note | esc: back to tags"
+		if m.chipFocus {
+			if w > 90 {
+				status = "←/→: select filter | d: remove filter | x: include/exclude | tab/esc: back to notes"
+			} else {
+				status = "←/→ | d: remove | x: exclude | esc: back"
+			}
+		} else if m.showingTaggedNotes() {
+			if w > 115 {
+				status = "↑/↓: nav | enter: open note | a: add tag | tab: edit filters | o: any/all | c: this folder | esc: back to tags"
+			} else if w > 70 {
+				status = "↑/↓: nav | enter: open | a: add tag | tab: filters | esc: back"
 			} else {
-				status = "↑/↓ k/j | enter: open | esc: back"
+				status = "↑/↓ k/j | enter: open | a: add | esc: back"
+			}
+		} else if m.addingFilter {
+			if w > 80 {
+				status = "↑/↓: nav | enter: add tag to filters | x: exclude tag | esc: back to notes"
+			} else {
+				status = "↑/↓ k/j | enter: add | esc: back"
 			}
 		} else {
-			if w > 70 {
-				status = "↑/↓: nav | enter: filter by tag | esc: back"
+			if w > 125 {
+				status = "↑/↓: nav | ←/→: fold | enter: filter by tag | x: exclude | s: sort | c: this folder | D: delete | M: merge | esc: back"
 			} else {
 				status = "↑/↓ k/j | enter: filter | esc: back"
 			}
 		}
 	case configView:
-		if w > 80 {
-			status = "↑/↓: select element | ←/→: adjust color index (0-255) | esc: save & exit"
+		if w > 105 {
+			status = "↑/↓: select element | ←/→: adjust color | enter: type 0-255 or #rrggbb | esc: save & exit"
 		} else if w > 60 {
-			status = "↑/↓: select | ←/→: adjust color (0-255) | esc: save"
+			status = "↑/↓: select | ←/→: adjust color | enter: type | esc: save"
 		} else {
 			status = "↑/↓ ←/→: adjust color | esc: save"
 		}
 	case helpView:
-		status = "esc/q/?: close help"
+		status = "tab: keymap | esc/q/?: close help"
+	case previewView:
+		status = "↑/↓: scroll | space/b: page | enter: edit | esc/q: back"
+		if position := m.previewPosition(); w-lipgloss.Width(status)-len(position) > 0 {
+			status += strings.Repeat(" ", w-lipgloss.Width(status)-len(position)) + position
+		}
 	}
 
-	return statusStyle.Width(w).Render(status)
-}
+	// A notice takes over the status bar, keeping its height
+	if m.notice != "" {
+		noticeStyle := statusStyle
+		if m.noticeErr {
+			noticeStyle = noticeStyle.Foreground(errorColor).Bold(true)
+		}
+		return noticeStyle.Width(w).Height(m.getStatusBarHeight()).Render(truncate(m.notice, w, "…"))
+	}
 
+	return statusStyle.Width(w).Render(truncateLines(status, w, "…"))
+}
 
 func (m model) View() string {
 	if m.quitting {
@@ -1641,14 +3958,25 @@ func (m model) View() string {
 
 	// Calculate dynamic heights based on status bar size
 	statusHeight := m.getStatusBarHeight()
-	contentHeight := m.height - 1 - statusHeight // total - title - status
-	borderedHeight := contentHeight - 2          // account for border padding
+	contentHeight := m.height - 1 - statusHeight - m.tagPickerHeight() // total - title - status - tag picker
+	borderedHeight := contentHeight - 2                                // account for border padding
+
+	// Listings are cut to the terminal width instead of wrapping
+	listWidth := m.width
+	if listWidth <= 0 {
+		listWidth = 80
+	}
 
 	var mainContent string
 	switch m.mode {
 	case editingView, creatingFolderView:
 		editorView := m.editor.View()
+		if config.StickyHeader {
+			editorView = m.stickyHeaderView() + "\n" + editorView
+		}
 		mainContent = contentStyle.Width(m.width).Height(contentHeight).Render(editorView)
+	case previewView:
+		mainContent = contentStyle.Width(m.width).Height(contentHeight).Render(m.previewView())
 	case trashView:
 		var s strings.Builder
 		if len(m.currentNode.children) == 0 {
@@ -1665,6 +3993,17 @@ func (m model) View() string {
 				if note.isDir {
 					name = lipgloss.NewStyle().Bold(true).Render(name) + "/"
 				}
+				var details []string
+				if origin := m.trashOrigin(note); origin != "" {
+					details = append(details, "from "+origin)
+				}
+				if age := m.trashAge(note); age != "" {
+					details = append(details, "trashed "+age)
+				}
+				if len(details) > 0 {
+					name += lipgloss.NewStyle().Faint(true).Render(" · " + strings.Join(details, " · "))
+				}
+				name = truncate(name, listWidth-6, "…")
 				if m.cursor == i {
 					line += selectedStyle.Render(name)
 				} else {
@@ -1675,21 +4014,57 @@ func (m model) View() string {
 		}
 		bordered := borderStyle.Width(m.width - 4).Height(borderedHeight).Render(s.String())
 		mainContent = contentStyle.Width(m.width).Height(contentHeight).Render(bordered)
+	case archiveView:
+		bordered := borderStyle.Width(m.width - 4).Height(borderedHeight).Render(m.archiveListView(listWidth, borderedHeight))
+		mainContent = contentStyle.Width(m.width).Height(contentHeight).Render(bordered)
+	case favoritesView:
+		bordered := borderStyle.Width(m.width - 4).Height(borderedHeight).Render(m.favoritesListView(listWidth, borderedHeight))
+		mainContent = contentStyle.Width(m.width).Height(contentHeight).Render(bordered)
+	case flatView:
+		bordered := borderStyle.Width(m.width - 4).Height(borderedHeight).Render(m.flatListView(listWidth, borderedHeight))
+		mainContent = contentStyle.Width(m.width).Height(contentHeight).Render(bordered)
 	case helpView:
 		var s strings.Builder
+		if m.helpKeymap {
+			s.WriteString("Notes v" + getVersion() + " - Keymap\n\n")
+			s.WriteString(keymapHelp())
+			bordered := borderStyle.Width(m.width - 4).Height(borderedHeight).Render(s.String())
+			mainContent = contentStyle.Width(m.width).Height(contentHeight).Render(bordered)
+			break
+		}
 		s.WriteString("Notes v" + getVersion() + " - Help\n\n")
 		s.WriteString("NAVIGATION VIEW\n")
 		s.WriteString("  ↑/↓, k/j     Navigate up/down (wraps)\n")
 		s.WriteString("  ←, esc       Go back to parent folder\n")
 		s.WriteString("  →, enter     Open note/folder\n")
+		s.WriteString("  v            Preview note read-only\n")
+		s.WriteString("  space        Mark for a bulk action, then\n")
+		s.WriteString("               d/f/m/+: trash, favorite, move, tag\n")
 		s.WriteString("  n            Create new note\n")
 		s.WriteString("  F            Create new folder\n")
 		s.WriteString("  f            Toggle favorite\n")
-		s.WriteString("  t            Toggle sort (name/date)\n")
+		s.WriteString("  p            Pin to the top of the folder\n")
+		s.WriteString("  ]/[          Jump to next/previous favorite\n")
+		s.WriteString("  }/{          Jump to next/previous note with a tag\n")
+		s.WriteString("  #            Choose the tag for }/{\n")
+		s.WriteString("  t            Cycle this folder's sort (name/date/size/\n")
+		s.WriteString("               created/manual)\n")
+		s.WriteString("  J/K          Move entry down/up in the manual order\n")
+		s.WriteString("  L            Switch between folder and tree layout\n")
+		s.WriteString("               (tree: l/h expand/collapse)\n")
 		s.WriteString("  r            Rename note/folder\n")
+		s.WriteString("  y            Duplicate note as \"Title (copy)\"\n")
 		s.WriteString("  d            Move to trash\n")
-		s.WriteString("  g            Open tag browser\n")
+		s.WriteString("  a            Archive note/folder\n")
+		s.WriteString("  A            View archive\n")
+		s.WriteString("  *            View favorites from all folders\n")
+		s.WriteString("  R            List every note in and under this folder\n")
+		s.WriteString("  g            Open tag browser (c: current folder only, s sorts by count, D deletes a tag, M merges it)\n")
 		s.WriteString("  c            Open configuration\n")
+		s.WriteString("  T            Cycle color themes\n")
+		s.WriteString("  B            Back up all notes to backup_folder\n")
+		s.WriteString("  V            Switch to another vault\n")
+		s.WriteString("  ctrl+r       Search and replace in all notes\n")
 		s.WriteString("  ctrl+t       View trash\n")
 		s.WriteString("  ctrl+e       Open in external editor\n")
 		s.WriteString("  ?            Show this help\n")
@@ -1698,11 +4073,28 @@ func (m model) View() string {
 		s.WriteString("EDITING VIEW\n")
 		s.WriteString("  esc          Save and close\n")
 		s.WriteString("  #            Trigger tag picker\n")
-		s.WriteString("  ctrl+e       Open in external editor\n\n")
+		s.WriteString("  ctrl+f       Search the note (n/N: next/previous match)\n")
+		s.WriteString("  ctrl+r       Find and replace\n")
+		s.WriteString("  alt+r        Toggle regex search (also in ctrl+p)\n")
+		s.WriteString("  alt+l        Toggle line numbers\n")
+		s.WriteString("  f7           Toggle spell checking\n")
+		s.WriteString("  f8           Spelling suggestions for the word at the cursor\n")
+		s.WriteString("  alt+a        Attachments: attach, open or link files\n")
+		s.WriteString("  alt+h        History: diff with or restore saved versions\n")
+		s.WriteString("  ctrl+e       Open in external editor\n")
+		s.WriteString("  vim_mode     hjkl, w/b, gg/G, i/a/o, x, dd, yy, p, v (config)\n\n")
 
 		s.WriteString("TAG BROWSER\n")
 		s.WriteString("  ↑/↓, k/j     Navigate tags/notes\n")
 		s.WriteString("  enter        Filter by tag / Open note\n")
+		s.WriteString("  a, +         Add another tag to the filters\n")
+		s.WriteString("  tab          Select filter chips (←/→, d to remove)\n")
+		s.WriteString("  o            Match any/all filter tags\n")
+		s.WriteString("  ←/→, h/l     Collapse/expand nested tags\n")
+		s.WriteString("  x, !         Exclude tag (x on a chip flips it)\n")
+		s.WriteString("  s            Sort tags by name/count\n")
+		s.WriteString("  c            Only tags in the current folder\n")
+		s.WriteString("  D / M        Delete / Merge tag in all notes\n")
 		s.WriteString("  esc          Back to tags / Exit\n\n")
 
 		s.WriteString("TRASH VIEW\n")
@@ -1711,12 +4103,22 @@ func (m model) View() string {
 		s.WriteString("  d            Delete permanently\n")
 		s.WriteString("  esc          Back to notes\n\n")
 
+		s.WriteString("ARCHIVE VIEW\n")
+		s.WriteString("  ↑/↓, k/j     Navigate notes\n")
+		s.WriteString("  r            Restore to the folder it came from\n")
+		s.WriteString("  esc          Back to notes\n\n")
+
 		s.WriteString("CONFIGURATION\n")
 		s.WriteString("  ↑/↓, k/j     Select element\n")
-		s.WriteString("  ←/→, h/l     Adjust color index\n")
+		s.WriteString("  ←/→, h/l     Pick a theme / Adjust color\n")
+		s.WriteString("  enter        Save as a theme / Type 0-255 or #rrggbb\n")
 		s.WriteString("  esc          Save and exit\n\n")
 
 		s.WriteString("GENERAL\n")
+		s.WriteString("  ctrl+space   Open the scratch note\n")
+		s.WriteString("  alt+t        Open today's journal note\n")
+		s.WriteString("  ctrl+p       Go to a note by name\n")
+		s.WriteString("  ctrl+o       Reopen a recently opened note\n")
 		s.WriteString("  ctrl+c       Quit from anywhere\n")
 
 		bordered := borderStyle.Width(m.width - 4).Height(borderedHeight).Render(s.String())
@@ -1767,40 +4169,55 @@ func (m model) View() string {
 		}
 		s.WriteString("\n")
 
+		// Theme
+		themeCursor := "  "
+		if m.configCursor == configThemeRow {
+			themeCursor = "> "
+		}
+		themeValue := m.tempTheme
+		if m.editingTheme {
+			themeValue = m.themeInput + "█" // Show cursor
+		} else if themeValue == "" {
+			themeValue = "(custom)"
+		}
+		themeLine := fmt.Sprintf("%s%-20s %s", themeCursor, "Theme:", themeValue)
+		if m.configCursor == configThemeRow {
+			themeLine = selectedStyle.Render(themeLine)
+		}
+		s.WriteString(themeLine + "\n")
+		if m.editingTheme {
+			s.WriteString("  (Type a name to save these colors as, Enter to save, Esc to cancel)\n")
+		} else if m.configCursor == configThemeRow {
+			s.WriteString("  (←/→ to pick a theme, Enter to save the colors as a theme)\n")
+		}
+		s.WriteString("\n")
+
 		// Color Elements
-		colorElements := []struct {
-			name  string
-			value int
-		}{
-			{"Title Background", m.tempConfig.TitleBg},
-			{"Title Foreground", m.tempConfig.TitleFg},
-			{"Status Background", m.tempConfig.StatusBg},
-			{"Status Foreground", m.tempConfig.StatusFg},
-			{"Border Color", m.tempConfig.BorderColor},
-			{"Selected Item", m.tempConfig.SelectedFg},
-			{"Favorite Marker", m.tempConfig.FavoriteColor},
-			{"Tag Bar Background", m.tempConfig.TagBarBg},
-			{"Tag Bar Foreground", m.tempConfig.TagBarFg},
-			{"Tag Selected Bg", m.tempConfig.TagSelectedBg},
-			{"Tag Selected Fg", m.tempConfig.TagSelectedFg},
-		}
-
-		for i, elem := range colorElements {
+		for i, elem := range m.tempConfig.fields() {
+			row := configColorRow + i
 			cursor := "  "
-			if m.configCursor == i+2 { // +2 because path is at 0, editor is at 1
+			if m.configCursor == row {
 				cursor = "> "
 			}
-			line := fmt.Sprintf("%s%-20s %3d", cursor, elem.name+":", elem.value)
-			if m.configCursor == i+2 {
+			value := string(*elem.value)
+			if m.editingColor && m.configCursor == row {
+				value = m.colorInput + "█" // Show cursor
+			}
+			line := fmt.Sprintf("%s%-20s %-8s", cursor, elem.name+":", value)
+			if m.configCursor == row {
 				line = selectedStyle.Render(line)
 			}
-			s.WriteString(line + "\n")
+			swatch := lipgloss.NewStyle().Foreground(lipgloss.Color(*elem.value)).Render("██")
+			s.WriteString(line + " " + swatch + "\n")
+			if m.editingColor && m.configCursor == row {
+				s.WriteString("  (Type 0-255 or #rrggbb, Enter to set, Esc to cancel)\n")
+			}
 		}
 
 		s.WriteString("\n--- Live Preview ---\n\n")
 
 		// Preview title bar
-		previewTitle := titleStyle.Render(" Notes v" + getVersion() + " - Preview ")
+		previewTitle := titleStyle.Render(" " + appTitle() + " - Preview ")
 		s.WriteString(previewTitle + "\n\n")
 
 		// Preview navigation with border
@@ -1813,19 +4230,19 @@ func (m model) View() string {
 
 		// Preview tag bar
 		tagBarPreviewStyle := lipgloss.NewStyle().
-			Background(lipgloss.Color(fmt.Sprintf("%d", m.tempConfig.TagBarBg))).
-			Foreground(lipgloss.Color(fmt.Sprintf("%d", m.tempConfig.TagBarFg))).
+			Background(lipgloss.Color(m.tempConfig.TagBarBg)).
+			Foreground(lipgloss.Color(m.tempConfig.TagBarFg)).
 			Padding(0, 1)
 
 		tagSelectedPreviewStyle := lipgloss.NewStyle().
-			Background(lipgloss.Color(fmt.Sprintf("%d", m.tempConfig.TagSelectedBg))).
-			Foreground(lipgloss.Color(fmt.Sprintf("%d", m.tempConfig.TagSelectedFg))).
+			Background(lipgloss.Color(m.tempConfig.TagSelectedBg)).
+			Foreground(lipgloss.Color(m.tempConfig.TagSelectedFg)).
 			Bold(true).
 			Padding(0, 1)
 
 		tagUnselectedPreviewStyle := lipgloss.NewStyle().
-			Background(lipgloss.Color(fmt.Sprintf("%d", m.tempConfig.TagBarBg))).
-			Foreground(lipgloss.Color(fmt.Sprintf("%d", m.tempConfig.TagBarFg))).
+			Background(lipgloss.Color(m.tempConfig.TagBarBg)).
+			Foreground(lipgloss.Color(m.tempConfig.TagBarFg)).
 			Padding(0, 1)
 
 		previewTagBar := "Tags: #filter │ " +
@@ -1843,28 +4260,52 @@ func (m model) View() string {
 		mainContent = contentStyle.Width(m.width).Height(contentHeight).Render(bordered)
 	case tagBrowserView:
 		var s strings.Builder
-		if len(m.filteredNotes) > 0 {
-			// Showing filtered notes by tag
-			s.WriteString("Notes with tag: #" + m.selectedTag + "\n\n")
+		if m.showingTaggedNotes() {
+			// Showing filtered notes, with the filters as removable chips
+			s.WriteString(m.tagChipsView() + "\n\n")
+			if len(m.filteredNotes) == 0 {
+				s.WriteString("  No notes match these tags.")
+			}
 			for i, note := range m.filteredNotes {
 				line := ""
-				if m.cursor == i {
-					line = "> " + selectedStyle.Render(note.title)
+				title := truncate(note.title, listWidth-6, "…")
+				if m.cursor == i && !m.chipFocus {
+					line = "> " + selectedStyle.Render(title)
 				} else {
-					line = "  " + note.title
+					line = "  " + title
 				}
 				s.WriteString(line + "\n")
 			}
 		} else if len(m.allTags) == 0 {
 			s.WriteString("\n  No tags found. Add tags to your notes using #tagname.")
 		} else {
-			s.WriteString("All Tags:\n\n")
-			for i, tag := range m.allTags {
+			if m.addingFilter {
+				s.WriteString("Add a tag to the filters:\n\n")
+			} else {
+				s.WriteString("All Tags:\n\n")
+			}
+			for i, tag := range m.tagTreeRows() {
+				depth := strings.Count(tag, "/")
+				marker := "  "
+				if m.hasNestedTags(tag) {
+					marker = "▾ "
+					if m.collapsedTags[tag] {
+						marker = "▸ "
+					}
+				}
+				label := strings.Repeat("  ", depth) + marker + "#" + tag[strings.LastIndex(tag, "/")+1:] +
+					lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf(" (%d)", m.tagCounts[tag]))
+				if slices.Contains(m.tagFilters, tag) {
+					label += " ✓"
+				} else if slices.Contains(m.tagFilters, "-"+tag) {
+					label += " ✗"
+				}
+				label = truncate(label, listWidth-6, "…")
 				line := ""
 				if m.cursor == i {
-					line = "> " + selectedStyle.Render("#"+tag)
+					line = "> " + selectedStyle.Render(label)
 				} else {
-					line = "  #" + tag
+					line = "  " + label
 				}
 				s.WriteString(line + "\n")
 			}
@@ -1874,38 +4315,51 @@ func (m model) View() string {
 	default: // navigationView
 		var s strings.Builder
 
-		// Add current folder title
-		folderTitle := m.currentNode.title
-		if m.currentNode.parent == nil {
-			folderTitle = "All Notes"
-		}
+		// Add the path to the current folder, each folder clickable
+		folderTitle := m.breadcrumbText(listWidth)
 		// Make title bold and prominent
 		s.WriteString("\n" + lipgloss.NewStyle().Bold(true).Render(folderTitle) + "\n")
-		s.WriteString(strings.Repeat("─", len(folderTitle)) + "\n\n")
+		s.WriteString(strings.Repeat("─", lipgloss.Width(folderTitle)) + "\n\n")
 
-		if len(m.currentNode.children) == 0 {
+		// Pinned favorites stay fixed above the scrolling listing
+		pinned := m.pinnedNotes()
+		if pinnedShown, _ := m.pinnedRows(pinned); pinnedShown > 0 {
+			s.WriteString(favoriteStyle.Render("★ Pinned") + "\n")
+			for i, note := range pinned[:pinnedShown] {
+				name := note.title
+				if config.PinnedSection == "all" && note.parent != nil && note.parent.parent != nil {
+					name += lipgloss.NewStyle().Faint(true).Render(" (" + note.parent.title + ")")
+				}
+				name = truncate(name, listWidth-2, "…")
+				if m.inPinned && m.pinnedCursor == i {
+					s.WriteString("> " + selectedStyle.Render(name) + "\n")
+				} else {
+					s.WriteString("  " + name + "\n")
+				}
+			}
+			s.WriteString("\n")
+		}
+
+		if config.TreeView {
+			s.WriteString(m.treeListView(listWidth, m.navListHeight()))
+		} else if len(m.currentNode.children) == 0 {
 			s.WriteString("  No notes yet. Press 'n' to create one or 'F' for a new folder.")
 		} else {
-			for i, note := range m.currentNode.children {
+			end := min(m.listOffset+m.navListHeight(), len(m.currentNode.children))
+			for i := m.listOffset; i < end; i++ {
+				note := m.currentNode.children[i]
+				selected := m.cursor == i && !m.inPinned
 				line := ""
-				if m.cursor == i {
+				if selected {
 					line = "> "
 				} else {
 					line = "  "
 				}
 
-				name := note.title
-				if note.isDir {
-					name = lipgloss.NewStyle().Bold(true).Render(name) + "/"
-				}
-
-				// Apply favorite marker
-				if note.favorite {
-					name = favoriteStyle.Render("★") + " " + name
-				}
+				name := truncate(m.listEntryName(note), listWidth-2, "…")
 
 				// Apply selection style
-				if m.cursor == i {
+				if selected {
 					line += selectedStyle.Render(name)
 				} else {
 					line += name
@@ -1915,7 +4369,7 @@ func (m model) View() string {
 			}
 		}
 		// No border, just render content like editing view
-		mainContent = contentStyle.Width(m.width).Height(contentHeight).Render(s.String())
+		mainContent = contentStyle.Width(m.width).Height(contentHeight).Render(strings.TrimSuffix(s.String(), "\n"))
 	}
 
 	// Build the view components
@@ -1935,16 +4389,13 @@ func (m model) View() string {
 
 	baseView := lipgloss.JoinVertical(lipgloss.Left, components...)
 
+	// Overlay the config problems found at startup or reload, over the rest
+	if m.configProblems != nil {
+		return overlayPopup(baseView, popupStyle().Render(m.configProblemsView()))
+	}
+
 	// Overlay rename popup if active
 	if m.showRenamePopup {
-		// Create popup box
-		popupStyle := lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color(fmt.Sprintf("%d", config.Colors.BorderColor))).
-			Padding(1, 2).
-			Background(lipgloss.Color(fmt.Sprintf("%d", config.Colors.StatusBg))).
-			Foreground(lipgloss.Color(fmt.Sprintf("%d", config.Colors.StatusFg)))
-
 		var content strings.Builder
 		itemType := "note"
 		if m.renamingNode != nil && m.renamingNode.isDir {
@@ -1956,73 +4407,31 @@ func (m model) View() string {
 		content.WriteString(inputDisplay + "\n\n")
 
 		if m.isNameTaken {
-			errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+			errorStyle := lipgloss.NewStyle().Foreground(errorColor)
 			content.WriteString(errorStyle.Render("⚠ Name already exists!") + "\n\n")
 		}
 
-		helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(fmt.Sprintf("%d", config.Colors.StatusFg)))
+		helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
 		content.WriteString(helpStyle.Render("Enter: confirm | Esc: cancel"))
 
-		popup := popupStyle.Render(content.String())
-
-		// Split base view into lines
-		baseLines := strings.Split(baseView, "\n")
-		popupLines := strings.Split(popup, "\n")
-
-		// Calculate popup position (centered)
-		popupHeight := len(popupLines)
-		popupWidth := lipgloss.Width(popup)
-		startRow := (len(baseLines) - popupHeight) / 2
-		if startRow < 0 {
-			startRow = 0
-		}
-
-		// Overlay popup lines onto base view lines
-		for i, popupLine := range popupLines {
-			row := startRow + i
-			if row >= 0 && row < len(baseLines) {
-				baseLine := baseLines[row]
-				baseWidth := lipgloss.Width(baseLine)
-				startCol := (baseWidth - popupWidth) / 2
-				if startCol < 0 {
-					startCol = 0
-				}
+		return overlayPopup(baseView, popupStyle().Render(content.String()))
+	}
 
-				// Replace the middle portion of the base line with the popup line
-				// This is a simplified overlay - just center the popup
-				if startCol < baseWidth {
-					// Build the overlaid line
-					prefix := ""
-					suffix := ""
-					if startCol > 0 {
-						// Extract prefix (before popup)
-						prefix = lipgloss.NewStyle().Width(startCol).Render(baseLine[:min(startCol, len(baseLine))])
-					}
-					endCol := startCol + popupWidth
-					if endCol < baseWidth {
-						// Extract suffix (after popup)
-						suffix = baseLine[min(endCol, len(baseLine)):]
-					}
-					baseLines[row] = prefix + popupLine + suffix
-				} else {
-					baseLines[row] = popupLine
-				}
-			}
-		}
+	// Overlay jump-to-tag popup if active
+	if m.showJumpTagPopup {
+		var content strings.Builder
+
+		content.WriteString(lipgloss.NewStyle().Bold(true).Render("Jump to tag") + "\n\n")
+		content.WriteString("#" + m.jumpTagInput + "█\n\n")
 
-		return strings.Join(baseLines, "\n")
+		helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
+		content.WriteString(helpStyle.Render("Enter: jump | Esc: cancel"))
+
+		return overlayPopup(baseView, popupStyle().Render(content.String()))
 	}
 
 	// Overlay folder creation popup if active
 	if m.showFolderPopup {
-		// Create popup box
-		popupStyle := lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color(fmt.Sprintf("%d", config.Colors.BorderColor))).
-			Padding(1, 2).
-			Background(lipgloss.Color(fmt.Sprintf("%d", config.Colors.StatusBg))).
-			Foreground(lipgloss.Color(fmt.Sprintf("%d", config.Colors.StatusFg)))
-
 		var content strings.Builder
 
 		content.WriteString(lipgloss.NewStyle().Bold(true).Render("New Folder") + "\n\n")
@@ -2030,76 +4439,284 @@ func (m model) View() string {
 		content.WriteString(inputDisplay + "\n\n")
 
 		if m.isNameTaken {
-			errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+			errorStyle := lipgloss.NewStyle().Foreground(errorColor)
 			content.WriteString(errorStyle.Render("⚠ Name already exists!") + "\n\n")
 		}
 
-		helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(fmt.Sprintf("%d", config.Colors.StatusFg)))
+		helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
 		content.WriteString(helpStyle.Render("Enter: create | Esc: cancel"))
 
-		popup := popupStyle.Render(content.String())
-
-		// Split base view into lines
-		baseLines := strings.Split(baseView, "\n")
-		popupLines := strings.Split(popup, "\n")
-
-		// Calculate popup position (centered)
-		popupHeight := len(popupLines)
-		popupWidth := lipgloss.Width(popup)
-		startRow := (len(baseLines) - popupHeight) / 2
-		if startRow < 0 {
-			startRow = 0
-		}
-
-		// Overlay popup lines onto base view lines
-		for i, popupLine := range popupLines {
-			row := startRow + i
-			if row >= 0 && row < len(baseLines) {
-				baseLine := baseLines[row]
-				baseWidth := lipgloss.Width(baseLine)
-				startCol := (baseWidth - popupWidth) / 2
-				if startCol < 0 {
-					startCol = 0
-				}
+		return overlayPopup(baseView, popupStyle().Render(content.String()))
+	}
 
-				// Replace the middle portion of the base line with the popup line
-				if startCol < baseWidth {
-					// Build the overlaid line
-					prefix := ""
-					suffix := ""
-					if startCol > 0 {
-						// Extract prefix (before popup)
-						prefix = lipgloss.NewStyle().Width(startCol).Render(baseLine[:min(startCol, len(baseLine))])
-					}
-					endCol := startCol + popupWidth
-					if endCol < baseWidth {
-						// Extract suffix (after popup)
-						suffix = baseLine[min(endCol, len(baseLine)):]
-					}
-					baseLines[row] = prefix + popupLine + suffix
-				} else {
-					baseLines[row] = popupLine
-				}
+	// Overlay the diff panel if a note changed on disk
+	if m.showDiffPanel {
+		var content strings.Builder
+
+		content.WriteString(lipgloss.NewStyle().Bold(true).Render("Note changed on disk") + "\n\n")
+
+		removedStyle := lipgloss.NewStyle().Foreground(removedColor)
+		addedStyle := lipgloss.NewStyle().Foreground(addedColor)
+		lineWidth := max(20, m.width-14)
+		end := min(m.diffOffset+m.diffPanelHeight(), len(m.diffResult))
+		for _, line := range m.diffResult[m.diffOffset:end] {
+			switch line.op {
+			case diffDelete:
+				content.WriteString(removedStyle.Render(truncate("- "+line.text, lineWidth, "…")))
+			case diffInsert:
+				content.WriteString(addedStyle.Render(truncate("+ "+line.text, lineWidth, "…")))
+			default:
+				content.WriteString(truncate("  "+line.text, lineWidth, "…"))
 			}
+			content.WriteString("\n")
 		}
+		content.WriteString("\n")
+
+		helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
+		content.WriteString(removedStyle.Render("- on disk") + "  " + addedStyle.Render("+ yours") + "\n")
+		content.WriteString(helpStyle.Render("k: keep mine | t: take theirs | c: save mine as a copy | m: merge | ↑/↓: scroll | Esc: cancel"))
+
+		return overlayPopup(baseView, popupStyle().Render(content.String()))
+	}
+
+	// Overlay the quick switcher if active
+	if m.showSwitcher {
+		return overlayPopup(baseView, popupStyle().Render(m.switcherView()))
+	}
+
+	// Overlay the search and replace across notes if active
+	if m.vaultReplace != nil {
+		return overlayPopup(baseView, popupStyle().Render(m.vaultReplaceView()))
+	}
+
+	// Overlay the move or tag popup for marked entries if active
+	if m.bulkAction != nil {
+		return overlayPopup(baseView, popupStyle().Render(m.bulkActionView()))
+	}
+
+	// Overlay the tag delete or merge popup if active
+	if m.tagEdit != nil {
+		return overlayPopup(baseView, popupStyle().Render(m.tagEditView()))
+	}
+
+	// Overlay the spelling suggestions if active
+	if m.spellSuggest != nil {
+		return overlayPopup(baseView, popupStyle().Render(m.spellSuggestView()))
+	}
+
+	// Overlay the history panel if active
+	if m.history != nil {
+		return overlayPopup(baseView, popupStyle().Render(m.historyView()))
+	}
+
+	// Overlay the attachments panel if active
+	if m.attachments != nil {
+		return overlayPopup(baseView, popupStyle().Render(m.attachmentsView()))
+	}
+
+	// Overlay the vault switcher if active
+	if m.vaultPicker != nil {
+		return overlayPopup(baseView, popupStyle().Render(m.vaultPickerView()))
+	}
+
+	// Overlay the unsaved drafts left at the last exit
+	if m.recovery != nil {
+		return overlayPopup(baseView, popupStyle().Render(m.draftRecoveryView()))
+	}
+
+	// Overlay delete confirmation if active
+	if m.showDeletePopup {
+		var content strings.Builder
+
+		content.WriteString(lipgloss.NewStyle().Bold(true).Render("Confirm") + "\n\n")
+		content.WriteString(m.deletePrompt + "\n\n")
+
+		helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
+		content.WriteString(helpStyle.Render("y: delete | n/Esc: cancel"))
+
+		return overlayPopup(baseView, popupStyle().Render(content.String()))
+	}
+
+	// Overlay empty note prompt if active
+	if m.showEmptyNotePopup {
+		var content strings.Builder
+
+		content.WriteString(lipgloss.NewStyle().Bold(true).Render("Note is empty") + "\n\n")
+		content.WriteString("Move it to the trash?\n\n")
 
-		return strings.Join(baseLines, "\n")
+		helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
+		content.WriteString(helpStyle.Render("y: trash | n: keep empty | Esc: cancel"))
+
+		return overlayPopup(baseView, popupStyle().Render(content.String()))
 	}
 
 	return baseView
 }
 
+// popupStyle is the bordered box used for all popups
+func popupStyle() lipgloss.Style {
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(uiColors.BorderColor)).
+		Padding(1, 2).
+		Background(lipgloss.Color(uiColors.StatusBg)).
+		Foreground(lipgloss.Color(uiColors.StatusFg))
+}
+
+// overlayPopup draws popup centered on top of baseView. Lines are cut by
+// display cells so styled and wide content on either side stays intact.
+func overlayPopup(baseView, popup string) string {
+	// Split base view into lines
+	baseLines := strings.Split(baseView, "\n")
+	popupLines := strings.Split(popup, "\n")
+
+	// Center on the whole view rather than each line, so the popup stays a
+	// rectangle even when base lines have different widths
+	popupWidth := lipgloss.Width(popup)
+	startRow := max((len(baseLines)-len(popupLines))/2, 0)
+	startCol := max((lipgloss.Width(baseView)-popupWidth)/2, 0)
+	endCol := startCol + popupWidth
+
+	// Overlay popup lines onto base view lines
+	for i, popupLine := range popupLines {
+		row := startRow + i
+		if row >= len(baseLines) {
+			break
+		}
+		baseLine := baseLines[row]
+		baseWidth := ansi.StringWidth(baseLine)
+
+		// Extract prefix (before popup), padding short lines and wide
+		// characters that straddle the edge
+		prefix := ansi.Truncate(baseLine, startCol, "")
+		if w := ansi.StringWidth(prefix); w < startCol {
+			prefix += strings.Repeat(" ", startCol-w)
+		}
+
+		// Extract suffix (after popup). TruncateLeft keeps the escape
+		// sequences before the cut, so the base styling resumes after the popup.
+		suffix := ""
+		if endCol < baseWidth {
+			suffix = ansi.TruncateLeft(baseLine, endCol, "")
+			if ansi.StringWidth(suffix) > baseWidth-endCol {
+				suffix = " " + ansi.TruncateLeft(baseLine, endCol+1, "")
+			}
+		}
+
+		// Pad ragged popup lines and reset styles at both edges so the base
+		// line's colors don't bleed into the popup or vice versa
+		if w := ansi.StringWidth(popupLine); w < popupWidth {
+			popupLine += strings.Repeat(" ", popupWidth-w)
+		}
+		baseLines[row] = prefix + ansi.ResetStyle + popupLine + ansi.ResetStyle + suffix
+	}
+
+	return strings.Join(baseLines, "\n")
+}
+
+// externalEditorDoneMsg is sent when the external editor exits
+type externalEditorDoneMsg struct {
+	path string
+	err  error
+}
+
+// noticeMsg shows a one-line message in the status bar until the next key
+type noticeMsg struct {
+	text  string
+	isErr bool
+}
+
+// commandCheck reports a missing program as a notice instead of letting
+// the spawned command fail silently. setting names the config option that
+// chooses the program.
+func commandCheck(name, setting string) tea.Cmd {
+	if _, err := exec.LookPath(name); err != nil {
+		return func() tea.Msg {
+			return noticeMsg{
+				text:  fmt.Sprintf("%q not found. Set %s in %s or in the config view (c).", name, setting, getConfigPath()),
+				isErr: true,
+			}
+		}
+	}
+	return nil
+}
+
+// externalEditorCommand returns the command that opens path in the external
+// editor. The setting is a program followed by its arguments, as in "code -w".
+func externalEditorCommand(path string) *exec.Cmd {
+	fields := strings.Fields(config.ExternalEditor)
+	if len(fields) == 0 {
+		return exec.Command("", path)
+	}
+	return exec.Command(fields[0], append(fields[1:], path)...)
+}
+
 func openInExternalEditor(path string) tea.Cmd {
-	editor := config.ExternalEditor
-	return tea.ExecProcess(exec.Command(editor, path), func(err error) tea.Msg {
-		return nil
+	editor := externalEditorCommand(path)
+	if cmd := commandCheck(editor.Args[0], "external_editor"); cmd != nil {
+		return cmd
+	}
+	return tea.ExecProcess(editor, func(err error) tea.Msg {
+		return externalEditorDoneMsg{path: path, err: err}
 	})
 }
 
+// cliSubcommand is a subcommand that does its work and exits without
+// opening the app
+type cliSubcommand struct {
+	name string
+	run  func(root *note, args []string) int
+}
+
+// cliSubcommands are all of them. tag and today open the app, so main
+// handles those itself.
+var cliSubcommands = []cliSubcommand{
+	{"new", func(_ *note, args []string) int { return runNew(args) }},
+	{"list", runList},
+	{"search", runSearch},
+	{"cat", runCat},
+	{"tags", runTags},
+	{"export", runExport},
+	{"import", func(_ *note, args []string) int { return runImport(args) }},
+	{"migrate", func(root *note, _ []string) int { return runMigrate(root) }},
+	{"backup", func(_ *note, args []string) int { return runBackup(args) }},
+	{"sync", func(_ *note, args []string) int { return runSync(args) }},
+}
+
+// findSubcommand returns the entry of cliSubcommands called name, or nil
+func findSubcommand(name string) *cliSubcommand {
+	for i := range cliSubcommands {
+		if cliSubcommands[i].name == name {
+			return &cliSubcommands[i]
+		}
+	}
+	return nil
+}
+
 func main() {
 	versionFlag := flag.Bool("v", false, "Print version and exit")
 	versionFlagLong := flag.Bool("version", false, "Print version and exit")
+	noMouseFlag := flag.Bool("no-mouse", false, "Disable mouse support for this session")
+	vaultFlag := flag.String("vault", "", "Open the vault of this name from the vaults option")
+	pathFlag := flag.String("path", "", "Open this folder instead of notes_path, leaving the config as it is")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: notes [flags] [folder | note]\n       notes tag <name>\n       notes today\n       notes new [title] [-f <folder>] [-t <tag>]\n       notes list [-folder <path>] [-tag <name>] [-favorites]\n       notes search [-regex] [-l] <query>\n       notes cat <title or path>...\n       notes tags [-sort name|count] [tag]\n       notes export [-folder <path> | -note <name>] [-format md|html|pdf] [-out <file>] [-sep <text>]\n       notes import [-folder <path>] <folder | notes.json | file.enex>\n       notes migrate\n       notes backup [-dir <folder>]\n       notes sync [-q]\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
+	args := flag.Args()
+	// A folder given in place of a subcommand is opened as with -path, and
+	// anything else names a note to open in the editor
+	var noteArg string
+	if len(args) == 1 && args[0] != "tag" && args[0] != "today" && findSubcommand(args[0]) == nil {
+		if info, err := os.Stat(args[0]); err == nil && info.IsDir() {
+			if *pathFlag == "" {
+				*pathFlag, args = args[0], nil
+			}
+		} else {
+			noteArg, args = args[0], nil
+		}
+	}
 
 	if *versionFlag || *versionFlagLong {
 		fmt.Println("notes version", getVersion())
@@ -2107,38 +4724,88 @@ func main() {
 	}
 
 	// Load configuration
-	config = loadConfig()
-	notesPath = config.NotesPath
+	moveLegacyFiles()
+	var configProblems []string
+	config, configProblems = loadConfig()
+	if *vaultFlag != "" && *pathFlag != "" {
+		fmt.Fprintln(os.Stderr, "notes: -vault and -path can't be used together")
+		os.Exit(2)
+	}
+	err := selectVault(*vaultFlag)
+	if *pathFlag != "" {
+		err = selectPath(*pathFlag)
+	}
+	// A note file outside the notes is opened with its folder, as with -path
+	if info, statErr := os.Stat(noteArg); err == nil && statErr == nil && !info.IsDir() && *vaultFlag == "" && *pathFlag == "" {
+		if abs, absErr := filepath.Abs(noteArg); absErr == nil {
+			if rel, relErr := filepath.Rel(notesPath, abs); relErr != nil || strings.HasPrefix(rel, "..") {
+				err = selectPath(filepath.Dir(abs))
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "notes: %v\n", err)
+		os.Exit(2)
+	}
 	applyColorConfig()
+	// -no-mouse only applies to this session, so it isn't stored in config
+	mouseEnabled := !config.DisableMouse && !*noMouseFlag
 
-	if err := os.MkdirAll(notesPath, 0755); err != nil {
-		log.Fatal("Could not create notes directory:", err)
-	}
-	trashPath := filepath.Join(notesPath, ".trash")
-	if err := os.MkdirAll(trashPath, 0755); err != nil {
-		log.Fatal("Could not create trash directory:", err)
+	if err := makeVaultFolders(); err != nil {
+		log.Fatal(err)
 	}
 
 	rootNote := loadNotes(notesPath)
-	trashNote := loadNotes(trashPath)
-
-	// Load cursor positions
-	cursorPositions := loadCursorPositions()
-
+	if len(args) > 0 {
+		if sub := findSubcommand(args[0]); sub != nil {
+			for _, problem := range configProblems {
+				fmt.Fprintf(os.Stderr, "notes: config: %s\n", problem)
+			}
+			os.Exit(sub.run(rootNote, args[1:]))
+		}
+	}
+	var startNote *note
+	if noteArg != "" {
+		if startNote = resolveNoteArg(rootNote, noteArg); startNote == nil {
+			fmt.Fprintf(os.Stderr, "notes: no note or folder %q in %s\n", noteArg, notesPath)
+			os.Exit(1)
+		}
+	}
 	// Initialize custom editor
 	editor := NewEditor()
 	editor.SetPlaceholder("Start typing your note...")
+	editor.SetMouseEnabled(mouseEnabled)
+	applyEditorConfig(&editor)
+
+	initialModel := vaultModel(rootNote, editor)
+	initialModel.configProblems = configProblems
+	if noteArg != "" {
+		initialModel.openNote(startNote)
+	} else if len(args) == 0 {
+		initialModel.openStartFolder()
+	}
 
-	initialModel := model{
-		mode:            navigationView,
-		currentNode:     rootNote,
-		trashNode:       trashNote,
-		editor:          editor,
-		cursorPositions: cursorPositions,
+	if len(args) > 0 {
+		switch args[0] {
+		case "today":
+			initialModel.openDailyNote()
+		case "tag":
+			if len(args) != 2 {
+				flag.Usage()
+				os.Exit(2)
+			}
+			initialModel.openTagBrowserOn(args[1])
+		default:
+			flag.Usage()
+			os.Exit(2)
+		}
 	}
-	initialModel.sortNotes()
 
-	p := tea.NewProgram(&initialModel, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	options := []tea.ProgramOption{tea.WithAltScreen()}
+	if mouseEnabled {
+		options = append(options, tea.WithMouseCellMotion())
+	}
+	p := tea.NewProgram(&initialModel, options...)
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Alas, there's been an error: %v", err)
 		os.Exit(1)
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// breadcrumbSeparator goes between the folders of the breadcrumb
+const breadcrumbSeparator = " / "
+
+// breadcrumb returns the folders from the root down to the current one, as
+// shown above the listing. The tree layout shows only the root.
+func (m *model) breadcrumb() []*note {
+	if config.TreeView {
+		return []*note{m.treeRoot()}
+	}
+	var path []*note
+	for dir := m.currentNode; dir != nil; dir = dir.parent {
+		path = append([]*note{dir}, path...)
+	}
+	return path
+}
+
+// breadcrumbLabel returns the name of a folder in the breadcrumb
+func breadcrumbLabel(dir *note) string {
+	if dir.parent == nil {
+		return "All Notes"
+	}
+	return dir.title
+}
+
+// breadcrumbText returns the breadcrumb as shown, cut to width
+func (m *model) breadcrumbText(width int) string {
+	var labels []string
+	for _, dir := range m.breadcrumb() {
+		labels = append(labels, breadcrumbLabel(dir))
+	}
+	return truncate(strings.Join(labels, breadcrumbSeparator), width, "…")
+}
+
+// breadcrumbAt returns the folder of the breadcrumb at column x, or nil
+func (m *model) breadcrumbAt(x int) *note {
+	start := 0
+	for _, dir := range m.breadcrumb() {
+		end := start + ansi.StringWidth(breadcrumbLabel(dir))
+		if x >= start && x < end {
+			return dir
+		}
+		start = end + len(breadcrumbSeparator)
+	}
+	return nil
+}
+
+// goToFolder makes dir, an ancestor of the current folder, the current one,
+// with the cursor on the folder that leads back down
+func (m *model) goToFolder(dir *note) {
+	if dir == m.currentNode {
+		return
+	}
+	from := m.currentNode
+	for from.parent != dir {
+		from = from.parent
+	}
+	m.clearMarks()
+	m.inPinned = false
+	m.currentNode = dir
+	m.sortNotes()
+	for i, child := range m.currentNode.children {
+		if child == from {
+			m.cursor = i
+			break
+		}
+	}
+}
+
+// navPopupOpen reports whether a popup over the navigation view has the
+// keyboard, and so the mouse is left alone
+func (m *model) navPopupOpen() bool {
+	return m.showRenamePopup || m.showFolderPopup || m.showJumpTagPopup ||
+		m.showSwitcher || m.vaultReplace != nil || m.bulkAction != nil || m.showDeletePopup || m.recovery != nil
+}
+
+// updateNavigationMouse handles the mouse in the navigation view: the wheel
+// moves the selection, a click selects an entry or a folder of the
+// breadcrumb, and a double click opens an entry as Enter does
+func (m *model) updateNavigationMouse(ev tea.MouseEvent) (tea.Model, tea.Cmd) {
+	if m.navPopupOpen() {
+		return m, nil
+	}
+	switch ev.Button {
+	case tea.MouseButtonWheelUp, tea.MouseButtonWheelDown:
+		step := 1
+		if ev.Button == tea.MouseButtonWheelUp {
+			step = -1
+		}
+		if config.TreeView {
+			rows := m.treeRows()
+			if row := m.treeCursorRow(rows) + step; row >= 0 && row < len(rows) {
+				m.selectTreeNote(rows[row].note)
+			}
+		} else if !m.inPinned && len(m.currentNode.children) > 0 {
+			m.cursor = min(max(m.cursor+step, 0), len(m.currentNode.children)-1)
+		}
+		m.ensureListCursorVisible()
+		return m, nil
+	case tea.MouseButtonLeft:
+		if ev.Action != tea.MouseActionPress {
+			return m, nil
+		}
+	default:
+		return m, nil
+	}
+
+	// Lines of the navigation view: title bar, blank line, breadcrumb,
+	// underline, blank line, then the pinned section and the listing
+	const breadcrumbY = 2
+	if ev.Y == breadcrumbY {
+		if dir := m.breadcrumbAt(ev.X); dir != nil {
+			m.goToFolder(dir)
+			m.ensureListCursorVisible()
+		}
+		return m, nil
+	}
+
+	pinned := m.pinnedNotes()
+	pinnedShown, pinnedLines := m.pinnedRows(pinned)
+	top := 1 + navHeaderHeight
+	row := -1
+	switch {
+	case pinnedShown > 0 && ev.Y > top && ev.Y <= top+pinnedShown:
+		m.inPinned = true
+		m.pinnedCursor = ev.Y - top - 1
+		row = -2 - m.pinnedCursor
+	case ev.Y >= top+pinnedLines && ev.Y < top+pinnedLines+m.navListHeight():
+		row = m.listOffset + ev.Y - top - pinnedLines
+		if config.TreeView {
+			rows := m.treeRows()
+			if row >= len(rows) {
+				return m, nil
+			}
+			m.selectTreeNote(rows[row].note)
+		} else {
+			if row >= len(m.currentNode.children) {
+				return m, nil
+			}
+			m.inPinned = false
+			m.cursor = row
+		}
+	default:
+		return m, nil
+	}
+
+	// A second click on the same entry opens it
+	now := time.Now()
+	double := now.Sub(m.lastClick) < doubleClickTime && row == m.lastClickRow
+	m.lastClick, m.lastClickRow = now, row
+	if double {
+		m.lastClick = time.Time{}
+		model, cmd := m.updateNavigationView(tea.KeyMsg{Type: tea.KeyEnter})
+		if m.mode == navigationView {
+			m.ensureListCursorVisible()
+		}
+		return model, cmd
+	}
+	m.ensureListCursorVisible()
+	return m, nil
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tagNameRegex matches a tag typed without its #
+var tagNameRegex = regexp.MustCompile(`^\w+(?:/\w+)*$`)
+
+// bulkAction is the popup asking where to move the marked notes or which
+// tag to add to them
+type bulkAction struct {
+	kind     string  // "move" or "tag"
+	input    string  // folder filter or tag typed so far
+	folders  []*note // folders the notes can move to
+	matches  []*note // folders matching the input
+	cursor   int
+	errorMsg string
+}
+
+// toggleMark marks or unmarks the note under the cursor and moves on to the
+// next one
+func (m *model) toggleMark() {
+	if len(m.currentNode.children) == 0 {
+		return
+	}
+	n := m.currentNode.children[m.cursor]
+	if m.marked == nil {
+		m.marked = make(map[*note]bool)
+	}
+	if m.marked[n] {
+		delete(m.marked, n)
+	} else {
+		m.marked[n] = true
+	}
+	if m.cursor < len(m.currentNode.children)-1 {
+		m.cursor++
+	}
+}
+
+// markedNotes returns the marked entries of the current folder in listing
+// order
+func (m *model) markedNotes() []*note {
+	if len(m.marked) == 0 {
+		return nil
+	}
+	var marked []*note
+	for _, child := range m.currentNode.children {
+		if m.marked[child] {
+			marked = append(marked, child)
+		}
+	}
+	return marked
+}
+
+// clearMarks unmarks everything
+func (m *model) clearMarks() {
+	m.marked = nil
+}
+
+// plural returns "1 note" or "n notes"
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// updateMarkedNotes applies the bulk actions to the marked notes. It reports
+// whether the key was one of them.
+func (m *model) updateMarkedNotes(msg tea.KeyMsg) bool {
+	marked := m.markedNotes()
+	if len(marked) == 0 {
+		return false
+	}
+	switch msg.String() {
+	case keyFor("trash"):
+		folders := 0
+		for _, n := range marked {
+			if n.isDir && len(n.children) > 0 {
+				folders++
+			}
+		}
+		if folders > 0 && !m.confirmDelete(fmt.Sprintf("Move %s to the trash, %s with notes in them?", plural(len(marked), "item"), plural(folders, "folder")), msg) {
+			return true
+		}
+		m.trashMarked(marked)
+	case keyFor("favorite"):
+		m.favoriteMarked(marked)
+	case "m":
+		m.openBulkMove()
+	case "+":
+		m.bulkAction = &bulkAction{kind: "tag"}
+	case "esc":
+		m.clearMarks()
+	default:
+		return false
+	}
+	return true
+}
+
+// trashMarked moves the marked entries into the trash
+func (m *model) trashMarked(marked []*note) {
+	failed := 0
+	for i := len(m.currentNode.children) - 1; i >= 0; i-- {
+		if m.marked[m.currentNode.children[i]] && m.moveToTrash(i) != nil {
+			failed++
+		}
+	}
+	m.cursor = min(m.cursor, max(0, len(m.currentNode.children)-1))
+	m.clearMarks()
+	m.notice = "Moved " + plural(len(marked)-failed, "item") + " to the trash"
+	if failed > 0 {
+		m.notice += fmt.Sprintf(", %d couldn't be moved", failed)
+		m.noticeErr = true
+	}
+}
+
+// favoriteMarked makes the marked notes favorites, or takes them out of the
+// favorites when they all are already
+func (m *model) favoriteMarked(marked []*note) {
+	favorite := false
+	for _, n := range marked {
+		if !n.isDir && !n.favorite {
+			favorite = true
+		}
+	}
+	count := 0
+	for _, n := range marked {
+		if n.isDir || n.favorite == favorite {
+			continue
+		}
+		n.favorite = favorite
+		if err := m.writeNote(n); err != nil {
+			log.Printf("Could not update note: %v", err)
+		}
+		count++
+	}
+	m.clearMarks()
+	if favorite {
+		m.notice = "Added " + plural(count, "note") + " to the favorites"
+	} else {
+		m.notice = "Removed " + plural(count, "note") + " from the favorites"
+	}
+}
+
+// openBulkMove asks which folder to move the marked entries to. A marked
+// folder can't move into itself, so it and its subfolders aren't offered.
+func (m *model) openBulkMove() {
+	rootNote := m.currentNode
+	for rootNote.parent != nil {
+		rootNote = rootNote.parent
+	}
+	ba := &bulkAction{kind: "move"}
+	var collect func(dir *note)
+	collect = func(dir *note) {
+		if m.marked[dir] {
+			return
+		}
+		if dir != m.currentNode {
+			ba.folders = append(ba.folders, dir)
+		}
+		for _, child := range dir.children {
+			if child.isDir {
+				collect(child)
+			}
+		}
+	}
+	collect(rootNote)
+	sort.SliceStable(ba.folders, func(i, j int) bool {
+		return folderLabel(ba.folders[i]) < folderLabel(ba.folders[j])
+	})
+	ba.matches = ba.folders
+	m.bulkAction = ba
+}
+
+// folderLabel returns the path of a folder from the notes root, or "/" for
+// the root itself
+func folderLabel(dir *note) string {
+	rootNote := dir
+	for rootNote.parent != nil {
+		rootNote = rootNote.parent
+	}
+	rel, err := filepath.Rel(rootNote.path, dir.path)
+	if err != nil || rel == "." {
+		return "/"
+	}
+	return "/" + filepath.ToSlash(rel)
+}
+
+// setNotePath moves n to path in the tree, along with everything inside it
+// when it's a folder
+func setNotePath(n *note, path string) {
+	n.path = path
+	for _, child := range n.children {
+		setNotePath(child, filepath.Join(path, filepath.Base(child.path)))
+	}
+}
+
+// moveMarked moves the marked entries into dest, skipping any whose name is
+// taken there
+func (m *model) moveMarked(dest *note) {
+	moved, skipped := 0, 0
+	for _, n := range m.markedNotes() {
+		oldPath := n.path
+		newPath := filepath.Join(dest.path, filepath.Base(oldPath))
+		if _, err := os.Stat(newPath); err == nil {
+			skipped++
+			continue
+		}
+		if err := os.Rename(oldPath, newPath); err != nil {
+			log.Printf("Could not move %s: %v", oldPath, err)
+			skipped++
+			continue
+		}
+		if i := slices.Index(m.currentNode.children, n); i >= 0 {
+			m.currentNode.children = slices.Delete(m.currentNode.children, i, i+1)
+		}
+		n.parent = dest
+		setNotePath(n, newPath)
+		if n.isDir {
+			m.folderSortsMoved(oldPath, newPath)
+			moveHistory(oldPath, newPath)
+		}
+		dest.children = append(dest.children, n)
+		if !n.isDir {
+			before := n.content
+			moveAttachments(n, oldPath, newPath)
+			if n.content != before {
+				m.writeNote(n)
+			}
+			m.notePathChanged(oldPath, newPath)
+		}
+		moved++
+	}
+	sortFolder(m.folderSorts, dest)
+	m.cursor = min(m.cursor, max(0, len(m.currentNode.children)-1))
+	m.clearMarks()
+	m.invalidateTagCache()
+	m.syncSearchIndex()
+
+	m.notice = fmt.Sprintf("Moved %s to %s", plural(moved, "item"), folderLabel(dest))
+	if skipped > 0 {
+		m.notice += fmt.Sprintf(", %d couldn't be moved", skipped)
+		m.noticeErr = true
+	}
+}
+
+// tagMarked adds tag to the marked notes that don't carry it yet
+func (m *model) tagMarked(tag string) {
+	count := 0
+	for _, n := range m.markedNotes() {
+		if n.isDir || slices.Contains(n.tags, tag) {
+			continue
+		}
+		n.content = withTags(n.path, n.content, []string{tag})
+		n.tags = noteTags(n.path, n.content)
+		if err := m.writeNote(n); err != nil {
+			log.Printf("Could not update note: %v", err)
+		}
+		count++
+	}
+	m.clearMarks()
+	m.invalidateTagCache()
+	m.rememberTags(tag)
+	m.notice = fmt.Sprintf("Tagged %s with #%s", plural(count, "note"), tag)
+}
+
+// filterFolders narrows the folders offered for a move to those whose path
+// contains the input
+func (ba *bulkAction) filterFolders() {
+	ba.matches = nil
+	input := strings.ToLower(ba.input)
+	for _, dir := range ba.folders {
+		if strings.Contains(strings.ToLower(folderLabel(dir)), input) {
+			ba.matches = append(ba.matches, dir)
+		}
+	}
+	ba.cursor = 0
+}
+
+func (m *model) updateBulkAction(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	ba := m.bulkAction
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.bulkAction = nil
+	case tea.KeyUp:
+		if ba.cursor > 0 {
+			ba.cursor--
+		}
+	case tea.KeyDown:
+		if ba.cursor < len(ba.matches)-1 {
+			ba.cursor++
+		}
+	case tea.KeyEnter:
+		if ba.kind == "move" {
+			if len(ba.matches) > 0 {
+				m.bulkAction = nil
+				m.moveMarked(ba.matches[ba.cursor])
+			}
+			return m, nil
+		}
+		tag := strings.TrimPrefix(strings.TrimSpace(ba.input), "#")
+		if !tagNameRegex.MatchString(tag) {
+			ba.errorMsg = "Tags are letters, digits and underscores, nested with /"
+			return m, nil
+		}
+		m.bulkAction = nil
+		m.tagMarked(tag)
+	case tea.KeyBackspace:
+		if runes := []rune(ba.input); len(runes) > 0 {
+			ba.input = string(runes[:len(runes)-1])
+			if ba.kind == "move" {
+				ba.filterFolders()
+			}
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		ba.input += string(msg.Runes)
+		if ba.kind == "move" {
+			ba.filterFolders()
+		}
+	}
+	return m, nil
+}
+
+// bulkActionView renders the contents of the move or tag popup
+func (m model) bulkActionView() string {
+	ba := m.bulkAction
+	var content strings.Builder
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
+	count := plural(len(m.markedNotes()), "item")
+
+	if ba.kind == "tag" {
+		content.WriteString(lipgloss.NewStyle().Bold(true).Render("Add a tag to "+count) + "\n\n")
+		content.WriteString("#" + ba.input + "█\n\n")
+		if ba.errorMsg != "" {
+			content.WriteString(lipgloss.NewStyle().Foreground(errorColor).Render(ba.errorMsg) + "\n\n")
+		}
+		content.WriteString(helpStyle.Render("Enter: add | Esc: cancel"))
+		return content.String()
+	}
+
+	content.WriteString(lipgloss.NewStyle().Bold(true).Render("Move "+count+" to") + "\n\n")
+	content.WriteString(ba.input + "█\n\n")
+	if len(ba.matches) == 0 {
+		content.WriteString("No matching folders\n")
+	}
+	const shown = 10
+	offset := max(0, ba.cursor-shown+1)
+	for i := offset; i < min(offset+shown, len(ba.matches)); i++ {
+		label := truncate(folderLabel(ba.matches[i]), max(20, m.width-14), "…")
+		if i == ba.cursor {
+			content.WriteString("> " + selectedStyle.Render(label) + "\n")
+		} else {
+			content.WriteString("  " + label + "\n")
+		}
+	}
+	content.WriteString("\n" + helpStyle.Render("Type to filter | Enter: move | Esc: cancel"))
+	return content.String()
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pinnedVault writes a folder of notes, some of them favorites, with a
+// favorite in a subfolder too
+func pinnedVault(t *testing.T) {
+	newTestVault(t)
+	writeTestNote(t, "Apple.txt", "a")
+	writeTestNote(t, "Banana.txt", "---\nfavorite: true\n---\nb")
+	writeTestNote(t, "Cherry.txt", "c")
+	writeTestNote(t, "Date.txt", "---\nfavorite: true\n---\nd")
+	writeTestNote(t, "Sub/Elder.txt", "---\nfavorite: true\n---\ne")
+}
+
+func TestPinnedNotes(t *testing.T) {
+	pinnedVault(t)
+	for _, tc := range []struct {
+		section string
+		want    []string
+	}{
+		{"", nil},
+		{"folder", []string{"Banana", "Date"}},
+		{"all", []string{"Banana", "Date", "Elder"}},
+	} {
+		config.PinnedSection = tc.section
+		m := newTestModel(t, 80, 24)
+		var got []string
+		for _, n := range m.pinnedNotes() {
+			got = append(got, n.title)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%q: pinned %q, want %q", tc.section, got, tc.want)
+		}
+	}
+
+	// The section is shown above the listing, which still lists every note
+	config.PinnedSection = "all"
+	m := newTestModel(t, 80, 24)
+	s := render(m)
+	label, _ := s.find("★ Pinned")
+	if label < 0 {
+		t.Fatalf("no pinned section:\n%s", strings.Join(s.rows, "\n"))
+	}
+	for i, want := range []string{"Banana", "Date", "Elder (Sub)"} {
+		if row, _ := s.find(want); row != label+1+i {
+			t.Errorf("%q is on row %d, want %d", want, row, label+1+i)
+		}
+	}
+	if row, _ := s.find("Apple"); row <= label+3 {
+		t.Errorf("the listing starts at row %d, inside the pinned section", row)
+	}
+}
+
+func TestPinnedSectionCapped(t *testing.T) {
+	newTestVault(t)
+	for _, name := range []string{"A", "B", "C", "D", "E", "F", "G", "H"} {
+		writeTestNote(t, name+".txt", "---\nfavorite: true\n---\n")
+	}
+	config.PinnedSection = "folder"
+	m := newTestModel(t, 80, 20)
+	shown, lines := m.pinnedRows(m.pinnedNotes())
+	area := 20 - 1 - m.getStatusBarHeight() - navHeaderHeight
+	if shown >= 8 || lines > area/2 {
+		t.Errorf("%d of 8 pinned notes shown on %d lines, more than half of %d", shown, lines, area)
+	}
+	if got := m.navListHeight(); got != area-lines {
+		t.Errorf("listing is %d rows, want %d", got, area-lines)
+	}
+}
+
+func TestPinnedCursorMovement(t *testing.T) {
+	pinnedVault(t)
+	config.PinnedSection = "folder"
+	m := newTestModel(t, 80, 24)
+	last := len(m.currentNode.children) - 1
+
+	// Up from the top of the listing goes into the pinned section, and
+	// through it back round to the bottom of the listing
+	press(m, "up")
+	if !m.inPinned || m.pinnedCursor != 1 {
+		t.Fatalf("up from the first entry: pinned %v at %d, want the last pinned note", m.inPinned, m.pinnedCursor)
+	}
+	press(m, "up")
+	if !m.inPinned || m.pinnedCursor != 0 {
+		t.Errorf("up in the pinned section: pinned %v at %d", m.inPinned, m.pinnedCursor)
+	}
+	press(m, "up")
+	if m.inPinned || m.cursor != last {
+		t.Errorf("up from the first pinned note: pinned %v, cursor %d, want %d", m.inPinned, m.cursor, last)
+	}
+	// And down the other way
+	press(m, "down")
+	if !m.inPinned || m.pinnedCursor != 0 {
+		t.Errorf("down from the last entry: pinned %v at %d", m.inPinned, m.pinnedCursor)
+	}
+	press(m, "down", "down")
+	if m.inPinned || m.cursor != 0 {
+		t.Errorf("down from the last pinned note: pinned %v, cursor %d", m.inPinned, m.cursor)
+	}
+
+	// The selected pinned row is the one marked
+	press(m, "up")
+	s := render(m)
+	if row, _ := s.find("> Date"); row < 0 {
+		t.Errorf("the pinned Date isn't marked selected:\n%s", strings.Join(s.rows, "\n"))
+	}
+	if row, _ := s.find("> Apple"); row >= 0 {
+		t.Error("the listing still marks an entry while a pinned note is selected")
+	}
+
+	// Enter opens the pinned note itself
+	press(m, "enter")
+	if m.mode != editingView || m.currentNotePath != m.currentNode.children[m.cursor].path || m.currentNode.children[m.cursor].title != "Date" {
+		t.Errorf("enter on a pinned note opens %q in mode %v", m.currentNotePath, m.mode)
+	}
+}
+
+func TestPinnedNoteInSubfolder(t *testing.T) {
+	pinnedVault(t)
+	config.PinnedSection = "all"
+	m := newTestModel(t, 80, 24)
+	press(m, "up")
+	if !m.inPinned || m.pinnedCursor != 2 {
+		t.Fatalf("up from the first entry: pinned %v at %d", m.inPinned, m.pinnedCursor)
+	}
+	// Acting on a note from another folder moves to it
+	press(m, "enter")
+	if m.currentNode.title != "Sub" || m.currentNode.children[m.cursor].title != "Elder" || m.mode != editingView {
+		t.Errorf("enter on Elder opens %q in %q", m.currentNotePath, m.currentNode.title)
+	}
+}
+
+// selectedTitle returns the title of the entry under the listing's cursor
+func selectedTitle(m *model) string {
+	return m.currentNode.children[m.cursor].title
+}
+
+func TestJumpToFavorite(t *testing.T) {
+	newTestVault(t)
+	writeTestNote(t, "Apple.txt", "a")
+	writeTestNote(t, "Banana.txt", "---\nfavorite: true\n---\nb")
+	writeTestNote(t, "Cherry.txt", "c")
+	writeTestNote(t, "Date.txt", "---\nfavorite: true\n---\nd")
+	writeTestNote(t, "Elder.txt", "e")
+	writeTestNote(t, "Fig/Fav.txt", "---\nfavorite: true\n---\nf")
+	m := newTestModel(t, 80, 24)
+	m.cursor = slices.Index(listed(m), "Apple")
+
+	// Forward, skipping regular notes and folders, wrapping at the end
+	for _, want := range []string{"Banana", "Date", "Banana"} {
+		press(m, "]")
+		if got := selectedTitle(m); got != want {
+			t.Fatalf("] moves to %q, want %q", got, want)
+		}
+	}
+	// And back
+	for _, want := range []string{"Date", "Banana"} {
+		press(m, "[")
+		if got := selectedTitle(m); got != want {
+			t.Fatalf("[ moves to %q, want %q", got, want)
+		}
+	}
+	// Starting between favorites
+	m.cursor = slices.Index(listed(m), "Cherry")
+	press(m, "[")
+	if got := selectedTitle(m); got != "Banana" {
+		t.Errorf("[ from Cherry moves to %q, want Banana", got)
+	}
+}
+
+func TestJumpToFavoriteNone(t *testing.T) {
+	newTestVault(t)
+	writeTestNote(t, "Apple.txt", "a")
+	writeTestNote(t, "Banana.txt", "---\nfavorite: true\n---\nb")
+	m := newTestModel(t, 80, 24)
+	m.cursor = slices.Index(listed(m), "Banana")
+	press(m, "]")
+	if got := selectedTitle(m); got != "Banana" {
+		t.Errorf("] with no other favorite moves to %q", got)
+	}
+}
+
+func TestJumpToTaggedNote(t *testing.T) {
+	newTestVault(t)
+	writeTestNote(t, "Apple.txt", "#work")
+	writeTestNote(t, "Banana.txt", "#home")
+	writeTestNote(t, "Cherry.txt", "#work/clientA")
+	writeTestNote(t, "Date.txt", "text")
+	m := newTestModel(t, 80, 24)
+	m.cursor = slices.Index(listed(m), "Apple")
+
+	// The first jump asks for the tag
+	press(m, "}")
+	if !m.showJumpTagPopup {
+		t.Fatal("} doesn't ask for a tag")
+	}
+	typeText(m, "#work")
+	press(m, "enter")
+	if got := selectedTitle(m); got != "Cherry" {
+		t.Errorf("} #work moves to %q, want Cherry", got)
+	}
+	// Then jumps with it, both ways
+	press(m, "}")
+	if got := selectedTitle(m); got != "Apple" || m.showJumpTagPopup {
+		t.Errorf("} again moves to %q, popup %v", got, m.showJumpTagPopup)
+	}
+	press(m, "{")
+	if got := selectedTitle(m); got != "Cherry" {
+		t.Errorf("{ moves to %q, want Cherry", got)
+	}
+	// # asks for another tag
+	press(m, "#")
+	if !m.showJumpTagPopup || m.jumpTagInput != "work" {
+		t.Fatalf("# shows popup %v with %q", m.showJumpTagPopup, m.jumpTagInput)
+	}
+	press(m, "backspace", "backspace", "backspace", "backspace")
+	typeText(m, "home")
+	press(m, "enter")
+	if got := selectedTitle(m); got != "Banana" {
+		t.Errorf("# home moves to %q, want Banana", got)
+	}
+}
+
+func TestChangedSinceSeen(t *testing.T) {
+	newTestVault(t)
+	path := writeTestNote(t, "Note.md", "text")
+	seen := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := newTestModel(t, 80, 24)
+	n := m.currentNode.children[0]
+
+	for _, tc := range []struct {
+		stored   bool
+		modified time.Time
+		want     bool
+	}{
+		{false, seen.Add(time.Hour), false}, // never opened here
+		{true, seen, false},
+		{true, seen.Add(-time.Hour), false}, // put back to an older copy
+		{true, seen.Add(time.Second), true},
+		{true, seen.Add(24 * time.Hour), true},
+	} {
+		clear(m.lastSeen)
+		if tc.stored {
+			m.lastSeen[path] = seen
+		}
+		if err := os.Chtimes(path, tc.modified, tc.modified); err != nil {
+			t.Fatal(err)
+		}
+		n.modTime = nil
+		if got := m.changedSinceSeen(n); got != tc.want {
+			t.Errorf("seen %v (stored %v), modified %v: changed %v, want %v", seen, tc.stored, tc.modified, got, tc.want)
+		}
+	}
+	m.lastSeen[notesPath] = time.Time{}
+	if m.changedSinceSeen(&note{path: notesPath, isDir: true}) {
+		t.Error("a folder is marked changed")
+	}
+}
+
+// A note changed on disk since it was opened is marked in the listing until
+// it's opened again, across restarts
+func TestChangedMarker(t *testing.T) {
+	newTestVault(t)
+	path := writeTestNote(t, "Synced.md", "text")
+	writeTestNote(t, "Other.md", "text")
+	m := newTestModel(t, 80, 24)
+	m.openNote(m.currentNode.children[1])
+	press(m, "esc")
+	if row, _ := render(m).find("•"); row >= 0 {
+		t.Errorf("a note just opened is marked on row %d", row)
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	m = newTestModel(t, 80, 24)
+	s := render(m)
+	row, _ := s.find("Synced •")
+	if row < 0 {
+		t.Fatalf("the note changed on disk isn't marked:\n%s", strings.Join(s.rows, "\n"))
+	}
+	if r, _ := s.find("Other •"); r >= 0 {
+		t.Error("a note never opened here is marked")
+	}
+
+	m.openNote(m.currentNode.children[1])
+	press(m, "esc")
+	m = newTestModel(t, 80, 24)
+	if row, _ := render(m).find("•"); row >= 0 {
+		t.Errorf("the note is still marked on row %d after it's opened", row)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen := loadLastSeen()[path]; !seen.Equal(info.ModTime()) {
+		t.Errorf("saved %v as seen, want the file's %v", seen, info.ModTime())
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runNew implements "notes new" and returns the exit code. The note is
+// titled by the words given, and holds the text piped in on standard input;
+// with no title, the first line of that text is the title, as in the editor.
+func runNew(args []string) int {
+	flags := flag.NewFlagSet("new", flag.ContinueOnError)
+	folder := flags.String("f", "", "Folder to create the note in, relative to the notes path (created if needed)")
+	var tags []string
+	flags.Func("t", "Tag to add to the note (can be repeated)", func(tag string) error {
+		tags = append(tags, tag)
+		return nil
+	})
+	// Flags may come before, after or between the words of the title
+	var words []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return 2
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		words = append(words, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	title := strings.TrimSpace(strings.Join(words, " "))
+
+	var body string
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "notes new: %v\n", err)
+			return 1
+		}
+		body = strings.TrimRight(string(data), "\n")
+	}
+	if title == "" {
+		first, rest, _ := strings.Cut(body, "\n")
+		title, body = strings.TrimSpace(first), rest
+	}
+	if title == "" {
+		fmt.Fprintln(os.Stderr, "notes new: give a title, or the note's text on standard input")
+		return 2
+	}
+
+	dir := *folder
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(notesPath, dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "notes new: %v\n", err)
+		return 1
+	}
+	path := newNotePath(dir, title)
+	if _, err := os.Stat(path); err == nil {
+		fmt.Fprintf(os.Stderr, "notes new: %s already exists\n", path)
+		return 1
+	}
+
+	content := withTags(path, withDefaultTags(path, titledContent(path, title, body)), tags)
+	n := &note{title: title, path: path, content: content, tags: noteTags(path, content)}
+	data := n.fileContent()
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "notes new: %v\n", err)
+		return 1
+	}
+	snapshotNote(path, data)
+	fmt.Println(path)
+	return 0
+}
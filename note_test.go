@@ -0,0 +1,83 @@
+package main
+
+import "testing"
+
+func TestFileContentTrailingNewline(t *testing.T) {
+	newTestVault(t)
+	for _, tc := range []struct {
+		ensure   bool
+		favorite bool
+		content  string
+		want     string
+	}{
+		{true, false, "text", "text\n"},
+		{true, false, "text\n", "text\n"},
+		{true, false, "text\n\n\n", "text\n"},
+		{true, false, "", ""},
+		{true, true, "text", "---\nfavorite: true\n---\ntext\n"},
+		{true, true, "", "---\nfavorite: true\n---\n"},
+		{false, false, "text", "text"},
+		{false, false, "text\n", "text\n"},
+		{false, false, "text\n\n", "text\n\n"},
+		{false, true, "text", "---\nfavorite: true\n---\ntext"},
+	} {
+		config.EnsureTrailingNewline = tc.ensure
+		n := &note{path: "note.txt", content: tc.content, favorite: tc.favorite}
+		if got := n.fileContent(); got != tc.want {
+			t.Errorf("ensure %v, favorite %v: %q written as %q, want %q", tc.ensure, tc.favorite, tc.content, got, tc.want)
+		}
+	}
+}
+
+func TestParseNoteFileTrailingNewline(t *testing.T) {
+	newTestVault(t)
+	for _, tc := range []struct {
+		ensure     bool
+		data, body string
+	}{
+		{true, "text\n", "text"},
+		{true, "text", "text"},
+		{true, "text\n\n", "text\n"},
+		{true, "---\nfavorite: true\n---\ntext\n", "text"},
+		{false, "text\n", "text\n"},
+		{false, "text", "text"},
+	} {
+		config.EnsureTrailingNewline = tc.ensure
+		if _, body := parseNoteFile("note.txt", tc.data); body != tc.body {
+			t.Errorf("ensure %v: %q read as %q, want %q", tc.ensure, tc.data, body, tc.body)
+		}
+	}
+}
+
+// The editor doesn't show the newline the file ends with, and saving the
+// note unchanged writes the file as it was
+func TestSaveTrailingNewline(t *testing.T) {
+	for _, tc := range []struct {
+		ensure             bool
+		file, shown, typed string
+		saved              string
+	}{
+		{true, "milk\n", "milk", "milk eggs", "milk eggs\n"},
+		{true, "milk", "milk", "milk eggs", "milk eggs\n"},
+		{false, "milk\n", "milk\n", "milk eggs\n", "milk eggs\n"},
+		{false, "milk", "milk", "milk eggs", "milk eggs"},
+	} {
+		newTestVault(t)
+		config.EnsureTrailingNewline = tc.ensure
+		writeTestNote(t, "Shopping.txt", tc.file)
+		m := newTestModel(t, 80, 24)
+		press(m, "enter")
+		if got := m.editor.Value(); got != tc.shown {
+			t.Errorf("ensure %v: %q shown as %q, want %q", tc.ensure, tc.file, got, tc.shown)
+		}
+		m.editor.SetCursor(len("milk"))
+		typeText(m, " eggs")
+		press(m, "ctrl+s")
+		if got := readTestNote(t, "Shopping.txt"); got != tc.saved {
+			t.Errorf("ensure %v: %q saved as %q, want %q", tc.ensure, tc.file, got, tc.saved)
+		}
+		if got := m.editor.Value(); got != tc.typed {
+			t.Errorf("ensure %v: editor shows %q after saving, want %q", tc.ensure, got, tc.typed)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestOverlayPopupKeepsEscapeSequences(t *testing.T) {
+	withColors(t)
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("#ff8800")).Background(lipgloss.Color("#202020")).Bold(true)
+	// Every cell of these lines is styled on its own, so a cut anywhere
+	// lands next to an escape sequence
+	var base []string
+	for i := 0; i < 7; i++ {
+		var line strings.Builder
+		for j := 0; j < 40; j++ {
+			line.WriteString(style.Render(string(rune('a' + (i+j)%26))))
+		}
+		base = append(base, line.String())
+	}
+	popup := lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Render("Rename\nname")
+
+	s := newScreen(overlayPopup(strings.Join(base, "\n"), popup))
+	s.assertIntact(t)
+	s.assertWidth(t, 40)
+
+	popupRows := strings.Split(ansi.Strip(popup), "\n")
+	startRow, startCol := (7-len(popupRows))/2, (40-lipgloss.Width(popup))/2
+	for i, want := range popupRows {
+		if got := s.cells(startRow+i, startCol, len([]rune(want))); got != want {
+			t.Errorf("row %d: popup shown as %q, want %q", startRow+i, got, want)
+		}
+	}
+	// The base line shows on both sides of the popup
+	plain := ansi.Strip(base[startRow])
+	if got := s.cells(startRow, 0, startCol); got != plain[:startCol] {
+		t.Errorf("left of the popup shows %q, want %q", got, plain[:startCol])
+	}
+	end := startCol + lipgloss.Width(popup)
+	if got := s.cells(startRow, end, 40-end); got != plain[end:] {
+		t.Errorf("right of the popup shows %q, want %q", got, plain[end:])
+	}
+	// Rows above and below are untouched
+	if s.raw[0] != base[0] || s.raw[6] != base[6] {
+		t.Error("rows outside the popup changed")
+	}
+}
+
+func TestViewRenamePopupOverStyledListing(t *testing.T) {
+	newTestVault(t)
+	withColors(t)
+	writeTestNote(t, "Groceries.txt", "milk #shopping")
+	writeTestNote(t, "Plans.txt", "# Plans\nsoon")
+	writeTestNote(t, "Work/Meeting.txt", "notes")
+	m := newTestModel(t, 60, 20)
+
+	press(m, "down", "r")
+	if !m.showRenamePopup {
+		t.Fatal("r didn't open the rename popup")
+	}
+	s := render(m)
+	if len(s.rows) != 20 {
+		t.Errorf("view is %d rows, want 20", len(s.rows))
+	}
+	s.assertIntact(t)
+	s.assertWidth(t, 60)
+	if row, _ := s.find(m.renameInput); row < 0 {
+		t.Errorf("the popup doesn't show the name being edited, %q:\n%s", m.renameInput, strings.Join(s.rows, "\n"))
+	}
+	// The popup's border lines up on every row it covers
+	top, left := s.find("╭")
+	bottom, bottomLeft := s.find("╰")
+	if top < 0 || bottom <= top || left != bottomLeft {
+		t.Fatalf("popup border not found as a box:\n%s", strings.Join(s.rows, "\n"))
+	}
+	for row := top + 1; row < bottom; row++ {
+		if got := s.cells(row, left, 1); got != "│" {
+			t.Errorf("row %d: popup's left border is %q at column %d", row, got, left)
+		}
+	}
+}
+
+func TestOverlayPopupOverWideCharacters(t *testing.T) {
+	withColors(t)
+	wide := lipgloss.NewStyle().Foreground(lipgloss.Color("#00aaff"))
+	// 20 cells each: the popup's edges at columns 7 and 13 fall in the
+	// middle of a wide character on the first two lines, and between
+	// characters on the last
+	base := []string{
+		wide.Render("漢字漢字漢字漢字漢字"),
+		wide.Render("😀😀😀😀😀😀😀😀😀😀"),
+		wide.Render("a漢字漢字漢字漢字漢") + "b",
+	}
+	popup := "[name]\n[name]\n[name]"
+
+	s := newScreen(overlayPopup(strings.Join(base, "\n"), popup))
+	s.assertIntact(t)
+	for row := range base {
+		if got := s.cells(row, 7, 6); got != "[name]" {
+			t.Errorf("row %d: popup shown as %q at column 7: %q", row, got, s.rows[row])
+		}
+		if w := ansi.StringWidth(s.rows[row]); w != ansi.StringWidth(base[row]) {
+			t.Errorf("row %d is %d cells wide, was %d: %q", row, w, ansi.StringWidth(base[row]), s.rows[row])
+		}
+	}
+	// A wide character cut by an edge is replaced by a space
+	if want := "漢字漢 [name] 字漢字"; s.rows[0] != want {
+		t.Errorf("row 0 is %q, want %q", s.rows[0], want)
+	}
+	if want := "😀😀😀 [name] 😀😀😀"; s.rows[1] != want {
+		t.Errorf("row 1 is %q, want %q", s.rows[1], want)
+	}
+	if want := "a漢字漢[name]漢字漢b"; s.rows[2] != want {
+		t.Errorf("row 2 is %q, want %q", s.rows[2], want)
+	}
+}
+
+func TestOverlayPopupOverRaggedStyledLines(t *testing.T) {
+	withColors(t)
+	status := lipgloss.NewStyle().Background(lipgloss.Color("#333333")).Foreground(lipgloss.Color("#eeeeee"))
+	base := []string{
+		status.Render(strings.Repeat("=", 30)),
+		"short",
+		"",
+		status.Render(strings.Repeat("-", 30)),
+	}
+	popup := "+--+\n|x|\n+--+"
+
+	s := newScreen(overlayPopup(strings.Join(base, "\n"), popup))
+	s.assertIntact(t)
+	// Centered on the widest line, the popup stays a rectangle over lines
+	// that end before it, and its ragged lines are padded
+	for i, want := range []string{"+--+", "|x| ", "+--+"} {
+		if got := s.cells(i, 13, 4); got != want {
+			t.Errorf("row %d: popup shown as %q at column 13, want %q: %q", i, got, want, s.rows[i])
+		}
+	}
+	if want := "short        |x| "; s.rows[1] != want {
+		t.Errorf("row 1 is %q, want %q", s.rows[1], want)
+	}
+	// The styled line's colors stop at the popup and resume after it
+	line := s.raw[0]
+	at := strings.Index(line, "+--+")
+	if !strings.HasSuffix(line[:at], ansi.ResetStyle) {
+		t.Errorf("styles aren't reset before the popup: %q", line)
+	}
+	after := line[at+len("+--+"):]
+	if !strings.HasPrefix(after, ansi.ResetStyle+"\x1b[") {
+		t.Errorf("the base style doesn't resume after the popup: %q", line)
+	}
+	if got := ansi.Strip(after); got != strings.Repeat("=", 13) {
+		t.Errorf("right of the popup shows %q", got)
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// noteMeta is the metadata a contentParser reads from a note file
+type noteMeta struct {
+	favorite bool
+	pinned   bool     // kept at the top of its folder whatever the sort
+	title    string   // title given in the content, "" to use the file name
+	tags     []string // every tag on the note, inline #tags included
+}
+
+// contentParser converts between a note file and the body shown in the
+// editor. ParseMeta strips the metadata the app manages from the content;
+// Format is its inverse and writes that metadata back around the body.
+// SetTitle gives the note a title in its body, which stays editable there.
+// ReplaceTag renames tag, and the tags nested under it, to replacement
+// wherever the body carries them, or removes them when replacement is "".
+// HeaderTags returns the tags listed in the note's header rather than
+// inline, and AddHeaderTags adds tags to that list, starting one if needed.
+type contentParser interface {
+	ParseMeta(content string) (noteMeta, string)
+	Format(meta noteMeta, body string) string
+	SetTitle(body, title string) string
+	ReplaceTag(body, tag, replacement string) string
+	HeaderTags(body string) []string
+	AddHeaderTags(body string, tags []string) string
+}
+
+// contentParsers maps lowercase file extensions to their parser. Notes with
+// any other extension use frontmatterParser.
+var contentParsers = map[string]contentParser{
+	".org": orgParser{},
+}
+
+// parserFor returns the parser for the note file at path
+func parserFor(path string) contentParser {
+	if p, ok := contentParsers[strings.ToLower(filepath.Ext(path))]; ok {
+		return p
+	}
+	return frontmatterParser{}
+}
+
+// noteTags returns the tags of a note with the given editor body
+func noteTags(path, body string) []string {
+	meta, _ := parserFor(path).ParseMeta(body)
+	return meta.tags
+}
+
+// fileTitle returns the title made from a note's file name
+func fileTitle(path string) string {
+	title := filepath.Base(path)
+	title = strings.TrimSuffix(title, filepath.Ext(title))
+	return strings.ReplaceAll(title, "-", " ")
+}
+
+// noteTitle returns the title of a note with the given editor body: the one
+// its content gives it, or else the one made from its file name
+func noteTitle(path, body string) string {
+	if meta, _ := parserFor(path).ParseMeta(body); meta.title != "" {
+		return meta.title
+	}
+	return fileTitle(path)
+}
+
+// titledContent returns the body of a new note saved at path, with title
+// kept in it when the file name can't carry it, such as for non-ASCII titles
+func titledContent(path, title, body string) string {
+	if title == "" || title == fileTitle(path) {
+		return body
+	}
+	return parserFor(path).SetTitle(body, title)
+}
+
+// favoritePrefix is the first line older versions marked favorites with
+const favoritePrefix = "favorite: true\n"
+
+// frontmatterParser keeps favorites and pins in the frontmatter and adds the
+// frontmatter "tags" list to the inline #tags. Other frontmatter keys stay in
+// the body so they can be edited. The title is the frontmatter "title", or
+// else a "# Heading" first line. The "favorite: true" first line of older
+// versions is read as well and becomes frontmatter on the next save.
+type frontmatterParser struct{}
+
+func (frontmatterParser) ParseMeta(content string) (noteMeta, string) {
+	var meta noteMeta
+	if strings.HasPrefix(content, favoritePrefix) {
+		meta.favorite = true
+		content = strings.TrimPrefix(content, favoritePrefix)
+	}
+
+	if value, body, found := removeFrontmatterKey(content, "favorite"); found {
+		meta.favorite = meta.favorite || value == "true"
+		content = body
+	}
+	if value, body, found := removeFrontmatterKey(content, "pinned"); found {
+		meta.pinned = value == "true"
+		content = body
+	}
+	fields, body, _ := parseFrontmatter(content)
+	meta.tags = splitTagList(fields["tags"])
+	meta.title = fields["title"]
+	if meta.title == "" {
+		first, _, _ := strings.Cut(strings.TrimLeft(body, "\n"), "\n")
+		if heading, ok := strings.CutPrefix(first, "# "); ok {
+			meta.title = strings.TrimSpace(heading)
+		}
+	}
+	meta.tags = append(meta.tags, extractTags(content)...)
+	return meta, content
+}
+
+func (frontmatterParser) SetTitle(body, title string) string {
+	return setFrontmatterKey(body, "title", quoteValue(title))
+}
+
+func (frontmatterParser) ReplaceTag(body, tag, replacement string) string {
+	if fields, _, ok := parseFrontmatter(body); ok {
+		if tags, changed := replaceTagList(splitTagList(fields["tags"]), tag, replacement); changed {
+			if len(tags) == 0 {
+				_, body, _ = removeFrontmatterKey(body, "tags")
+			} else {
+				body = setFrontmatterKey(body, "tags", "["+strings.Join(tags, ", ")+"]")
+			}
+		}
+	}
+	return replaceInlineTags(body, tag, replacement)
+}
+
+func (frontmatterParser) HeaderTags(body string) []string {
+	fields, _, _ := parseFrontmatter(body)
+	return splitTagList(fields["tags"])
+}
+
+func (p frontmatterParser) AddHeaderTags(body string, tags []string) string {
+	tags = append(p.HeaderTags(body), tags...)
+	return setFrontmatterKey(body, "tags", "["+strings.Join(tags, ", ")+"]")
+}
+
+func (frontmatterParser) Format(meta noteMeta, body string) string {
+	var fields string
+	if meta.favorite {
+		fields += "favorite: true\n"
+	}
+	if meta.pinned {
+		fields += "pinned: true\n"
+	}
+	if fields == "" {
+		return body
+	}
+	if _, _, ok := parseFrontmatter(body); ok {
+		return frontmatterDelim + "\n" + fields + body[len(frontmatterDelim)+1:]
+	}
+	return frontmatterDelim + "\n" + fields + frontmatterDelim + "\n" + body
+}
+
+// needsMigration reports whether a note file still has metadata in a form
+// frontmatterParser rewrites when the note is saved
+func needsMigration(path, data string) bool {
+	_, ok := parserFor(path).(frontmatterParser)
+	return ok && strings.HasPrefix(data, favoritePrefix)
+}
+
+// orgParser keeps favorites and pins as "#+FAVORITE: t" and "#+PINNED: t"
+// first lines and reads tags from "#+TAGS:" and "#+FILETAGS:" header lines
+// as well as inline #tags.
+type orgParser struct{}
+
+const (
+	orgFavoriteLine = "#+FAVORITE: t\n"
+	orgPinnedLine   = "#+PINNED: t\n"
+)
+
+func (orgParser) ParseMeta(content string) (noteMeta, string) {
+	var meta noteMeta
+header:
+	for {
+		line, rest, _ := strings.Cut(content, "\n")
+		key, value, _ := strings.Cut(line, ":")
+		value = strings.ToLower(strings.TrimSpace(value))
+		switch strings.ToUpper(key) {
+		case "#+FAVORITE":
+			meta.favorite = value == "t" || value == "true"
+		case "#+PINNED":
+			meta.pinned = value == "t" || value == "true"
+		default:
+			break header
+		}
+		content = rest
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if !strings.HasPrefix(line, "#+") {
+			break
+		}
+		key, value, found := strings.Cut(line[2:], ":")
+		if !found {
+			continue
+		}
+		switch strings.ToUpper(strings.TrimSpace(key)) {
+		case "TAGS", "FILETAGS":
+			meta.tags = append(meta.tags, splitTagList(value)...)
+		case "TITLE":
+			meta.title = strings.TrimSpace(value)
+		}
+	}
+	meta.tags = append(meta.tags, extractTags(content)...)
+	return meta, content
+}
+
+func (orgParser) SetTitle(body, title string) string {
+	lines := strings.SplitAfter(body, "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "#+") {
+			break
+		}
+		if key, _, _ := strings.Cut(line[2:], ":"); strings.EqualFold(strings.TrimSpace(key), "TITLE") {
+			lines[i] = "#+TITLE: " + title + "\n"
+			return strings.Join(lines, "")
+		}
+	}
+	return "#+TITLE: " + title + "\n" + body
+}
+
+func (orgParser) ReplaceTag(body, tag, replacement string) string {
+	lines := strings.SplitAfter(body, "\n")
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "#+") {
+			break
+		}
+		key, value, found := strings.Cut(strings.TrimRight(lines[i], "\n"), ":")
+		if !found {
+			continue
+		}
+		switch strings.ToUpper(strings.TrimSpace(key[2:])) {
+		case "TAGS", "FILETAGS":
+			tags, changed := replaceTagList(splitTagList(value), tag, replacement)
+			switch {
+			case !changed:
+			case len(tags) == 0:
+				lines = slices.Delete(lines, i, i+1)
+				i--
+			default:
+				lines[i] = orgTagLine(key, value, tags)
+			}
+		}
+	}
+	return replaceInlineTags(strings.Join(lines, ""), tag, replacement)
+}
+
+// orgTagLine returns the header line for key listing tags, in the ":a:b:"
+// or "a b" form the line's old value was written in
+func orgTagLine(key, value string, tags []string) string {
+	if strings.HasPrefix(strings.TrimSpace(value), ":") {
+		return key + ": :" + strings.Join(tags, ":") + ":\n"
+	}
+	return key + ": " + strings.Join(tags, " ") + "\n"
+}
+
+func (orgParser) HeaderTags(body string) []string {
+	var tags []string
+	for _, line := range strings.Split(body, "\n") {
+		if !strings.HasPrefix(line, "#+") {
+			break
+		}
+		key, value, _ := strings.Cut(line[2:], ":")
+		switch strings.ToUpper(strings.TrimSpace(key)) {
+		case "TAGS", "FILETAGS":
+			tags = append(tags, splitTagList(value)...)
+		}
+	}
+	return tags
+}
+
+// AddHeaderTags adds tags to the first "#+FILETAGS:" or "#+TAGS:" line, or
+// starts a "#+FILETAGS:" line at the top
+func (orgParser) AddHeaderTags(body string, tags []string) string {
+	lines := strings.SplitAfter(body, "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "#+") {
+			break
+		}
+		key, value, found := strings.Cut(strings.TrimRight(line, "\n"), ":")
+		if !found {
+			continue
+		}
+		switch strings.ToUpper(strings.TrimSpace(key[2:])) {
+		case "TAGS", "FILETAGS":
+			lines[i] = orgTagLine(key, value, append(splitTagList(value), tags...))
+			return strings.Join(lines, "")
+		}
+	}
+	return "#+FILETAGS: :" + strings.Join(tags, ":") + ":\n" + body
+}
+
+func (orgParser) Format(meta noteMeta, body string) string {
+	if meta.pinned {
+		body = orgPinnedLine + body
+	}
+	if meta.favorite {
+		body = orgFavoriteLine + body
+	}
+	return body
+}
+
+// splitTagList splits a header tag list such as "[a, b]", "a b" or
+// ":a:b:" into tag names
+func splitTagList(value string) []string {
+	value = strings.Trim(strings.TrimSpace(value), "[]")
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ':' || r == ' ' || r == '\t'
+	})
+
+	var tags []string
+	for _, field := range fields {
+		if tag := strings.Trim(strings.TrimPrefix(field, "#"), `"'`); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// runMigrate implements "notes migrate", rewriting every note that still has
+// metadata in an older form with frontmatter, and returns the exit code
+func runMigrate(root *note) int {
+	migrated, failed := 0, 0
+	var walk func(dir *note)
+	walk = func(dir *note) {
+		for _, child := range dir.children {
+			if child.isDir {
+				walk(child)
+				continue
+			}
+			data, err := os.ReadFile(child.path)
+			if err != nil || !needsMigration(child.path, string(data)) {
+				continue
+			}
+			if err := os.WriteFile(child.path, []byte(child.fileContent()), 0644); err != nil {
+				fmt.Fprintf(os.Stderr, "notes migrate: %v\n", err)
+				failed++
+				continue
+			}
+			fmt.Println(child.path)
+			migrated++
+		}
+	}
+	walk(root)
+	fmt.Printf("Migrated %d notes\n", migrated)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParserFor(t *testing.T) {
+	for path, want := range map[string]contentParser{
+		"a.txt":     frontmatterParser{},
+		"a.md":      frontmatterParser{},
+		"a":         frontmatterParser{},
+		"a.org":     orgParser{},
+		"dir/A.ORG": orgParser{},
+	} {
+		if got := parserFor(path); got != want {
+			t.Errorf("%s is read with %T, want %T", path, got, want)
+		}
+	}
+}
+
+func TestParseMeta(t *testing.T) {
+	for _, tc := range []struct {
+		path, content string
+		want          noteMeta
+		body          string
+	}{
+		// The default parser, as notes have always been read
+		{"a.txt", "plain #one", noteMeta{tags: []string{"one"}}, "plain #one"},
+		{"a.txt", "favorite: true\ntext", noteMeta{favorite: true}, "text"},
+		{"a.md", "---\nfavorite: true\npinned: true\ntags: [one, two]\n---\n# Heading\nbody #three",
+			noteMeta{favorite: true, pinned: true, title: "Heading", tags: []string{"one", "two", "three"}},
+			"---\ntags: [one, two]\n---\n# Heading\nbody #three"},
+		{"a.md", "---\ntitle: \"Café\"\n---\ntext", noteMeta{title: "Café"}, "---\ntitle: \"Café\"\n---\ntext"},
+		// And the org parser
+		{"a.org", "#+FAVORITE: t\n#+PINNED: t\n#+TITLE: Plans\n#+FILETAGS: :one:two:\n* Heading #three",
+			noteMeta{favorite: true, pinned: true, title: "Plans", tags: []string{"one", "two", "three"}},
+			"#+TITLE: Plans\n#+FILETAGS: :one:two:\n* Heading #three"},
+		{"a.org", "#+tags: one two\ntext", noteMeta{tags: []string{"one", "two"}}, "#+tags: one two\ntext"},
+		{"a.org", "text\n#+TAGS: late", noteMeta{}, "text\n#+TAGS: late"},
+	} {
+		meta, body := parserFor(tc.path).ParseMeta(tc.content)
+		if meta.favorite != tc.want.favorite || meta.pinned != tc.want.pinned || meta.title != tc.want.title || !slices.Equal(meta.tags, tc.want.tags) {
+			t.Errorf("%s %q: meta %+v, want %+v", tc.path, tc.content, meta, tc.want)
+		}
+		if body != tc.body {
+			t.Errorf("%s %q: body %q, want %q", tc.path, tc.content, body, tc.body)
+		}
+	}
+}
+
+// Format puts back what ParseMeta takes out
+func TestParserRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		path string
+		meta noteMeta
+		body string
+		file string
+	}{
+		{"a.txt", noteMeta{}, "text", "text"},
+		{"a.txt", noteMeta{favorite: true}, "text", "---\nfavorite: true\n---\ntext"},
+		{"a.md", noteMeta{favorite: true, pinned: true}, "---\ntags: [x]\n---\ntext", "---\nfavorite: true\npinned: true\ntags: [x]\n---\ntext"},
+		{"a.org", noteMeta{}, "* text", "* text"},
+		{"a.org", noteMeta{favorite: true, pinned: true}, "#+TITLE: T\n* text", "#+FAVORITE: t\n#+PINNED: t\n#+TITLE: T\n* text"},
+	} {
+		p := parserFor(tc.path)
+		file := p.Format(tc.meta, tc.body)
+		if file != tc.file {
+			t.Errorf("%s %+v: formatted as %q, want %q", tc.path, tc.meta, file, tc.file)
+		}
+		meta, body := p.ParseMeta(file)
+		if body != tc.body || meta.favorite != tc.meta.favorite || meta.pinned != tc.meta.pinned {
+			t.Errorf("%s %q: read back as %+v %q", tc.path, file, meta, body)
+		}
+	}
+}
+
+func TestParserHeaderTags(t *testing.T) {
+	for _, tc := range []struct {
+		path, body string
+		header     []string
+		added      string
+		replaced   string // "one" renamed to "uno"
+	}{
+		{"a.md", "text #one", nil, "---\ntags: [new]\n---\ntext #one", "text #uno"},
+		{"a.md", "---\ntags: [one, two]\n---\ntext", []string{"one", "two"}, "---\ntags: [one, two, new]\n---\ntext", "---\ntags: [uno, two]\n---\ntext"},
+		{"a.org", "* text #one/sub", nil, "#+FILETAGS: :new:\n* text #one/sub", "* text #uno/sub"},
+		{"a.org", "#+FILETAGS: :one:two:\ntext", []string{"one", "two"}, "#+FILETAGS: :one:two:new:\ntext", "#+FILETAGS: :uno:two:\ntext"},
+		{"a.org", "#+TAGS: one two\ntext", []string{"one", "two"}, "#+TAGS: one two new\ntext", "#+TAGS: uno two\ntext"},
+	} {
+		p := parserFor(tc.path)
+		if got := p.HeaderTags(tc.body); !slices.Equal(got, tc.header) {
+			t.Errorf("%s %q: header tags %q, want %q", tc.path, tc.body, got, tc.header)
+		}
+		if got := p.AddHeaderTags(tc.body, []string{"new"}); got != tc.added {
+			t.Errorf("%s %q: adding a tag gives %q, want %q", tc.path, tc.body, got, tc.added)
+		}
+		if got := p.ReplaceTag(tc.body, "one", "uno"); got != tc.replaced {
+			t.Errorf("%s %q: renaming a tag gives %q, want %q", tc.path, tc.body, got, tc.replaced)
+		}
+	}
+	// Removing the only header tag removes its line
+	if got := (orgParser{}).ReplaceTag("#+FILETAGS: :one:\ntext", "one", ""); got != "text" {
+		t.Errorf("removing the only org tag gives %q", got)
+	}
+}
+
+// Notes of each format are loaded and saved through their parser
+func TestLoadAndSaveOrgNote(t *testing.T) {
+	newTestVault(t)
+	writeTestNote(t, "Plans.org", "#+FAVORITE: t\n#+TITLE: My plans\n#+FILETAGS: :work:\n* Soon")
+	writeTestNote(t, "Notes.md", "---\nfavorite: true\n---\ntext #home")
+	m := newTestModel(t, 80, 24)
+
+	byTitle := make(map[string]*note)
+	for _, n := range m.currentNode.children {
+		byTitle[n.title] = n
+	}
+	org, md := byTitle["My plans"], byTitle["Notes"]
+	if org == nil || md == nil {
+		t.Fatalf("loaded %v", byTitle)
+	}
+	if !org.favorite || !slices.Equal(org.tags, []string{"work"}) || org.content != "#+TITLE: My plans\n#+FILETAGS: :work:\n* Soon" {
+		t.Errorf("org note loaded as favorite %v, tags %q, content %q", org.favorite, org.tags, org.content)
+	}
+	if !md.favorite || !slices.Equal(md.tags, []string{"home"}) || md.content != "text #home" {
+		t.Errorf("md note loaded as favorite %v, tags %q, content %q", md.favorite, md.tags, md.content)
+	}
+
+	// Unmarking the favorite writes each back in its own format
+	m.cursor = slices.Index(m.currentNode.children, org)
+	press(m, "f")
+	m.cursor = slices.Index(m.currentNode.children, md)
+	press(m, "f", "f")
+	if got, want := readTestNote(t, "Plans.org"), "#+TITLE: My plans\n#+FILETAGS: :work:\n* Soon\n"; got != want {
+		t.Errorf("org note saved as %q, want %q", got, want)
+	}
+	if got, want := readTestNote(t, "Notes.md"), "---\nfavorite: true\n---\ntext #home\n"; got != want {
+		t.Errorf("md note saved as %q, want %q", got, want)
+	}
+}
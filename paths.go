@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// The config and the files the user writes by hand (themes, the personal
+// dictionary) live in the config folder, $XDG_CONFIG_HOME/notes. What the
+// app keeps track of on its own goes in the state folder, $XDG_STATE_HOME/notes,
+// and what it can rebuild in the cache folder, $XDG_CACHE_HOME/notes.
+// NOTES_CONFIG names another config file.
+
+// xdgDir returns the notes folder under the base folder named by env, or
+// under fallback in the home folder when env isn't set. The spec has
+// relative paths ignored.
+func xdgDir(env string, fallback ...string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "notes")
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(append(append([]string{homeDir}, fallback...), "notes")...)
+}
+
+func getConfigDir() string {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// getStateDir and getCacheDir give each vault other than the default, and
+// each folder opened with -path, a folder of their own
+func getStateDir() string {
+	return vaultDir(xdgDir("XDG_STATE_HOME", ".local", "state"))
+}
+
+func getCacheDir() string {
+	return vaultDir(xdgDir("XDG_CACHE_HOME", ".cache"))
+}
+
+func vaultDir(dir string) string {
+	switch {
+	case openedPath != "":
+		return filepath.Join(dir, "paths", openedPathKey())
+	case vaultName != "":
+		return filepath.Join(dir, "vaults", vaultName)
+	}
+	return dir
+}
+
+// legacyFiles are the files once kept in ~/.config/notes, by the folder
+// they've moved to
+var legacyFiles = map[string][]string{
+	"state": {"cursor_positions.json", "last_seen.json", "recent_notes.json", "bookmarks.json", "folder_sort.json", "trash_info.json", "recovery"},
+	"cache": {"search_index.json"},
+}
+
+// moveLegacyFiles moves the state and cache files an older version left in
+// ~/.config/notes to their own folders, unless they're there already
+func moveLegacyFiles() {
+	homeDir, _ := os.UserHomeDir()
+	oldDir := filepath.Join(homeDir, ".config", "notes")
+	dirs := map[string]string{"state": getStateDir(), "cache": getCacheDir()}
+	for kind, names := range legacyFiles {
+		for _, name := range names {
+			oldPath, newPath := filepath.Join(oldDir, name), filepath.Join(dirs[kind], name)
+			if oldPath == newPath {
+				continue
+			}
+			if _, err := os.Stat(oldPath); err != nil {
+				continue
+			}
+			if _, err := os.Stat(newPath); err == nil {
+				continue
+			}
+			if err := os.MkdirAll(dirs[kind], 0755); err != nil {
+				log.Printf("Could not move %s: %v", oldPath, err)
+				continue
+			}
+			if err := os.Rename(oldPath, newPath); err != nil {
+				log.Printf("Could not move %s: %v", oldPath, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openPreview shows n read-only from the top, leaving the editor and the
+// saved cursor position alone
+func (m *model) openPreview(n *note) {
+	m.previewNote = n
+	m.previewOffset = 0
+	m.mode = previewView
+	m.markSeen(n)
+}
+
+// previewLines returns the note wrapped to the terminal width
+func (m model) previewLines() []string {
+	width := m.width
+	if width <= 0 {
+		width = 80
+	}
+	var lines []string
+	for _, line := range strings.Split(m.previewNote.content, "\n") {
+		runes := []rune(line)
+		starts := wrapLine(runes, width)
+		for i, start := range starts {
+			end := len(runes)
+			if i+1 < len(starts) {
+				end = starts[i+1]
+			}
+			lines = append(lines, expandTabs(string(runes[start:end])))
+		}
+	}
+	return lines
+}
+
+// previewHeight is the number of note lines that fit on screen
+func (m model) previewHeight() int {
+	return max(1, m.height-1-m.getStatusBarHeight())
+}
+
+// scrollPreview moves the preview by delta lines, keeping the last page full
+func (m *model) scrollPreview(delta int) {
+	maxOffset := max(0, len(m.previewLines())-m.previewHeight())
+	m.previewOffset = min(max(m.previewOffset+delta, 0), maxOffset)
+}
+
+func (m *model) updatePreviewView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	page := m.previewHeight()
+	switch msg.String() {
+	case "up", "k":
+		m.scrollPreview(-1)
+	case "down", "j":
+		m.scrollPreview(1)
+	case "pgup", "b":
+		m.scrollPreview(-page)
+	case "pgdown", " ":
+		m.scrollPreview(page)
+	case "home", "g":
+		m.previewOffset = 0
+	case "end", "G":
+		m.scrollPreview(len(m.previewLines()))
+	case "enter", "e":
+		m.openNote(m.previewNote)
+		m.previewNote = nil
+	case "esc", "q", "left":
+		m.mode = navigationView
+		m.previewNote = nil
+	}
+	return m, nil
+}
+
+// previewView renders the visible part of the note
+func (m model) previewView() string {
+	lines := m.previewLines()
+	end := min(m.previewOffset+m.previewHeight(), len(lines))
+	return strings.Join(lines[m.previewOffset:end], "\n")
+}
+
+// previewPosition describes how far through the note the preview is
+func (m model) previewPosition() string {
+	total := len(m.previewLines())
+	switch {
+	case total <= m.previewHeight():
+		return "All"
+	case m.previewOffset == 0:
+		return "Top"
+	case m.previewOffset+m.previewHeight() >= total:
+		return "Bot"
+	}
+	return fmt.Sprintf("%d%%", m.previewOffset*100/(total-m.previewHeight()))
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// While a note has unsaved edits, the buffer is written every
+// recovery_interval seconds to a draft in the state folder. The
+// draft goes away once the note is saved; one left behind by a killed
+// terminal is offered back at the next startup.
+
+// draft is the unsaved buffer of a note, or of a new note not yet named
+type draft struct {
+	Path    string    `json:"path,omitempty"` // "" for a new note
+	Folder  string    `json:"folder"`         // where a new note was being written
+	Content string    `json:"content"`
+	Saved   time.Time `json:"saved"`
+
+	file string // the draft's own file
+}
+
+// draftRecovery is the startup popup offering back the drafts left behind
+type draftRecovery struct {
+	drafts []draft // newest first
+	cursor int
+}
+
+// draftTickMsg is sent every recovery_interval seconds to save a draft
+type draftTickMsg struct{}
+
+func getRecoveryDir() string {
+	return filepath.Join(getStateDir(), "recovery")
+}
+
+// draftTick waits for the next draft save, or returns nil when drafts are
+// turned off
+func draftTick() tea.Cmd {
+	if config.RecoveryInterval <= 0 {
+		return nil
+	}
+	return tea.Tick(time.Duration(config.RecoveryInterval)*time.Second, func(time.Time) tea.Msg {
+		return draftTickMsg{}
+	})
+}
+
+// draftFileFor names the draft of the note at path. A new note gets a name
+// of its own, so it doesn't replace another new note's draft.
+func draftFileFor(path string) string {
+	name := fmt.Sprintf("new-%d", time.Now().UnixNano())
+	if path != "" {
+		sum := sha1.Sum([]byte(path))
+		name = hex.EncodeToString(sum[:])
+	}
+	return filepath.Join(getRecoveryDir(), name+".draft")
+}
+
+// saveDraft writes the buffer to its draft while it has unsaved edits, and
+// removes the draft once it hasn't. A draft already holding the text isn't
+// written again.
+func (m *model) saveDraft() {
+	if m.mode != editingView || !m.editor.Dirty() {
+		m.dropDraft()
+		return
+	}
+	if m.draftFile != "" && m.draftRevision == m.editor.Revision() {
+		return
+	}
+	if m.draftFile == "" {
+		m.draftFile = draftFileFor(m.currentNotePath)
+	}
+	d := draft{Path: m.currentNotePath, Folder: m.currentNode.path, Content: m.editor.Value(), Saved: time.Now()}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(getRecoveryDir(), 0755); err != nil {
+		return
+	}
+	if os.WriteFile(m.draftFile, data, 0644) == nil {
+		m.draftRevision = m.editor.Revision()
+	}
+}
+
+// dropDraft removes the draft of the buffer, if one was written
+func (m *model) dropDraft() {
+	if m.draftFile != "" {
+		os.Remove(m.draftFile)
+		m.draftFile = ""
+	}
+}
+
+// loadDrafts returns the drafts left behind, newest first. Drafts whose note
+// was saved with the same text after all are removed.
+func loadDrafts() []draft {
+	entries, err := os.ReadDir(getRecoveryDir())
+	if err != nil {
+		return nil
+	}
+	var drafts []draft
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".draft" {
+			continue
+		}
+		file := filepath.Join(getRecoveryDir(), entry.Name())
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var d draft
+		if json.Unmarshal(data, &d) != nil {
+			continue
+		}
+		d.file = file
+		if d.Path != "" {
+			if saved, err := os.ReadFile(d.Path); err == nil {
+				if _, body := parseNoteFile(d.Path, string(saved)); body == d.Content {
+					os.Remove(file)
+					continue
+				}
+			}
+		}
+		drafts = append(drafts, d)
+	}
+	sort.Slice(drafts, func(i, j int) bool { return drafts[i].Saved.After(drafts[j].Saved) })
+	return drafts
+}
+
+// draftLabel names the note a draft belongs to
+func draftLabel(d draft) string {
+	if d.Path == "" {
+		rel, err := filepath.Rel(notesPath, d.Folder)
+		if err != nil || rel == "." {
+			return "New note"
+		}
+		return "New note in /" + filepath.ToSlash(rel)
+	}
+	rel, err := filepath.Rel(notesPath, d.Path)
+	if err != nil {
+		return d.Path
+	}
+	return "/" + filepath.ToSlash(rel)
+}
+
+// restoreDraft opens the note of a draft in the editor with the draft's text,
+// unsaved. A new note is started again in its folder.
+func (m *model) restoreDraft(d draft) {
+	if d.Path != "" {
+		m.openPath(d.Path, "")
+		if m.mode != editingView || m.currentNotePath != d.Path {
+			m.notice = "Could not open " + draftLabel(d)
+			m.noticeErr = true
+			return
+		}
+	} else {
+		rootNote := m.currentNode
+		for rootNote.parent != nil {
+			rootNote = rootNote.parent
+		}
+		folder := rootNote
+		if info, err := os.Stat(d.Folder); err == nil && info.IsDir() {
+			if dir := ensureNote(rootNote, d.Folder, true); dir != nil {
+				folder = dir
+			}
+		}
+		m.clearMarks()
+		m.inPinned = false
+		m.currentNode = folder
+		m.sortNotes()
+		m.startNewNote()
+	}
+	m.editor.SetValue(d.Content)
+	m.editor.MarkDirty()
+	m.draftFile = d.file
+	m.notice = "Restored the draft of " + draftLabel(d) + ", save to keep it"
+}
+
+func (m *model) updateDraftRecovery(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := m.recovery
+	switch msg.String() {
+	case "up", "k":
+		if r.cursor > 0 {
+			r.cursor--
+		}
+	case "down", "j":
+		if r.cursor < len(r.drafts)-1 {
+			r.cursor++
+		}
+	case "enter":
+		m.recovery = nil
+		m.restoreDraft(r.drafts[r.cursor])
+	case "d":
+		os.Remove(r.drafts[r.cursor].file)
+		r.drafts = append(r.drafts[:r.cursor], r.drafts[r.cursor+1:]...)
+		r.cursor = min(r.cursor, len(r.drafts)-1)
+		if len(r.drafts) == 0 {
+			m.recovery = nil
+		}
+	case "esc", "q":
+		m.recovery = nil
+	}
+	return m, nil
+}
+
+// draftRecoveryView renders the contents of the draft recovery popup
+func (m model) draftRecoveryView() string {
+	r := m.recovery
+	var content strings.Builder
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
+
+	content.WriteString(lipgloss.NewStyle().Bold(true).Render("Unsaved drafts") + "\n\n")
+	content.WriteString("These edits weren't saved when notes last closed:\n\n")
+	const shown = 10
+	offset := max(0, r.cursor-shown+1)
+	for i := offset; i < min(offset+shown, len(r.drafts)); i++ {
+		d := r.drafts[i]
+		label := truncate(draftLabel(d), max(20, m.width-34), "…") + " · " + d.Saved.Format("Jan 2 15:04")
+		if i == r.cursor {
+			content.WriteString("> " + selectedStyle.Render(label) + "\n")
+		} else {
+			content.WriteString("  " + label + "\n")
+		}
+	}
+	content.WriteString("\n" + helpStyle.Render("Enter: restore | d: discard | Esc: later"))
+	return content.String()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// config.json is checked every configPollInterval while the app runs, and
+// edits made to it elsewhere are applied: colors and keys at once, editor
+// settings to the open editor, and a new notes_path once the notes list is
+// showing. The app's own saves don't count as edits.
+
+const configPollInterval = 2 * time.Second
+
+// configTickMsg is sent every configPollInterval to check the config file
+type configTickMsg struct{}
+
+// configStamp is the modification time and size of the config file when it
+// was last read or written here
+var configStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// stampConfig remembers the config file as it is now
+func stampConfig() {
+	if info, err := os.Stat(getConfigPath()); err == nil {
+		configStamp.modTime, configStamp.size = info.ModTime(), info.Size()
+	}
+}
+
+// configChanged reports whether the config file was written since it was
+// last stamped
+func configChanged() bool {
+	info, err := os.Stat(getConfigPath())
+	if err != nil {
+		return false
+	}
+	return !info.ModTime().Equal(configStamp.modTime) || info.Size() != configStamp.size
+}
+
+func configTick() tea.Cmd {
+	return tea.Tick(configPollInterval, func(time.Time) tea.Msg {
+		return configTickMsg{}
+	})
+}
+
+// applyEditorConfig passes the editor settings of the config on to e
+func applyEditorConfig(e *Editor) {
+	e.SetListContinuation(config.ContinueLists, config.RenumberLists)
+	e.SetIndent(config.IndentWithTabs, config.IndentWidth)
+	e.SetSnippets(config.Snippets)
+	e.SetVimKeys(config.VimMode)
+	e.SetLineNumbers(config.LineNumbers, config.RelativeLineNumbers)
+}
+
+// reloadConfig reads the config file again after it was edited elsewhere.
+// A file that doesn't parse leaves the config as it was.
+func (m *model) reloadConfig() {
+	stampConfig()
+	cfg, _, problems, err := readConfig()
+	if err != nil {
+		m.notice = fmt.Sprintf("Config not reloaded: %v", err)
+		m.noticeErr = true
+		return
+	}
+	config = cfg
+	m.configProblems = problems
+	applyColorConfig()
+	applyEditorConfig(&m.editor)
+	m.sortNotes()
+	m.invalidateTagCache()
+	m.gitRoot = gitRepoRoot(notesPath)
+	m.notice = "Config reloaded"
+	if vaultName == "" && openedPath == "" && config.NotesPath != notesPath {
+		m.reopenPending = true
+	}
+}
+
+// reopenDefaultVault opens the notes at the new notes_path, once the notes
+// list is showing so no edit is cut short, and no sync is running in the
+// notes open
+func (m *model) reopenDefaultVault() {
+	if m.mode != navigationView || m.showRenamePopup || m.showFolderPopup || m.vaultPicker != nil || m.syncing {
+		return
+	}
+	m.reopenPending = false
+	if vaultName != "" || openedPath != "" || config.NotesPath == notesPath {
+		return
+	}
+	prevPath := notesPath
+	notesPath = config.NotesPath
+	if err := makeVaultFolders(); err != nil {
+		notesPath = prevPath
+		m.notice = fmt.Sprintf("Could not open the new notes_path: %v", err)
+		m.noticeErr = true
+		return
+	}
+	m.reopenVault()
+	m.notice = "Opened the notes at " + notesPath
+}
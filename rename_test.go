@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// dirNames returns the names in the notes folder, hidden ones left out
+func dirNames(t *testing.T) []string {
+	t.Helper()
+	entries, err := os.ReadDir(notesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Name()[0] != '.' {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+func TestRenameCaseOnly(t *testing.T) {
+	newTestVault(t)
+	path := writeTestNote(t, "groceries.txt", "milk")
+	// A case-insensitive filesystem finds the note under the new name too,
+	// which a second link to it stands in for while the name is typed
+	link := filepath.Join(notesPath, "Groceries.txt")
+	if err := os.Link(path, link); err != nil {
+		t.Skip("no hard links:", err)
+	}
+	m := newTestModel(t, 80, 24)
+	m.cursor = slices.IndexFunc(m.currentNode.children, func(n *note) bool { return n.title == "groceries" })
+
+	press(m, "r")
+	for range len("groceries") {
+		press(m, "backspace")
+	}
+	typeText(m, "Groceries")
+	if m.isNameTaken {
+		t.Fatal("the note's own name in another case is taken")
+	}
+	if err := os.Remove(link); err != nil {
+		t.Fatal(err)
+	}
+	press(m, "enter")
+	if got := dirNames(t); !slices.Equal(got, []string{"Groceries.txt"}) {
+		t.Errorf("notes folder holds %q after the rename", got)
+	}
+	n := m.currentNode.children[m.cursor]
+	if n.title != "Groceries" || n.path != link {
+		t.Errorf("renamed note is %q at %s", n.title, n.path)
+	}
+	if got := readTestNote(t, "Groceries.txt"); got != "milk" {
+		t.Errorf("renamed note holds %q", got)
+	}
+}
+
+func TestRenameNameTaken(t *testing.T) {
+	newTestVault(t)
+	writeTestNote(t, "groceries.txt", "milk")
+	writeTestNote(t, "Plans.txt", "soon")
+	m := newTestModel(t, 80, 24)
+	m.cursor = slices.IndexFunc(m.currentNode.children, func(n *note) bool { return n.title == "groceries" })
+	press(m, "r")
+	for range len("groceries") {
+		press(m, "backspace")
+	}
+	typeText(m, "Plans")
+	if !m.isNameTaken {
+		t.Fatal("another note's name isn't taken")
+	}
+	press(m, "enter")
+	if got := dirNames(t); !slices.Equal(got, []string{"Plans.txt", "groceries.txt"}) {
+		t.Errorf("notes folder holds %q", got)
+	}
+}
+
+func TestRenamePathCaseOnly(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(old, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := renamePath(old, filepath.Join(dir, "Notes.txt")); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != "Notes.txt" {
+		t.Errorf("folder holds %v, want just Notes.txt", entries)
+	}
+}
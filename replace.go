@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// vaultReplace is a search and replace across every note, confirmed match
+// by match. Notes are written as soon as their last match is dealt with.
+type vaultReplace struct {
+	query       string
+	replacement string
+	field       int // 0 while typing the search text, 1 for the replacement
+	regex       bool
+	status      string // why the replace couldn't start
+
+	confirming   bool
+	notes        []*note // notes with matches, in tree order
+	current      int     // index into notes
+	text         []rune  // content of the current note with the replacements so far
+	pos          int     // where to look for the next match in text
+	match        textMatch
+	changed      bool // text has replacements to write
+	all          bool // replace the remaining matches without asking
+	replaced     int
+	skipped      int
+	notesChanged int
+}
+
+// startVaultReplace collects the notes with matches and shows the first one
+func (m *model) startVaultReplace() {
+	vr := m.vaultReplace
+	if vr.query == "" {
+		return
+	}
+	if _, err := findTextMatches("", vr.query, vr.regex); err != nil {
+		vr.status = "invalid regex"
+		return
+	}
+
+	rootNote := m.currentNode
+	for rootNote.parent != nil {
+		rootNote = rootNote.parent
+	}
+	vr.notes = nil
+	var collect func(n *note)
+	collect = func(n *note) {
+		for _, child := range n.children {
+			if child.isDir {
+				collect(child)
+			} else if matches, _ := findTextMatches(child.content, vr.query, vr.regex); len(matches) > 0 {
+				vr.notes = append(vr.notes, child)
+			}
+		}
+	}
+	collect(rootNote)
+	if len(vr.notes) == 0 {
+		vr.status = "no matches"
+		return
+	}
+
+	vr.confirming = true
+	vr.current = 0
+	vr.loadNote()
+	m.advanceVaultReplace()
+}
+
+// loadNote starts on the current note
+func (vr *vaultReplace) loadNote() {
+	if vr.current < len(vr.notes) {
+		vr.text = []rune(vr.notes[vr.current].content)
+	}
+	vr.pos = 0
+	vr.changed = false
+}
+
+// nextMatch returns the first match in the current note from pos on
+func (vr *vaultReplace) nextMatch() (textMatch, bool) {
+	matches, _ := findTextMatches(string(vr.text), vr.query, vr.regex)
+	for _, match := range matches {
+		if match.start >= vr.pos {
+			return match, true
+		}
+	}
+	return textMatch{}, false
+}
+
+// replaceMatch replaces the current match, continuing after the replacement
+func (vr *vaultReplace) replaceMatch() {
+	matched := string(vr.text[vr.match.start:vr.match.end])
+	replacement := []rune(expandReplacement(vr.query, vr.replacement, matched, vr.regex))
+	vr.text = slices.Concat(vr.text[:vr.match.start], replacement, vr.text[vr.match.end:])
+	vr.pos = vr.match.start + len(replacement)
+	vr.changed = true
+	vr.replaced++
+}
+
+// advanceVaultReplace moves on to the next match to confirm, writing each
+// note once it has no matches left. Finishes after the last note.
+func (m *model) advanceVaultReplace() {
+	vr := m.vaultReplace
+	for vr.current < len(vr.notes) {
+		if match, ok := vr.nextMatch(); ok {
+			vr.match = match
+			if !vr.all {
+				return
+			}
+			vr.replaceMatch()
+			continue
+		}
+		m.writeReplacedNote()
+		vr.current++
+		vr.loadNote()
+	}
+	m.finishVaultReplace()
+}
+
+// writeReplacedNote saves the current note if anything in it was replaced
+func (m *model) writeReplacedNote() {
+	vr := m.vaultReplace
+	if !vr.changed {
+		return
+	}
+	n := vr.notes[vr.current]
+	n.content = string(vr.text)
+	n.tags = noteTags(n.path, n.content)
+	if err := m.writeNote(n); err != nil {
+		log.Printf("Error saving note: %v", err)
+	}
+	m.invalidateTagCache()
+	vr.changed = false
+	vr.notesChanged++
+}
+
+// finishVaultReplace closes the replace and reports what was done
+func (m *model) finishVaultReplace() {
+	vr := m.vaultReplace
+	m.vaultReplace = nil
+	m.notice = fmt.Sprintf("Replaced %d of %d matches in %d notes", vr.replaced, vr.replaced+vr.skipped, vr.notesChanged)
+}
+
+func (m *model) updateVaultReplace(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	vr := m.vaultReplace
+
+	if vr.confirming {
+		switch msg.String() {
+		case "y":
+			vr.replaceMatch()
+			m.advanceVaultReplace()
+		case "n":
+			vr.pos = vr.match.end
+			vr.skipped++
+			m.advanceVaultReplace()
+		case "a":
+			vr.all = true
+			vr.replaceMatch()
+			m.advanceVaultReplace()
+		case "q", "esc":
+			m.writeReplacedNote()
+			m.finishVaultReplace()
+		}
+		return m, nil
+	}
+
+	field := &vr.query
+	if vr.field == 1 {
+		field = &vr.replacement
+	}
+	switch msg.String() {
+	case "esc":
+		m.vaultReplace = nil
+	case "tab":
+		vr.field = 1 - vr.field
+	case "alt+r":
+		vr.regex = !vr.regex
+	case "enter":
+		vr.status = ""
+		m.startVaultReplace()
+	case "backspace":
+		if runes := []rune(*field); len(runes) > 0 {
+			*field = string(runes[:len(runes)-1])
+		}
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			*field += string(msg.Runes)
+		}
+	}
+	return m, nil
+}
+
+// vaultReplaceView renders the contents of the replace popup
+func (m model) vaultReplaceView() string {
+	vr := m.vaultReplace
+	var content strings.Builder
+	lineWidth := max(48, min(80, m.width-14))
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
+
+	title := "Replace in all notes"
+	if vr.regex {
+		title += " (regex)"
+	}
+
+	if !vr.confirming {
+		content.WriteString(lipgloss.NewStyle().Bold(true).Render(title) + "\n\n")
+		query, replacement := vr.query, vr.replacement
+		if vr.field == 0 {
+			query += "█"
+		} else {
+			replacement += "█"
+		}
+		content.WriteString("Find:    " + query + "\n")
+		content.WriteString("Replace: " + replacement + "\n\n")
+		if vr.status != "" {
+			content.WriteString(lipgloss.NewStyle().Foreground(errorColor).Render(vr.status) + "\n\n")
+		}
+		content.WriteString(helpStyle.Render("Enter: start | Tab: switch | Alt+r: regex | Esc: cancel"))
+		return lipgloss.NewStyle().Width(lineWidth).Render(content.String())
+	}
+
+	title += fmt.Sprintf(" - %d replaced, %d skipped", vr.replaced, vr.skipped)
+	content.WriteString(lipgloss.NewStyle().Bold(true).Render(title) + "\n\n")
+	content.WriteString(noteLabel(m.currentNode, vr.notes[vr.current]) + "\n\n")
+
+	// The line holding the match, before and after replacing it
+	text := vr.text
+	lineStart := vr.match.start
+	for lineStart > 0 && text[lineStart-1] != '\n' {
+		lineStart--
+	}
+	lineEnd := vr.match.end
+	for lineEnd < len(text) && text[lineEnd] != '\n' {
+		lineEnd++
+	}
+	before := []rune(strings.TrimLeft(string(text[lineStart:vr.match.start]), " \t"))
+	if keep := lineWidth / 3; len(before) > keep {
+		before = append([]rune("…"), before[len(before)-keep:]...)
+	}
+	matched := string(text[vr.match.start:vr.match.end])
+	replacement := expandReplacement(vr.query, vr.replacement, matched, vr.regex)
+	after := string(text[vr.match.end:lineEnd])
+	visible := func(s string) string { return strings.ReplaceAll(s, "\n", "⏎") }
+
+	removedStyle := lipgloss.NewStyle().Foreground(removedColor).Bold(true)
+	addedStyle := lipgloss.NewStyle().Foreground(addedColor).Bold(true)
+	content.WriteString(truncate("- "+string(before)+removedStyle.Render(visible(matched))+after, lineWidth, "…") + "\n")
+	content.WriteString(truncate("+ "+string(before)+addedStyle.Render(visible(replacement))+after, lineWidth, "…") + "\n\n")
+
+	content.WriteString(helpStyle.Render("y: replace | n: skip | a: replace all | q: stop"))
+	return lipgloss.NewStyle().Width(lineWidth).Render(content.String())
+}
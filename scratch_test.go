@@ -0,0 +1,69 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestScratchFromAnyView(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		keys []string
+		mode viewMode
+	}{
+		{"notes list", nil, navigationView},
+		{"tag browser", []string{"g"}, tagBrowserView},
+		{"trash", []string{"ctrl+t"}, trashView},
+		{"help", []string{"?"}, helpView},
+		{"favorites", []string{"*"}, favoritesView},
+		{"preview", []string{"v"}, previewView},
+		{"config", []string{"c"}, configView},
+		{"editor", []string{"enter"}, editingView},
+	} {
+		newTestVault(t)
+		writeTestNote(t, "Plans.txt", "#soon")
+		m := newTestModel(t, 80, 24)
+		press(m, tc.keys...)
+		if m.mode != tc.mode {
+			t.Fatalf("%s: %q leads to mode %v", tc.name, tc.keys, m.mode)
+		}
+		press(m, "ctrl+@")
+		if m.mode != editingView || m.currentNotePath != filepath.Join(notesPath, "scratch.txt") {
+			t.Errorf("%s: scratch key opens %q in mode %v", tc.name, m.currentNotePath, m.mode)
+		}
+		if got := readTestNote(t, "scratch.txt"); got != "" {
+			t.Errorf("%s: scratch note created holding %q", tc.name, got)
+		}
+	}
+}
+
+func TestScratchExisting(t *testing.T) {
+	newTestVault(t)
+	config.ScratchNote = "Inbox/jot.txt"
+	writeTestNote(t, "Inbox/jot.txt", "remember this")
+	writeTestNote(t, "Plans.txt", "soon")
+	m := newTestModel(t, 80, 24)
+
+	// A note being edited is saved before the scratch note opens
+	m.openNote(m.currentNode.children[slices.IndexFunc(m.currentNode.children, func(n *note) bool { return n.title == "Plans" })])
+	m.editor.SetValue("later")
+	m.editor.MarkDirty()
+	press(m, "ctrl+@")
+	if got := readTestNote(t, "Plans.txt"); got != "later\n" {
+		t.Errorf("the edited note was saved as %q", got)
+	}
+	if m.currentNotePath != filepath.Join(notesPath, "Inbox", "jot.txt") || m.editor.Value() != "remember this" {
+		t.Errorf("scratch key opens %q holding %q", m.currentNotePath, m.editor.Value())
+	}
+	if m.currentNode.title != "Inbox" {
+		t.Errorf("the listing is at %q, want the scratch note's folder", m.currentNode.title)
+	}
+
+	// Pressed again it keeps the scratch note as it is
+	typeText(m, "!")
+	press(m, "ctrl+@")
+	if m.mode != editingView || !m.editor.Dirty() {
+		t.Errorf("scratch key in the scratch note: mode %v, dirty %v", m.mode, m.editor.Dirty())
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// runSearch implements "notes search" and returns the exit code. It looks
+// the query up the way the quick switcher does, in the search index, with
+// the same tag:, folder: and fav: filters, or as a regex with -regex. Like
+// grep it prints path:line:text for each matching line, or only the paths
+// with -l, and exits with 1 when nothing matches.
+func runSearch(root *note, args []string) int {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	regex := flags.Bool("regex", false, "Match the query as a regular expression")
+	filesOnly := flags.Bool("l", false, "Only print the paths of the matching notes")
+	// The query may be split across arguments, with flags among them
+	var words []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return 2
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		words = append(words, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	filters, text := parseSearchFilters(strings.Join(words, " "))
+	if strings.TrimSpace(text) == "" {
+		fmt.Fprintln(os.Stderr, "notes search: give the text to search for")
+		return 2
+	}
+
+	var notes []*note
+	var walk func(n *note)
+	walk = func(n *note) {
+		for _, child := range n.children {
+			if child.isDir {
+				walk(child)
+			} else if filters.match(switcherEntry{note: child, folder: noteFolder(root, child)}) {
+				notes = append(notes, child)
+			}
+		}
+	}
+	walk(root)
+
+	// matchLine picks the lines to print from a matching note
+	var matches []*note
+	var matchLine func(line string) bool
+	if *regex {
+		re, err := searchRegexp(text)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "notes search: %v\n", err)
+			return 2
+		}
+		for _, n := range notes {
+			if re.MatchString(n.content) {
+				matches = append(matches, n)
+			}
+		}
+		matchLine = re.MatchString
+	} else {
+		idx := loadSearchIndex()
+		if idx.sync(root) {
+			saveSearchIndex(idx)
+		}
+		byPath := make(map[string]*note, len(notes))
+		for _, n := range notes {
+			byPath[n.path] = n
+		}
+		for _, path := range idx.search(text) {
+			if n, ok := byPath[path]; ok {
+				matches = append(matches, n)
+			}
+		}
+		queryWords := indexWords(text)
+		matchLine = func(line string) bool {
+			lower := strings.ToLower(line)
+			for _, word := range queryWords {
+				if strings.Contains(lower, word) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].path < matches[j].path })
+
+	for _, n := range matches {
+		if *filesOnly {
+			fmt.Println(n.path)
+			continue
+		}
+		// Number lines as in the file, past the frontmatter
+		offset := 1
+		if file := n.fileContent(); strings.Contains(file, n.content) {
+			offset += strings.Count(file[:strings.Index(file, n.content)], "\n")
+		}
+		for i, line := range strings.Split(n.content, "\n") {
+			if matchLine(line) {
+				fmt.Printf("%s:%d:%s\n", n.path, i+offset, line)
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// session is where the app was left at the last exit, to start there again
+// with reopen_last
+type session struct {
+	Folder  string `json:"folder"`
+	Note    string `json:"note,omitempty"`    // note selected in the folder, or open in the editor
+	Editing bool   `json:"editing,omitempty"` // Note was open in the editor
+}
+
+func getSessionPath() string {
+	return filepath.Join(getStateDir(), "session.json")
+}
+
+func loadSession() (session, bool) {
+	var s session
+	data, err := os.ReadFile(getSessionPath())
+	if err != nil {
+		return s, false
+	}
+	return s, json.Unmarshal(data, &s) == nil && s.Folder != ""
+}
+
+// saveSession remembers the folder shown and the note selected or being
+// edited, for the next start
+func (m *model) saveSession() error {
+	s := session{Folder: m.currentNode.path}
+	switch {
+	case m.mode == editingView && m.currentNotePath != "":
+		s.Note, s.Editing = m.currentNotePath, true
+	case m.mode == navigationView && !m.inPinned && m.cursor < len(m.currentNode.children):
+		s.Note = m.currentNode.children[m.cursor].path
+	}
+	if err := os.MkdirAll(getStateDir(), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(getSessionPath(), data, 0644)
+}
+
+// openStartFolder shows the folder the app starts in: where the last
+// session was left with reopen_last, else start_folder. Folders and notes
+// gone since are skipped.
+func (m *model) openStartFolder() {
+	rootNote := m.currentNode
+	folder, selected, editing := "", "", false
+	if s, ok := loadSession(); ok && config.ReopenLast {
+		folder, selected, editing = s.Folder, s.Note, s.Editing
+	} else if config.StartFolder != "" {
+		folder = filepath.Join(notesPath, config.StartFolder)
+	}
+	if folder == "" {
+		return
+	}
+	dir := findNoteByPath(rootNote, folder)
+	if dir == nil || !dir.isDir {
+		return
+	}
+	if dir != m.currentNode {
+		m.currentNode = dir
+		m.sortNotes()
+	}
+	for i, child := range m.currentNode.children {
+		if child.path != selected {
+			continue
+		}
+		m.cursor = i
+		if editing && !child.isDir {
+			m.openNote(child)
+		}
+		break
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// snippetPlaceholderRegex matches {{name}} placeholders in a snippet body
+var snippetPlaceholderRegex = regexp.MustCompile(`\{\{(\w+)\}\}`)
+
+// snippetStop is a placeholder left in an expanded snippet, as rune offsets
+// into the editor text
+type snippetStop struct {
+	start, end int
+}
+
+// renderSnippet fills in the {{date}} and {{time}} placeholders of body.
+// Other placeholders are replaced by their name and returned as stops, with
+// {{cursor}} (an empty stop) always last. Offsets are relative to the start
+// of the returned text.
+func renderSnippet(body string, now time.Time) (string, []snippetStop) {
+	var text strings.Builder
+	var stops []snippetStop
+	cursor := -1
+	offset := 0 // rune length of text so far
+	last := 0
+
+	for _, match := range snippetPlaceholderRegex.FindAllStringSubmatchIndex(body, -1) {
+		literal := body[last:match[0]]
+		text.WriteString(literal)
+		offset += len([]rune(literal))
+		last = match[1]
+
+		name := body[match[2]:match[3]]
+		var value string
+		switch name {
+		case "date":
+			value = now.Format("2006-01-02")
+		case "time":
+			value = now.Format("15:04")
+		case "cursor":
+			cursor = offset
+			continue
+		default:
+			value = name
+			stops = append(stops, snippetStop{offset, offset + len([]rune(value))})
+		}
+		text.WriteString(value)
+		offset += len([]rune(value))
+	}
+	text.WriteString(body[last:])
+
+	if cursor >= 0 {
+		stops = append(stops, snippetStop{cursor, cursor})
+	}
+	return text.String(), stops
+}
+
+// SetSnippets sets the snippets expanded with Tab, keyed by trigger
+func (e *Editor) SetSnippets(snippets map[string]string) {
+	e.snippets = snippets
+}
+
+// snippetTrigger returns the configured trigger directly before the cursor
+// and the column it starts at
+func (e *Editor) snippetTrigger() (string, int, bool) {
+	if e.cursorRow >= e.lines.len() || len(e.snippets) == 0 {
+		return "", 0, false
+	}
+	line := e.lines.line(e.cursorRow)
+	start := e.cursorCol
+	for start > 0 && !unicode.IsSpace(line[start-1]) {
+		start--
+	}
+	trigger := string(line[start:e.cursorCol])
+	if _, ok := e.snippets[trigger]; !ok || trigger == "" {
+		return "", 0, false
+	}
+	return trigger, start, true
+}
+
+// expandSnippet replaces the trigger before the cursor with its snippet and
+// selects the first placeholder. Returns false if there's no trigger.
+func (e *Editor) expandSnippet() bool {
+	trigger, start, ok := e.snippetTrigger()
+	if !ok {
+		return false
+	}
+
+	line := e.lines.line(e.cursorRow)
+	e.lines.set(e.cursorRow, append(line[:start:start], line[e.cursorCol:]...))
+	e.cursorCol = start
+	e.clearSelection()
+
+	text, stops := renderSnippet(e.snippets[trigger], time.Now())
+	base := e.GetCursor()
+	e.insertText(text)
+	e.MarkDirty()
+
+	e.snippetStops = e.snippetStops[:0]
+	for _, stop := range stops {
+		e.snippetStops = append(e.snippetStops, snippetStop{base + stop.start, base + stop.end})
+	}
+	e.nextSnippetStop()
+	return true
+}
+
+// nextSnippetStop selects the next placeholder of the last expanded snippet,
+// accounting for text typed over the current one. Returns false when there
+// are no placeholders left.
+func (e *Editor) nextSnippetStop() bool {
+	if len(e.snippetStops) == 0 {
+		return false
+	}
+
+	// Whatever was typed over the current placeholder moved the rest
+	delta := 0
+	if e.snippetActive {
+		delta = e.GetCursor() - e.snippetStopEnd
+	}
+	stop := e.snippetStops[0]
+	e.snippetStops = e.snippetStops[1:]
+	for i := range e.snippetStops {
+		e.snippetStops[i].start += delta
+		e.snippetStops[i].end += delta
+	}
+	stop.start += delta
+	stop.end += delta
+
+	e.SetCursor(stop.start)
+	e.selectionAnchor = stop.start
+	e.SetCursor(stop.end)
+	e.hasSelection = stop.end > stop.start
+	e.snippetActive = true
+	e.snippetStopEnd = stop.end
+	return true
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRenderSnippet(t *testing.T) {
+	now := time.Date(2026, 3, 9, 14, 5, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		body, text string
+		stops      []snippetStop
+	}{
+		{"plain", "plain", nil},
+		{"{{date}} {{time}}", "2026-03-09 14:05", nil},
+		{"# {{title}}\n{{cursor}}", "# title\n", []snippetStop{{2, 7}, {8, 8}}},
+		{"{{cursor}}to: {{who}}, {{what}}", "to: who, what", []snippetStop{{4, 7}, {9, 13}, {0, 0}}},
+		{"é {{x}} {{unknown thing}}", "é x {{unknown thing}}", []snippetStop{{2, 3}}},
+	} {
+		text, stops := renderSnippet(tc.body, now)
+		if text != tc.text || !slices.Equal(stops, tc.stops) {
+			t.Errorf("%q renders %q %v, want %q %v", tc.body, text, stops, tc.text, tc.stops)
+		}
+	}
+}
+
+// snippetEditor returns an editor holding text, with the cursor at its end
+func snippetEditor(text string) Editor {
+	e := NewEditor()
+	e.Focus()
+	e.SetSnippets(map[string]string{
+		";sig":  "-- me",
+		";meet": "## {{topic}} on {{date}}\nwith {{who}}\n{{cursor}}",
+	})
+	e.SetValue(text)
+	e.SetCursor(len([]rune(text)))
+	return e
+}
+
+func TestSnippetTrigger(t *testing.T) {
+	for _, tc := range []struct {
+		text    string
+		trigger string
+		start   int
+		ok      bool
+	}{
+		{";sig", ";sig", 0, true},
+		{"thanks ;sig", ";sig", 7, true},
+		{"line\n\t;meet", ";meet", 1, true},
+		{"x;sig", "", 0, false},
+		{";si", "", 0, false},
+		{";sig ", "", 0, false},
+		{"", "", 0, false},
+	} {
+		e := snippetEditor(tc.text)
+		trigger, start, ok := e.snippetTrigger()
+		if trigger != tc.trigger || start != tc.start || ok != tc.ok {
+			t.Errorf("%q: trigger %q at %d, %v, want %q at %d, %v", tc.text, trigger, start, ok, tc.trigger, tc.start, tc.ok)
+		}
+	}
+}
+
+func TestExpandSnippet(t *testing.T) {
+	tab := tea.KeyMsg{Type: tea.KeyTab}
+	e := snippetEditor("thanks ;sig")
+	e.Update(tab)
+	if got := e.Value(); got != "thanks -- me" || e.GetCursor() != 12 {
+		t.Errorf("tab expands to %q with the cursor at %d", got, e.GetCursor())
+	}
+
+	// Placeholders are selected in turn, typed over, and the cursor ends up
+	// at {{cursor}}
+	today := time.Now().Format("2006-01-02")
+	e = snippetEditor("notes\n;meet")
+	e.Update(tab)
+	if got, want := e.Value(), "notes\n## topic on "+today+"\nwith who\n"; got != want {
+		t.Fatalf("tab expands to %q, want %q", got, want)
+	}
+	if got := e.getSelectedText(); got != "topic" {
+		t.Errorf("first placeholder selected is %q", got)
+	}
+	for _, r := range "Budget review" {
+		e.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	e.Update(tab)
+	if got := e.getSelectedText(); got != "who" {
+		t.Errorf("second placeholder selected is %q", got)
+	}
+	for _, r := range "Sam" {
+		e.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	e.Update(tab)
+	if got, want := e.Value(), "notes\n## Budget review on "+today+"\nwith Sam\n"; got != want {
+		t.Errorf("filled in snippet is %q, want %q", got, want)
+	}
+	if got, want := e.GetCursor(), len([]rune(e.Value())); got != want || e.hasSelection {
+		t.Errorf("cursor at %d, selection %v, want at {{cursor}}, %d", got, e.hasSelection, want)
+	}
+
+	// Once the placeholders are done tab indents again
+	e.Update(tab)
+	if got, want := e.Value(), "notes\n## Budget review on "+today+"\nwith Sam\n    "; got != want {
+		t.Errorf("tab after the snippet gives %q, want %q", got, want)
+	}
+
+	// A trigger inside a word isn't one: tab indents
+	e = snippetEditor("x;sig")
+	e.Update(tab)
+	if got, want := e.Value(), "x;sig   "; got != want {
+		t.Errorf("tab after x;sig gives %q, want %q", got, want)
+	}
+}
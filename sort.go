@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// folderSort is the sort order chosen for one folder. Order lists the
+// entries' file names for manual ordering; entries missing from it come
+// last, by name.
+type folderSort struct {
+	Mode  string   `json:"mode"`
+	Order []string `json:"order,omitempty"`
+}
+
+func getFolderSortsPath() string {
+	return filepath.Join(getStateDir(), "folder_sort.json")
+}
+
+// loadFolderSorts reads the sort orders chosen per folder, keyed by folder path
+func loadFolderSorts() map[string]folderSort {
+	sorts := make(map[string]folderSort)
+	data, err := os.ReadFile(getFolderSortsPath())
+	if err != nil {
+		return sorts
+	}
+	_ = json.Unmarshal(data, &sorts)
+	return sorts
+}
+
+func saveFolderSorts(sorts map[string]folderSort) error {
+	if err := os.MkdirAll(filepath.Dir(getFolderSortsPath()), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(sorts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(getFolderSortsPath(), data, 0644)
+}
+
+// folderSortMode returns the sort mode of the folder at path: the one chosen
+// for it, or the default sort_mode
+func folderSortMode(sorts map[string]folderSort, path string) sortMode {
+	if s, ok := sorts[path]; ok && s.Mode != "" {
+		return parseSortMode(s.Mode)
+	}
+	return parseSortMode(config.SortMode)
+}
+
+// sortFolder sorts the listing of dir by the order chosen for it
+func sortFolder(sorts map[string]folderSort, dir *note) {
+	sortChildren(dir.children, folderSortMode(sorts, dir.path), sorts[dir.path].Order)
+}
+
+// noteSize returns the length of a note, or the total length of the notes
+// in a folder
+func noteSize(n *note) int {
+	if !n.isDir {
+		return len(n.content)
+	}
+	size := 0
+	for _, child := range n.children {
+		size += noteSize(child)
+	}
+	return size
+}
+
+// noteCreated returns when the note was created: the date in its "created"
+// frontmatter key, as imported notes have, or else when its file was
+func noteCreated(n *note) time.Time {
+	if created, ok := frontmatterCreated(n.content); ok {
+		return created
+	}
+	info := n.modTime
+	if info == nil {
+		var err error
+		if info, err = os.Stat(n.path); err != nil {
+			return time.Time{}
+		}
+	}
+	return fileCreated(n.path, info)
+}
+
+// sortChildren sorts a folder listing by title, modification time, size or
+// creation time, the latter three largest or newest first, or in the manual
+// order given. Pinned notes come before the rest whatever the mode, and
+// folders before them with dirs_first set.
+func sortChildren(children []*note, mode sortMode, order []string) {
+	var less func(a, b *note) bool
+	switch mode {
+	case sortByDate:
+		less = func(a, b *note) bool { return a.modified().After(b.modified()) }
+	case sortBySize:
+		sizes := make(map[*note]int, len(children))
+		for _, child := range children {
+			sizes[child] = noteSize(child)
+		}
+		less = func(a, b *note) bool { return sizes[a] > sizes[b] }
+	case sortByCreated:
+		created := make(map[*note]time.Time, len(children))
+		for _, child := range children {
+			created[child] = noteCreated(child)
+		}
+		less = func(a, b *note) bool { return created[a].After(created[b]) }
+	case sortManual:
+		position := make(map[string]int, len(order))
+		for i, name := range order {
+			position[name] = i
+		}
+		less = func(a, b *note) bool {
+			i, aKnown := position[filepath.Base(a.path)]
+			j, bKnown := position[filepath.Base(b.path)]
+			if aKnown != bKnown {
+				return aKnown
+			}
+			if aKnown {
+				return i < j
+			}
+			return a.title < b.title
+		}
+	default:
+		less = func(a, b *note) bool { return a.title < b.title }
+	}
+	sort.SliceStable(children, func(i, j int) bool {
+		a, b := children[i], children[j]
+		if config.DirsFirst && a.isDir != b.isDir {
+			return a.isDir
+		}
+		if a.pinned != b.pinned {
+			return a.pinned
+		}
+		return less(a, b)
+	})
+}
+
+// listingOrder returns the file names of the current folder's entries as
+// they're listed
+func (m *model) listingOrder() []string {
+	order := make([]string, len(m.currentNode.children))
+	for i, child := range m.currentNode.children {
+		order[i] = filepath.Base(child.path)
+	}
+	return order
+}
+
+// setFolderSort chooses mode for the current folder and remembers it. The
+// manual order starts out as the listing was.
+func (m *model) setFolderSort(mode sortMode) {
+	if m.folderSorts == nil {
+		m.folderSorts = make(map[string]folderSort)
+	}
+	s := m.folderSorts[m.currentNode.path]
+	s.Mode = sortModeNames[mode]
+	if mode == sortManual && m.sort != sortManual {
+		s.Order = m.listingOrder()
+	}
+	m.folderSorts[m.currentNode.path] = s
+	saveFolderSorts(m.folderSorts)
+	m.sortNotes()
+}
+
+// cycleSort switches the current folder to the next sort mode
+func (m *model) cycleSort() {
+	m.setFolderSort((m.sort + 1) % sortMode(len(sortModeNames)))
+	m.notice = fmt.Sprintf("Sorted by %s", sortModeNames[m.sort])
+	if m.sort == sortManual {
+		m.notice = "Sorted manually"
+	}
+}
+
+// moveInOrder moves the selected entry up (dir -1) or down (dir 1) in the
+// manual order, switching the folder to manual ordering first if need be.
+// Entries don't move past pinned notes, or folders with dirs_first set.
+func (m *model) moveInOrder(dir int) {
+	children := m.currentNode.children
+	j := m.cursor + dir
+	if m.cursor < 0 || m.cursor >= len(children) || j < 0 || j >= len(children) {
+		return
+	}
+	if m.sort != sortManual {
+		m.setFolderSort(sortManual)
+		m.notice = "Sorted manually"
+	}
+	moved := children[m.cursor]
+	children[m.cursor], children[j] = children[j], children[m.cursor]
+	s := m.folderSorts[m.currentNode.path]
+	s.Order = m.listingOrder()
+	m.folderSorts[m.currentNode.path] = s
+	saveFolderSorts(m.folderSorts)
+	m.sortNotes()
+	m.cursor = slices.Index(m.currentNode.children, moved)
+}
+
+// folderSortsMoved carries the sort orders of the folder at oldPath and the
+// folders inside it over to newPath, and the entry's place in its parent's
+// manual order when it keeps the same parent
+func (m *model) folderSortsMoved(oldPath, newPath string) {
+	var moved []string
+	for path := range m.folderSorts {
+		if path == oldPath || strings.HasPrefix(path, oldPath+string(filepath.Separator)) {
+			moved = append(moved, path)
+		}
+	}
+	for _, path := range moved {
+		m.folderSorts[newPath+strings.TrimPrefix(path, oldPath)] = m.folderSorts[path]
+		delete(m.folderSorts, path)
+	}
+	changed := len(moved) > 0
+	if filepath.Dir(oldPath) == filepath.Dir(newPath) {
+		if s, ok := m.folderSorts[filepath.Dir(oldPath)]; ok {
+			if i := slices.Index(s.Order, filepath.Base(oldPath)); i >= 0 {
+				s.Order[i] = filepath.Base(newPath)
+				changed = true
+			}
+		}
+	}
+	if changed {
+		saveFolderSorts(m.folderSorts)
+	}
+}
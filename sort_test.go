@@ -0,0 +1,86 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// listed returns the titles of the current folder's entries in order
+func listed(m *model) []string {
+	var titles []string
+	for _, n := range m.currentNode.children {
+		titles = append(titles, n.title)
+	}
+	return titles
+}
+
+func TestSortModeRestored(t *testing.T) {
+	newTestVault(t)
+	writeTestNote(t, "Apple.txt", "a")
+	writeTestNote(t, "Banana.txt", "banana banana banana")
+	writeTestNote(t, "Cherry.txt", "cherry cherry")
+	writeTestNote(t, "Sub/Zed.txt", "zed zed zed")
+	writeTestNote(t, "Sub/Ant.txt", "a")
+	m := newTestModel(t, 80, 24)
+	if m.sort != sortByName {
+		t.Fatalf("starts sorted by %s, want the default name", sortModeNames[m.sort])
+	}
+
+	press(m, "t", "t")
+	if m.sort != sortBySize {
+		t.Fatalf("t t sorts by %s, want size", sortModeNames[m.sort])
+	}
+	bySize := listed(m)
+	if want := []string{"Banana", "Cherry", "Sub", "Apple"}; !slices.Equal(bySize, want) {
+		t.Errorf("sorted by size: %q, want %q", bySize, want)
+	}
+
+	// The next session sorts the folder the same way, and other folders
+	// the default way
+	m = newTestModel(t, 80, 24)
+	if m.sort != sortBySize || !slices.Equal(listed(m), bySize) {
+		t.Errorf("restarted sorted by %s: %q, want by size: %q", sortModeNames[m.sort], listed(m), bySize)
+	}
+	m.cursor = slices.Index(listed(m), "Sub")
+	press(m, "enter")
+	if m.currentNode.title != "Sub" {
+		t.Fatalf("entered %q, want Sub", m.currentNode.title)
+	}
+	if m.sort != sortByName || !slices.Equal(listed(m), []string{"Ant", "Zed"}) {
+		t.Errorf("another folder sorted by %s: %q", sortModeNames[m.sort], listed(m))
+	}
+
+	// The sort_mode option is the default for folders not sorted with t
+	config.SortMode = "size"
+	m = newTestModel(t, 80, 24)
+	m.cursor = slices.Index(listed(m), "Sub")
+	press(m, "enter")
+	if m.sort != sortBySize || !slices.Equal(listed(m), []string{"Zed", "Ant"}) {
+		t.Errorf("with sort_mode size, another folder sorted by %s: %q", sortModeNames[m.sort], listed(m))
+	}
+}
+
+func TestManualOrderRestored(t *testing.T) {
+	newTestVault(t)
+	writeTestNote(t, "Apple.txt", "a")
+	writeTestNote(t, "Banana.txt", "b")
+	writeTestNote(t, "Cherry.txt", "c")
+	m := newTestModel(t, 80, 24)
+	m.cursor = 2
+	press(m, "K", "K")
+	want := []string{"Cherry", "Apple", "Banana"}
+	if m.sort != sortManual || !slices.Equal(listed(m), want) {
+		t.Fatalf("moved Cherry up twice: sorted by %s, %q, want manually %q", sortModeNames[m.sort], listed(m), want)
+	}
+
+	m = newTestModel(t, 80, 24)
+	if m.sort != sortManual || !slices.Equal(listed(m), want) {
+		t.Errorf("restarted sorted by %s: %q, want manually %q", sortModeNames[m.sort], listed(m), want)
+	}
+	// A note added since goes last
+	writeTestNote(t, "Aardvark.txt", "a")
+	m = newTestModel(t, 80, 24)
+	if got := listed(m); !slices.Equal(got, append(want, "Aardvark")) {
+		t.Errorf("with a new note: %q", got)
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// spellChecker checks words against a word list and the words added to the
+// personal dictionary
+type spellChecker struct {
+	words map[string]bool
+}
+
+func getPersonalDictionaryPath() string {
+	return filepath.Join(getConfigDir(), "dictionary.txt")
+}
+
+// loadSpellChecker reads the word list at path, one word per line, along
+// with the personal dictionary
+func loadSpellChecker(path string) (*spellChecker, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sc := &spellChecker{words: make(map[string]bool)}
+	sc.addWords(string(data))
+	if personal, err := os.ReadFile(getPersonalDictionaryPath()); err == nil {
+		sc.addWords(string(personal))
+	}
+	return sc, nil
+}
+
+func (sc *spellChecker) addWords(text string) {
+	for _, line := range strings.Split(text, "\n") {
+		if word := strings.TrimSpace(line); word != "" {
+			sc.words[word] = true
+		}
+	}
+}
+
+// add accepts word from now on and saves it to the personal dictionary
+func (sc *spellChecker) add(word string) error {
+	sc.words[word] = true
+	path := getPersonalDictionaryPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(word + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// correct reports whether word is in the dictionary. A capitalized word is
+// also accepted in lowercase, and a possessive 's is ignored.
+func (sc *spellChecker) correct(word string) bool {
+	word = strings.TrimSuffix(strings.TrimSuffix(word, "'s"), "’s")
+	return sc.words[word] || sc.words[strings.ToLower(word)]
+}
+
+// isWordApostrophe reports whether line[i] is an apostrophe inside a word,
+// as in "don't"
+func isWordApostrophe(line []rune, i int) bool {
+	return (line[i] == '\'' || line[i] == '’') && i > 0 && i+1 < len(line) &&
+		unicode.IsLetter(line[i-1]) && unicode.IsLetter(line[i+1])
+}
+
+// spellWords returns the rune ranges of the words in line worth checking.
+// Tags, mentions, paths, link schemes, words with digits, all-caps
+// abbreviations and single letters are left out.
+func spellWords(line []rune) [][2]int {
+	var words [][2]int
+	for i := 0; i < len(line); {
+		if !unicode.IsLetter(line[i]) && !unicode.IsDigit(line[i]) {
+			i++
+			continue
+		}
+		start := i
+		digits, upper := false, 0
+		for i < len(line) && (unicode.IsLetter(line[i]) || unicode.IsMark(line[i]) ||
+			unicode.IsDigit(line[i]) || isWordApostrophe(line, i)) {
+			if unicode.IsDigit(line[i]) {
+				digits = true
+			} else if unicode.IsUpper(line[i]) {
+				upper++
+			}
+			i++
+		}
+		switch {
+		case i-start < 2 || digits || upper == i-start:
+		case start > 0 && strings.ContainsRune("#@/\\.", line[start-1]):
+		case strings.HasPrefix(string(line[i:]), "://"):
+		default:
+			words = append(words, [2]int{start, i})
+		}
+	}
+	return words
+}
+
+// misspelled returns the rune ranges of the misspelled words in line
+func (sc *spellChecker) misspelled(line []rune) [][2]int {
+	var ranges [][2]int
+	for _, word := range spellWords(line) {
+		if !sc.correct(string(line[word[0]:word[1]])) {
+			ranges = append(ranges, word)
+		}
+	}
+	return ranges
+}
+
+// editDistance returns the number of insertions, deletions, substitutions
+// and swaps of neighbouring letters turning a into b, or limit+1 once it's
+// known to be over limit
+func editDistance(a, b []rune, limit int) int {
+	if len(a)-len(b) > limit || len(b)-len(a) > limit {
+		return limit + 1
+	}
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+// suggest returns up to n dictionary words at most two edits from word,
+// closest first, capitalized like word
+func (sc *spellChecker) suggest(word string, n int) []string {
+	lower := []rune(strings.ToLower(word))
+	type candidate struct {
+		word string
+		dist int
+	}
+	seen := make(map[string]bool)
+	var found []candidate
+	for w := range sc.words {
+		lw := strings.ToLower(w)
+		if seen[lw] {
+			continue
+		}
+		if dist := editDistance(lower, []rune(lw), 2); dist <= 2 {
+			seen[lw] = true
+			found = append(found, candidate{w, dist})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].dist != found[j].dist {
+			return found[i].dist < found[j].dist
+		}
+		return found[i].word < found[j].word
+	})
+
+	capitalized := unicode.IsUpper([]rune(word)[0])
+	var suggestions []string
+	for _, c := range found[:min(n, len(found))] {
+		s := c.word
+		if capitalized {
+			r := []rune(s)
+			r[0] = unicode.ToUpper(r[0])
+			s = string(r)
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions
+}
+
+// SetSpellChecker underlines the words sc doesn't know, or stops checking
+// when sc is nil
+func (e *Editor) SetSpellChecker(sc *spellChecker) {
+	e.spell = sc
+}
+
+// SpellChecking reports whether misspelled words are underlined
+func (e *Editor) SpellChecking() bool {
+	return e.spell != nil
+}
+
+// misspelledColumns returns the misspelled words of a line. The word being
+// typed at the cursor isn't flagged until the cursor leaves it.
+func (e *Editor) misspelledColumns(row int) [][2]int {
+	ranges := e.spell.misspelled(e.lines.line(row))
+	if row == e.cursorRow {
+		for i, r := range ranges {
+			if r[1] == e.cursorCol {
+				ranges = append(ranges[:i], ranges[i+1:]...)
+				break
+			}
+		}
+	}
+	return ranges
+}
+
+// WordAtCursor returns the word the cursor is on or just after, as rune
+// offsets into the note, or ok false when there's none
+func (e *Editor) WordAtCursor() (start, end int, ok bool) {
+	lineStart := e.GetCursor() - e.cursorCol
+	for _, word := range spellWords(e.lines.line(e.cursorRow)) {
+		if word[0] <= e.cursorCol && e.cursorCol <= word[1] {
+			return lineStart + word[0], lineStart + word[1], true
+		}
+	}
+	return 0, 0, false
+}
+
+// ReplaceRange replaces the text between the rune offsets start and end,
+// leaving the cursor after the new text
+func (e *Editor) ReplaceRange(start, end int, text string) {
+	e.selectionAnchor = start
+	e.SetCursor(end)
+	e.hasSelection = true
+	e.deleteSelection()
+	e.insertText(text)
+	e.MarkDirty()
+}
+
+// spellSuggest is the popup offering corrections for the word at the cursor
+type spellSuggest struct {
+	word        string
+	start, end  int // rune offsets of the word in the note
+	suggestions []string
+	cursor      int
+}
+
+// toggleSpellCheck turns spell checking on or off for this session. The
+// dictionary is read the first time it's turned on.
+func (m *model) toggleSpellCheck() {
+	if m.editor.SpellChecking() {
+		m.editor.SetSpellChecker(nil)
+		m.notice = "Spell checking off"
+		return
+	}
+	if m.spellChecker == nil {
+		sc, err := loadSpellChecker(config.SpellDictionary)
+		if err != nil {
+			m.notice = fmt.Sprintf("Can't read the dictionary: %v", err)
+			m.noticeErr = true
+			return
+		}
+		m.spellChecker = sc
+	}
+	m.editor.SetSpellChecker(m.spellChecker)
+	m.notice = "Spell checking on"
+}
+
+// openSpellSuggest offers corrections for the word at the cursor
+func (m *model) openSpellSuggest() {
+	if !m.editor.SpellChecking() {
+		m.toggleSpellCheck()
+		if !m.editor.SpellChecking() {
+			return
+		}
+	}
+	start, end, ok := m.editor.WordAtCursor()
+	if !ok {
+		m.notice = "No word at the cursor"
+		return
+	}
+	word := m.editor.textRange(start, end)
+	if m.spellChecker.correct(word) {
+		m.notice = fmt.Sprintf("%q is spelled correctly", word)
+		return
+	}
+	m.spellSuggest = &spellSuggest{
+		word:        word,
+		start:       start,
+		end:         end,
+		suggestions: m.spellChecker.suggest(word, 8),
+	}
+}
+
+func (m *model) updateSpellSuggest(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	ss := m.spellSuggest
+	switch msg.String() {
+	case "up", "ctrl+p":
+		if ss.cursor > 0 {
+			ss.cursor--
+		}
+	case "down", "ctrl+n":
+		if ss.cursor < len(ss.suggestions)-1 {
+			ss.cursor++
+		}
+	case "enter":
+		if len(ss.suggestions) > 0 {
+			m.editor.ReplaceRange(ss.start, ss.end, ss.suggestions[ss.cursor])
+		}
+		m.spellSuggest = nil
+	case "+":
+		if err := m.spellChecker.add(ss.word); err != nil {
+			m.notice = fmt.Sprintf("Couldn't save the dictionary: %v", err)
+			m.noticeErr = true
+		} else {
+			m.notice = fmt.Sprintf("Added %q to the dictionary", ss.word)
+		}
+		m.spellSuggest = nil
+	case "esc":
+		m.spellSuggest = nil
+	}
+	return m, nil
+}
+
+// spellSuggestView renders the contents of the suggestions popup
+func (m model) spellSuggestView() string {
+	ss := m.spellSuggest
+	var content strings.Builder
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
+
+	content.WriteString(lipgloss.NewStyle().Bold(true).Render("Spelling: "+ss.word) + "\n\n")
+	if len(ss.suggestions) == 0 {
+		content.WriteString("No suggestions\n")
+	}
+	for i, s := range ss.suggestions {
+		if i == ss.cursor {
+			content.WriteString("> " + selectedStyle.Render(s) + "\n")
+		} else {
+			content.WriteString("  " + s + "\n")
+		}
+	}
+	content.WriteString("\n" + helpStyle.Render("Enter: replace | +: add to dictionary | Esc: cancel"))
+	return content.String()
+}
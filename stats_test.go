@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComputeNoteStats(t *testing.T) {
+	for _, tc := range []struct {
+		content string
+		want    noteStats
+		over    bool
+		shown   string
+	}{
+		{"one two three", noteStats{words: 3, chars: 13}, false, "3 words, 13 chars"},
+		{"---\nlimit: 10\n---\nhéllo wörld", noteStats{words: 2, chars: 11, charLimit: 10}, true, "2 words, 11/10 chars"},
+		{"---\nlimit: 11\n---\nhéllo wörld", noteStats{words: 2, chars: 11, charLimit: 11}, false, "2 words, 11/11 chars"},
+		{"---\nword_limit: 2\n---\na b c", noteStats{words: 3, chars: 5, wordLimit: 2}, true, "3/2 words, 5 chars"},
+		{"---\nword_limit: 3\nlimit: 100\n---\na b c", noteStats{words: 3, chars: 5, charLimit: 100, wordLimit: 3}, false, "3/3 words, 5/100 chars"},
+		{"---\nlimit: none\nword_limit: -4\n---\na", noteStats{words: 1, chars: 1}, false, "1 words, 1 chars"},
+	} {
+		got := computeNoteStats(tc.content)
+		if got != tc.want {
+			t.Errorf("%q: stats %+v, want %+v", tc.content, got, tc.want)
+		}
+		if got.overLimit() != tc.over {
+			t.Errorf("%q: over limit %v, want %v", tc.content, got.overLimit(), tc.over)
+		}
+		if got.String() != tc.shown {
+			t.Errorf("%q: shown as %q, want %q", tc.content, got.String(), tc.shown)
+		}
+	}
+}
+
+func TestOverLimitIndicator(t *testing.T) {
+	newTestVault(t)
+	withColors(t)
+	writeTestNote(t, "Draft.txt", "---\nword_limit: 3\n---\none two three")
+	m := newTestModel(t, 140, 20)
+	m.openNote(m.currentNode.children[0])
+
+	s := render(m)
+	if row, _ := s.find("3/3 words"); row < 0 {
+		t.Fatalf("counts not shown:\n%s", strings.Join(s.rows, "\n"))
+	}
+	if row, _ := s.find("[OVER LIMIT]"); row >= 0 {
+		t.Error("a note at its limit is shown over it")
+	}
+	within := s.raw[len(s.raw)-1]
+
+	m.editor.SetCursor(m.editor.length())
+	typeText(m, " four")
+	s = render(m)
+	if row, _ := s.find("[OVER LIMIT]"); row != 0 {
+		t.Errorf("the title doesn't show the note over its limit:\n%s", strings.Join(s.rows, "\n"))
+	}
+	over := statusStyle.Foreground(errorColor).Bold(true).Render("4/3 words, 18 chars")
+	if status := s.raw[len(s.raw)-1]; !strings.Contains(status, over) {
+		t.Errorf("counts over the limit aren't in the error style: %q", status)
+	}
+	if strings.Contains(within, statusStyle.Foreground(errorColor).Bold(true).Render("3/3 words, 13 chars")) {
+		t.Error("counts within the limit are in the error style")
+	}
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// switcherEntry is a note listed in the quick switcher with the label it is
+// matched against: its folder path relative to the notes root and its title
+type switcherEntry struct {
+	note    *note
+	label   string
+	folder  string // folder path relative to the notes root, "" at the root
+	score   int
+	context string   // line of the note matching a content search, or the alias matched
+	aliases []string // other names from the note's frontmatter
+}
+
+// fuzzyScore reports whether every rune of query appears in target in
+// order, ignoring case, and scores the match. Consecutive runes and runes
+// starting a word score higher, so "mn" ranks "Meeting notes" above
+// "Commands".
+func fuzzyScore(query, target string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	if len(q) == 0 {
+		return 0, true
+	}
+	t := []rune(strings.ToLower(target))
+
+	score, qi, last := 0, 0, -1
+	for ti, r := range t {
+		if r != q[qi] {
+			continue
+		}
+		score++
+		if ti == last+1 {
+			score += 3
+		}
+		if ti == 0 || !unicode.IsLetter(t[ti-1]) && !unicode.IsDigit(t[ti-1]) {
+			score += 2
+		}
+		last = ti
+		qi++
+		if qi == len(q) {
+			// Prefer shorter labels among equal matches
+			return score*100 - len(t), true
+		}
+	}
+	return 0, false
+}
+
+// noteFolder returns the folder of n relative to the notes root, or "" for
+// notes at the root. node may be any note in the tree.
+func noteFolder(node, n *note) string {
+	for node.parent != nil {
+		node = node.parent
+	}
+	if rel, err := filepath.Rel(node.path, filepath.Dir(n.path)); err == nil && rel != "." {
+		return filepath.ToSlash(rel)
+	}
+	return ""
+}
+
+// noteLabel returns n's title prefixed with its folder path, as notes from
+// the whole tree are listed
+func noteLabel(node, n *note) string {
+	if folder := noteFolder(node, n); folder != "" {
+		return folder + "/" + n.title
+	}
+	return n.title
+}
+
+// canOpenSwitcher reports whether the quick switcher may open, which it
+// can't while a popup or the tag picker has the keyboard
+func (m *model) canOpenSwitcher() bool {
+	if m.showRenamePopup || m.showFolderPopup || m.showJumpTagPopup || m.showTagPicker ||
+		m.showEmptyNotePopup || m.showDiffPanel || m.markPending != "" {
+		return false
+	}
+	return m.mode == navigationView || m.mode == editingView || m.mode == tagBrowserView
+}
+
+// openSwitcher shows the quick switcher listing every note in the tree, or
+// only the recently opened notes when recent is set
+func (m *model) openSwitcher(recent bool) {
+	rootNote := m.currentNode
+	for rootNote.parent != nil {
+		rootNote = rootNote.parent
+	}
+
+	m.switcherNotes = m.switcherNotes[:0]
+	var collect func(n *note)
+	collect = func(n *note) {
+		for _, child := range n.children {
+			if child.isDir {
+				collect(child)
+				continue
+			}
+			m.switcherNotes = append(m.switcherNotes, switcherEntry{
+				note:    child,
+				label:   noteLabel(rootNote, child),
+				folder:  noteFolder(rootNote, child),
+				aliases: noteAliases(child.content),
+			})
+		}
+	}
+	collect(rootNote)
+
+	if recent {
+		// Most recent first, leaving out the note being edited and notes
+		// that no longer exist
+		byPath := make(map[string]switcherEntry, len(m.switcherNotes))
+		for _, entry := range m.switcherNotes {
+			byPath[entry.note.path] = entry
+		}
+		m.switcherNotes = m.switcherNotes[:0]
+		for _, path := range m.recentNotes {
+			if entry, ok := byPath[path]; ok && !(m.mode == editingView && path == m.currentNotePath) {
+				m.switcherNotes = append(m.switcherNotes, entry)
+			}
+		}
+	}
+
+	m.showSwitcher = true
+	m.switcherRecent = recent
+	m.switcherInput = ""
+	m.filterSwitcher()
+}
+
+// searchFilters are the structured filters of a search: tag:name,
+// -tag:name, folder:path and fav:true or fav:false. A note must pass all
+// of them.
+type searchFilters struct {
+	tags        []string
+	excludeTags []string // tags the note mustn't carry
+	folders     []string
+	favorite    bool
+	favoriteSet bool
+}
+
+// parseSearchFilters takes the filters out of a search, returning them and
+// the remaining free text. A filter with no value yet, like a "tag:" still
+// being typed, is dropped.
+func parseSearchFilters(input string) (searchFilters, string) {
+	var filters searchFilters
+	var rest []string
+	found := false
+	for _, field := range strings.Fields(input) {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok {
+			rest = append(rest, field)
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "tag":
+			if value = strings.TrimPrefix(value, "#"); value != "" {
+				filters.tags = append(filters.tags, value)
+			}
+		case "-tag":
+			if value = strings.TrimPrefix(value, "#"); value != "" {
+				filters.excludeTags = append(filters.excludeTags, value)
+			}
+		case "folder":
+			if value != "" {
+				filters.folders = append(filters.folders, strings.ToLower(value))
+			}
+		case "fav":
+			switch strings.ToLower(value) {
+			case "true", "yes", "t", "1":
+				filters.favorite, filters.favoriteSet = true, true
+			case "false", "no", "f", "0":
+				filters.favorite, filters.favoriteSet = false, true
+			}
+		default:
+			rest = append(rest, field)
+			continue
+		}
+		found = true
+	}
+	if !found {
+		return filters, input
+	}
+	return filters, strings.Join(rest, " ")
+}
+
+// match reports whether the note of entry passes every filter
+func (f searchFilters) match(entry switcherEntry) bool {
+	if f.favoriteSet && entry.note.favorite != f.favorite {
+		return false
+	}
+	hasTag := func(tag string) bool {
+		return slices.ContainsFunc(entry.note.tags, func(t string) bool {
+			return strings.EqualFold(t, tag) || strings.HasPrefix(strings.ToLower(t), strings.ToLower(tag)+"/")
+		})
+	}
+	for _, tag := range f.tags {
+		if !hasTag(tag) {
+			return false
+		}
+	}
+	if slices.ContainsFunc(f.excludeTags, hasTag) {
+		return false
+	}
+	for _, folder := range f.folders {
+		if !strings.Contains(strings.ToLower(entry.folder), folder) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterSwitcher matches the switcher input against the note labels, then
+// lists the notes containing its words. With no input every note is listed,
+// most recently modified first. In regex mode the input is matched against
+// labels and note contents instead. Filters in the input narrow down the
+// notes searched.
+func (m *model) filterSwitcher() {
+	m.switcherMatches = m.switcherMatches[:0]
+	m.switcherInvalid = false
+
+	filters, text := parseSearchFilters(m.switcherInput)
+	candidates := make([]switcherEntry, 0, len(m.switcherNotes))
+	for _, entry := range m.switcherNotes {
+		if filters.match(entry) {
+			candidates = append(candidates, entry)
+		}
+	}
+
+	if m.switcherRegex && text != "" {
+		m.filterSwitcherRegex(candidates, text)
+	} else {
+		for _, entry := range candidates {
+			score, ok := fuzzyScore(text, entry.label)
+			for _, alias := range entry.aliases {
+				if aliasScore, aliasOk := fuzzyScore(text, alias); aliasOk && (!ok || aliasScore > score) {
+					score, ok = aliasScore, true
+					entry.context = "alias: " + alias
+				}
+			}
+			if ok {
+				entry.score = score
+				m.switcherMatches = append(m.switcherMatches, entry)
+			}
+		}
+	}
+	if text == "" && !m.switcherRecent {
+		sort.SliceStable(m.switcherMatches, func(i, j int) bool {
+			return m.switcherMatches[i].note.modified().After(m.switcherMatches[j].note.modified())
+		})
+	} else {
+		sort.SliceStable(m.switcherMatches, func(i, j int) bool {
+			return m.switcherMatches[i].score > m.switcherMatches[j].score
+		})
+		if !m.switcherRegex {
+			m.addContentMatches(candidates, text)
+		}
+	}
+	m.switcherCursor = 0
+	m.switcherOffset = 0
+}
+
+// addContentMatches appends the candidates whose content has every word of
+// text, looked up in the search index, after the title matches
+func (m *model) addContentMatches(candidates []switcherEntry, text string) {
+	words := indexWords(text)
+	if len(words) == 0 {
+		return
+	}
+	listed := make(map[*note]bool, len(m.switcherMatches))
+	for _, entry := range m.switcherMatches {
+		listed[entry.note] = true
+	}
+	byPath := make(map[string]switcherEntry, len(candidates))
+	for _, entry := range candidates {
+		byPath[entry.note.path] = entry
+	}
+
+	for _, path := range m.searchIndex.search(text) {
+		entry, ok := byPath[path]
+		if !ok || listed[entry.note] {
+			continue
+		}
+		for _, line := range strings.Split(entry.note.content, "\n") {
+			if strings.Contains(strings.ToLower(line), words[0]) {
+				entry.context = strings.TrimSpace(line)
+				break
+			}
+		}
+		m.switcherMatches = append(m.switcherMatches, entry)
+	}
+}
+
+// filterSwitcherRegex lists the candidates whose label or content matches
+// pattern, label matches first
+func (m *model) filterSwitcherRegex(candidates []switcherEntry, pattern string) {
+	re, err := searchRegexp(pattern)
+	if err != nil {
+		m.switcherInvalid = true
+		return
+	}
+	for _, entry := range candidates {
+		switch {
+		case re.MatchString(entry.label):
+			entry.score = 2
+		case re.MatchString(entry.note.content):
+			loc := re.FindStringIndex(entry.note.content)
+			lineStart := strings.LastIndex(entry.note.content[:loc[0]], "\n") + 1
+			line, _, _ := strings.Cut(entry.note.content[lineStart:], "\n")
+			entry.score = 1
+			entry.context = strings.TrimSpace(line)
+		default:
+			continue
+		}
+		m.switcherMatches = append(m.switcherMatches, entry)
+	}
+}
+
+// switcherRows is how many matches the switcher popup shows at once
+func (m model) switcherRows() int {
+	return max(3, min(10, m.height-12))
+}
+
+func (m *model) updateSwitcher(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.showSwitcher = false
+	case "enter":
+		m.showSwitcher = false
+		if m.switcherCursor < len(m.switcherMatches) {
+			target := m.switcherMatches[m.switcherCursor].note
+			if m.mode == editingView && m.currentNotePath == target.path {
+				return m, nil
+			}
+			if !m.leaveEditor() {
+				return m, nil
+			}
+			m.chipFocus = false
+			m.openNote(target)
+			m.fitEditorHeight()
+		}
+	case "up", "ctrl+p":
+		if m.switcherCursor > 0 {
+			m.switcherCursor--
+		}
+	case "down", "ctrl+n":
+		if m.switcherCursor < len(m.switcherMatches)-1 {
+			m.switcherCursor++
+		}
+	case "alt+r":
+		m.switcherRegex = !m.switcherRegex
+		m.filterSwitcher()
+	case "backspace":
+		if input := []rune(m.switcherInput); len(input) > 0 {
+			m.switcherInput = string(input[:len(input)-1])
+			m.filterSwitcher()
+		}
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			m.switcherInput += string(msg.Runes)
+			m.filterSwitcher()
+		}
+	}
+
+	// Keep the cursor within the visible rows
+	rows := m.switcherRows()
+	if m.switcherCursor < m.switcherOffset {
+		m.switcherOffset = m.switcherCursor
+	} else if m.switcherCursor >= m.switcherOffset+rows {
+		m.switcherOffset = m.switcherCursor - rows + 1
+	}
+	return m, nil
+}
+
+// switcherView renders the contents of the quick switcher popup
+func (m model) switcherView() string {
+	var content strings.Builder
+
+	title := "Go to note"
+	if m.switcherRecent {
+		title = "Recent notes"
+	}
+	if m.switcherRegex {
+		title = "Search notes (regex)"
+	}
+	content.WriteString(lipgloss.NewStyle().Bold(true).Render(title) + "\n\n")
+	content.WriteString("> " + m.switcherInput + "█\n")
+	if m.switcherInput == "" {
+		content.WriteString(lipgloss.NewStyle().Faint(true).Render("  filters: tag:name -tag:name folder:path fav:true") + "\n")
+	}
+	content.WriteString("\n")
+
+	lineWidth := max(48, min(72, m.width-14))
+	switch {
+	case m.switcherInvalid:
+		content.WriteString("  Invalid regex\n")
+	case len(m.switcherMatches) == 0:
+		content.WriteString("  No matching notes\n")
+	}
+	contextStyle := lipgloss.NewStyle().Faint(true)
+	end := min(m.switcherOffset+m.switcherRows(), len(m.switcherMatches))
+	for i := m.switcherOffset; i < end; i++ {
+		label := m.switcherMatches[i].label
+		if context := m.switcherMatches[i].context; context != "" {
+			label += contextStyle.Render(" — " + context)
+		}
+		label = truncate(label, lineWidth-2, "…")
+		if i == m.switcherCursor {
+			content.WriteString("> " + selectedStyle.Render(label) + "\n")
+		} else {
+			content.WriteString("  " + label + "\n")
+		}
+	}
+	content.WriteString("\n")
+
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
+	content.WriteString(helpStyle.Render("Enter: open | ↑/↓: select | Alt+r: regex | Esc: cancel"))
+
+	// A fixed width keeps the popup from resizing while typing
+	return lipgloss.NewStyle().Width(lineWidth).Render(content.String())
+}
@@ -0,0 +1,781 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Sync keeps the notes folder and a folder on a WebDAV server, such as
+// Nextcloud, the same. Each sync compares both sides with how they were left
+// by the last one, recorded in sync.json in the state folder: files changed
+// on one side are copied to the other, and files deleted on one side and
+// unchanged on the other are deleted too. A note changed on both sides keeps
+// the local version, with the server's saved next to it as a "(conflicted
+// copy)". The .trash, .history and .git folders aren't synced.
+
+// syncedFile is a file as the last sync left it on both sides
+type syncedFile struct {
+	Size    int64     `json:"size"`     // local size
+	ModTime time.Time `json:"mod_time"` // local modification time
+	ETag    string    `json:"etag"`     // the server's version
+}
+
+// syncState is what the last sync left behind
+type syncState struct {
+	LastSync time.Time             `json:"last_sync"`
+	Files    map[string]syncedFile `json:"files"` // by slash-separated path relative to the notes folder
+}
+
+func getSyncStatePath() string {
+	return filepath.Join(getStateDir(), "sync.json")
+}
+
+func loadSyncState(path string) syncState {
+	s := syncState{Files: make(map[string]syncedFile)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s
+	}
+	_ = json.Unmarshal(data, &s)
+	if s.Files == nil {
+		s.Files = make(map[string]syncedFile)
+	}
+	return s
+}
+
+func saveSyncState(path string, s syncState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// syncTarget is what a sync works on. It's taken from the open vault when
+// the sync starts, as the sync goes on in the background.
+type syncTarget struct {
+	root      string // the notes folder
+	statePath string // sync.json
+	url       string // the folder on the server
+	ownFolder bool   // url is the vault's own folder under sync_url, made on its first sync
+	user      string
+	password  string
+}
+
+// openSyncTarget returns the sync target of the open vault
+func openSyncTarget() (syncTarget, error) {
+	rawURL, err := getSyncURL()
+	if err != nil {
+		return syncTarget{}, err
+	}
+	return syncTarget{
+		root:      notesPath,
+		statePath: getSyncStatePath(),
+		url:       rawURL,
+		ownFolder: vaultName != "",
+		user:      config.SyncUser,
+		password:  syncPassword(),
+	}, nil
+}
+
+// getSyncURL returns the folder on the server the open vault syncs with:
+// sync_url itself for the default vault, or a folder named after the vault
+// in it, so that vaults don't sync over each other
+func getSyncURL() (string, error) {
+	switch {
+	case config.SyncURL == "":
+		return "", errors.New("sync_url isn't set in " + getConfigPath())
+	case openedPath != "":
+		return "", errors.New("only vaults are synced, not folders opened with -path")
+	case vaultName != "":
+		return strings.TrimSuffix(config.SyncURL, "/") + "/" + url.PathEscape(vaultName) + "/", nil
+	}
+	return config.SyncURL, nil
+}
+
+// syncPassword returns the password for the server, from the
+// NOTES_SYNC_PASSWORD environment variable when it's set so that it needn't
+// be kept in the config
+func syncPassword() string {
+	if password, ok := os.LookupEnv("NOTES_SYNC_PASSWORD"); ok {
+		return password
+	}
+	return config.SyncPassword
+}
+
+// davClient talks to the folder on a WebDAV server the notes are synced
+// with. Paths are slash-separated and relative to that folder.
+type davClient struct {
+	base     *url.URL // ends with a slash
+	user     string
+	password string
+	client   *http.Client
+}
+
+// davFile is a file listed on the server
+type davFile struct {
+	etag string
+}
+
+func newDavClient(rawURL, user, password string) (*davClient, error) {
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if base.Scheme != "http" && base.Scheme != "https" || base.Host == "" {
+		return nil, fmt.Errorf("%q isn't an http or https URL", rawURL)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	base.RawPath = ""
+	return &davClient{base: base, user: user, password: password, client: &http.Client{Timeout: time.Minute}}, nil
+}
+
+// url returns the URL of the file or folder at rel
+func (c *davClient) url(rel string) string {
+	u := *c.base
+	u.Path += rel
+	return u.String()
+}
+
+// do sends a request for rel and fails unless the server answers with one
+// of the ok statuses, or any 2xx status when none are given
+func (c *davClient) do(method, rel string, body []byte, header http.Header, ok ...int) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, c.url(rel), reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if c.user != "" || c.password != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	accepted := resp.StatusCode >= 200 && resp.StatusCode < 300
+	if len(ok) > 0 {
+		accepted = false
+		for _, status := range ok {
+			accepted = accepted || resp.StatusCode == status
+		}
+	}
+	if !accepted {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusUnauthorized {
+			return nil, errors.New("the server refused sync_user and the password")
+		}
+		return nil, fmt.Errorf("%s /%s: %s", method, rel, resp.Status)
+	}
+	return resp, nil
+}
+
+// davPropfind asks for what a listing needs to know of each entry
+const davPropfind = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:getetag/><d:getlastmodified/><d:resourcetype/></d:prop></d:propfind>`
+
+// davMultistatus is the answer to a PROPFIND, matched by local names so the
+// namespace prefix the server picks doesn't matter
+type davMultistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				ETag         string `xml:"getetag"`
+				LastModified string `xml:"getlastmodified"`
+				ResourceType struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// list returns every file under the folder, and the folders found, skipping
+// the folders that aren't synced. The folders are listed one level at a
+// time, as servers often refuse listing everything at once.
+func (c *davClient) list() (map[string]davFile, map[string]bool, error) {
+	files := make(map[string]davFile)
+	dirs := map[string]bool{"": true}
+	pending := []string{""}
+	for len(pending) > 0 {
+		dir := pending[0]
+		pending = pending[1:]
+		resp, err := c.do("PROPFIND", dir, []byte(davPropfind), http.Header{
+			"Depth":        {"1"},
+			"Content-Type": {"application/xml; charset=utf-8"},
+		}, http.StatusMultiStatus)
+		if err != nil {
+			return nil, nil, err
+		}
+		var ms davMultistatus
+		err = xml.NewDecoder(resp.Body).Decode(&ms)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("PROPFIND /%s: %w", dir, err)
+		}
+		for _, r := range ms.Responses {
+			href, err := url.Parse(r.Href)
+			if err != nil {
+				continue
+			}
+			rel, ok := strings.CutPrefix(href.Path, c.base.Path)
+			if !ok {
+				continue
+			}
+			isDir := strings.HasSuffix(rel, "/")
+			rel = strings.Trim(rel, "/")
+			if rel == strings.Trim(dir, "/") || syncSkipped(rel) {
+				continue
+			}
+			var etag, modified string
+			for _, ps := range r.Propstat {
+				if !strings.Contains(ps.Status, " 200") {
+					continue
+				}
+				isDir = isDir || ps.Prop.ResourceType.Collection != nil
+				etag, modified = ps.Prop.ETag, ps.Prop.LastModified
+			}
+			if isDir {
+				dirs[rel] = true
+				pending = append(pending, rel+"/")
+				continue
+			}
+			// Without an ETag, the modification time tells versions apart
+			if etag == "" {
+				etag = modified
+			}
+			files[rel] = davFile{etag: etag}
+		}
+	}
+	return files, dirs, nil
+}
+
+// get downloads the file at rel, with its ETag
+func (c *davClient) get(rel string) ([]byte, string, error) {
+	resp, err := c.do(http.MethodGet, rel, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return data, resp.Header.Get("ETag"), err
+}
+
+// put uploads data to rel and returns the ETag of the new version, or ""
+// when the server doesn't tell
+func (c *davClient) put(rel string, data []byte) (string, error) {
+	resp, err := c.do(http.MethodPut, rel, data, nil)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+// remove deletes the file at rel, which may be gone already
+func (c *davClient) remove(rel string) error {
+	resp, err := c.do(http.MethodDelete, rel, nil, nil, http.StatusOK, http.StatusNoContent, http.StatusNotFound)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// mkdirAll creates the folder at rel and those it's in, unless they're in
+// dirs, the folders known to be there
+func (c *davClient) mkdirAll(rel string, dirs map[string]bool) error {
+	if rel == "." || rel == "" || dirs[rel] {
+		return nil
+	}
+	if err := c.mkdirAll(path.Dir(rel), dirs); err != nil {
+		return err
+	}
+	if err := c.mkcol(rel + "/"); err != nil {
+		return err
+	}
+	dirs[rel] = true
+	return nil
+}
+
+// mkcol creates the folder at rel, which may be there already
+func (c *davClient) mkcol(rel string) error {
+	// 405 is the answer when the folder is there already
+	resp, err := c.do("MKCOL", rel, nil, nil, http.StatusCreated, http.StatusMethodNotAllowed)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// syncSkipped reports whether the file or folder at rel, relative to the
+// notes folder, is left out of syncing
+func syncSkipped(rel string) bool {
+	top, _, _ := strings.Cut(rel, "/")
+	return top == ".trash" || top == ".history" || top == ".git"
+}
+
+// localSyncFiles returns every file in the notes folder root that's synced,
+// by slash-separated path
+func localSyncFiles(root string) (map[string]fs.FileInfo, error) {
+	files := make(map[string]fs.FileInfo)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if syncSkipped(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil // folders come with their files, symlinks aren't synced
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files[rel] = info
+		return nil
+	})
+	return files, err
+}
+
+// syncResult is what a sync did
+type syncResult struct {
+	uploaded, downloaded, deleted, conflicts int
+	trashed                                  map[string]string // notes deleted on the server, by where they are in the trash, to where they were
+	when                                     time.Time
+}
+
+// changedLocal reports whether the sync changed the notes folder
+func (r syncResult) changedLocal() bool {
+	return r.downloaded > 0 || r.conflicts > 0 || len(r.trashed) > 0
+}
+
+func (r syncResult) String() string {
+	var parts []string
+	for _, part := range []struct {
+		n    int
+		what string
+	}{
+		{r.uploaded, "uploaded"},
+		{r.downloaded, "downloaded"},
+		{r.deleted + len(r.trashed), "deleted"},
+	} {
+		if part.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", part.n, part.what))
+		}
+	}
+	if r.conflicts > 0 {
+		parts = append(parts, plural(r.conflicts, "conflict"))
+	}
+	if len(parts) == 0 {
+		return "Synced, nothing changed"
+	}
+	return "Synced: " + strings.Join(parts, ", ")
+}
+
+// syncAction is what a sync does with a file
+type syncAction int
+
+const (
+	syncUpload syncAction = iota
+	syncDownload
+	syncMerge // changed on both sides, or there on both at the first sync
+	syncDeleteRemote
+	syncDeleteLocal
+)
+
+// syncVault syncs the notes folder of target with the server, calling
+// progress after each file that needed work. Files synced before an error
+// are recorded, so the next sync goes on from there.
+func syncVault(target syncTarget, progress func(done, total int)) (syncResult, error) {
+	result := syncResult{trashed: make(map[string]string)}
+	c, err := newDavClient(target.url, target.user, target.password)
+	if err != nil {
+		return result, err
+	}
+	if target.ownFolder {
+		if err := c.mkcol(""); err != nil {
+			return result, err
+		}
+	}
+	local, err := localSyncFiles(target.root)
+	if err != nil {
+		return result, err
+	}
+	remote, dirs, err := c.list()
+	if err != nil {
+		return result, err
+	}
+	state := loadSyncState(target.statePath)
+	localFile := func(rel string) string {
+		return filepath.Join(target.root, filepath.FromSlash(rel))
+	}
+
+	type task struct {
+		rel    string
+		action syncAction
+	}
+	var tasks []task
+	seen := make(map[string]bool)
+	var rels []string
+	for _, names := range []map[string]bool{keysOf(local), keysOf(remote), keysOf(state.Files)} {
+		for rel := range names {
+			if !seen[rel] {
+				seen[rel] = true
+				rels = append(rels, rel)
+			}
+		}
+	}
+	sort.Strings(rels)
+	for _, rel := range rels {
+		l, inLocal := local[rel]
+		r, inRemote := remote[rel]
+		base, known := state.Files[rel]
+		localChanged := inLocal && (!known || l.Size() != base.Size || !l.ModTime().Equal(base.ModTime))
+		remoteChanged := inRemote && (!known || r.etag != base.ETag)
+		switch {
+		case inLocal && inRemote && localChanged && remoteChanged:
+			tasks = append(tasks, task{rel, syncMerge})
+		case inLocal && inRemote && localChanged:
+			tasks = append(tasks, task{rel, syncUpload})
+		case inLocal && inRemote && remoteChanged:
+			tasks = append(tasks, task{rel, syncDownload})
+		case inLocal && !inRemote && known && !localChanged:
+			tasks = append(tasks, task{rel, syncDeleteLocal})
+		case inLocal && !inRemote:
+			tasks = append(tasks, task{rel, syncUpload})
+		case inRemote && !inLocal && known && !remoteChanged:
+			tasks = append(tasks, task{rel, syncDeleteRemote})
+		case inRemote && !inLocal:
+			tasks = append(tasks, task{rel, syncDownload})
+		case !inLocal && !inRemote:
+			delete(state.Files, rel)
+		}
+	}
+
+	// record notes the file at rel as it now is on both sides
+	record := func(rel, etag string) error {
+		info, err := os.Stat(localFile(rel))
+		if err != nil {
+			return err
+		}
+		state.Files[rel] = syncedFile{Size: info.Size(), ModTime: info.ModTime(), ETag: etag}
+		return nil
+	}
+	upload := func(rel string, data []byte) error {
+		if err := c.mkdirAll(path.Dir(rel), dirs); err != nil {
+			return err
+		}
+		etag, err := c.put(rel, data)
+		if err != nil {
+			return err
+		}
+		return record(rel, etag)
+	}
+	// download writes data to rel, unless it's there already
+	download := func(rel string, data []byte, etag string) error {
+		dest := localFile(rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		if current, err := os.ReadFile(dest); err != nil || !bytes.Equal(current, data) {
+			if err := os.WriteFile(dest, data, 0644); err != nil {
+				return err
+			}
+			result.downloaded++
+		}
+		return record(rel, etag)
+	}
+
+	for i, t := range tasks {
+		localPath := localFile(t.rel)
+		var err error
+		switch t.action {
+		case syncUpload:
+			var data []byte
+			if data, err = os.ReadFile(localPath); err == nil {
+				err = upload(t.rel, data)
+				result.uploaded++
+			}
+		case syncDownload:
+			var data []byte
+			var etag string
+			if data, etag, err = c.get(t.rel); err == nil {
+				err = download(t.rel, data, cmp.Or(etag, remote[t.rel].etag))
+			}
+		case syncMerge:
+			err = syncMergeFile(c, target.root, t.rel, remote[t.rel].etag, upload, record, &result)
+		case syncDeleteRemote:
+			if err = c.remove(t.rel); err == nil {
+				delete(state.Files, t.rel)
+				result.deleted++
+			}
+		case syncDeleteLocal:
+			// Into the trash, like notes deleted in the app
+			dest := trashDest(filepath.Join(target.root, ".trash"), localPath)
+			if err = os.Rename(localPath, dest); err == nil {
+				delete(state.Files, t.rel)
+				result.trashed[dest] = localPath
+			}
+		}
+		if err != nil {
+			return result, syncFinish(target, state, err)
+		}
+		if progress != nil {
+			progress(i+1, len(tasks))
+		}
+	}
+	result.when = time.Now()
+	state.LastSync = result.when
+	return result, syncFinish(target, state, nil)
+}
+
+// syncFinish saves how far the sync got and returns err, or the error
+// saving it
+func syncFinish(target syncTarget, state syncState, err error) error {
+	if saveErr := saveSyncState(target.statePath, state); err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+// syncMergeFile syncs the file at rel, changed both locally and on the
+// server. Unless they're the same, the server's version is kept next to the
+// local one as a conflicted copy, and the local one replaces it.
+func syncMergeFile(c *davClient, root, rel, listedETag string, upload func(string, []byte) error, record func(string, string) error, result *syncResult) error {
+	theirs, etag, err := c.get(rel)
+	if err != nil {
+		return err
+	}
+	localPath := filepath.Join(root, filepath.FromSlash(rel))
+	mine, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(mine, theirs) {
+		return record(rel, cmp.Or(etag, listedETag))
+	}
+	copied := copyPath(localPath, "conflicted copy")
+	if err := os.WriteFile(copied, theirs, 0644); err != nil {
+		return err
+	}
+	copiedRel, _ := filepath.Rel(root, copied)
+	if err := upload(filepath.ToSlash(copiedRel), theirs); err != nil {
+		return err
+	}
+	result.conflicts++
+	if err := upload(rel, mine); err != nil {
+		return err
+	}
+	result.uploaded++
+	return nil
+}
+
+// keysOf returns the keys of m as a set
+func keysOf[V any](m map[string]V) map[string]bool {
+	keys := make(map[string]bool, len(m))
+	for key := range m {
+		keys[key] = true
+	}
+	return keys
+}
+
+// recordTrashed adds the notes a sync moved to the trash to the trash info,
+// so they can be restored to where they were
+func recordTrashed(info map[string]trashEntry, trashed map[string]string) {
+	for dest, from := range trashed {
+		info[filepath.Base(dest)] = trashEntry{Trashed: time.Now(), From: from}
+	}
+}
+
+// syncProgressMsg reports how many of the files needing work a sync has
+// done, and waits for the next report
+type syncProgressMsg struct {
+	done, total int
+	updates     chan tea.Msg
+}
+
+// syncDoneMsg ends a sync
+type syncDoneMsg struct {
+	result syncResult
+	err    error
+}
+
+// syncCmd syncs target in the background, reporting progress until it's
+// done
+func syncCmd(target syncTarget) tea.Cmd {
+	updates := make(chan tea.Msg, 1)
+	go func() {
+		result, err := syncVault(target, func(done, total int) {
+			updates <- syncProgressMsg{done: done, total: total, updates: updates}
+		})
+		updates <- syncDoneMsg{result: result, err: err}
+	}()
+	return waitSync(updates)
+}
+
+func waitSync(updates chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-updates
+	}
+}
+
+// startSync starts a sync unless one is running or it isn't set up
+func (m *model) startSync() tea.Cmd {
+	if m.syncing {
+		m.notice = "Already syncing"
+		return nil
+	}
+	target, err := openSyncTarget()
+	if err != nil {
+		m.notice = fmt.Sprintf("Can't sync: %v", err)
+		m.noticeErr = true
+		return nil
+	}
+	m.syncing, m.syncDone, m.syncTotal = true, 0, 0
+	m.notice = "Syncing…"
+	return syncCmd(target)
+}
+
+// finishSync shows how the sync went, and lists the notes it changed once
+// the notes list is showing
+func (m *model) finishSync(msg syncDoneMsg) {
+	m.syncing = false
+	if len(msg.result.trashed) > 0 {
+		if m.trashInfo == nil {
+			m.trashInfo = make(map[string]trashEntry)
+		}
+		recordTrashed(m.trashInfo, msg.result.trashed)
+		saveTrashInfo(m.trashInfo)
+	}
+	m.syncReload = m.syncReload || msg.result.changedLocal()
+	if msg.err != nil {
+		m.notice = fmt.Sprintf("Sync failed: %v", msg.err)
+		m.noticeErr = true
+	} else {
+		m.lastSync = msg.result.when
+		m.notice = msg.result.String()
+	}
+	m.reloadSynced()
+}
+
+// reloadSynced reads the notes folder again after a sync changed it, in
+// the same folder and on the same entry. Anywhere but the notes list it
+// waits, so nothing being edited is swept away.
+func (m *model) reloadSynced() {
+	if !m.syncReload || m.mode != navigationView || m.showRenamePopup || m.showFolderPopup {
+		return
+	}
+	m.syncReload = false
+	var selected string
+	if !m.inPinned && m.cursor >= 0 && m.cursor < len(m.currentNode.children) {
+		selected = m.currentNode.children[m.cursor].path
+	}
+	root := loadNotes(notesPath)
+	folder := findNoteByPath(root, m.currentNode.path)
+	if folder == nil || !folder.isDir {
+		folder = root
+	}
+	m.currentNode = folder
+	m.trashNode = loadNotes(filepath.Join(notesPath, ".trash"))
+	m.sortNotes()
+	m.cursor = 0
+	for i, child := range folder.children {
+		if child.path == selected {
+			m.cursor = i
+		}
+	}
+	m.invalidateTagCache()
+	m.syncSearchIndex()
+}
+
+// syncStatus is shown in the status bar of the notes list: the progress of
+// a sync, or when the last one finished
+func (m model) syncStatus() string {
+	switch {
+	case m.syncing && m.syncTotal > 0:
+		return fmt.Sprintf("Syncing %d/%d", m.syncDone, m.syncTotal)
+	case m.syncing:
+		return "Syncing"
+	case m.lastSync.IsZero():
+		return ""
+	case m.lastSync.Format("2006-01-02") == time.Now().Format("2006-01-02"):
+		return "Synced " + m.lastSync.Format("15:04")
+	}
+	return "Synced " + m.lastSync.Format("Jan 2 15:04")
+}
+
+// runSync implements "notes sync" and returns the exit code
+func runSync(args []string) int {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	quiet := flags.Bool("q", false, "Don't print progress")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	printed := false
+	progress := func(done, total int) {
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "\rSyncing %d/%d", done, total)
+			printed = true
+		}
+	}
+	target, err := openSyncTarget()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "notes sync: %v\n", err)
+		return 1
+	}
+	result, err := syncVault(target, progress)
+	if printed {
+		fmt.Fprintln(os.Stderr)
+	}
+	if len(result.trashed) > 0 {
+		info := loadTrashInfo()
+		recordTrashed(info, result.trashed)
+		saveTrashInfo(info)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "notes sync: %v\n", err)
+		return 1
+	}
+	fmt.Println(result)
+	return 0
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDav is a WebDAV server keeping its files in memory, under /dav/
+type fakeDav struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	etags map[string]int
+	dirs  map[string]bool
+	next  int
+}
+
+func newFakeDav(t *testing.T) (*fakeDav, string) {
+	dav := &fakeDav{files: map[string][]byte{}, etags: map[string]int{}, dirs: map[string]bool{"": true}}
+	srv := httptest.NewServer(dav)
+	t.Cleanup(srv.Close)
+	return dav, srv.URL + "/dav/"
+}
+
+func (f *fakeDav) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if user, password, _ := r.BasicAuth(); user != "me" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	rel := strings.Trim(strings.TrimPrefix(r.URL.Path, "/dav/"), "/")
+	switch r.Method {
+	case "PROPFIND":
+		if !f.dirs[rel] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:">`)
+		entry := func(href, props string) {
+			fmt.Fprintf(w, `<d:response><d:href>%s</d:href><d:propstat><d:prop>%s</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`,
+				strings.ReplaceAll("/dav/"+href, " ", "%20"), props)
+		}
+		entry(rel+"/", `<d:resourcetype><d:collection/></d:resourcetype>`)
+		for dir := range f.dirs {
+			if dir != "" && path.Dir(dir) == cmpDot(rel) {
+				entry(dir+"/", `<d:resourcetype><d:collection/></d:resourcetype>`)
+			}
+		}
+		for name := range f.files {
+			if path.Dir(name) == cmpDot(rel) {
+				entry(name, fmt.Sprintf(`<d:getetag>"%d"</d:getetag><d:resourcetype/>`, f.etags[name]))
+			}
+		}
+		fmt.Fprint(w, `</d:multistatus>`)
+	case http.MethodGet:
+		data, ok := f.files[rel]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, f.etags[rel]))
+		w.Write(data)
+	case http.MethodPut:
+		if !f.dirs[strings.TrimSuffix(path.Dir(rel), ".")] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		f.put(rel, data)
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, f.etags[rel]))
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		delete(f.files, rel)
+		w.WriteHeader(http.StatusNoContent)
+	case "MKCOL":
+		if f.dirs[rel] {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !f.dirs[strings.TrimSuffix(path.Dir(rel), ".")] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.dirs[rel] = true
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// cmpDot returns the folder rel as path.Dir names it, "." for the top
+func cmpDot(rel string) string {
+	if rel == "" {
+		return "."
+	}
+	return rel
+}
+
+// put stores a new version of the file at rel, as if another device
+// uploaded it
+func (f *fakeDav) put(rel string, data []byte) {
+	f.next++
+	f.files[rel] = data
+	f.etags[rel] = f.next
+}
+
+func (f *fakeDav) set(rel, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.put(rel, []byte(content))
+}
+
+func (f *fakeDav) content(rel string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return string(f.files[rel])
+}
+
+func (f *fakeDav) names() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var names []string
+	for name := range f.files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func localNames(t *testing.T, root string) []string {
+	t.Helper()
+	files, err := localSyncFiles(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// newSyncVault sets up an empty vault syncing with a fake server at the
+// folder notes
+func newSyncVault(t *testing.T) *fakeDav {
+	newTestVault(t)
+	dav, base := newFakeDav(t)
+	dav.dirs["notes"] = true
+	config.SyncURL = base + "notes"
+	config.SyncUser = "me"
+	t.Setenv("NOTES_SYNC_PASSWORD", "secret")
+	return dav
+}
+
+func runTestSync(t *testing.T) syncResult {
+	t.Helper()
+	target, err := openSyncTarget()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := syncVault(target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestSyncVault(t *testing.T) {
+	dav := newSyncVault(t)
+	writeTestNote(t, "same.txt", "same")
+	writeTestNote(t, "Work/plan one.txt", "plan")
+	writeTestNote(t, ".history/plan one/1.txt", "old")
+	dav.set("notes/same.txt", "same")
+	dav.set("notes/phone.txt", "from the phone")
+
+	// The first sync brings both sides together
+	if got := runTestSync(t).String(); got != "Synced: 1 uploaded, 1 downloaded" {
+		t.Errorf("first sync: %s", got)
+	}
+	want := []string{"notes/Work/plan one.txt", "notes/phone.txt", "notes/same.txt"}
+	if got := dav.names(); !slices.Equal(got, want) {
+		t.Errorf("server has %v, want %v", got, want)
+	}
+	if got := readTestNote(t, "phone.txt"); got != "from the phone" {
+		t.Errorf("downloaded %q", got)
+	}
+	if got := runTestSync(t).String(); got != "Synced, nothing changed" {
+		t.Errorf("second sync: %s", got)
+	}
+
+	// Changes and deletions on either side go to the other
+	time.Sleep(10 * time.Millisecond)
+	writeTestNote(t, "same.txt", "edited here")
+	dav.set("notes/phone.txt", "edited on the phone")
+	os.Remove(filepath.Join(notesPath, "Work", "plan one.txt"))
+	result := runTestSync(t)
+	if got := result.String(); got != "Synced: 1 uploaded, 1 downloaded, 1 deleted" {
+		t.Errorf("third sync: %s", got)
+	}
+	if got := dav.content("notes/same.txt"); got != "edited here" {
+		t.Errorf("local edit not uploaded: %q", got)
+	}
+	if got := readTestNote(t, "phone.txt"); got != "edited on the phone" {
+		t.Errorf("server's edit not downloaded: %q", got)
+	}
+	if slices.Contains(dav.names(), "notes/Work/plan one.txt") {
+		t.Error("note deleted here is still on the server")
+	}
+
+	// A note deleted on the server goes to the trash here
+	dav.mu.Lock()
+	delete(dav.files, "notes/phone.txt")
+	dav.mu.Unlock()
+	result = runTestSync(t)
+	if _, err := os.Stat(filepath.Join(notesPath, "phone.txt")); !os.IsNotExist(err) {
+		t.Error("note deleted on the server is still here")
+	}
+	if len(result.trashed) != 1 {
+		t.Fatalf("trashed %v", result.trashed)
+	}
+	for dest, from := range result.trashed {
+		if filepath.Dir(dest) != filepath.Join(notesPath, ".trash") || from != filepath.Join(notesPath, "phone.txt") {
+			t.Errorf("trashed %s to %s", from, dest)
+		}
+	}
+
+	// Changed on both sides: ours wins, theirs is kept as a conflicted copy
+	time.Sleep(10 * time.Millisecond)
+	writeTestNote(t, "same.txt", "mine")
+	dav.set("notes/same.txt", "theirs")
+	if got := runTestSync(t).String(); got != "Synced: 1 uploaded, 1 conflict" {
+		t.Errorf("conflicting sync: %s", got)
+	}
+	if got := readTestNote(t, "same (conflicted copy).txt"); got != "theirs" {
+		t.Errorf("conflicted copy holds %q", got)
+	}
+	if got := dav.content("notes/same (conflicted copy).txt"); got != "theirs" {
+		t.Errorf("conflicted copy on the server holds %q", got)
+	}
+	if got := readTestNote(t, "same.txt"); got != "mine" || dav.content("notes/same.txt") != "mine" {
+		t.Errorf("local version not kept: %q", got)
+	}
+	if got := localNames(t, notesPath); !slices.Equal(got, []string{"same (conflicted copy).txt", "same.txt"}) {
+		t.Errorf("notes folder has %v", got)
+	}
+}
+
+func TestSyncVaultWrongPassword(t *testing.T) {
+	newSyncVault(t)
+	t.Setenv("NOTES_SYNC_PASSWORD", "wrong")
+	target, _ := openSyncTarget()
+	if _, err := syncVault(target, nil); err == nil || !strings.Contains(err.Error(), "refused") {
+		t.Errorf("got %v, want the password refused", err)
+	}
+}
+
+func TestSyncVaultMakesVaultFolder(t *testing.T) {
+	dav := newSyncVault(t)
+	config.Vaults = map[string]string{"work": t.TempDir()}
+	if err := selectVault("work"); err != nil {
+		t.Fatal(err)
+	}
+	if err := makeVaultFolders(); err != nil {
+		t.Fatal(err)
+	}
+	writeTestNote(t, "todo.txt", "work")
+
+	runTestSync(t)
+	if got := dav.names(); !slices.Equal(got, []string{"notes/work/todo.txt"}) {
+		t.Errorf("server has %v", got)
+	}
+	// The folder being there already is fine
+	if got := runTestSync(t).String(); got != "Synced, nothing changed" {
+		t.Errorf("second sync: %s", got)
+	}
+}
+
+func TestSyncKeepsToVaultItStartedIn(t *testing.T) {
+	dav := newSyncVault(t)
+	defaultNotes := notesPath
+	dav.set("notes/phone.txt", "from the phone")
+	target, err := openSyncTarget()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another vault opens before the sync gets going
+	config.Vaults = map[string]string{"work": t.TempDir()}
+	if err := selectVault("work"); err != nil {
+		t.Fatal(err)
+	}
+	if err := makeVaultFolders(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := syncVault(target, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(defaultNotes, "phone.txt")); err != nil {
+		t.Errorf("not downloaded to the vault the sync started in: %v", err)
+	}
+	if got := localNames(t, notesPath); len(got) != 0 {
+		t.Errorf("downloaded into the vault opened since: %v", got)
+	}
+	if _, err := os.Stat(getSyncStatePath()); !os.IsNotExist(err) {
+		t.Error("the sync state went to the vault opened since")
+	}
+	if len(loadSyncState(target.statePath).Files) != 1 {
+		t.Error("the sync state of the vault the sync started in wasn't saved")
+	}
+}
+
+func TestVaultsStayWhileSyncing(t *testing.T) {
+	newSyncVault(t)
+	config.Vaults = map[string]string{"work": t.TempDir()}
+	m := newTestModel(t, 80, 24)
+	m.syncing = true
+	defaultNotes := notesPath
+
+	m.switchVault("work")
+	if notesPath != defaultNotes || vaultName != "" {
+		t.Errorf("switched to %s while syncing", notesPath)
+	}
+
+	config.NotesPath = t.TempDir()
+	m.reopenPending = true
+	m.reopenDefaultVault()
+	if notesPath != defaultNotes || !m.reopenPending {
+		t.Errorf("reopened notes_path while syncing, or forgot to")
+	}
+	m.syncing = false
+	m.reopenDefaultVault()
+	if notesPath != config.NotesPath {
+		t.Errorf("notes_path not reopened once the sync was done")
+	}
+}
+
+func TestSyncModelReloadsChangedNotes(t *testing.T) {
+	dav := newSyncVault(t)
+	writeTestNote(t, "here.txt", "here")
+	dav.set("notes/phone.txt", "from the phone")
+	m := newTestModel(t, 80, 24)
+
+	cmd := m.startSync()
+	for cmd != nil {
+		_, cmd = m.Update(cmd())
+	}
+	if m.syncing || m.lastSync.IsZero() {
+		t.Fatalf("sync not finished: %s", m.notice)
+	}
+	if findNoteByPath(m.currentNode, filepath.Join(notesPath, "phone.txt")) == nil {
+		t.Error("downloaded note isn't listed")
+	}
+	m.notice = ""
+	if row, _ := render(m).find("Synced " + m.lastSync.Format("15:04")); row < 0 {
+		t.Error("status bar doesn't show the last sync")
+	}
+}
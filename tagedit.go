@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tagEdit is the popup confirming that a tag is to be removed from every
+// note, or asking which tag to merge it into
+type tagEdit struct {
+	kind     string  // "delete" or "merge"
+	tag      string  // the tag removed or merged, with the tags nested under it
+	notes    []*note // notes carrying it
+	input    string  // tag to merge into, typed so far
+	errorMsg string
+}
+
+// replacedTag returns tag renamed from from to to, keeping what's nested
+// under from, or "" when to is "". ok is false when tag isn't from or
+// nested under it.
+func replacedTag(tag, from, to string) (string, bool) {
+	if !tagMatches(tag, from) {
+		return tag, false
+	}
+	if to == "" {
+		return "", true
+	}
+	return to + tag[len(from):], true
+}
+
+// replaceTagList renames or removes from in a header tag list, dropping any
+// duplicate a merge leaves
+func replaceTagList(tags []string, from, to string) ([]string, bool) {
+	var out []string
+	changed := false
+	for _, tag := range tags {
+		tag, ok := replacedTag(tag, from, to)
+		changed = changed || ok
+		if tag != "" && !slices.Contains(out, tag) {
+			out = append(out, tag)
+		}
+	}
+	return out, changed
+}
+
+// replaceInlineTags renames or removes the inline #tags matching from.
+// Lines left blank by a removal are dropped.
+func replaceInlineTags(body, from, to string) string {
+	lines := strings.Split(body, "\n")
+	out := lines[:0]
+	for _, line := range lines {
+		replaced := tagRegex.ReplaceAllStringFunc(line, func(match string) string {
+			sub := tagRegex.FindStringSubmatch(match)
+			tag, ok := replacedTag(sub[2], from, to)
+			switch {
+			case !ok:
+				return match
+			case tag == "":
+				return ""
+			}
+			return sub[1] + "#" + tag
+		})
+		if replaced != line && to == "" {
+			if strings.TrimSpace(replaced) == "" {
+				continue
+			}
+			replaced = strings.TrimLeft(replaced, " \t")
+			if indent := len(line) - len(strings.TrimLeft(line, " \t")); indent > 0 {
+				replaced = line[:indent] + replaced
+			}
+		}
+		out = append(out, replaced)
+	}
+	return strings.Join(out, "\n")
+}
+
+// openTagEdit opens the delete or merge popup for the selected tag in the
+// tag browser
+func (m *model) openTagEdit(kind string) {
+	rows := m.tagTreeRows()
+	if m.showingTaggedNotes() || m.addingFilter || m.cursor >= len(rows) {
+		return
+	}
+	te := &tagEdit{kind: kind, tag: rows[m.cursor]}
+	findNotesByTags(m.tagBrowserRoot(), []string{te.tag}, nil, true, &te.notes)
+	m.tagEdit = te
+}
+
+// replaceTagInNotes renames the tag of the popup to to, or removes it when
+// to is "", rewriting the files of the notes carrying it
+func (m *model) replaceTagInNotes(to string) {
+	te := m.tagEdit
+	m.tagEdit = nil
+	count := 0
+	for _, n := range te.notes {
+		content := parserFor(n.path).ReplaceTag(n.content, te.tag, to)
+		if content == n.content {
+			continue
+		}
+		n.content = content
+		n.tags = noteTags(n.path, n.content)
+		if err := m.writeNote(n); err != nil {
+			log.Printf("Could not update note: %v", err)
+			continue
+		}
+		count++
+	}
+
+	m.invalidateTagCache()
+	m.loadTagBrowserTags()
+	m.cursor = min(m.cursor, max(0, len(m.tagTreeRows())-1))
+	if to == "" {
+		m.notice = fmt.Sprintf("Removed #%s from %s", te.tag, plural(count, "note"))
+	} else {
+		m.rememberTags(to)
+		m.notice = fmt.Sprintf("Merged #%s into #%s in %s", te.tag, to, plural(count, "note"))
+	}
+}
+
+func (m *model) updateTagEdit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	te := m.tagEdit
+	if te.kind == "delete" {
+		switch msg.String() {
+		case "y", "enter":
+			m.replaceTagInNotes("")
+		case "n", "esc", "q":
+			m.tagEdit = nil
+		}
+		return m, nil
+	}
+
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.tagEdit = nil
+	case tea.KeyEnter:
+		to := strings.TrimPrefix(strings.TrimSpace(te.input), "#")
+		switch {
+		case !tagNameRegex.MatchString(to):
+			te.errorMsg = "Tags are letters, digits and underscores, nested with /"
+		case tagMatches(to, te.tag):
+			te.errorMsg = "Can't merge a tag into itself"
+		default:
+			m.replaceTagInNotes(to)
+		}
+	case tea.KeyBackspace:
+		if runes := []rune(te.input); len(runes) > 0 {
+			te.input = string(runes[:len(runes)-1])
+		}
+	case tea.KeyRunes:
+		te.input += string(msg.Runes)
+	}
+	return m, nil
+}
+
+// tagEditView renders the contents of the delete or merge popup
+func (m model) tagEditView() string {
+	te := m.tagEdit
+	var content strings.Builder
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
+	count := plural(len(te.notes), "note")
+	nested := ""
+	if m.hasNestedTags(te.tag) {
+		nested = " and the tags nested under it"
+	}
+
+	if te.kind == "delete" {
+		content.WriteString(lipgloss.NewStyle().Bold(true).Render("Remove #"+te.tag+" from "+count+"?") + "\n\n")
+		if nested != "" {
+			content.WriteString("This removes" + nested + " too.\n\n")
+		}
+		content.WriteString(helpStyle.Render("y: remove | n: cancel"))
+		return content.String()
+	}
+
+	content.WriteString(lipgloss.NewStyle().Bold(true).Render("Merge #"+te.tag+" in "+count+" into") + "\n\n")
+	content.WriteString("#" + te.input + "█\n\n")
+	if nested != "" {
+		content.WriteString("Tags nested under #" + te.tag + " move along.\n\n")
+	}
+	if te.errorMsg != "" {
+		content.WriteString(lipgloss.NewStyle().Foreground(errorColor).Render(te.errorMsg) + "\n\n")
+	}
+	content.WriteString(helpStyle.Render("Enter: merge | Esc: cancel"))
+	return content.String()
+}
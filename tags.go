@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// runTags implements "notes tags" and returns the exit code. It prints
+// every tag with how many notes carry it, tab separated, or with a tag
+// given, the notes carrying it or a tag nested under it, as "notes list"
+// prints them.
+func runTags(root *note, args []string) int {
+	flags := flag.NewFlagSet("tags", flag.ContinueOnError)
+	sortMode := flags.String("sort", config.TagSort, `Order of the tags, "name" or "count"`)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *sortMode != "name" && *sortMode != "count" {
+		fmt.Fprintf(os.Stderr, "notes tags: -sort is \"name\" or \"count\", not %q\n", *sortMode)
+		return 2
+	}
+
+	switch flags.NArg() {
+	case 0:
+		counts := countTags(root)
+		tags := getAllTags(root)
+		if *sortMode == "count" {
+			sort.SliceStable(tags, func(i, j int) bool { return counts[tags[i]] > counts[tags[j]] })
+		}
+		for _, tag := range tags {
+			fmt.Printf("%s\t%d\n", tag, counts[tag])
+		}
+		return 0
+	case 1:
+		tag := strings.TrimPrefix(flags.Arg(0), "#")
+		var notes []*note
+		findNotesByTags(root, []string{tag}, nil, true, &notes)
+		if len(notes) == 0 {
+			fmt.Fprintf(os.Stderr, "notes tags: no note is tagged #%s\n", tag)
+			return 1
+		}
+		sort.Slice(notes, func(i, j int) bool { return notes[i].path < notes[j].path })
+		for _, n := range notes {
+			title := strings.ReplaceAll(n.title, "\t", " ")
+			fmt.Printf("%s\t%s\t%s\n", n.path, title, n.modified().Format("2006-01-02 15:04"))
+		}
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, "notes tags: give at most one tag")
+	return 2
+}
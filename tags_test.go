@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNewNoteGetsDefaultTags(t *testing.T) {
+	newTestVault(t)
+	config.DefaultTags = []string{"inbox", "#todo", " inbox "}
+	m := newTestModel(t, 80, 24)
+
+	press(m, "n")
+	typeText(m, "Groceries\nmilk and #todo")
+	press(m, "esc", "ctrl+s")
+	if got, want := readTestNote(t, "Groceries.txt"), "milk and #todo\n\n#inbox\n"; got != want {
+		t.Errorf("new note saved as %q, want %q", got, want)
+	}
+	// Only the first save adds them
+	m.editor.SetValue("milk")
+	press(m, "ctrl+s")
+	if got := readTestNote(t, "Groceries.txt"); got != "milk\n" {
+		t.Errorf("saving the note again gives %q", got)
+	}
+}
+
+func TestWithDefaultTags(t *testing.T) {
+	newTestVault(t)
+	config.DefaultTags = []string{"inbox", "todo", "#inbox", ""}
+	for _, tc := range []struct {
+		path, content, want string
+	}{
+		{"a.txt", "", "#inbox #todo"},
+		{"a.txt", "text", "text\n\n#inbox #todo"},
+		{"a.txt", "text\n", "text\n\n#inbox #todo"},
+		{"a.txt", "text #todo", "text #todo\n\n#inbox"},
+		{"a.txt", "#inbox #todo", "#inbox #todo"},
+		{"a.md", "---\ntags: [todo]\n---\ntext", "---\ntags: [todo, inbox]\n---\ntext"},
+	} {
+		if got := withDefaultTags(tc.path, tc.content); got != tc.want {
+			t.Errorf("%s %q gets %q, want %q", tc.path, tc.content, got, tc.want)
+		}
+	}
+
+	config.FrontmatterTags = true
+	if got, want := withDefaultTags("a.md", "text #inbox"), "---\ntags: [todo]\n---\ntext #inbox"; got != want {
+		t.Errorf("with frontmatter_tags, gets %q, want %q", got, want)
+	}
+}
+
+// noteTitles returns the titles of notes, sorted
+func noteTitles(notes []*note) []string {
+	var titles []string
+	for _, n := range notes {
+		titles = append(titles, n.title)
+	}
+	slices.Sort(titles)
+	return titles
+}
+
+func TestOpenTagBrowserOn(t *testing.T) {
+	newTestVault(t)
+	writeTestNote(t, "Alpha.txt", "#work/clientA")
+	writeTestNote(t, "Beta.txt", "#work")
+	writeTestNote(t, "Gamma.txt", "#home")
+	writeTestNote(t, "Old/Delta.txt", "#work and #home")
+
+	for _, arg := range []string{"work", "#work"} {
+		m := newTestModel(t, 80, 24)
+		m.openTagBrowserOn(arg)
+		if m.mode != tagBrowserView {
+			t.Fatalf("%q: starts in mode %v, want the tag browser", arg, m.mode)
+		}
+		if !slices.Equal(m.tagFilters, []string{"work"}) {
+			t.Errorf("%q: filters are %q", arg, m.tagFilters)
+		}
+		if got, want := noteTitles(m.filteredNotes), []string{"Alpha", "Beta", "Delta"}; !slices.Equal(got, want) {
+			t.Errorf("%q: lists %q, want %q", arg, got, want)
+		}
+		// Just as picking the tag in the browser would
+		picked := newTestModel(t, 80, 24)
+		picked.openTagBrowser()
+		picked.selectTag("work")
+		if !slices.Equal(noteTitles(m.filteredNotes), noteTitles(picked.filteredNotes)) {
+			t.Errorf("%q: lists %q, picking the tag lists %q", arg, noteTitles(m.filteredNotes), noteTitles(picked.filteredNotes))
+		}
+	}
+
+	// A nested tag is matched by itself
+	m := newTestModel(t, 80, 24)
+	m.openTagBrowserOn("work/clientA")
+	if got := noteTitles(m.filteredNotes); !slices.Equal(got, []string{"Alpha"}) {
+		t.Errorf("work/clientA lists %q", got)
+	}
+
+	// A tag no note has leaves the browser unfiltered
+	m = newTestModel(t, 80, 24)
+	m.openTagBrowserOn("missing")
+	if m.mode != tagBrowserView || len(m.tagFilters) != 0 || len(m.filteredNotes) != 0 {
+		t.Errorf("an unknown tag starts in mode %v filtered by %q", m.mode, m.tagFilters)
+	}
+}
+
+// manyTagsModel returns a model editing a note in a vault with 5000 tags
+func manyTagsModel(tb testing.TB) *model {
+	newTestVault(tb)
+	var tags strings.Builder
+	for i := range 5000 {
+		fmt.Fprintf(&tags, "#project%d/task%d ", i, i%37)
+	}
+	writeTestNote(tb, "Tags.txt", tags.String())
+	writeTestNote(tb, "Note.txt", "text ")
+	m := newTestModel(tb, 80, 24)
+	m.openNote(m.currentNode.children[slices.IndexFunc(m.currentNode.children, func(n *note) bool { return n.title == "Note" })])
+	m.editor.SetCursor(m.editor.length())
+	return m
+}
+
+// Filtering the tag picker on each key stops at tag_picker_limit matches
+// and reuses its buffer, so a vault of thousands of tags doesn't lag
+func TestTagPickerManyTags(t *testing.T) {
+	m := manyTagsModel(t)
+	press(m, "#")
+	if len(m.tagPickerFiltered) != 5000 {
+		t.Fatalf("the picker lists %d tags, want 5000", len(m.tagPickerFiltered))
+	}
+	press(m, "p", "r", "o")
+	if len(m.tagPickerFiltered) != config.TagPickerLimit || !m.tagPickerMore {
+		t.Errorf("%d matches, more %v: want them cut off at %d", len(m.tagPickerFiltered), m.tagPickerMore, config.TagPickerLimit)
+	}
+	press(m, "j", "e", "c", "t", "4", "9", "9", "9")
+	if !slices.Equal(m.tagPickerFiltered, []string{"project4999/task4"}) || m.tagPickerMore {
+		t.Errorf("project4999 matches %q", m.tagPickerFiltered)
+	}
+
+	m.tagPickerFilter = "task3"
+	if allocs := testing.AllocsPerRun(100, m.filterTags); allocs > 0 {
+		t.Errorf("filtering makes %v allocations per key, want none", allocs)
+	}
+}
+
+func BenchmarkTagPickerFilter(b *testing.B) {
+	m := manyTagsModel(b)
+	press(m, "#")
+	filters := []string{"p", "pr", "task", "task3", "project49", "zzz"}
+	b.ResetTimer()
+	for i := range b.N {
+		m.tagPickerFilter = filters[i%len(filters)]
+		m.filterTags()
+	}
+}
+
+func TestTagPickerRecentFirst(t *testing.T) {
+	newTestVault(t)
+	config.RecentTagsLimit = 2
+	writeTestNote(t, "Tagged.txt", "#alpha #beta #delta #gamma")
+	writeTestNote(t, "Note.txt", "text ")
+	m := newTestModel(t, 80, 24)
+	m.openNote(m.currentNode.children[slices.IndexFunc(m.currentNode.children, func(n *note) bool { return n.title == "Note" })])
+	m.editor.SetCursor(m.editor.length())
+
+	press(m, "#")
+	if got, want := m.tagPickerFiltered, []string{"alpha", "beta", "delta", "gamma"}; !slices.Equal(got, want) {
+		t.Errorf("with no recent tags the picker lists %q, want %q", got, want)
+	}
+	press(m, "esc")
+
+	// Saving a note remembers its tags, the latest first and only as many
+	// as recent_tags_limit
+	m.editor.SetValue("text #beta #delta #gamma")
+	press(m, "ctrl+s")
+	m.rememberTags("missing")
+	if got, want := m.recentTags, []string{"missing", "gamma"}; !slices.Equal(got, want) {
+		t.Errorf("recent tags are %q, want %q", got, want)
+	}
+	m.editor.SetCursor(m.editor.length())
+	press(m, " ", "#")
+	// A recent tag no note has any more isn't listed
+	if got, want := m.tagPickerFiltered, []string{"gamma", "alpha", "beta", "delta"}; !slices.Equal(got, want) {
+		t.Errorf("the picker lists %q, want %q", got, want)
+	}
+	// Typing a filter lists matches in their usual order
+	press(m, "a")
+	if got, want := m.tagPickerFiltered, []string{"alpha", "beta", "delta", "gamma"}; !slices.Equal(got, want) {
+		t.Errorf("filtered on a, the picker lists %q, want %q", got, want)
+	}
+}
+
+// pickTag moves the tag browser's cursor to tag and presses key on it
+func pickTag(t *testing.T, m *model, tag, key string) {
+	t.Helper()
+	m.cursor = slices.Index(m.tagTreeRows(), tag)
+	if m.cursor < 0 {
+		t.Fatalf("%s isn't listed in %q", tag, m.tagTreeRows())
+	}
+	press(m, key)
+}
+
+func TestTagFilterChips(t *testing.T) {
+	newTestVault(t)
+	writeTestNote(t, "Alpha.txt", "#work #urgent")
+	writeTestNote(t, "Beta.txt", "#work")
+	writeTestNote(t, "Gamma.txt", "#home")
+	writeTestNote(t, "Delta.txt", "#work #home")
+	m := newTestModel(t, 80, 24)
+	press(m, "g")
+
+	check := func(step string, filters, titles []string) {
+		t.Helper()
+		if !slices.Equal(m.tagFilters, filters) {
+			t.Errorf("%s: filters %q, want %q", step, m.tagFilters, filters)
+		}
+		if got := noteTitles(m.filteredNotes); !slices.Equal(got, titles) {
+			t.Errorf("%s: lists %q, want %q", step, got, titles)
+		}
+	}
+
+	pickTag(t, m, "work", "enter")
+	check("work", []string{"work"}, []string{"Alpha", "Beta", "Delta"})
+	press(m, "a")
+	pickTag(t, m, "home", "enter")
+	check("work and home", []string{"work", "home"}, []string{"Delta"})
+	if row, _ := render(m).find("Filters (all):  #work ×   #home ×"); row < 0 {
+		t.Errorf("chips not shown:\n%s", strings.Join(render(m).rows, "\n"))
+	}
+	press(m, "o")
+	check("work or home", []string{"work", "home"}, []string{"Alpha", "Beta", "Delta", "Gamma"})
+	press(m, "o")
+
+	// Excluding a tag, from the list or by flipping a chip
+	press(m, "a")
+	pickTag(t, m, "urgent", "x")
+	check("not urgent", []string{"work", "home", "-urgent"}, []string{"Delta"})
+	press(m, "tab")
+	if !m.chipFocus || m.chipCursor != 2 {
+		t.Fatalf("tab: chip focus %v at %d, want the last chip", m.chipFocus, m.chipCursor)
+	}
+	press(m, "left", "x")
+	check("flipped", []string{"work", "-home", "-urgent"}, []string{"Beta"})
+
+	// Removing chips widens the filter, and removing the last goes back to
+	// the tag list
+	press(m, "d")
+	check("home removed", []string{"work", "-urgent"}, []string{"Beta", "Delta"})
+	if m.chipCursor != 1 {
+		t.Errorf("chip cursor at %d after removing, want 1", m.chipCursor)
+	}
+	press(m, "d", "d")
+	check("all removed", nil, nil)
+	if m.chipFocus || m.showingTaggedNotes() {
+		t.Errorf("with no chips: chip focus %v, showing notes %v", m.chipFocus, m.showingTaggedNotes())
+	}
+}
+
+func TestTagPickerRows(t *testing.T) {
+	newTestVault(t)
+	var tags strings.Builder
+	for i := range 30 {
+		fmt.Fprintf(&tags, "#tag%02d ", i)
+	}
+	writeTestNote(t, "Tags.txt", tags.String())
+	writeTestNote(t, "Note.txt", "text ")
+	m := newTestModel(t, 60, 24)
+	m.openNote(m.currentNode.children[slices.IndexFunc(m.currentNode.children, func(n *note) bool { return n.title == "Note" })])
+	m.editor.SetCursor(m.editor.length())
+	status := m.getStatusBarHeight()
+
+	// pickerRows checks the picker's height is what the view shows and what
+	// the editor made room for, and returns the picker's rows
+	pickerRows := func(step string, height int) []string {
+		t.Helper()
+		if got := m.tagPickerHeight(); got != height {
+			t.Errorf("%s: picker is %d rows, want %d", step, got, height)
+		}
+		if got, want := m.editor.height, 24-1-status-height; got != want {
+			t.Errorf("%s: editor is %d rows, want %d", step, got, want)
+		}
+		s := render(m)
+		if len(s.rows) != 24 {
+			t.Errorf("%s: view is %d rows, want 24", step, len(s.rows))
+		}
+		s.assertWidth(t, 60)
+		first, _ := s.find("Tags")
+		if first != 24-status-height {
+			t.Fatalf("%s: picker starts on row %d, want %d:\n%s", step, first, 24-status-height, strings.Join(s.rows, "\n"))
+		}
+		return s.rows[first : first+height]
+	}
+
+	// A single row until a filter is typed
+	press(m, "#")
+	rows := pickerRows("#", 1)
+	if !strings.Contains(rows[0], "#tag00") || !strings.Contains(rows[0], "more") {
+		t.Errorf("single row is %q", rows[0])
+	}
+
+	// Then as many as tag_picker_rows, three tags to a row here, the rest
+	// counted on the last
+	press(m, "t")
+	rows = pickerRows("#t", 4)
+	for i, row := range rows {
+		for j := range 3 {
+			if tag := fmt.Sprintf("#tag%02d", 3*i+j); !strings.Contains(row, tag) {
+				t.Errorf("row %d is %q, want %s on it", i, row, tag)
+			}
+		}
+	}
+	if !strings.HasSuffix(strings.TrimRight(rows[3], " "), "... 18 more") {
+		t.Errorf("last row is %q, want the hidden tags counted", rows[3])
+	}
+	// Only the first row is labelled, the others line up under it
+	if !strings.HasPrefix(rows[0], " Tags: #t │ ") || strings.TrimSpace(rows[1])[:1] != "#" {
+		t.Errorf("rows start %q and %q", rows[0], rows[1])
+	}
+
+	// Moving past the last row shown scrolls the rows
+	for range 15 {
+		press(m, "down")
+	}
+	rows = pickerRows("cursor on tag15", 4)
+	if !strings.Contains(rows[3], "#tag15") || strings.Contains(strings.Join(rows, ""), "#tag05") {
+		t.Errorf("with the cursor on #tag15 the picker shows %q", rows)
+	}
+
+	// Fewer matches take fewer rows
+	typeText(m, "ag2")
+	pickerRows("#tag2", 4)
+	press(m, "9")
+	pickerRows("#tag29", 1)
+	press(m, "esc")
+	if got, want := m.editor.height, 24-1-status; got != want {
+		t.Errorf("closed picker: editor is %d rows, want %d", got, want)
+	}
+
+	config.TagPickerRows = 1
+	press(m, "#", "t")
+	pickerRows("tag_picker_rows 1", 1)
+}
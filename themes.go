@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// colorValue is a color of the config: a 0-255 palette index or a "#rrggbb"
+// true color. Indexes are kept as numbers in config.json, as before true
+// colors were allowed.
+type colorValue string
+
+// hexColorRegex matches a true color, short or long form
+var hexColorRegex = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// parseColor reads a color typed or written in the config, reporting
+// whether it's one
+func parseColor(s string) (colorValue, bool) {
+	s = strings.TrimSpace(s)
+	if index, err := strconv.Atoi(s); err == nil {
+		return colorValue(strconv.Itoa(index)), index >= 0 && index <= 255
+	}
+	if !hexColorRegex.MatchString(s) {
+		return "", false
+	}
+	s = strings.ToLower(s)
+	if len(s) == 4 {
+		s = string([]byte{'#', s[1], s[1], s[2], s[2], s[3], s[3]})
+	}
+	return colorValue(s), true
+}
+
+// index returns the palette index of the color, if it's one
+func (c colorValue) index() (int, bool) {
+	index, err := strconv.Atoi(string(c))
+	return index, err == nil
+}
+
+// step moves a palette index by delta, wrapping around, and lightens or
+// darkens a true color
+func (c colorValue) step(delta int) colorValue {
+	if index, ok := c.index(); ok {
+		return colorValue(strconv.Itoa((index + delta + 256) % 256))
+	}
+	rgb, err := strconv.ParseUint(strings.TrimPrefix(string(c), "#"), 16, 32)
+	if err != nil {
+		return c
+	}
+	channel := func(shift uint) int {
+		return min(max(int(rgb>>shift&0xff)+delta*8, 0), 255)
+	}
+	return colorValue(fmt.Sprintf("#%02x%02x%02x", channel(16), channel(8), channel(0)))
+}
+
+func (c colorValue) MarshalJSON() ([]byte, error) {
+	if index, ok := c.index(); ok {
+		return json.Marshal(index)
+	}
+	return json.Marshal(string(c))
+}
+
+// UnmarshalJSON takes an index as a number or a string, or a true color. A
+// color it can't read is reported and leaves the default in place rather
+// than failing the whole config.
+func (c *colorValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		s = string(data)
+	}
+	parsed, ok := parseColor(s)
+	if !ok {
+		log.Printf("Ignoring color %s in config: use 0-255 or \"#rrggbb\"", data)
+		return nil
+	}
+	*c = parsed
+	return nil
+}
+
+// colorField is one color of the config view
+type colorField struct {
+	name  string
+	value *colorValue
+}
+
+// fields returns the colors in the order the config view lists them
+func (c *ColorConfig) fields() []colorField {
+	return []colorField{
+		{"Title Background", &c.TitleBg},
+		{"Title Foreground", &c.TitleFg},
+		{"Status Background", &c.StatusBg},
+		{"Status Foreground", &c.StatusFg},
+		{"Border Color", &c.BorderColor},
+		{"Selected Item", &c.SelectedFg},
+		{"Favorite Marker", &c.FavoriteColor},
+		{"Tag Bar Background", &c.TagBarBg},
+		{"Tag Bar Foreground", &c.TagBarFg},
+		{"Tag Selected Bg", &c.TagSelectedBg},
+		{"Tag Selected Fg", &c.TagSelectedFg},
+	}
+}
+
+// colorTheme is a built-in color preset
+type colorTheme struct {
+	name   string
+	colors ColorConfig
+}
+
+// colorThemes are the built-in presets cycled through from the navigation
+// view and the config view. The first one is the default color scheme.
+var colorThemes = []colorTheme{
+	{"default", ColorConfig{
+		TitleBg:       "4",   // Blue
+		TitleFg:       "15",  // Bright White
+		StatusBg:      "8",   // Dark Gray
+		StatusFg:      "7",   // Light Gray
+		BorderColor:   "12",  // Bright Blue
+		SelectedFg:    "11",  // Bright Yellow
+		FavoriteColor: "9",   // Bright Red
+		TagBarBg:      "235", // Dark Gray
+		TagBarFg:      "250", // Light Gray
+		TagSelectedBg: "11",  // Bright Yellow
+		TagSelectedFg: "0",   // Black
+	}},
+	{"dark", ColorConfig{
+		TitleBg: "#313244", TitleFg: "#cdd6f4", StatusBg: "#181825", StatusFg: "#a6adc8", BorderColor: "#89b4fa", SelectedFg: "#f9e2af",
+		FavoriteColor: "#f38ba8", TagBarBg: "#1e1e2e", TagBarFg: "#bac2de", TagSelectedBg: "#89b4fa", TagSelectedFg: "#1e1e2e",
+	}},
+	{"light", ColorConfig{
+		TitleBg: "253", TitleFg: "0", StatusBg: "252", StatusFg: "236", BorderColor: "25", SelectedFg: "25",
+		FavoriteColor: "160", TagBarBg: "254", TagBarFg: "236", TagSelectedBg: "25", TagSelectedFg: "15",
+	}},
+	{"solarized", ColorConfig{
+		TitleBg: "#073642", TitleFg: "#93a1a1", StatusBg: "#002b36", StatusFg: "#839496", BorderColor: "#268bd2", SelectedFg: "#b58900",
+		FavoriteColor: "#dc322f", TagBarBg: "#073642", TagBarFg: "#93a1a1", TagSelectedBg: "#268bd2", TagSelectedFg: "#002b36",
+	}},
+	{"gruvbox", ColorConfig{
+		TitleBg: "#3c3836", TitleFg: "#ebdbb2", StatusBg: "#282828", StatusFg: "#a89984", BorderColor: "#83a598", SelectedFg: "#fabd2f",
+		FavoriteColor: "#fb4934", TagBarBg: "#3c3836", TagBarFg: "#ebdbb2", TagSelectedBg: "#fabd2f", TagSelectedFg: "#282828",
+	}},
+	{"ocean", ColorConfig{
+		TitleBg: "24", TitleFg: "15", StatusBg: "236", StatusFg: "152", BorderColor: "38", SelectedFg: "51",
+		FavoriteColor: "209", TagBarBg: "236", TagBarFg: "152", TagSelectedBg: "38", TagSelectedFg: "0",
+	}},
+	{"forest", ColorConfig{
+		TitleBg: "22", TitleFg: "15", StatusBg: "235", StatusFg: "151", BorderColor: "71", SelectedFg: "148",
+		FavoriteColor: "203", TagBarBg: "235", TagBarFg: "151", TagSelectedBg: "71", TagSelectedFg: "0",
+	}},
+	{"mono", ColorConfig{
+		TitleBg: "250", TitleFg: "0", StatusBg: "238", StatusFg: "252", BorderColor: "245", SelectedFg: "15",
+		FavoriteColor: "15", TagBarBg: "236", TagBarFg: "250", TagSelectedBg: "15", TagSelectedFg: "0",
+	}},
+}
+
+// defaultLightColors replace the default theme's colors on terminals with
+// a light background, where its yellows and light grays don't show
+var defaultLightColors = ColorConfig{
+	TitleBg:       "4",   // Blue
+	TitleFg:       "15",  // Bright White
+	StatusBg:      "252", // Light Gray
+	StatusFg:      "238", // Dark Gray
+	BorderColor:   "25",  // Dark Blue
+	SelectedFg:    "130", // Dark Orange
+	FavoriteColor: "160", // Dark Red
+	TagBarBg:      "254", // Light Gray
+	TagBarFg:      "236", // Dark Gray
+	TagSelectedBg: "11",  // Bright Yellow
+	TagSelectedFg: "0",   // Black
+}
+
+// Colors drawn outside the themes, darker on light terminals
+var (
+	errorColor   = lipgloss.AdaptiveColor{Light: "160", Dark: "9"}
+	removedColor = lipgloss.AdaptiveColor{Light: "160", Dark: "9"}
+	addedColor   = lipgloss.AdaptiveColor{Light: "28", Dark: "10"}
+	linkColor    = lipgloss.AdaptiveColor{Light: "25", Dark: "39"}
+)
+
+// uiColors are the colors the app is drawn in: the config's, or
+// defaultLightColors when they're the default theme's on a light terminal
+var uiColors ColorConfig
+
+// detectBackground applies the background option, leaving it to the
+// terminal to tell when it's "auto"
+func detectBackground() {
+	switch config.Background {
+	case "dark":
+		lipgloss.SetHasDarkBackground(true)
+	case "light":
+		lipgloss.SetHasDarkBackground(false)
+	}
+}
+
+// resolveColors picks uiColors for the terminal's background
+func resolveColors() {
+	uiColors = config.Colors
+	if config.Colors == colorThemes[0].colors && !lipgloss.HasDarkBackground() {
+		uiColors = defaultLightColors
+	}
+}
+
+// themeNameRegex matches the names themes can be saved under
+var themeNameRegex = regexp.MustCompile(`^[\w-]+$`)
+
+func getThemesDir() string {
+	return filepath.Join(getConfigDir(), "themes")
+}
+
+// loadThemes returns the built-in themes followed by the user's theme files,
+// <name>.json under the themes folder holding colors as in config.json. A
+// file named after a built-in theme replaces it.
+func loadThemes() []colorTheme {
+	themes := slices.Clone(colorThemes)
+	entries, err := os.ReadDir(getThemesDir())
+	if err != nil {
+		return themes
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(getThemesDir(), entry.Name()))
+		if err != nil {
+			continue
+		}
+		// Colors missing from the file are the default ones
+		colors := colorThemes[0].colors
+		if err := json.Unmarshal(data, &colors); err != nil {
+			log.Printf("Could not read theme %s: %v", entry.Name(), err)
+			continue
+		}
+		theme := colorTheme{name, colors}
+		if i := slices.IndexFunc(themes, func(t colorTheme) bool { return t.name == name }); i >= 0 {
+			themes[i] = theme
+		} else {
+			themes = append(themes, theme)
+		}
+	}
+	return themes
+}
+
+// saveTheme writes colors to the theme file called name
+func saveTheme(name string, colors ColorConfig) error {
+	if err := os.MkdirAll(getThemesDir(), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(colors, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(getThemesDir(), name+".json"), data, 0644)
+}
+
+// nextTheme returns the theme step places after (or before, for a negative
+// step) the one named current, starting from the first when current isn't a
+// theme
+func nextTheme(current string, step int) colorTheme {
+	themes := loadThemes()
+	for i, theme := range themes {
+		if theme.name == current {
+			return themes[((i+step)%len(themes)+len(themes))%len(themes)]
+		}
+	}
+	return themes[0]
+}
+
+// cycleTheme switches to the next color preset, applies it right away and
+// saves it to the config
+func (m *model) cycleTheme() {
+	theme := nextTheme(config.Theme, 1)
+	config.Theme = theme.name
+	config.Colors = theme.colors
+	applyColorConfig()
+	saveConfig(config)
+	m.notice = "Theme: " + theme.name
+	m.noticeErr = false
+}
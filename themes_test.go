@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestCycleTheme(t *testing.T) {
+	newTestVault(t)
+	m := newTestModel(t, 80, 24)
+
+	for i := 1; i <= len(colorThemes); i++ {
+		want := colorThemes[i%len(colorThemes)]
+		press(m, config.ThemeKey)
+		if config.Theme != want.name || config.Colors != want.colors {
+			t.Fatalf("press %d: theme %q, want %q and its colors", i, config.Theme, want.name)
+		}
+		if uiColors != want.colors {
+			t.Errorf("press %d: %q isn't applied", i, want.name)
+		}
+		if m.notice != "Theme: "+want.name || m.noticeErr {
+			t.Errorf("press %d: notice %q", i, m.notice)
+		}
+		saved, _ := loadConfig()
+		if saved.Theme != want.name || saved.Colors != want.colors {
+			t.Errorf("press %d: saved theme %q, want %q", i, saved.Theme, want.name)
+		}
+	}
+}
+
+// Saved themes come after the built-in ones, and a config whose theme is
+// gone starts over at the first
+func TestNextTheme(t *testing.T) {
+	newTestVault(t)
+	colors := colorThemes[0].colors
+	colors.TitleBg = "#123456"
+	if err := saveTheme("mine", colors); err != nil {
+		t.Fatal(err)
+	}
+
+	last := colorThemes[len(colorThemes)-1].name
+	for _, tc := range []struct {
+		current string
+		step    int
+		want    string
+	}{
+		{"default", 1, colorThemes[1].name},
+		{last, 1, "mine"},
+		{"mine", 1, "default"},
+		{"default", -1, "mine"},
+		{"deleted", 1, "default"},
+	} {
+		if got := nextTheme(tc.current, tc.step); got.name != tc.want {
+			t.Errorf("%+d from %q is %q, want %q", tc.step, tc.current, got.name, tc.want)
+		}
+	}
+
+	config.Theme = last
+	m := newTestModel(t, 80, 24)
+	press(m, config.ThemeKey)
+	if config.Theme != "mine" || config.Colors != colors {
+		t.Errorf("cycled to %q, want the saved theme", config.Theme)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// trashEntry is what's recorded about an entry of the trash
+type trashEntry struct {
+	Trashed time.Time `json:"trashed"`
+	From    string    `json:"from,omitempty"` // path it was trashed from
+}
+
+// getTrashInfoPath returns the file recording the trash entries, keyed by
+// their names in the trash
+func getTrashInfoPath() string {
+	return filepath.Join(getStateDir(), "trash_info.json")
+}
+
+func loadTrashInfo() map[string]trashEntry {
+	info := make(map[string]trashEntry)
+	data, err := os.ReadFile(getTrashInfoPath())
+	if err != nil {
+		return info
+	}
+	_ = json.Unmarshal(data, &info)
+	return info
+}
+
+func saveTrashInfo(info map[string]trashEntry) error {
+	if err := os.MkdirAll(filepath.Dir(getTrashInfoPath()), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(getTrashInfoPath(), data, 0644)
+}
+
+// purgeTrash permanently deletes the trash entries trashed more than days
+// ago, keeping everything when days is 0. Entries with nothing recorded,
+// such as those trashed by older versions, count from now, and records of
+// entries no longer in the trash are dropped. It returns how many entries
+// were deleted.
+func purgeTrash(trashPath string, info map[string]trashEntry, days int) int {
+	entries, err := os.ReadDir(trashPath)
+	if err != nil {
+		return 0
+	}
+	now := time.Now()
+	present := make(map[string]bool)
+	purged := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		e, ok := info[name]
+		if !ok {
+			info[name] = trashEntry{Trashed: now}
+		} else if days > 0 && now.Sub(e.Trashed) > time.Duration(days)*24*time.Hour {
+			if err := os.RemoveAll(filepath.Join(trashPath, name)); err != nil {
+				log.Printf("Could not purge %s from the trash: %v", name, err)
+			} else {
+				delete(info, name)
+				purged++
+				continue
+			}
+		}
+		present[name] = true
+	}
+	for name := range info {
+		if !present[name] {
+			delete(info, name)
+		}
+	}
+	saveTrashInfo(info)
+	return purged
+}
+
+// trashDest returns where the entry at path goes in the trash: under its
+// own name, or with the time it's trashed added when an entry of that name
+// is there already, so nothing in the trash is ever overwritten
+func trashDest(trashPath, path string) string {
+	ext := filepath.Ext(path)
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		ext = ""
+	}
+	base := strings.TrimSuffix(filepath.Base(path), ext)
+	dest := filepath.Join(trashPath, base+ext)
+	stamp := time.Now().Format("2006-01-02 150405")
+	for n := 1; ; n++ {
+		if _, err := os.Lstat(dest); os.IsNotExist(err) {
+			return dest
+		}
+		dest = filepath.Join(trashPath, fmt.Sprintf("%s %s%s", base, stamp, ext))
+		if n > 1 {
+			dest = filepath.Join(trashPath, fmt.Sprintf("%s %s (%d)%s", base, stamp, n, ext))
+		}
+	}
+}
+
+// setTrashed records that the entry at from was trashed now, as path in the
+// trash
+func (m *model) setTrashed(path, from string) {
+	if m.trashInfo == nil {
+		m.trashInfo = make(map[string]trashEntry)
+	}
+	m.trashInfo[filepath.Base(path)] = trashEntry{Trashed: time.Now(), From: from}
+	saveTrashInfo(m.trashInfo)
+}
+
+// forgetTrashed drops the record of the trash entry at path once it's
+// restored or deleted
+func (m *model) forgetTrashed(path string) {
+	delete(m.trashInfo, filepath.Base(path))
+	saveTrashInfo(m.trashInfo)
+}
+
+// restoreDest returns where a trash entry is restored to: back where it was
+// trashed from, recreating the folder if it's gone, or the notes root when
+// that isn't known or is taken
+func (m model) restoreDest(n *note) string {
+	from := m.trashInfo[filepath.Base(n.path)].From
+	if strings.HasPrefix(from, notesPath+string(filepath.Separator)) {
+		if _, err := os.Lstat(from); os.IsNotExist(err) && os.MkdirAll(filepath.Dir(from), 0755) == nil {
+			return from
+		}
+	}
+	return filepath.Join(notesPath, filepath.Base(n.path))
+}
+
+// trashOrigin returns the folder a trash entry came from, as a path from
+// the notes root, or "" when it isn't known
+func (m model) trashOrigin(n *note) string {
+	from := m.trashInfo[filepath.Base(n.path)].From
+	if from == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(notesPath, filepath.Dir(from))
+	if err != nil || rel == "." {
+		return "/"
+	}
+	return "/" + filepath.ToSlash(rel)
+}
+
+// trashAge describes how long ago a trash entry was trashed, and with
+// trash_retention_days set, when it's deleted for good
+func (m model) trashAge(n *note) string {
+	e, ok := m.trashInfo[filepath.Base(n.path)]
+	if !ok {
+		return ""
+	}
+	days := int(time.Since(e.Trashed) / (24 * time.Hour))
+	age := fmt.Sprintf("%d days ago", days)
+	switch days {
+	case 0:
+		age = "today"
+	case 1:
+		age = "yesterday"
+	}
+	if config.TrashRetentionDays > 0 {
+		age += ", deleted in " + plural(max(config.TrashRetentionDays-days, 0), "day")
+	}
+	return age
+}
+
+// confirmDelete reports whether the delete asked for with key can go ahead.
+// With confirm_delete set it first shows prompt in a popup and replays key
+// once the user agrees.
+func (m *model) confirmDelete(prompt string, key tea.KeyMsg) bool {
+	if !config.ConfirmDelete || m.deleteConfirmed {
+		m.deleteConfirmed = false
+		return true
+	}
+	m.showDeletePopup = true
+	m.deletePrompt = prompt
+	m.deleteKey = key
+	return false
+}
+
+func (m *model) updateDeletePopup(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y", "enter":
+		m.showDeletePopup = false
+		m.deleteConfirmed = true
+		defer func() { m.deleteConfirmed = false }()
+		if m.mode == trashView {
+			return m.updateTrashView(m.deleteKey)
+		}
+		return m.updateNavigationView(m.deleteKey)
+	case "n", "N", "esc", "q":
+		m.showDeletePopup = false
+	}
+	return m, nil
+}
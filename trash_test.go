@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// emptiedNoteModel opens Todo.txt in the editor and deletes all of its text
+func emptiedNoteModel(t *testing.T, action string) *model {
+	t.Helper()
+	newTestVault(t)
+	config.EmptyNoteAction = action
+	writeTestNote(t, "Todo.txt", "buy milk\n")
+	writeTestNote(t, "Other.txt", "other\n")
+	m := newTestModel(t, 80, 24)
+	for _, n := range m.currentNode.children {
+		if n.title == "Todo" {
+			m.openNote(n)
+		}
+	}
+	m.editor.SetValue(" \n")
+	m.editor.MarkDirty()
+	return m
+}
+
+// trashed reports whether the trash holds a note named name
+func trashed(t *testing.T, name string) bool {
+	_, err := os.Stat(filepath.Join(notesPath, ".trash", name))
+	return err == nil
+}
+
+func TestEmptiedNoteKept(t *testing.T) {
+	for _, key := range []string{"ctrl+s", "esc"} {
+		m := emptiedNoteModel(t, "keep")
+		press(m, key)
+		if m.showEmptyNotePopup {
+			t.Errorf("%s: asked about the emptied note", key)
+		}
+		if got := readTestNote(t, "Todo.txt"); got != " \n" {
+			t.Errorf("%s: note saved as %q", key, got)
+		}
+	}
+}
+
+func TestEmptiedNoteTrashed(t *testing.T) {
+	for _, key := range []string{"ctrl+s", "esc"} {
+		m := emptiedNoteModel(t, "trash")
+		press(m, key)
+		if !trashed(t, "Todo.txt") {
+			t.Fatalf("%s: the emptied note isn't in the trash", key)
+		}
+		// With what it held before, so it can be restored
+		data, _ := os.ReadFile(filepath.Join(notesPath, ".trash", "Todo.txt"))
+		if string(data) != "buy milk\n" {
+			t.Errorf("%s: trashed note holds %q", key, data)
+		}
+		if m.mode != navigationView || len(m.currentNode.children) != 1 {
+			t.Errorf("%s: in mode %v listing %d notes, want back in the list without it", key, m.mode, len(m.currentNode.children))
+		}
+	}
+}
+
+func TestEmptiedNotePrompt(t *testing.T) {
+	// y trashes it
+	m := emptiedNoteModel(t, "prompt")
+	press(m, "esc")
+	if !m.showEmptyNotePopup {
+		t.Fatal("no prompt for the emptied note")
+	}
+	press(m, "y")
+	if m.showEmptyNotePopup || !trashed(t, "Todo.txt") || m.mode != navigationView {
+		t.Errorf("y: popup %v, trashed %v, mode %v", m.showEmptyNotePopup, trashed(t, "Todo.txt"), m.mode)
+	}
+
+	// n keeps it, and carries on with the key that asked
+	m = emptiedNoteModel(t, "prompt")
+	press(m, "esc", "n")
+	if m.showEmptyNotePopup || trashed(t, "Todo.txt") || m.mode != navigationView {
+		t.Errorf("n: popup %v, trashed %v, mode %v", m.showEmptyNotePopup, trashed(t, "Todo.txt"), m.mode)
+	}
+	if got := readTestNote(t, "Todo.txt"); got != " \n" {
+		t.Errorf("n: note saved as %q", got)
+	}
+
+	// esc goes back to editing with nothing saved
+	m = emptiedNoteModel(t, "prompt")
+	press(m, "ctrl+s", "esc")
+	if m.showEmptyNotePopup || m.mode != editingView || !m.editor.Dirty() {
+		t.Errorf("esc: popup %v, mode %v, dirty %v", m.showEmptyNotePopup, m.mode, m.editor.Dirty())
+	}
+	if got := readTestNote(t, "Todo.txt"); got != "buy milk\n" {
+		t.Errorf("esc: note saved as %q", got)
+	}
+}
+
+// A note that was already empty is saved as usual
+func TestEmptyNoteNotAsked(t *testing.T) {
+	newTestVault(t)
+	config.EmptyNoteAction = "prompt"
+	writeTestNote(t, "Blank.txt", "")
+	m := newTestModel(t, 80, 24)
+	m.openNote(m.currentNode.children[0])
+	press(m, "ctrl+s")
+	if m.showEmptyNotePopup {
+		t.Error("asked about a note that was empty already")
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// In the tree layout the whole folder hierarchy is listed at once, with
+// folders expanded and collapsed in place. The selected row is still kept
+// as the current folder and a cursor into it, so every action on the
+// selected entry works the same in both layouts.
+
+// treeRow is one entry of the tree listing
+type treeRow struct {
+	note  *note
+	depth int
+}
+
+// treeRoot returns the folder the tree is drawn from
+func (m *model) treeRoot() *note {
+	rootNote := m.currentNode
+	for rootNote.parent != nil {
+		rootNote = rootNote.parent
+	}
+	return rootNote
+}
+
+// treeRows returns the rows of the tree: the notes and folders at the top
+// level, and inside every expanded folder
+func (m *model) treeRows() []treeRow {
+	var rows []treeRow
+	var walk func(dir *note, depth int)
+	walk = func(dir *note, depth int) {
+		for _, child := range dir.children {
+			rows = append(rows, treeRow{child, depth})
+			if child.isDir && m.expanded[child.path] {
+				walk(child, depth+1)
+			}
+		}
+	}
+	walk(m.treeRoot(), 0)
+	return rows
+}
+
+// treeCursorRow returns the row of the selected entry, or -1 when the
+// current folder is empty
+func (m *model) treeCursorRow(rows []treeRow) int {
+	if m.cursor < 0 || m.cursor >= len(m.currentNode.children) {
+		return -1
+	}
+	selected := m.currentNode.children[m.cursor]
+	return slices.IndexFunc(rows, func(r treeRow) bool { return r.note == selected })
+}
+
+// selectTreeNote selects n, making its folder the current one
+func (m *model) selectTreeNote(n *note) {
+	if n.parent != m.currentNode {
+		m.clearMarks()
+	}
+	m.currentNode = n.parent
+	m.cursor = slices.Index(m.currentNode.children, n)
+}
+
+// setExpanded expands or collapses the folder dir, sorting it on the way
+// open
+func (m *model) setExpanded(dir *note, expanded bool) {
+	if m.expanded == nil {
+		m.expanded = make(map[string]bool)
+	}
+	if expanded {
+		m.expanded[dir.path] = true
+		sortFolder(m.folderSorts, dir)
+	} else {
+		delete(m.expanded, dir.path)
+	}
+}
+
+// toggleTreeView switches between the tree and the one folder at a time
+// layout and saves the choice. The tree opens with the folders down to the
+// current one expanded.
+func (m *model) toggleTreeView() {
+	config.TreeView = !config.TreeView
+	saveConfig(config)
+	m.inPinned = false
+	if config.TreeView {
+		for dir := m.currentNode; dir.parent != nil; dir = dir.parent {
+			m.setExpanded(dir, true)
+		}
+		sortFolder(m.folderSorts, m.treeRoot())
+		if len(m.currentNode.children) == 0 && m.currentNode.parent != nil {
+			m.selectTreeNote(m.currentNode)
+		}
+		m.notice = "Tree layout"
+	} else {
+		m.notice = "Folder layout"
+	}
+	m.listOffset = 0
+}
+
+// updateTreeNavigation handles the keys that move around the tree. Other
+// keys are left to the navigation view, acting on the selected entry.
+func (m *model) updateTreeNavigation(msg tea.KeyMsg) bool {
+	rows := m.treeRows()
+	row := m.treeCursorRow(rows)
+	switch msg.String() {
+	case "up", "k":
+		if len(rows) > 0 {
+			m.selectTreeNote(rows[(max(row, 0)+len(rows)-1)%len(rows)].note)
+		}
+	case "down", "j":
+		if len(rows) > 0 {
+			m.selectTreeNote(rows[(row+1)%len(rows)].note)
+		}
+	case "right", "l", "enter":
+		if row < 0 {
+			return false
+		}
+		n := rows[row].note
+		if !n.isDir {
+			return false // opens the note
+		}
+		switch {
+		case msg.String() == "enter":
+			m.setExpanded(n, !m.expanded[n.path])
+		case !m.expanded[n.path]:
+			m.setExpanded(n, true)
+		case len(n.children) > 0:
+			m.selectTreeNote(n.children[0])
+		}
+	case "left", "h", "esc":
+		if row >= 0 && rows[row].note.isDir && m.expanded[rows[row].note.path] {
+			m.setExpanded(rows[row].note, false)
+		} else if m.currentNode.parent != nil {
+			m.selectTreeNote(m.currentNode)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// treeListView renders the rows of the tree from the list offset, height
+// rows at most
+func (m model) treeListView(width, height int) string {
+	rows := m.treeRows()
+	if len(rows) == 0 {
+		return "  No notes yet. Press 'n' to create one or 'F' for a new folder."
+	}
+	cursorRow := m.treeCursorRow(rows)
+	var s strings.Builder
+	end := min(m.listOffset+height, len(rows))
+	for i := m.listOffset; i < end; i++ {
+		r := rows[i]
+		marker := "  "
+		if r.note.isDir {
+			marker = "▸ "
+			if m.expanded[r.note.path] {
+				marker = "▾ "
+			}
+		}
+		name := truncate(strings.Repeat("  ", r.depth)+marker+m.listEntryName(r.note), width-2, "…")
+		if i == cursorRow {
+			s.WriteString("> " + selectedStyle.Render(name) + "\n")
+		} else {
+			s.WriteString("  " + name + "\n")
+		}
+	}
+	return s.String()
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// truncate shortens s to at most maxCells terminal cells, ending it with
+// ellipsis when anything was cut. Widths are measured in display cells, so
+// wide characters are never split and ANSI styling is kept intact.
+func truncate(s string, maxCells int, ellipsis string) string {
+	if maxCells <= 0 {
+		return ""
+	}
+	if ansi.StringWidth(s) <= maxCells {
+		return s
+	}
+	if ansi.StringWidth(ellipsis) > maxCells {
+		ellipsis = ""
+	}
+	return ansi.Truncate(s, maxCells, ellipsis)
+}
+
+// truncateLines applies truncate to every line of s
+func truncateLines(s string, maxCells int, ellipsis string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = truncate(line, maxCells, ellipsis)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestTruncate(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		maxCells int
+		ellipsis string
+		want     string
+	}{
+		{"hello", 10, "…", "hello"},
+		{"hello", 5, "…", "hello"},
+		{"hello world", 8, "…", "hello w…"},
+		{"hello", 0, "…", ""},
+		{"hello", -1, "…", ""},
+		{"hello", 2, "...", "he"}, // no room for the ellipsis
+		{"日本語のテキスト", 16, "…", "日本語のテキスト"},
+		{"日本語のテキスト", 7, "…", "日本語…"},
+		{"日本語のテキスト", 8, "…", "日本語…"}, // の isn't cut in half
+		{"日本", 1, "", ""},
+		{"a日本", 2, "", "a"},
+		{"🙂🙂🙂", 5, "…", "🙂🙂…"},
+		{"🙂🙂🙂", 6, "…", "🙂🙂🙂"},
+		{"👨‍👩‍👧 family", 3, "…", "👨‍👩‍👧…"}, // one character of 2 cells
+		{"café été", 6, "…", "café …"},
+	} {
+		got := truncate(tc.s, tc.maxCells, tc.ellipsis)
+		if got != tc.want {
+			t.Errorf("truncate(%q, %d, %q) = %q, want %q", tc.s, tc.maxCells, tc.ellipsis, got, tc.want)
+		}
+		if w := ansi.StringWidth(got); w > max(tc.maxCells, 0) {
+			t.Errorf("truncate(%q, %d, %q) is %d cells wide", tc.s, tc.maxCells, tc.ellipsis, w)
+		}
+	}
+}
+
+// Styling is kept and not counted, and sequences aren't cut short
+func TestTruncateANSI(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		maxCells int
+		want     string // as shown
+		start    string // what it still starts with
+	}{
+		{"\x1b[1mbold\x1b[0m text", 9, "bold text", "\x1b[1mbold\x1b[0m"},
+		{"\x1b[1mbold\x1b[0m text", 6, "bold …", "\x1b[1mbold\x1b[0m"},
+		{"\x1b[38;2;255;0;0mred 日本語\x1b[0m", 8, "red 日…", "\x1b[38;2;255;0;0mred"},
+		{"\x1b]8;;https://example.com\x07link text\x1b]8;;\x07 after", 6, "link …", "\x1b]8;;https://example.com\x07link"},
+		{"plain \x1b[7m🙂🙂\x1b[0m", 9, "plain 🙂…", "plain \x1b[7m🙂"},
+	} {
+		got := truncate(tc.s, tc.maxCells, "…")
+		if shown := ansi.Strip(got); shown != tc.want {
+			t.Errorf("truncate(%q, %d) shows %q, want %q", tc.s, tc.maxCells, shown, tc.want)
+		}
+		s := newScreen(got)
+		s.assertIntact(t)
+		s.assertWidth(t, tc.maxCells)
+		if !strings.HasPrefix(got, tc.start) {
+			t.Errorf("truncate(%q, %d) = %q, want it to start %q", tc.s, tc.maxCells, got, tc.start)
+		}
+	}
+}
+
+func TestTruncateLines(t *testing.T) {
+	got := truncateLines("short\n日本語のテキスト\n\x1b[1mlong bold line\x1b[0m\n", 6, "…")
+	want := []string{"short", "日本…", "long …", ""}
+	s := newScreen(got)
+	if strings.Join(s.rows, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines show %q, want %q", s.rows, want)
+	}
+	s.assertIntact(t)
+	s.assertWidth(t, 6)
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Besides the notes at notes_path, the vaults option names other folders of
+// notes to switch to with V or open with -vault. Each vault keeps its own
+// trash, and its own state and cache folders, so cursor positions, bookmarks
+// and the search index don't mix.
+
+// defaultVault is the name the notes at notes_path go by
+const defaultVault = "default"
+
+// vaultName is the vault open, "" for the one at notes_path
+var vaultName string
+
+// openedPath is the folder opened with -path in place of a vault, "" when a
+// vault is open
+var openedPath string
+
+// vaultNameRegex matches the names vaults can have, as they name their state
+// folders
+var vaultNameRegex = regexp.MustCompile(`^[\w-]+$`)
+
+// vaultPicker is the popup listing the vaults to switch to
+type vaultPicker struct {
+	names  []string // defaultVault first, then the vaults option by name
+	cursor int
+}
+
+// vaultNames returns the vaults that can be opened
+func vaultNames() []string {
+	var names []string
+	for name := range config.Vaults {
+		if name != defaultVault && vaultNameRegex.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{defaultVault}, names...)
+}
+
+// vaultPath returns the folder of the vault name
+func vaultPath(name string) string {
+	if name == "" || name == defaultVault {
+		return config.NotesPath
+	}
+	return config.Vaults[name]
+}
+
+// appTitle starts the title bar, naming the vault unless it's the default
+func appTitle() string {
+	if vaultName == "" && openedPath == "" {
+		return "Notes v" + getVersion()
+	}
+	return "Notes v" + getVersion() + " [" + vaultLabel() + "]"
+}
+
+// vaultLabel names the open vault, or the folder opened with -path
+func vaultLabel() string {
+	if openedPath != "" {
+		return openedPath
+	}
+	if vaultName == "" {
+		return defaultVault
+	}
+	return vaultName
+}
+
+// selectVault makes name the open vault
+func selectVault(name string) error {
+	if name == "" || name == defaultVault {
+		vaultName, openedPath = "", ""
+		notesPath = config.NotesPath
+		return nil
+	}
+	if !vaultNameRegex.MatchString(name) {
+		return fmt.Errorf("vault names are letters, digits, - and _, not %q", name)
+	}
+	path, ok := config.Vaults[name]
+	if !ok || path == "" {
+		return fmt.Errorf("no vault named %q in %s", name, getConfigPath())
+	}
+	vaultName, openedPath = name, ""
+	notesPath = path
+	return nil
+}
+
+// selectPath opens the folder dir in place of a vault. notes_path stays as
+// it is in the config.
+func selectPath(dir string) error {
+	path, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a folder", dir)
+	}
+	vaultName, openedPath = "", path
+	notesPath = path
+	return nil
+}
+
+// openedPathKey names the state, cache and backup folders of the folder
+// opened with -path: its name and a hash of where it is, as folders of the
+// same name can be opened from different places
+func openedPathKey() string {
+	sum := sha1.Sum([]byte(openedPath))
+	return filepath.Base(openedPath) + "-" + hex.EncodeToString(sum[:4])
+}
+
+// makeVaultFolders creates the notes and trash folders of the open vault
+func makeVaultFolders() error {
+	if err := os.MkdirAll(notesPath, 0755); err != nil {
+		return fmt.Errorf("could not create notes directory: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(notesPath, ".trash"), 0755); err != nil {
+		return fmt.Errorf("could not create trash directory: %w", err)
+	}
+	return nil
+}
+
+// vaultModel returns a model listing the notes under rootNote, with the
+// trash and the state kept for the open vault
+func vaultModel(rootNote *note, editor Editor) model {
+	// Empty the trash of entries past the retention period
+	trashPath := filepath.Join(notesPath, ".trash")
+	trashInfo := loadTrashInfo()
+	purgeTrash(trashPath, trashInfo, config.TrashRetentionDays)
+
+	// Re-index only the notes changed since the last run
+	searchIndex := loadSearchIndex()
+	if searchIndex.sync(rootNote) {
+		saveSearchIndex(searchIndex)
+	}
+
+	// Load cursor positions, forgetting notes that have since been deleted
+	cursorPositions := loadCursorPositions()
+	if pruneCursorPositions(cursorPositions, config.CursorPositionsLimit) {
+		saveCursorPositions(cursorPositions)
+	}
+
+	m := model{
+		mode:            navigationView,
+		currentNode:     rootNote,
+		trashNode:       loadNotes(trashPath),
+		trashInfo:       trashInfo,
+		editor:          editor,
+		cursorPositions: cursorPositions,
+		bookmarks:       loadBookmarks(),
+		lastSeen:        loadLastSeen(),
+		recentNotes:     loadRecentNotes(),
+		searchIndex:     searchIndex,
+		folderSorts:     loadFolderSorts(),
+		statsCache:      &noteStatsCache{revision: -1},
+		gitRoot:         gitRepoRoot(notesPath),
+		lastSync:        loadSyncState(getSyncStatePath()).LastSync,
+	}
+	m.sortNotes()
+	if drafts := loadDrafts(); len(drafts) > 0 {
+		m.recovery = &draftRecovery{drafts: drafts}
+	}
+	return m
+}
+
+// openVaultPicker lists the vaults, with the cursor on the open one
+func (m *model) openVaultPicker() {
+	names := vaultNames()
+	if len(names) == 1 {
+		m.notice = "No other vaults, add them to vaults in " + getConfigPath()
+		return
+	}
+	vp := &vaultPicker{names: names}
+	for i, name := range names {
+		if name == vaultLabel() {
+			vp.cursor = i
+		}
+	}
+	m.vaultPicker = vp
+}
+
+// switchVault opens the vault name in place of the one open
+func (m *model) switchVault(name string) {
+	if name == vaultLabel() {
+		return
+	}
+	// The sync works on the open vault's folder until it's done
+	if m.syncing {
+		m.notice = "Wait for the sync to finish before switching vaults"
+		return
+	}
+	prevName, prevPath, prevOpened := vaultName, notesPath, openedPath
+	err := selectVault(name)
+	if err == nil {
+		err = makeVaultFolders()
+	}
+	if err != nil {
+		vaultName, notesPath, openedPath = prevName, prevPath, prevOpened
+		m.notice = fmt.Sprintf("Could not open the %s vault: %v", name, err)
+		m.noticeErr = true
+		return
+	}
+	m.reopenVault()
+	m.notice = "Opened the " + name + " vault"
+}
+
+// reopenVault starts over in the notes list of the vault at notesPath
+func (m *model) reopenVault() {
+	m.flushGit()
+	next := vaultModel(loadNotes(notesPath), m.editor)
+	next.width, next.height = m.width, m.height
+	next.spellChecker = m.spellChecker
+	*m = next
+}
+
+func (m *model) updateVaultPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	vp := m.vaultPicker
+	switch msg.String() {
+	case "up", "k":
+		if vp.cursor > 0 {
+			vp.cursor--
+		}
+	case "down", "j":
+		if vp.cursor < len(vp.names)-1 {
+			vp.cursor++
+		}
+	case "enter":
+		m.vaultPicker = nil
+		m.switchVault(vp.names[vp.cursor])
+	case "esc", "q", keyFor("vaults"):
+		m.vaultPicker = nil
+	}
+	return m, nil
+}
+
+// vaultPickerView renders the contents of the vault popup
+func (m model) vaultPickerView() string {
+	vp := m.vaultPicker
+	var content strings.Builder
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(uiColors.StatusFg))
+	pathStyle := lipgloss.NewStyle().Faint(true)
+
+	content.WriteString(lipgloss.NewStyle().Bold(true).Render("Vaults") + "\n\n")
+	for i, name := range vp.names {
+		label := name
+		if name == vaultLabel() {
+			label += " (open)"
+		}
+		path := pathStyle.Render(" · " + truncate(vaultPath(name), max(20, m.width-lipgloss.Width(label)-20), "…"))
+		if i == vp.cursor {
+			content.WriteString("> " + selectedStyle.Render(label) + path + "\n")
+		} else {
+			content.WriteString("  " + label + path + "\n")
+		}
+	}
+	content.WriteString("\n" + helpStyle.Render("Enter: open | Esc: close"))
+	return content.String()
+}
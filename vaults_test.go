@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A vault whose folder can't be created leaves the folder opened with -path
+// open, with its own state folders
+func TestSwitchVaultFolderFails(t *testing.T) {
+	newTestVault(t)
+	dir := filepath.Join(t.TempDir(), "opened")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// A file where the vault's parent folder would be
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	config.Vaults = map[string]string{"broken": filepath.Join(blocker, "notes")}
+	t.Cleanup(func() { selectVault("") })
+
+	if err := selectPath(dir); err != nil {
+		t.Fatal(err)
+	}
+	stateDir := getStateDir()
+	m := newTestModel(t, 80, 24)
+	m.switchVault("broken")
+
+	if !m.noticeErr || !strings.Contains(m.notice, "Could not open the broken vault") {
+		t.Errorf("notice is %q", m.notice)
+	}
+	if notesPath != dir || openedPath != dir || vaultName != "" {
+		t.Errorf("after the failed switch notes are in %q, opened %q, vault %q, want %q opened", notesPath, openedPath, vaultName, dir)
+	}
+	if got := getStateDir(); got != stateDir {
+		t.Errorf("state is kept in %q, want %q", got, stateDir)
+	}
+	if got := vaultLabel(); got != dir {
+		t.Errorf("vault shown as %q, want %q", got, dir)
+	}
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type vimMode int
+
+const (
+	vimNormal vimMode = iota
+	vimInsert
+	vimVisual
+)
+
+// SetVimKeys turns the modal vim key bindings on or off
+func (e *Editor) SetVimKeys(enabled bool) {
+	e.vimKeys = enabled
+	e.vimMode = vimNormal
+}
+
+// VimMode returns the name of the current vim mode for the status bar, or ""
+// when vim keys are off
+func (e *Editor) VimMode() string {
+	if !e.vimKeys {
+		return ""
+	}
+	switch e.vimMode {
+	case vimInsert:
+		return "INSERT"
+	case vimVisual:
+		return "VISUAL"
+	}
+	return "NORMAL"
+}
+
+// resetVimMode starts a note in normal mode, or in insert mode when it's
+// empty so a new note can be typed straight away
+func (e *Editor) resetVimMode() {
+	e.vimMode = vimNormal
+	e.vimPending = 0
+	e.vimCount = 0
+	if e.lines.len() == 1 && len(e.lines.line(0)) == 0 {
+		e.vimMode = vimInsert
+	}
+	e.vimClampCursor()
+}
+
+// vimClampCursor keeps the cursor on a character, as vim does outside insert
+// mode
+func (e *Editor) vimClampCursor() {
+	e.clampCursor()
+	if e.cursorRow < e.lines.len() {
+		if line := e.lines.line(e.cursorRow); e.cursorCol >= len(line) {
+			e.cursorCol = prevGrapheme(line, len(line))
+		}
+	}
+}
+
+// vimTakeCount returns the count typed before a command, 1 if none
+func (e *Editor) vimTakeCount() int {
+	count := max(1, e.vimCount)
+	e.vimCount = 0
+	return count
+}
+
+// vimCharEnd returns the offset after the character at offset, combining
+// marks included. At the end of a line that's after the newline.
+func (e *Editor) vimCharEnd(offset int) int {
+	lineStart := 0
+	for row := range e.lines.len() {
+		line := e.lines.line(row)
+		if offset < lineStart+len(line) {
+			return lineStart + nextGrapheme(line, offset-lineStart)
+		}
+		if offset == lineStart+len(line) {
+			break
+		}
+		lineStart += len(line) + 1
+	}
+	return offset + 1
+}
+
+// vimVisualRange returns the characters covered by the visual selection,
+// which includes the character under the cursor
+func (e *Editor) vimVisualRange() (int, int) {
+	start, end := e.selectionOffsets()
+	if e.GetCursor() >= e.selectionAnchor {
+		end = e.vimCharEnd(end)
+	}
+	return start, min(end, e.length())
+}
+
+// vimUpdateVisual moves the selection along with the cursor, keeping the
+// character where visual mode started selected
+func (e *Editor) vimUpdateVisual() {
+	e.selectionAnchor = e.vimVisualStart
+	if e.GetCursor() < e.vimVisualStart {
+		e.selectionAnchor = e.vimCharEnd(e.vimVisualStart)
+	}
+	e.hasSelection = true
+}
+
+// vimExitVisual goes back to normal mode, dropping the selection
+func (e *Editor) vimExitVisual() {
+	e.vimMode = vimNormal
+	e.clearSelection()
+}
+
+// vimFirstNonBlank moves the cursor to the first non-blank character of the line
+func (e *Editor) vimFirstNonBlank() {
+	e.cursorCol = 0
+	if e.cursorRow < e.lines.len() {
+		line := e.lines.line(e.cursorRow)
+		for e.cursorCol < len(line) && unicode.IsSpace(line[e.cursorCol]) {
+			e.cursorCol++
+		}
+	}
+	e.updateDesiredCol()
+	e.ensureCursorVisible()
+}
+
+// vimGotoLine moves to the first non-blank character of line n, counting from 1
+func (e *Editor) vimGotoLine(n int) {
+	e.cursorRow = min(max(n, 1), e.lines.len()) - 1
+	e.vimFirstNonBlank()
+}
+
+// vimYankLines copies count lines from the cursor line into the kill ring
+func (e *Editor) vimYankLines(count int) {
+	end := min(e.cursorRow+count, e.lines.len())
+	var sb strings.Builder
+	for row := e.cursorRow; row < end; row++ {
+		sb.WriteString(string(e.lines.line(row)))
+		sb.WriteRune('\n')
+	}
+	e.pushKill(sb.String())
+	e.vimLineYank = sb.String()
+}
+
+// vimDeleteLines deletes count lines from the cursor line, keeping them in
+// the kill ring
+func (e *Editor) vimDeleteLines(count int) {
+	e.vimYankLines(count)
+	end := min(e.cursorRow+count, e.lines.len())
+	e.lines.remove(e.cursorRow, end)
+	if e.lines.len() == 0 {
+		e.lines.insert(0, []rune{})
+	}
+	e.cursorRow = min(e.cursorRow, e.lines.len()-1)
+	e.vimFirstNonBlank()
+	e.MarkDirty()
+}
+
+// vimPut pastes the kill ring after the cursor, or before it when before
+// is set. Whole lines from dd or yy go below or above the cursor line.
+func (e *Editor) vimPut(before bool) {
+	text := e.currentKill()
+	if text == "" {
+		return
+	}
+	if text == e.vimLineYank {
+		var lines [][]rune
+		for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+			lines = append(lines, []rune(line))
+		}
+		row := e.cursorRow + 1
+		if before {
+			row = e.cursorRow
+		}
+		e.lines.insert(row, lines...)
+		e.cursorRow = row
+		e.vimFirstNonBlank()
+		e.MarkDirty()
+		return
+	}
+
+	if !before {
+		e.cursorCol = nextGrapheme(e.lines.line(e.cursorRow), e.cursorCol)
+	}
+	e.insertText(text)
+	e.moveLeft()
+}
+
+// HandleVimKey handles a key when vim keys are on. In insert mode only esc
+// is taken, back to normal mode. In normal and visual mode the vim commands
+// are handled and other typed characters ignored. Keys left for normal
+// handling return false, such as esc in normal mode, which saves and closes
+// the note.
+func (e *Editor) HandleVimKey(msg tea.KeyMsg) bool {
+	key := msg.String()
+	if e.vimMode == vimInsert {
+		if key != "esc" {
+			return false
+		}
+		e.vimMode = vimNormal
+		if e.cursorCol > 0 {
+			e.cursorCol--
+		}
+		e.updateDesiredCol()
+		return true
+	}
+
+	switch key {
+	case "left", "backspace":
+		key = "h"
+	case "right", " ":
+		key = "l"
+	case "up":
+		key = "k"
+	case "down", "enter":
+		key = "j"
+	case "home":
+		key = "0"
+	case "end":
+		key = "$"
+	case "delete":
+		key = "x"
+	case "tab":
+		return true // no snippets outside insert mode
+	case "esc":
+		switch {
+		case e.vimMode == vimVisual:
+			e.vimExitVisual()
+		case e.vimPending != 0 || e.vimCount > 0:
+			e.vimPending = 0
+			e.vimCount = 0
+		default:
+			return false
+		}
+		return true
+	}
+	if (msg.Type != tea.KeyRunes && len(key) > 1) || msg.Alt {
+		// Ctrl keys and the like keep working as usual
+		e.vimPending = 0
+		e.vimCount = 0
+		if e.vimMode == vimVisual {
+			e.vimExitVisual()
+		}
+		return false
+	}
+
+	if r := []rune(key); len(r) == 1 && unicode.IsDigit(r[0]) && (r[0] != '0' || e.vimCount > 0) {
+		e.vimCount = e.vimCount*10 + int(r[0]-'0')
+		return true
+	}
+
+	if e.vimPending != 0 {
+		pending := e.vimPending
+		e.vimPending = 0
+		count := e.vimTakeCount()
+		switch {
+		case pending == 'd' && key == "d":
+			e.vimDeleteLines(count)
+		case pending == 'y' && key == "y":
+			e.vimYankLines(count)
+		case pending == 'g' && key == "g":
+			e.vimGotoLine(count)
+			if e.vimMode == vimVisual {
+				e.vimUpdateVisual()
+			}
+		}
+		e.vimClampCursor()
+		return true
+	}
+
+	visual := e.vimMode == vimVisual
+	if e.hasSelection && !visual {
+		e.clearSelection()
+	}
+	hadCount := e.vimCount > 0
+	count := e.vimTakeCount()
+	switch key {
+	case "h":
+		for range count {
+			e.cursorCol = prevGrapheme(e.lines.line(e.cursorRow), e.cursorCol)
+		}
+		e.updateDesiredCol()
+	case "l":
+		line := e.lines.line(e.cursorRow)
+		for range count {
+			if next := nextGrapheme(line, e.cursorCol); next < len(line) {
+				e.cursorCol = next
+			}
+		}
+		e.updateDesiredCol()
+	case "j":
+		for range count {
+			e.moveDown()
+		}
+	case "k":
+		for range count {
+			e.moveUp()
+		}
+	case "w":
+		for range count {
+			e.jumpWordForward()
+		}
+	case "b":
+		for range count {
+			e.jumpWordBackward()
+		}
+	case "0":
+		e.moveToLineStart()
+	case "^":
+		e.vimFirstNonBlank()
+	case "$":
+		e.moveToLineEnd()
+	case "G":
+		if hadCount {
+			e.vimGotoLine(count)
+		} else {
+			e.vimGotoLine(e.lines.len())
+		}
+	case "g":
+		e.vimPending = 'g'
+		if hadCount {
+			e.vimCount = count
+		}
+	case "v":
+		if visual {
+			e.vimExitVisual()
+		} else {
+			e.vimMode = vimVisual
+			e.vimVisualStart = e.GetCursor()
+		}
+	}
+	if visual && e.vimMode == vimVisual {
+		e.vimClampCursor()
+		return e.vimVisualKey(key)
+	}
+
+	switch key {
+	case "i":
+		e.vimMode = vimInsert
+		return true
+	case "a":
+		if e.cursorRow < e.lines.len() {
+			e.cursorCol = nextGrapheme(e.lines.line(e.cursorRow), e.cursorCol)
+		}
+		e.vimMode = vimInsert
+		return true
+	case "I":
+		e.vimFirstNonBlank()
+		e.vimMode = vimInsert
+		return true
+	case "A":
+		e.moveToLineEnd()
+		e.vimMode = vimInsert
+		return true
+	case "o":
+		e.moveToLineEnd()
+		e.insertNewlineContinuingList()
+		e.vimMode = vimInsert
+		return true
+	case "O":
+		e.lines.insert(e.cursorRow, []rune{})
+		e.cursorCol = 0
+		e.updateDesiredCol()
+		e.ensureCursorVisible()
+		e.MarkDirty()
+		e.vimMode = vimInsert
+		return true
+	case "x":
+		if line := e.lines.line(e.cursorRow); len(line) > 0 {
+			end := e.cursorCol
+			for range count {
+				end = nextGrapheme(line, end)
+			}
+			e.pushKill(string(line[e.cursorCol:end]))
+			e.lines.set(e.cursorRow, slices.Delete(line, e.cursorCol, end))
+			e.MarkDirty()
+		}
+	case "d", "y":
+		e.vimPending = rune(key[0])
+		if hadCount {
+			e.vimCount = count
+		}
+	case "p", "P":
+		for range count {
+			e.vimPut(key == "P")
+		}
+	case "v":
+		if e.vimMode == vimVisual {
+			e.vimUpdateVisual()
+		}
+	}
+	if e.vimMode != vimInsert {
+		e.vimClampCursor()
+	}
+	return true
+}
+
+// vimVisualKey handles a key in visual mode after any motion moved the
+// cursor: d, x and y act on the selection, anything else extends it
+func (e *Editor) vimVisualKey(key string) bool {
+	switch key {
+	case "d", "x", "y":
+		start, end := e.vimVisualRange()
+		e.pushKill(e.textRange(start, end))
+		e.vimLineYank = ""
+		if key == "y" {
+			e.vimExitVisual()
+			e.SetCursor(start)
+		} else {
+			e.selectionAnchor = start
+			e.SetCursor(end)
+			e.deleteSelection()
+			e.vimMode = vimNormal
+		}
+		e.vimClampCursor()
+	default:
+		e.vimUpdateVisual()
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// wikilinkRegex matches [[Note Title]] links, optionally with the text shown
+// after a pipe: [[Note Title|shown text]]
+var wikilinkRegex = regexp.MustCompile(`\[\[([^\[\]\n]+)\]\]`)
+
+// wikilinkColumns returns the rune ranges of the [[links]] in line, brackets
+// included
+func wikilinkColumns(line []rune) [][2]int {
+	text := string(line)
+	if !strings.Contains(text, "[[") {
+		return nil
+	}
+	var ranges [][2]int
+	for _, match := range wikilinkRegex.FindAllStringIndex(text, -1) {
+		start := utf8.RuneCountInString(text[:match[0]])
+		ranges = append(ranges, [2]int{start, start + utf8.RuneCountInString(text[match[0]:match[1]])})
+	}
+	return ranges
+}
+
+// wikilinkTarget returns the title a link points to, given the text between
+// its brackets
+func wikilinkTarget(inner string) string {
+	target, _, _ := strings.Cut(inner, "|")
+	return strings.TrimSpace(target)
+}
+
+// WikilinkAtCursor returns the title of the [[link]] the cursor is on or just
+// after. With inside set the cursor must be within the brackets, so Enter at
+// either end of a link still breaks the line.
+func (e *Editor) WikilinkAtCursor(inside bool) (string, bool) {
+	line := e.lines.line(e.cursorRow)
+	for _, r := range wikilinkColumns(line) {
+		if inside && (e.cursorCol <= r[0] || e.cursorCol >= r[1]) {
+			continue
+		}
+		if e.cursorCol >= r[0] && e.cursorCol <= r[1] {
+			target := wikilinkTarget(string(line[r[0]+2 : r[1]-2]))
+			return target, target != ""
+		}
+	}
+	return "", false
+}
+
+// findNoteByTitle returns the note a link to title leads to: one with that
+// title, ignoring case and the characters file names leave out, or failing
+// that one with title among its frontmatter aliases
+func findNoteByTitle(root *note, title string) *note {
+	sanitized := strings.ReplaceAll(sanitizeTitle(title), "-", " ")
+	var byAlias *note
+	var walk func(dir *note) *note
+	walk = func(dir *note) *note {
+		for _, child := range dir.children {
+			if child.isDir {
+				if found := walk(child); found != nil {
+					return found
+				}
+				continue
+			}
+			if strings.EqualFold(child.title, title) || strings.EqualFold(child.title, sanitized) {
+				return child
+			}
+			if byAlias == nil {
+				for _, alias := range noteAliases(child.content) {
+					if strings.EqualFold(alias, title) {
+						byAlias = child
+						break
+					}
+				}
+			}
+		}
+		return nil
+	}
+	if found := walk(root); found != nil {
+		return found
+	}
+	return byAlias
+}
+
+// followWikilink opens the note the [[link]] at the cursor points to,
+// creating it next to the current note if there's none. It reports whether
+// there was a link to follow.
+func (m *model) followWikilink(inside bool) bool {
+	title, ok := m.editor.WikilinkAtCursor(inside)
+	if !ok {
+		return false
+	}
+	rootNote := m.currentNode
+	for rootNote.parent != nil {
+		rootNote = rootNote.parent
+	}
+	if target := findNoteByTitle(rootNote, title); target != nil {
+		if target.path != m.currentNotePath && m.leaveEditor() {
+			m.openNote(target)
+		}
+		return true
+	}
+	m.openPath(newNotePath(m.currentNode.path, title), "")
+	return true
+}